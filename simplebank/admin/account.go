@@ -0,0 +1,188 @@
+package admin
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	db "github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/db/sqlc"
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/util"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+type AccountResult struct {
+	DryRun  bool       `json:"dry_run"`
+	Account db.Account `json:"account"`
+}
+
+type CreateAccountParams struct {
+	Owner    string
+	Currency string
+	DryRun   bool
+}
+
+// CreateAccount opens an empty account. Money is put into it with Adjust.
+func (admin *Admin) CreateAccount(ctx context.Context, arg CreateAccountParams) (AccountResult, error) {
+	result := AccountResult{DryRun: arg.DryRun}
+
+	if !util.IsSupportedCurrency(arg.Currency) {
+		return result, fmt.Errorf("%w: %q is not a supported currency", ErrInvalidArgument, arg.Currency)
+	}
+
+	if _, err := admin.getUser(ctx, arg.Owner); err != nil {
+		return result, err
+	}
+
+	if arg.DryRun {
+		result.Account = db.Account{Owner: arg.Owner, Currency: arg.Currency}
+		return result, nil
+	}
+
+	var err error
+	result.Account, err = db.CreateAccountWithNumber(ctx, admin.store, admin.accountNumberCountry, db.CreateAccountParams{
+		Owner:    arg.Owner,
+		Currency: arg.Currency,
+		Balance:  0,
+	})
+	return result, err
+}
+
+type AdjustParams struct {
+	// Account is the id or account number of the account to correct
+	Account string
+	// ContraAccount takes the other side of the adjustment, e.g. a suspense
+	// account of the bank
+	ContraAccount string
+	// Type is db.LegTypeCredit to put money into Account and
+	// db.LegTypeDebit to take money out of it
+	Type   string
+	Amount int64
+	// Reason is recorded as the description of the journal
+	Reason string
+	DryRun bool
+}
+
+type AdjustResult struct {
+	DryRun bool `json:"dry_run"`
+	db.PostJournalResult
+}
+
+// Adjust corrects the balance of an account with an adjustment journal, so
+// the correction shows up in the ledger like any other movement of money.
+// A dry run returns the entries and balances the journal would produce.
+func (admin *Admin) Adjust(ctx context.Context, arg AdjustParams) (AdjustResult, error) {
+	result := AdjustResult{DryRun: arg.DryRun}
+
+	if arg.Type != db.LegTypeCredit && arg.Type != db.LegTypeDebit {
+		return result, fmt.Errorf("%w: unknown adjustment type %q", ErrInvalidArgument, arg.Type)
+	}
+	if arg.Amount <= 0 {
+		return result, fmt.Errorf("%w: amount must be positive", ErrInvalidArgument)
+	}
+	if arg.Reason == "" {
+		return result, fmt.Errorf("%w: reason is required", ErrInvalidArgument)
+	}
+
+	account, err := admin.getAccount(ctx, arg.Account)
+	if err != nil {
+		return result, err
+	}
+	contraAccount, err := admin.getAccount(ctx, arg.ContraAccount)
+	if err != nil {
+		return result, err
+	}
+
+	if account.ID == contraAccount.ID {
+		return result, fmt.Errorf("%w: the contra account must be another account", ErrInvalidArgument)
+	}
+	if account.Currency != contraAccount.Currency {
+		return result, fmt.Errorf("%w: %s vs %s", ErrCurrencyMismatch, account.Currency, contraAccount.Currency)
+	}
+
+	journal := db.AdjustmentJournal(account.ID, contraAccount.ID, arg.Type, arg.Amount, arg.Reason)
+
+	if arg.DryRun {
+		result.PostJournalResult = previewJournal(journal, account, contraAccount)
+		return result, nil
+	}
+
+	result.PostJournalResult, err = admin.store.PostJournal(ctx, journal)
+	return result, err
+}
+
+// previewJournal returns what posting the journal would return, without the
+// ids and times only the database can give
+func previewJournal(journal db.PostJournalParams, accounts ...db.Account) db.PostJournalResult {
+	slices.SortFunc(accounts, func(a, b db.Account) int { return cmp.Compare(a.ID, b.ID) })
+
+	result := db.PostJournalResult{
+		Journal:  db.Journal{Kind: journal.Kind, Description: journal.Description},
+		Entries:  make([]db.Entry, len(journal.Legs)),
+		Accounts: accounts,
+	}
+
+	for i, leg := range journal.Legs {
+		amount := leg.Amount
+		if leg.Type == db.LegTypeDebit {
+			amount = -amount
+		}
+		result.Entries[i] = db.Entry{AccountID: leg.AccountID, Amount: amount, LegType: leg.Type}
+
+		for j := range result.Accounts {
+			if result.Accounts[j].ID == leg.AccountID {
+				result.Accounts[j].Balance += amount
+			}
+		}
+	}
+
+	return result
+}
+
+type FreezeAccountParams struct {
+	// Account is an account id or account number
+	Account string
+	DryRun  bool
+}
+
+// FreezeAccount blocks transfers from and to an account until it is
+// unfrozen. Freezing a frozen account changes nothing.
+func (admin *Admin) FreezeAccount(ctx context.Context, arg FreezeAccountParams) (AccountResult, error) {
+	result := AccountResult{DryRun: arg.DryRun}
+
+	account, err := admin.getAccount(ctx, arg.Account)
+	if err != nil {
+		return result, err
+	}
+
+	if arg.DryRun {
+		if !account.FrozenAt.Valid {
+			account.FrozenAt = pgtype.Timestamptz{Time: time.Now(), Valid: true}
+		}
+		result.Account = account
+		return result, nil
+	}
+
+	result.Account, err = admin.store.FreezeAccount(ctx, account.ID)
+	return result, err
+}
+
+// UnfreezeAccount allows transfers from and to a frozen account again
+func (admin *Admin) UnfreezeAccount(ctx context.Context, arg FreezeAccountParams) (AccountResult, error) {
+	result := AccountResult{DryRun: arg.DryRun}
+
+	account, err := admin.getAccount(ctx, arg.Account)
+	if err != nil {
+		return result, err
+	}
+
+	if arg.DryRun {
+		account.FrozenAt = pgtype.Timestamptz{}
+		result.Account = account
+		return result, nil
+	}
+
+	result.Account, err = admin.store.UnfreezeAccount(ctx, account.ID)
+	return result, err
+}
@@ -0,0 +1,411 @@
+package admin
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	mockdb "github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/db/mock"
+	db "github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/db/sqlc"
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/util"
+	"github.com/golang/mock/gomock"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateAccount(t *testing.T) {
+	user := randomUser()
+	account := randomAccount(user.Username)
+	account.Balance = 0
+
+	testCases := []struct {
+		name       string
+		arg        CreateAccountParams
+		buildStubs func(store *mockdb.MockStore)
+		check      func(t *testing.T, result AccountResult, err error)
+	}{
+		{
+			name: "OK",
+			arg:  CreateAccountParams{Owner: user.Username, Currency: account.Currency},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				store.EXPECT().
+					CreateAccount(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg db.CreateAccountParams) (db.Account, error) {
+						require.Equal(t, user.Username, arg.Owner)
+						require.Equal(t, account.Currency, arg.Currency)
+						require.Zero(t, arg.Balance)
+						require.True(t, util.IsValidAccountNumber(arg.AccountNumber))
+						return account, nil
+					})
+			},
+			check: func(t *testing.T, result AccountResult, err error) {
+				require.NoError(t, err)
+				require.Equal(t, account, result.Account)
+			},
+		},
+		{
+			name: "AccountNumberCollision",
+			arg:  CreateAccountParams{Owner: user.Username, Currency: account.Currency},
+			buildStubs: func(store *mockdb.MockStore) {
+				collision := &pgconn.PgError{Code: "23505", ConstraintName: db.AccountNumberConstraint}
+
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				gomock.InOrder(
+					store.EXPECT().CreateAccount(gomock.Any(), gomock.Any()).Times(1).Return(db.Account{}, collision),
+					store.EXPECT().CreateAccount(gomock.Any(), gomock.Any()).Times(1).Return(account, nil),
+				)
+			},
+			check: func(t *testing.T, result AccountResult, err error) {
+				require.NoError(t, err)
+				require.Equal(t, account, result.Account)
+			},
+		},
+		{
+			name: "DryRun",
+			arg:  CreateAccountParams{Owner: user.Username, Currency: account.Currency, DryRun: true},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				store.EXPECT().CreateAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			check: func(t *testing.T, result AccountResult, err error) {
+				require.NoError(t, err)
+				require.True(t, result.DryRun)
+				require.Equal(t, user.Username, result.Account.Owner)
+				require.Equal(t, account.Currency, result.Account.Currency)
+			},
+		},
+		{
+			name: "OwnerNotFound",
+			arg:  CreateAccountParams{Owner: user.Username, Currency: account.Currency},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(db.User{}, pgx.ErrNoRows)
+				store.EXPECT().CreateAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			check: func(t *testing.T, _ AccountResult, err error) {
+				require.ErrorIs(t, err, ErrNotFound)
+			},
+		},
+		{
+			name: "UnsupportedCurrency",
+			arg:  CreateAccountParams{Owner: user.Username, Currency: "XYZ"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(0)
+			},
+			check: func(t *testing.T, _ AccountResult, err error) {
+				require.ErrorIs(t, err, ErrInvalidArgument)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			result, err := newTestAdmin(t, store).CreateAccount(context.Background(), tc.arg)
+			tc.check(t, result, err)
+		})
+	}
+}
+
+func TestAdjust(t *testing.T) {
+	account := randomAccount(util.RandomOwner())
+	contraAccount := randomAccount(util.RandomOwner())
+	contraAccount.ID = account.ID + 1
+	contraRef := strconv.FormatInt(contraAccount.ID, 10)
+	amount := util.RandomInt(1, 1000)
+
+	testCases := []struct {
+		name       string
+		arg        AdjustParams
+		buildStubs func(store *mockdb.MockStore)
+		check      func(t *testing.T, result AdjustResult, err error)
+	}{
+		{
+			name: "Credit",
+			arg: AdjustParams{
+				Account:       account.AccountNumber,
+				ContraAccount: contraRef,
+				Type:          db.LegTypeCredit,
+				Amount:        amount,
+				Reason:        "refund of a duplicate fee",
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Eq(account.AccountNumber)).Times(1).Return(account, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(contraAccount.ID)).Times(1).Return(contraAccount, nil)
+
+				journal := db.PostJournalParams{
+					Kind:        db.JournalKindAdjustment,
+					Description: "refund of a duplicate fee",
+					Legs: []db.JournalLeg{
+						{AccountID: account.ID, Type: db.LegTypeCredit, Amount: amount},
+						{AccountID: contraAccount.ID, Type: db.LegTypeDebit, Amount: amount},
+					},
+				}
+				store.EXPECT().
+					PostJournal(gomock.Any(), gomock.Eq(journal)).
+					Times(1).
+					Return(db.PostJournalResult{Journal: db.Journal{ID: 7, Kind: db.JournalKindAdjustment}}, nil)
+			},
+			check: func(t *testing.T, result AdjustResult, err error) {
+				require.NoError(t, err)
+				require.False(t, result.DryRun)
+				require.Equal(t, int64(7), result.Journal.ID)
+			},
+		},
+		{
+			name: "DebitDryRun",
+			arg: AdjustParams{
+				Account:       account.AccountNumber,
+				ContraAccount: contraRef,
+				Type:          db.LegTypeDebit,
+				Amount:        amount,
+				Reason:        "chargeback",
+				DryRun:        true,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Eq(account.AccountNumber)).Times(1).Return(account, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(contraAccount.ID)).Times(1).Return(contraAccount, nil)
+				store.EXPECT().PostJournal(gomock.Any(), gomock.Any()).Times(0)
+			},
+			check: func(t *testing.T, result AdjustResult, err error) {
+				require.NoError(t, err)
+				require.True(t, result.DryRun)
+				require.Equal(t, db.JournalKindAdjustment, result.Journal.Kind)
+				require.Equal(t, "chargeback", result.Journal.Description)
+
+				require.Len(t, result.Entries, 2)
+				require.Equal(t, -amount, result.Entries[0].Amount)
+				require.Equal(t, db.LegTypeDebit, result.Entries[0].LegType)
+				require.Equal(t, amount, result.Entries[1].Amount)
+
+				require.Len(t, result.Accounts, 2)
+				require.Equal(t, account.ID, result.Accounts[0].ID)
+				require.Equal(t, account.Balance-amount, result.Account(account.ID).Balance)
+				require.Equal(t, contraAccount.Balance+amount, result.Account(contraAccount.ID).Balance)
+			},
+		},
+		{
+			name: "CurrencyMismatch",
+			arg: AdjustParams{
+				Account:       account.AccountNumber,
+				ContraAccount: contraRef,
+				Type:          db.LegTypeCredit,
+				Amount:        amount,
+				Reason:        "correction",
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				contra := contraAccount
+				contra.Currency = util.EUR
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Eq(account.AccountNumber)).Times(1).Return(account, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(contraAccount.ID)).Times(1).Return(contra, nil)
+				store.EXPECT().PostJournal(gomock.Any(), gomock.Any()).Times(0)
+			},
+			check: func(t *testing.T, _ AdjustResult, err error) {
+				require.ErrorIs(t, err, ErrCurrencyMismatch)
+			},
+		},
+		{
+			name: "SameAccount",
+			arg: AdjustParams{
+				Account:       account.AccountNumber,
+				ContraAccount: account.AccountNumber,
+				Type:          db.LegTypeCredit,
+				Amount:        amount,
+				Reason:        "correction",
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Eq(account.AccountNumber)).Times(2).Return(account, nil)
+				store.EXPECT().PostJournal(gomock.Any(), gomock.Any()).Times(0)
+			},
+			check: func(t *testing.T, _ AdjustResult, err error) {
+				require.ErrorIs(t, err, ErrInvalidArgument)
+			},
+		},
+		{
+			name: "AccountNotFound",
+			arg: AdjustParams{
+				Account:       "999",
+				ContraAccount: contraRef,
+				Type:          db.LegTypeCredit,
+				Amount:        amount,
+				Reason:        "correction",
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(int64(999))).Times(1).Return(db.Account{}, pgx.ErrNoRows)
+				store.EXPECT().PostJournal(gomock.Any(), gomock.Any()).Times(0)
+			},
+			check: func(t *testing.T, _ AdjustResult, err error) {
+				require.ErrorIs(t, err, ErrNotFound)
+			},
+		},
+		{
+			name: "InvalidAccountRef",
+			arg: AdjustParams{
+				Account:       "not-an-account",
+				ContraAccount: contraRef,
+				Type:          db.LegTypeCredit,
+				Amount:        amount,
+				Reason:        "correction",
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			check: func(t *testing.T, _ AdjustResult, err error) {
+				require.ErrorIs(t, err, ErrInvalidArgument)
+			},
+		},
+		{
+			name: "MissingReason",
+			arg: AdjustParams{
+				Account:       account.AccountNumber,
+				ContraAccount: contraRef,
+				Type:          db.LegTypeCredit,
+				Amount:        amount,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Any()).Times(0)
+			},
+			check: func(t *testing.T, _ AdjustResult, err error) {
+				require.ErrorIs(t, err, ErrInvalidArgument)
+			},
+		},
+		{
+			name: "NonPositiveAmount",
+			arg: AdjustParams{
+				Account:       account.AccountNumber,
+				ContraAccount: contraRef,
+				Type:          db.LegTypeCredit,
+				Amount:        0,
+				Reason:        "correction",
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Any()).Times(0)
+			},
+			check: func(t *testing.T, _ AdjustResult, err error) {
+				require.ErrorIs(t, err, ErrInvalidArgument)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			result, err := newTestAdmin(t, store).Adjust(context.Background(), tc.arg)
+			tc.check(t, result, err)
+		})
+	}
+}
+
+func TestFreezeAccount(t *testing.T) {
+	account := randomAccount(util.RandomOwner())
+	frozenAccount := account
+	frozenAccount.FrozenAt = pgtype.Timestamptz{Time: time.Now().Add(-time.Hour), Valid: true}
+
+	testCases := []struct {
+		name       string
+		arg        FreezeAccountParams
+		buildStubs func(store *mockdb.MockStore)
+		check      func(t *testing.T, result AccountResult, err error)
+	}{
+		{
+			name: "OK",
+			arg:  FreezeAccountParams{Account: account.AccountNumber},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Eq(account.AccountNumber)).Times(1).Return(account, nil)
+				store.EXPECT().FreezeAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(frozenAccount, nil)
+			},
+			check: func(t *testing.T, result AccountResult, err error) {
+				require.NoError(t, err)
+				require.Equal(t, frozenAccount, result.Account)
+			},
+		},
+		{
+			name: "DryRun",
+			arg:  FreezeAccountParams{Account: account.AccountNumber, DryRun: true},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Eq(account.AccountNumber)).Times(1).Return(account, nil)
+				store.EXPECT().FreezeAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			check: func(t *testing.T, result AccountResult, err error) {
+				require.NoError(t, err)
+				require.True(t, result.DryRun)
+				require.True(t, result.Account.FrozenAt.Valid)
+				require.WithinDuration(t, time.Now(), result.Account.FrozenAt.Time, time.Second)
+			},
+		},
+		{
+			name: "DryRunAlreadyFrozen",
+			arg:  FreezeAccountParams{Account: account.AccountNumber, DryRun: true},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Eq(account.AccountNumber)).Times(1).Return(frozenAccount, nil)
+			},
+			check: func(t *testing.T, result AccountResult, err error) {
+				require.NoError(t, err)
+				require.Equal(t, frozenAccount.FrozenAt, result.Account.FrozenAt)
+			},
+		},
+		{
+			name: "AccountNotFound",
+			arg:  FreezeAccountParams{Account: account.AccountNumber},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Eq(account.AccountNumber)).Times(1).Return(db.Account{}, pgx.ErrNoRows)
+				store.EXPECT().FreezeAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			check: func(t *testing.T, _ AccountResult, err error) {
+				require.ErrorIs(t, err, ErrNotFound)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			result, err := newTestAdmin(t, store).FreezeAccount(context.Background(), tc.arg)
+			tc.check(t, result, err)
+		})
+	}
+}
+
+func TestUnfreezeAccount(t *testing.T) {
+	account := randomAccount(util.RandomOwner())
+	frozenAccount := account
+	frozenAccount.FrozenAt = pgtype.Timestamptz{Time: time.Now(), Valid: true}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(2).Return(frozenAccount, nil)
+	store.EXPECT().UnfreezeAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+
+	admin := newTestAdmin(t, store)
+	ref := strconv.FormatInt(account.ID, 10)
+
+	result, err := admin.UnfreezeAccount(context.Background(), FreezeAccountParams{Account: ref, DryRun: true})
+	require.NoError(t, err)
+	require.True(t, result.DryRun)
+	require.False(t, result.Account.FrozenAt.Valid)
+
+	result, err = admin.UnfreezeAccount(context.Background(), FreezeAccountParams{Account: ref})
+	require.NoError(t, err)
+	require.Equal(t, account, result.Account)
+}
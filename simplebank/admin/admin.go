@@ -0,0 +1,81 @@
+// Package admin implements the operations tasks of the admin command. Every
+// task goes through db.Store, so money only moves through journals, and can
+// run as a dry run that checks the task and reports what it would do
+// without writing anything.
+package admin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	db "github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/db/sqlc"
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/util"
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	ErrInvalidArgument  = errors.New("invalid argument")
+	ErrNotFound         = errors.New("not found")
+	ErrUserExists       = errors.New("user already exists")
+	ErrCurrencyMismatch = errors.New("accounts have different currencies")
+)
+
+// Admin runs the operations tasks against a store
+type Admin struct {
+	store                db.Store
+	passwordHasher       util.PasswordHasher
+	passwordPolicy       *util.PasswordPolicy
+	accountNumberCountry string
+}
+
+// New returns an Admin that hashes passwords and numbers accounts the way
+// the servers configured by config do
+func New(config util.Config, store db.Store) (*Admin, error) {
+	passwordHasher, err := util.NewConfiguredPasswordHasher(config)
+	if err != nil {
+		return nil, err
+	}
+
+	passwordPolicy, err := util.NewConfiguredPasswordPolicy(config)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Admin{
+		store:                store,
+		passwordHasher:       passwordHasher,
+		passwordPolicy:       passwordPolicy,
+		accountNumberCountry: config.AccountNumberCountry,
+	}, nil
+}
+
+// getAccount looks up an account by its account number or its numeric id
+func (admin *Admin) getAccount(ctx context.Context, ref string) (db.Account, error) {
+	var account db.Account
+	var err error
+	if util.IsValidAccountNumber(ref) {
+		account, err = admin.store.GetAccountByNumber(ctx, ref)
+	} else {
+		id, parseErr := strconv.ParseInt(ref, 10, 64)
+		if parseErr != nil || id <= 0 {
+			return account, fmt.Errorf("%w: %q is neither an account id nor an account number", ErrInvalidArgument, ref)
+		}
+		account, err = admin.store.GetAccount(ctx, id)
+	}
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return account, fmt.Errorf("%w: account %s", ErrNotFound, ref)
+	}
+	return account, err
+}
+
+// getUser looks up a user by username
+func (admin *Admin) getUser(ctx context.Context, username string) (db.User, error) {
+	user, err := admin.store.GetUser(ctx, username)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return user, fmt.Errorf("%w: user %s", ErrNotFound, username)
+	}
+	return user, err
+}
@@ -0,0 +1,83 @@
+package admin
+
+import (
+	"context"
+	"fmt"
+
+	db "github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/db/sqlc"
+)
+
+// maxLedgerLimit bounds how many entries Ledger returns at once
+const maxLedgerLimit = 1000
+
+type LedgerParams struct {
+	// Account is an account id or account number
+	Account string
+	Limit   int32
+	Offset  int32
+}
+
+// LedgerEntry is an entry with the journal that posted it. Entries posted
+// outside of a journal have no journal kind.
+type LedgerEntry struct {
+	db.Entry
+	JournalKind        string `json:"journal_kind,omitempty"`
+	JournalDescription string `json:"journal_description,omitempty"`
+}
+
+type Ledger struct {
+	Account db.Account    `json:"account"`
+	Entries []LedgerEntry `json:"entries"`
+}
+
+// Ledger returns an account with a page of its entries, oldest first
+func (admin *Admin) Ledger(ctx context.Context, arg LedgerParams) (Ledger, error) {
+	var ledger Ledger
+
+	if arg.Limit < 1 || arg.Limit > maxLedgerLimit {
+		return ledger, fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidArgument, maxLedgerLimit)
+	}
+	if arg.Offset < 0 {
+		return ledger, fmt.Errorf("%w: offset must not be negative", ErrInvalidArgument)
+	}
+
+	account, err := admin.getAccount(ctx, arg.Account)
+	if err != nil {
+		return ledger, err
+	}
+
+	entries, err := admin.store.ListEntriesForAccount(ctx, db.ListEntriesForAccountParams{
+		AccountID: account.ID,
+		Limit:     arg.Limit,
+		Offset:    arg.Offset,
+	})
+	if err != nil {
+		return ledger, err
+	}
+
+	ledger.Account = account
+	ledger.Entries = make([]LedgerEntry, len(entries))
+
+	// entries of the same journal share its lookup
+	journals := make(map[int64]db.Journal)
+	for i, entry := range entries {
+		ledger.Entries[i].Entry = entry
+		if !entry.JournalID.Valid {
+			continue
+		}
+
+		journal, ok := journals[entry.JournalID.Int64]
+		if !ok {
+			journal, err = admin.store.GetJournal(ctx, entry.JournalID.Int64)
+			if err != nil {
+				return ledger, err
+			}
+			journals[journal.ID] = journal
+		}
+
+		ledger.Entries[i].JournalKind = journal.Kind
+		ledger.Entries[i].JournalDescription = journal.Description
+	}
+
+	return ledger, nil
+}
@@ -0,0 +1,72 @@
+package admin
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	mockdb "github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/db/mock"
+	db "github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/db/sqlc"
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/util"
+	"github.com/golang/mock/gomock"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLedger(t *testing.T) {
+	account := randomAccount(util.RandomOwner())
+	transfer := db.Journal{ID: 3, Kind: db.JournalKindTransfer, Description: "transfer 3"}
+	adjustment := db.Journal{ID: 4, Kind: db.JournalKindAdjustment, Description: "chargeback"}
+
+	entries := []db.Entry{
+		{ID: 1, AccountID: account.ID, Amount: 100, CreatedAt: time.Now()},
+		{ID: 2, AccountID: account.ID, Amount: -30, JournalID: pgtype.Int8{Int64: transfer.ID, Valid: true}},
+		{ID: 3, AccountID: account.ID, Amount: -5, JournalID: pgtype.Int8{Int64: transfer.ID, Valid: true}},
+		{ID: 4, AccountID: account.ID, Amount: -10, JournalID: pgtype.Int8{Int64: adjustment.ID, Valid: true}},
+	}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Eq(account.AccountNumber)).Times(1).Return(account, nil)
+	store.EXPECT().
+		ListEntriesForAccount(gomock.Any(), gomock.Eq(db.ListEntriesForAccountParams{AccountID: account.ID, Limit: 10, Offset: 5})).
+		Times(1).
+		Return(entries, nil)
+	// each journal is looked up once
+	store.EXPECT().GetJournal(gomock.Any(), gomock.Eq(transfer.ID)).Times(1).Return(transfer, nil)
+	store.EXPECT().GetJournal(gomock.Any(), gomock.Eq(adjustment.ID)).Times(1).Return(adjustment, nil)
+
+	ledger, err := newTestAdmin(t, store).Ledger(context.Background(), LedgerParams{Account: account.AccountNumber, Limit: 10, Offset: 5})
+	require.NoError(t, err)
+	require.Equal(t, account, ledger.Account)
+	require.Len(t, ledger.Entries, len(entries))
+
+	for i, entry := range ledger.Entries {
+		require.Equal(t, entries[i], entry.Entry)
+	}
+	require.Empty(t, ledger.Entries[0].JournalKind)
+	require.Equal(t, db.JournalKindTransfer, ledger.Entries[1].JournalKind)
+	require.Equal(t, db.JournalKindTransfer, ledger.Entries[2].JournalKind)
+	require.Equal(t, db.JournalKindAdjustment, ledger.Entries[3].JournalKind)
+	require.Equal(t, "chargeback", ledger.Entries[3].JournalDescription)
+}
+
+func TestLedgerInvalidPage(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+	admin := newTestAdmin(t, store)
+
+	for _, arg := range []LedgerParams{
+		{Account: "1", Limit: 0},
+		{Account: "1", Limit: maxLedgerLimit + 1},
+		{Account: "1", Limit: 10, Offset: -1},
+	} {
+		_, err := admin.Ledger(context.Background(), arg)
+		require.ErrorIs(t, err, ErrInvalidArgument)
+	}
+}
@@ -0,0 +1,39 @@
+package admin
+
+import (
+	"testing"
+
+	db "github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/db/sqlc"
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestAdmin(t *testing.T, store db.Store) *Admin {
+	config := util.Config{
+		AccountNumberCountry: util.DefaultAccountNumberCountry,
+	}
+
+	admin, err := New(config, store)
+	require.NoError(t, err)
+
+	return admin
+}
+
+func randomUser() db.User {
+	return db.User{
+		Username: util.RandomOwner(),
+		FullName: util.RandomName(),
+		Email:    util.RandomEmail(),
+		Role:     util.DepositorRole,
+	}
+}
+
+func randomAccount(username string) db.Account {
+	return db.Account{
+		ID:            util.RandomInt(1, 1000),
+		Owner:         username,
+		Balance:       util.RandomMoney(),
+		Currency:      util.USD,
+		AccountNumber: util.RandomAccountNumber(),
+	}
+}
@@ -0,0 +1,207 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/mail"
+	"time"
+
+	db "github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/db/sqlc"
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/util"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// generatedPasswordBytes is the entropy of generated passwords, which are
+// hex encoded and so twice as long
+const generatedPasswordBytes = 16
+
+// User is a user without its password hash and TOTP secret
+type User struct {
+	Username          string    `json:"username"`
+	FullName          string    `json:"full_name"`
+	Email             string    `json:"email"`
+	IsEmailVerified   bool      `json:"is_email_verified"`
+	Role              string    `json:"role"`
+	PasswordChangedAt time.Time `json:"password_changed_at"`
+	CreatedAt         time.Time `json:"created_at"`
+}
+
+func newUser(user db.User) User {
+	return User{
+		Username:          user.Username,
+		FullName:          user.FullName,
+		Email:             user.Email,
+		IsEmailVerified:   user.IsEmailVerified,
+		Role:              user.Role,
+		PasswordChangedAt: user.PasswordChangedAt,
+		CreatedAt:         user.CreatedAt,
+	}
+}
+
+type UserResult struct {
+	DryRun bool `json:"dry_run"`
+	User   User `json:"user"`
+	// GeneratedPassword is only set when the password was generated, as it
+	// cannot be shown again
+	GeneratedPassword string `json:"generated_password,omitempty"`
+}
+
+type CreateUserParams struct {
+	Username string
+	FullName string
+	Email    string
+	// Role is util.DepositorRole when empty
+	Role string
+	// Password is generated when empty
+	Password string
+	DryRun   bool
+}
+
+// CreateUser creates a user with the given role. Its email address is left
+// unverified, as it is for users signing up themselves.
+func (admin *Admin) CreateUser(ctx context.Context, arg CreateUserParams) (UserResult, error) {
+	result := UserResult{DryRun: arg.DryRun}
+
+	if arg.Role == "" {
+		arg.Role = util.DepositorRole
+	}
+	if err := validateUser(arg); err != nil {
+		return result, err
+	}
+	if err := admin.checkPassword(arg.Password); err != nil {
+		return result, err
+	}
+
+	_, err := admin.store.GetUser(ctx, arg.Username)
+	if err == nil {
+		return result, fmt.Errorf("%w: %s", ErrUserExists, arg.Username)
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return result, err
+	}
+
+	if arg.DryRun {
+		result.User = User{Username: arg.Username, FullName: arg.FullName, Email: arg.Email, Role: arg.Role}
+		return result, nil
+	}
+
+	hashedPassword, generated, err := admin.newPassword(arg.Password)
+	if err != nil {
+		return result, err
+	}
+
+	user, err := admin.store.CreateUserTx(ctx, db.CreateUserTxParams{
+		CreateUserParams: db.CreateUserParams{
+			Username:       arg.Username,
+			HashedPassword: hashedPassword,
+			FullName:       arg.FullName,
+			Email:          arg.Email,
+		},
+		Role: arg.Role,
+	})
+	if err != nil {
+		return result, err
+	}
+
+	result.User = newUser(user)
+	result.GeneratedPassword = generated
+	return result, nil
+}
+
+func validateUser(arg CreateUserParams) error {
+	if arg.Username == "" {
+		return fmt.Errorf("%w: username is required", ErrInvalidArgument)
+	}
+	for _, r := range arg.Username {
+		if !('a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' || '0' <= r && r <= '9') {
+			return fmt.Errorf("%w: username must contain only letters and digits", ErrInvalidArgument)
+		}
+	}
+	if arg.FullName == "" {
+		return fmt.Errorf("%w: full name is required", ErrInvalidArgument)
+	}
+	if address, err := mail.ParseAddress(arg.Email); err != nil || address.Address != arg.Email {
+		return fmt.Errorf("%w: %q is not a valid email address", ErrInvalidArgument, arg.Email)
+	}
+	if arg.Role != util.DepositorRole && arg.Role != util.AdminRole {
+		return fmt.Errorf("%w: unknown role %q", ErrInvalidArgument, arg.Role)
+	}
+	return nil
+}
+
+type ResetPasswordParams struct {
+	Username string
+	// Password is generated when empty
+	Password string
+	DryRun   bool
+}
+
+// ResetPassword sets a new password. Bumping password_changed_at revokes
+// every token and password reset issued before.
+func (admin *Admin) ResetPassword(ctx context.Context, arg ResetPasswordParams) (UserResult, error) {
+	result := UserResult{DryRun: arg.DryRun}
+
+	if err := admin.checkPassword(arg.Password); err != nil {
+		return result, err
+	}
+
+	user, err := admin.getUser(ctx, arg.Username)
+	if err != nil {
+		return result, err
+	}
+
+	if arg.DryRun {
+		result.User = newUser(user)
+		return result, nil
+	}
+
+	hashedPassword, generated, err := admin.newPassword(arg.Password)
+	if err != nil {
+		return result, err
+	}
+
+	user, err = admin.store.UpdateUser(ctx, db.UpdateUserParams{
+		Username:          user.Username,
+		HashedPassword:    pgtype.Text{String: hashedPassword, Valid: true},
+		PasswordChangedAt: pgtype.Timestamptz{Time: time.Now(), Valid: true},
+	})
+	if err != nil {
+		return result, err
+	}
+
+	result.User = newUser(user)
+	result.GeneratedPassword = generated
+	return result, nil
+}
+
+// checkPassword checks a password given by the operator against the password
+// policy. An empty password is fine, as one is generated instead.
+func (admin *Admin) checkPassword(password string) error {
+	if password == "" {
+		return nil
+	}
+	if err := admin.passwordPolicy.Check(password); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidArgument, err)
+	}
+	return nil
+}
+
+// newPassword hashes a checked password. When password is empty it
+// generates one, which it returns with the hash.
+func (admin *Admin) newPassword(password string) (hashedPassword, generated string, err error) {
+	if password == "" {
+		generated, err = util.GenerateSecret(generatedPasswordBytes)
+		if err != nil {
+			return "", "", err
+		}
+		password = generated
+	}
+
+	hashedPassword, err = admin.passwordHasher.Hash(password)
+	if err != nil {
+		return "", "", err
+	}
+	return hashedPassword, generated, nil
+}
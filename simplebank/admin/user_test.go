@@ -0,0 +1,259 @@
+package admin
+
+import (
+	"context"
+	"testing"
+
+	mockdb "github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/db/mock"
+	db "github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/db/sqlc"
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/util"
+	"github.com/golang/mock/gomock"
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateUser(t *testing.T) {
+	user := randomUser()
+	password := util.RandomString(12)
+
+	// expectCreate creates the user with the role and keeps the password hash
+	// it was given
+	var hashedPassword string
+	expectCreate := func(store *mockdb.MockStore, role string) {
+		store.EXPECT().
+			CreateUserTx(gomock.Any(), gomock.Any()).
+			Times(1).
+			DoAndReturn(func(_ context.Context, arg db.CreateUserTxParams) (db.User, error) {
+				require.Equal(t, user.Username, arg.Username)
+				require.Equal(t, user.Email, arg.Email)
+				require.Equal(t, role, arg.Role)
+				hashedPassword = arg.HashedPassword
+
+				created := user
+				created.Role = role
+				return created, nil
+			})
+	}
+
+	testCases := []struct {
+		name       string
+		arg        CreateUserParams
+		buildStubs func(store *mockdb.MockStore)
+		check      func(t *testing.T, result UserResult, err error)
+	}{
+		{
+			name: "OK",
+			arg:  CreateUserParams{Username: user.Username, FullName: user.FullName, Email: user.Email, Password: password},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(db.User{}, pgx.ErrNoRows)
+				expectCreate(store, util.DepositorRole)
+			},
+			check: func(t *testing.T, result UserResult, err error) {
+				require.NoError(t, err)
+				require.False(t, result.DryRun)
+				require.Equal(t, newUser(user), result.User)
+				require.Empty(t, result.GeneratedPassword)
+				require.NoError(t, util.CheckPassword(password, hashedPassword))
+			},
+		},
+		{
+			name: "GeneratedPassword",
+			arg:  CreateUserParams{Username: user.Username, FullName: user.FullName, Email: user.Email},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(db.User{}, pgx.ErrNoRows)
+				expectCreate(store, util.DepositorRole)
+			},
+			check: func(t *testing.T, result UserResult, err error) {
+				require.NoError(t, err)
+				require.Len(t, result.GeneratedPassword, 2*generatedPasswordBytes)
+				require.NoError(t, util.CheckPassword(result.GeneratedPassword, hashedPassword))
+			},
+		},
+		{
+			name: "AdminRole",
+			arg:  CreateUserParams{Username: user.Username, FullName: user.FullName, Email: user.Email, Role: util.AdminRole, Password: password},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(db.User{}, pgx.ErrNoRows)
+				expectCreate(store, util.AdminRole)
+			},
+			check: func(t *testing.T, result UserResult, err error) {
+				require.NoError(t, err)
+				require.Equal(t, util.AdminRole, result.User.Role)
+			},
+		},
+		{
+			name: "DryRun",
+			arg:  CreateUserParams{Username: user.Username, FullName: user.FullName, Email: user.Email, DryRun: true},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(db.User{}, pgx.ErrNoRows)
+				store.EXPECT().CreateUserTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			check: func(t *testing.T, result UserResult, err error) {
+				require.NoError(t, err)
+				require.True(t, result.DryRun)
+				require.Equal(t, user.Username, result.User.Username)
+				require.Equal(t, util.DepositorRole, result.User.Role)
+				require.Empty(t, result.GeneratedPassword)
+			},
+		},
+		{
+			name: "UserExists",
+			arg:  CreateUserParams{Username: user.Username, FullName: user.FullName, Email: user.Email},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				store.EXPECT().CreateUserTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			check: func(t *testing.T, _ UserResult, err error) {
+				require.ErrorIs(t, err, ErrUserExists)
+			},
+		},
+		{
+			name: "InvalidUsername",
+			arg:  CreateUserParams{Username: "not-alphanum", FullName: user.FullName, Email: user.Email},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(0)
+			},
+			check: func(t *testing.T, _ UserResult, err error) {
+				require.ErrorIs(t, err, ErrInvalidArgument)
+			},
+		},
+		{
+			name: "InvalidEmail",
+			arg:  CreateUserParams{Username: user.Username, FullName: user.FullName, Email: "invalid-email"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(0)
+			},
+			check: func(t *testing.T, _ UserResult, err error) {
+				require.ErrorIs(t, err, ErrInvalidArgument)
+			},
+		},
+		{
+			name: "UnknownRole",
+			arg:  CreateUserParams{Username: user.Username, FullName: user.FullName, Email: user.Email, Role: "root"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(0)
+			},
+			check: func(t *testing.T, _ UserResult, err error) {
+				require.ErrorIs(t, err, ErrInvalidArgument)
+			},
+		},
+		{
+			name: "BreachedPassword",
+			arg:  CreateUserParams{Username: user.Username, FullName: user.FullName, Email: user.Email, Password: "password1", DryRun: true},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(0)
+			},
+			check: func(t *testing.T, _ UserResult, err error) {
+				require.ErrorIs(t, err, ErrInvalidArgument)
+				require.ErrorIs(t, err, util.ErrPasswordBreached)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			result, err := newTestAdmin(t, store).CreateUser(context.Background(), tc.arg)
+			tc.check(t, result, err)
+		})
+	}
+}
+
+func TestResetPassword(t *testing.T) {
+	user := randomUser()
+	password := util.RandomString(12)
+
+	testCases := []struct {
+		name       string
+		arg        ResetPasswordParams
+		buildStubs func(store *mockdb.MockStore)
+		check      func(t *testing.T, result UserResult, err error)
+	}{
+		{
+			name: "OK",
+			arg:  ResetPasswordParams{Username: user.Username, Password: password},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				store.EXPECT().
+					UpdateUser(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg db.UpdateUserParams) (db.User, error) {
+						require.Equal(t, user.Username, arg.Username)
+						require.NoError(t, util.CheckPassword(password, arg.HashedPassword.String))
+						require.True(t, arg.PasswordChangedAt.Valid)
+						require.False(t, arg.Email.Valid)
+						return user, nil
+					})
+			},
+			check: func(t *testing.T, result UserResult, err error) {
+				require.NoError(t, err)
+				require.Equal(t, newUser(user), result.User)
+				require.Empty(t, result.GeneratedPassword)
+			},
+		},
+		{
+			name: "GeneratedPassword",
+			arg:  ResetPasswordParams{Username: user.Username},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				store.EXPECT().UpdateUser(gomock.Any(), gomock.Any()).Times(1).Return(user, nil)
+			},
+			check: func(t *testing.T, result UserResult, err error) {
+				require.NoError(t, err)
+				require.Len(t, result.GeneratedPassword, 2*generatedPasswordBytes)
+			},
+		},
+		{
+			name: "DryRun",
+			arg:  ResetPasswordParams{Username: user.Username, DryRun: true},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				store.EXPECT().UpdateUser(gomock.Any(), gomock.Any()).Times(0)
+			},
+			check: func(t *testing.T, result UserResult, err error) {
+				require.NoError(t, err)
+				require.True(t, result.DryRun)
+				require.Empty(t, result.GeneratedPassword)
+			},
+		},
+		{
+			name: "UserNotFound",
+			arg:  ResetPasswordParams{Username: user.Username},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(db.User{}, pgx.ErrNoRows)
+				store.EXPECT().UpdateUser(gomock.Any(), gomock.Any()).Times(0)
+			},
+			check: func(t *testing.T, _ UserResult, err error) {
+				require.ErrorIs(t, err, ErrNotFound)
+			},
+		},
+		{
+			name: "TooShortPassword",
+			arg:  ResetPasswordParams{Username: user.Username, Password: "short"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(0)
+			},
+			check: func(t *testing.T, _ UserResult, err error) {
+				require.ErrorIs(t, err, ErrInvalidArgument)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			result, err := newTestAdmin(t, store).ResetPassword(context.Background(), tc.arg)
+			tc.check(t, result, err)
+		})
+	}
+}
@@ -1,14 +1,24 @@
 package api
 
 import (
+	"context"
 	"net/http"
+	"strconv"
+	"time"
 
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/apperr"
 	db "github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/db/sqlc"
 	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/middleware"
 	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/token"
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/util"
 	"github.com/gin-gonic/gin"
 	"github.com/jackc/pgx/v5"
-	"github.com/jackc/pgx/v5/pgconn"
+)
+
+var (
+	errAccountNotFound = apperr.New(http.StatusNotFound, "account_not_found", "account not found")
+	errAccountNotOwned = apperr.New(http.StatusForbidden, "account_not_owned", "account doesn't belong to the authenticated user")
+	errAccountFrozen   = apperr.New(http.StatusConflict, "account_frozen", "account is frozen")
 )
 
 type CreateAccountRequest struct {
@@ -18,58 +28,40 @@ type CreateAccountRequest struct {
 func (server *Server) createAccount(ctx *gin.Context) {
 	var req CreateAccountRequest
 	if err := ctx.ShouldBindJSON(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, errorsResponse(err))
+		ctx.Error(apperr.FromBinding(err))
 		return
 	}
 
 	authPayload := ctx.MustGet(middleware.AuthorizationPayloadKey).(*token.Payload)
 
-	arg := db.CreateAccountParams{
+	account, err := db.CreateAccountWithNumber(ctx, server.store, server.config.AccountNumberCountry, db.CreateAccountParams{
 		Owner:    authPayload.Username,
 		Currency: req.Currency,
 		Balance:  0,
-	}
-
-	account, err := server.store.CreateAccount(ctx, arg)
+	})
 	if err != nil {
-		if pqErr, ok := err.(*pgconn.PgError); ok {
-			switch pqErr.Code {
-			case "23505", "23503":
-				ctx.JSON(http.StatusForbidden, errorsResponse(err))
-				return
-			}
-		}
-		ctx.JSON(http.StatusInternalServerError, errorsResponse(err))
+		ctx.Error(err)
 		return
 	}
 
 	ctx.JSON(http.StatusOK, account)
 }
 
+// GetAccountRequest identifies an account by its numeric id or its account number
 type GetAccountRequest struct {
-	ID int64 `uri:"id" binding:"required,min=1"`
+	ID string `uri:"id" binding:"required,account_ref"`
 }
 
 func (server *Server) getAccount(ctx *gin.Context) {
 	var req GetAccountRequest
 	if err := ctx.ShouldBindUri(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, errorsResponse(err))
+		ctx.Error(apperr.FromBinding(err))
 		return
 	}
 
-	account, err := server.store.GetAccount(ctx, req.ID)
+	account, err := server.getOwnAccount(ctx, req.ID)
 	if err != nil {
-		if err == pgx.ErrNoRows {
-			ctx.JSON(http.StatusNotFound, errorsResponse(err))
-			return
-		}
-		ctx.JSON(http.StatusInternalServerError, errorsResponse(err))
-		return
-	}
-
-	authPayload := ctx.MustGet(middleware.AuthorizationPayloadKey).(*token.Payload)
-	if account.Owner != authPayload.Username {
-		ctx.JSON(http.StatusUnauthorized, errorsResponse(err))
+		ctx.Error(err)
 		return
 	}
 
@@ -84,7 +76,7 @@ type ListAccountsRequest struct {
 func (server *Server) listAccounts(ctx *gin.Context) {
 	var req ListAccountsRequest
 	if err := ctx.ShouldBindQuery(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, errorsResponse(err))
+		ctx.Error(apperr.FromBinding(err))
 		return
 	}
 
@@ -98,15 +90,147 @@ func (server *Server) listAccounts(ctx *gin.Context) {
 
 	accounts, err := server.store.ListAccountsForUser(ctx, arg)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, errorsResponse(err))
+		ctx.Error(err)
 		return
 	}
 
 	ctx.JSON(http.StatusOK, accounts)
 }
 
+// GetAccountBalanceRequest selects the instant of the balance; it defaults to now
+type GetAccountBalanceRequest struct {
+	At time.Time `form:"at" time_format:"2006-01-02T15:04:05Z07:00"`
+}
+
+type AccountBalanceResponse struct {
+	AccountID     int64     `json:"account_id"`
+	AccountNumber string    `json:"account_number"`
+	Currency      string    `json:"currency"`
+	At            time.Time `json:"at"`
+	Balance       int64     `json:"balance"`
+}
+
+func (server *Server) getAccountBalance(ctx *gin.Context) {
+	var uri GetAccountRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.Error(apperr.FromBinding(err))
+		return
+	}
+
+	var req GetAccountBalanceRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.Error(apperr.FromBinding(err))
+		return
+	}
+
+	at := req.At
+	if at.IsZero() {
+		at = time.Now()
+	}
+
+	account, err := server.getOwnAccount(ctx, uri.ID)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	balance, err := server.store.GetAccountBalanceAt(ctx, db.GetAccountBalanceAtParams{
+		AccountID: account.ID,
+		At:        at,
+	})
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	rsp := AccountBalanceResponse{
+		AccountID:     account.ID,
+		AccountNumber: account.AccountNumber,
+		Currency:      account.Currency,
+		At:            at,
+		Balance:       balance,
+	}
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+// getOwnAccount looks up an account of the authenticated user by a reference
+// accepted by the account_ref validator
+func (server *Server) getOwnAccount(ctx *gin.Context, ref string) (db.Account, error) {
+	account, err := server.getAccountByRef(ctx, ref)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return account, errAccountNotFound
+		}
+		return account, err
+	}
+
+	authPayload := ctx.MustGet(middleware.AuthorizationPayloadKey).(*token.Payload)
+	if account.Owner != authPayload.Username {
+		return account, errAccountNotOwned
+	}
+	return account, nil
+}
+
+// getAccountByRef looks up an account by a reference accepted by the account_ref validator
+func (server *Server) getAccountByRef(ctx context.Context, ref string) (db.Account, error) {
+	if util.IsValidAccountNumber(ref) {
+		return server.store.GetAccountByNumber(ctx, ref)
+	}
+
+	id, err := strconv.ParseInt(ref, 10, 64)
+	if err != nil {
+		return db.Account{}, err
+	}
+	return server.store.GetAccount(ctx, id)
+}
+
+var accountOperations = []apiOperation{
+	{
+		method:   http.MethodPost,
+		path:     "/accounts",
+		tag:      "accounts",
+		summary:  "Open an account",
+		auth:     authTokenOrAPIKey,
+		scopes:   []string{token.ScopeAccountsWrite},
+		body:     CreateAccountRequest{},
+		response: db.Account{},
+	},
+	{
+		method:   http.MethodGet,
+		path:     "/accounts/:id",
+		tag:      "accounts",
+		summary:  "Get an account by id or account number",
+		auth:     authTokenOrAPIKey,
+		scopes:   []string{token.ScopeAccountsRead},
+		uri:      GetAccountRequest{},
+		response: db.Account{},
+	},
+	{
+		method:   http.MethodGet,
+		path:     "/accounts/:id/balance",
+		tag:      "accounts",
+		summary:  "Get the balance of an account at an instant",
+		auth:     authTokenOrAPIKey,
+		scopes:   []string{token.ScopeAccountsRead},
+		uri:      GetAccountRequest{},
+		query:    GetAccountBalanceRequest{},
+		response: AccountBalanceResponse{},
+	},
+	{
+		method:   http.MethodGet,
+		path:     "/accounts",
+		tag:      "accounts",
+		summary:  "List the accounts of the authenticated user",
+		auth:     authTokenOrAPIKey,
+		scopes:   []string{token.ScopeAccountsRead},
+		query:    ListAccountsRequest{},
+		response: []db.Account{},
+	},
+}
+
 func (server *Server) setupAccountRoutes(router gin.IRoutes) {
-	router.POST("/accounts", server.createAccount)
-	router.GET("/accounts/:id", server.getAccount)
-	router.GET("/accounts", server.listAccounts)
+	router.POST("/accounts", middleware.RequireScopes(token.ScopeAccountsWrite), server.createAccount)
+	router.GET("/accounts/:id", middleware.RequireScopes(token.ScopeAccountsRead), server.getAccount)
+	router.GET("/accounts/:id/balance", middleware.RequireScopes(token.ScopeAccountsRead), server.getAccountBalance)
+	router.GET("/accounts", middleware.RequireScopes(token.ScopeAccountsRead), server.listAccounts)
 }
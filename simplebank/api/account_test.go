@@ -7,9 +7,11 @@ import (
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
 	"testing"
 	"time"
 
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/apperr"
 	mockdb "github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/db/mock"
 	db "github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/db/sqlc"
 	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/middleware"
@@ -49,7 +51,7 @@ func TestCreateAccountAPI(t *testing.T) {
 				}
 
 				store.EXPECT().
-					CreateAccount(gomock.Any(), gomock.Eq(arg)).
+					CreateAccount(gomock.Any(), EqCreateAccountParams(arg)).
 					Times(1).
 					Return(account, nil)
 			},
@@ -58,6 +60,57 @@ func TestCreateAccountAPI(t *testing.T) {
 				requireBodyMatchAccount(t, recorder, account)
 			},
 		},
+		{
+			name: "AccountNumberCollision",
+			body: gin.H{
+				"currency": account.Currency,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				middleware.AddTestAuthorization(t, request, tokenMaker, middleware.AuthorizationTypeBearer, user.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.CreateAccountParams{
+					Owner:    account.Owner,
+					Currency: account.Currency,
+					Balance:  0,
+				}
+
+				collision := &pgconn.PgError{Code: "23505", ConstraintName: db.AccountNumberConstraint}
+				gomock.InOrder(
+					store.EXPECT().
+						CreateAccount(gomock.Any(), EqCreateAccountParams(arg)).
+						Times(1).
+						Return(db.Account{}, collision),
+					store.EXPECT().
+						CreateAccount(gomock.Any(), EqCreateAccountParams(arg)).
+						Times(1).
+						Return(account, nil),
+				)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				requireBodyMatchAccount(t, recorder, account)
+			},
+		},
+		{
+			name: "AccountNumberCollisionExhausted",
+			body: gin.H{
+				"currency": account.Currency,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				middleware.AddTestAuthorization(t, request, tokenMaker, middleware.AuthorizationTypeBearer, user.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				collision := &pgconn.PgError{Code: "23505", ConstraintName: db.AccountNumberConstraint}
+				store.EXPECT().
+					CreateAccount(gomock.Any(), gomock.Any()).
+					Times(db.MaxAccountNumberAttempts).
+					Return(db.Account{}, collision)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireProblem(t, recorder, http.StatusInternalServerError, apperr.CodeInternal)
+			},
+		},
 		{
 			name: "DuplicateOwner",
 			body: gin.H{
@@ -74,12 +127,12 @@ func TestCreateAccountAPI(t *testing.T) {
 				}
 
 				store.EXPECT().
-					CreateAccount(gomock.Any(), gomock.Eq(arg)).
+					CreateAccount(gomock.Any(), EqCreateAccountParams(arg)).
 					Times(1).
 					Return(db.Account{}, &pgconn.PgError{Code: "23505"}) // 23505 = unique_violation
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
-				require.Equal(t, http.StatusForbidden, recorder.Code)
+				requireProblem(t, recorder, http.StatusConflict, apperr.CodeAlreadyExists)
 			},
 		},
 		{
@@ -98,12 +151,12 @@ func TestCreateAccountAPI(t *testing.T) {
 				}
 
 				store.EXPECT().
-					CreateAccount(gomock.Any(), gomock.Eq(arg)).
+					CreateAccount(gomock.Any(), EqCreateAccountParams(arg)).
 					Times(1).
 					Return(db.Account{}, &pgconn.PgError{Code: "23503"}) // 23503 = foreign_key_violation
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
-				require.Equal(t, http.StatusForbidden, recorder.Code)
+				requireProblem(t, recorder, http.StatusConflict, apperr.CodeConstraintViolation)
 			},
 		},
 		{
@@ -165,6 +218,7 @@ func TestCreateAccountAPI(t *testing.T) {
 
 			store := mockdb.NewMockStore(ctrl)
 			tc.buildStubs(store)
+			stubAuthUser(store)
 
 			server := newTestServer(t, store)
 			recorder := httptest.NewRecorder()
@@ -216,6 +270,24 @@ func TestGetAccountAPI(t *testing.T) {
 				requireBodyMatchAccount(t, recorder, account)
 			},
 		},
+		{
+			name:      "OKByAccountNumber",
+			accountID: account.AccountNumber,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				middleware.AddTestAuthorization(t, request, tokenMaker, middleware.AuthorizationTypeBearer, user.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.
+					EXPECT().
+					GetAccountByNumber(gomock.Any(), gomock.Eq(account.AccountNumber)).
+					Times(1).
+					Return(account, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				requireBodyMatchAccount(t, recorder, account)
+			},
+		},
 		{
 			name:      "NotFound",
 			accountID: account.ID,
@@ -230,7 +302,24 @@ func TestGetAccountAPI(t *testing.T) {
 					Return(db.Account{}, pgx.ErrNoRows)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
-				require.Equal(t, http.StatusNotFound, recorder.Code)
+				requireProblem(t, recorder, http.StatusNotFound, "account_not_found")
+			},
+		},
+		{
+			name:      "UnauthorizedUser",
+			accountID: account.ID,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				middleware.AddTestAuthorization(t, request, tokenMaker, middleware.AuthorizationTypeBearer, "unauthorized_user", time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.
+					EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+					Times(1).
+					Return(account, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireProblem(t, recorder, http.StatusForbidden, "account_not_owned")
 			},
 		},
 		{
@@ -254,6 +343,7 @@ func TestGetAccountAPI(t *testing.T) {
 		getAccountAPInvalidIDTestCase(0, user.Username),
 		getAccountAPInvalidIDTestCase(1.1, user.Username),
 		getAccountAPInvalidIDTestCase("abc", user.Username),
+		getAccountAPInvalidIDTestCase("NG000000000000", user.Username),
 	}
 
 	for _, tc := range testCases {
@@ -263,6 +353,7 @@ func TestGetAccountAPI(t *testing.T) {
 
 			mockStore := mockdb.NewMockStore(ctrl)
 			tc.buildStubs(mockStore)
+			stubAuthUser(mockStore)
 
 			// Start test server and send request
 			server := newTestServer(t, mockStore)
@@ -282,13 +373,40 @@ func TestGetAccountAPI(t *testing.T) {
 
 func randomAccount(username string) db.Account {
 	return db.Account{
-		ID:       util.RandomInt(1, 1000),
-		Owner:    username,
-		Balance:  util.RandomAmount(),
-		Currency: util.RandomCurrency(),
+		ID:            util.RandomInt(1, 1000),
+		Owner:         username,
+		Balance:       util.RandomAmount(),
+		Currency:      util.RandomCurrency(),
+		AccountNumber: util.RandomAccountNumber(),
 	}
 }
 
+type eqCreateAccountParamsMatcher struct {
+	arg db.CreateAccountParams
+}
+
+func (e eqCreateAccountParamsMatcher) Matches(x interface{}) bool {
+	arg, ok := x.(db.CreateAccountParams)
+	if !ok {
+		return false
+	}
+
+	if !util.IsValidAccountNumber(arg.AccountNumber) {
+		return false
+	}
+
+	e.arg.AccountNumber = arg.AccountNumber
+	return reflect.DeepEqual(e.arg, arg)
+}
+
+func (e eqCreateAccountParamsMatcher) String() string {
+	return fmt.Sprintf("matches arg %v with a valid account number", e.arg)
+}
+
+func EqCreateAccountParams(arg db.CreateAccountParams) gomock.Matcher {
+	return eqCreateAccountParamsMatcher{arg}
+}
+
 func requireBodyMatchAccount(t *testing.T, body *httptest.ResponseRecorder, account db.Account) {
 	data, err := io.ReadAll(body.Body)
 	require.NoError(t, err)
@@ -319,3 +437,179 @@ func getAccountAPInvalidIDTestCase(invalidID any, username string) testGetAccoun
 
 	return testCase
 }
+
+func TestGetAccountBalanceAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	account := randomAccount(user.Username)
+
+	otherUser, _ := randomUser(t)
+
+	at := time.Date(2026, time.March, 31, 23, 59, 59, 0, time.UTC)
+	balance := util.RandomMoney()
+
+	testCases := []struct {
+		name          string
+		accountID     any
+		query         string
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:      "OK",
+			accountID: account.ID,
+			query:     "at=2026-03-31T23:59:59Z",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				middleware.AddTestAuthorization(t, request, tokenMaker, middleware.AuthorizationTypeBearer, user.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+					Times(1).
+					Return(account, nil)
+
+				arg := db.GetAccountBalanceAtParams{
+					AccountID: account.ID,
+					At:        at,
+				}
+				store.EXPECT().
+					GetAccountBalanceAt(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(balance, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp AccountBalanceResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+				require.Equal(t, account.ID, rsp.AccountID)
+				require.Equal(t, account.AccountNumber, rsp.AccountNumber)
+				require.Equal(t, balance, rsp.Balance)
+				require.True(t, at.Equal(rsp.At))
+			},
+		},
+		{
+			name:      "DefaultsToNow",
+			accountID: account.AccountNumber,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				middleware.AddTestAuthorization(t, request, tokenMaker, middleware.AuthorizationTypeBearer, user.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAccountByNumber(gomock.Any(), gomock.Eq(account.AccountNumber)).
+					Times(1).
+					Return(account, nil)
+				store.EXPECT().
+					GetAccountBalanceAt(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(account.Balance, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp AccountBalanceResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+				require.Equal(t, account.Balance, rsp.Balance)
+				require.WithinDuration(t, time.Now(), rsp.At, time.Second)
+			},
+		},
+		{
+			name:      "UnauthorizedUser",
+			accountID: account.ID,
+			query:     "at=2026-03-31T23:59:59Z",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				middleware.AddTestAuthorization(t, request, tokenMaker, middleware.AuthorizationTypeBearer, otherUser.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+					Times(1).
+					Return(account, nil)
+				store.EXPECT().
+					GetAccountBalanceAt(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireProblem(t, recorder, http.StatusForbidden, "account_not_owned")
+			},
+		},
+		{
+			name:      "NotFound",
+			accountID: account.ID,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				middleware.AddTestAuthorization(t, request, tokenMaker, middleware.AuthorizationTypeBearer, user.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+					Times(1).
+					Return(db.Account{}, pgx.ErrNoRows)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name:      "InternalError",
+			accountID: account.ID,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				middleware.AddTestAuthorization(t, request, tokenMaker, middleware.AuthorizationTypeBearer, user.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+					Times(1).
+					Return(account, nil)
+				store.EXPECT().
+					GetAccountBalanceAt(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(int64(0), pgx.ErrTxClosed)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+		{
+			name:      "InvalidAt",
+			accountID: account.ID,
+			query:     "at=yesterday",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				middleware.AddTestAuthorization(t, request, tokenMaker, middleware.AuthorizationTypeBearer, user.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Any()).
+					Times(0)
+				store.EXPECT().
+					GetAccountBalanceAt(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+			stubAuthUser(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			url := fmt.Sprintf("/accounts/%v/balance?%s", tc.accountID, tc.query)
+			request, err := http.NewRequest(http.MethodGet, url, nil)
+			require.NoError(t, err)
+
+			tc.setupAuth(t, request, server.tokenMaker)
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/apperr"
+	db "github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/db/sqlc"
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/middleware"
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/token"
+	"github.com/gin-gonic/gin"
+)
+
+var errRoleRequired = apperr.New(http.StatusForbidden, "role_required", "you are not allowed to do this")
+
+// requireRole only lets through users with the given role. It must run after
+// the auth middleware has loaded the user.
+func (server *Server) requireRole(role string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		user := ctx.MustGet(authorizationUserKey).(db.User)
+		if user.Role != role {
+			middleware.AbortWithError(ctx, errRoleRequired)
+			return
+		}
+		ctx.Next()
+	}
+}
+
+type UnlockLoginRequest struct {
+	Username string `json:"username" binding:"required_without=IP,omitempty,alphanum"`
+	IP       string `json:"ip" binding:"omitempty,ip"`
+}
+
+type UnlockLoginResponse struct {
+	// Unlocked is false when there were no failed logins to clear
+	Unlocked bool `json:"unlocked"`
+}
+
+// unlockLogin clears the failed logins, and with them any lockout, of a
+// username, a client ip or both.
+func (server *Server) unlockLogin(ctx *gin.Context) {
+	var req UnlockLoginRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.Error(apperr.FromBinding(err))
+		return
+	}
+
+	throttles := []db.DeleteLoginThrottleParams{
+		{Kind: db.LoginThrottleKindUsername, Subject: req.Username},
+		{Kind: db.LoginThrottleKindIP, Subject: req.IP},
+	}
+
+	var rsp UnlockLoginResponse
+	for _, throttle := range throttles {
+		if throttle.Subject == "" {
+			continue
+		}
+
+		deleted, err := server.store.DeleteLoginThrottle(ctx, throttle)
+		if err != nil {
+			ctx.Error(err)
+			return
+		}
+		rsp.Unlocked = rsp.Unlocked || deleted > 0
+	}
+
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+var adminOperations = []apiOperation{
+	{
+		method:   http.MethodPost,
+		path:     "/admin/login_locks/unlock",
+		tag:      "admin",
+		summary:  "Clear the failed logins of a username or ip address",
+		auth:     authTokenOrAPIKey,
+		scopes:   []string{token.ScopeAdmin},
+		body:     UnlockLoginRequest{},
+		response: UnlockLoginResponse{},
+	},
+}
+
+func (server *Server) setupAdminRoutes(router gin.IRoutes) {
+	router.POST("/admin/login_locks/unlock", server.unlockLogin)
+}
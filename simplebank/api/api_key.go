@@ -0,0 +1,219 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/apperr"
+	db "github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/db/sqlc"
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/token"
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/util"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+var (
+	errInvalidAPIKey  = apperr.New(http.StatusUnauthorized, "invalid_api_key", util.ErrInvalidAPIKey.Error())
+	errAPIKeyRevoked  = apperr.New(http.StatusUnauthorized, "api_key_revoked", "api key has been revoked")
+	errAPIKeyExpired  = apperr.New(http.StatusUnauthorized, "api_key_expired", "api key has expired")
+	errAPIKeyNotFound = apperr.New(http.StatusNotFound, "api_key_not_found", "api key not found")
+)
+
+type CreateAPIKeyRequest struct {
+	Name   string   `json:"name" binding:"required,max=64"`
+	Scopes []string `json:"scopes" binding:"required,min=1,unique,dive,scope,ne=user"`
+	// ExpiresInDays defaults to the longest lifetime allowed
+	ExpiresInDays int `json:"expires_in_days" binding:"omitempty,min=1"`
+}
+
+type APIKeyResponse struct {
+	ID         uuid.UUID  `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	ExpiredAt  time.Time  `json:"expired_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+func newAPIKeyResponse(apiKey db.ApiKey) APIKeyResponse {
+	return APIKeyResponse{
+		ID:         apiKey.ID,
+		Name:       apiKey.Name,
+		Prefix:     apiKey.Prefix,
+		Scopes:     apiKey.Scopes,
+		ExpiredAt:  apiKey.ExpiredAt,
+		LastUsedAt: timeOrNil(apiKey.LastUsedAt),
+		RevokedAt:  timeOrNil(apiKey.RevokedAt),
+		CreatedAt:  apiKey.CreatedAt,
+	}
+}
+
+func timeOrNil(t pgtype.Timestamptz) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
+
+type CreateAPIKeyResponse struct {
+	// Key is only ever shown in this response
+	Key    string         `json:"key"`
+	APIKey APIKeyResponse `json:"api_key"`
+}
+
+// createAPIKey issues an api key for server-to-server clients of the
+// authenticated user, limited to the requested scopes.
+func (server *Server) createAPIKey(ctx *gin.Context) {
+	var req CreateAPIKeyRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.Error(apperr.FromBinding(err))
+		return
+	}
+
+	user := ctx.MustGet(authorizationUserKey).(db.User)
+	if slices.Contains(req.Scopes, token.ScopeAdmin) && user.Role != util.AdminRole {
+		ctx.Error(errRoleRequired)
+		return
+	}
+
+	duration := server.config.APIKeyMaxDuration
+	if req.ExpiresInDays > 0 {
+		duration = time.Duration(req.ExpiresInDays) * 24 * time.Hour
+		if duration > server.config.APIKeyMaxDuration {
+			ctx.Error(apperr.InvalidArgument(fmt.Sprintf("api keys cannot be valid for longer than %s", server.config.APIKeyMaxDuration)))
+			return
+		}
+	}
+
+	id, err := uuid.NewRandom()
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	key, prefix, hashedSecret, err := util.GenerateAPIKey()
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	apiKey, err := server.store.CreateAPIKey(ctx, db.CreateAPIKeyParams{
+		ID:           id,
+		Username:     user.Username,
+		Name:         req.Name,
+		Prefix:       prefix,
+		HashedSecret: hashedSecret,
+		Scopes:       req.Scopes,
+		ExpiredAt:    time.Now().Add(duration),
+	})
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	rsp := CreateAPIKeyResponse{
+		Key:    key,
+		APIKey: newAPIKeyResponse(apiKey),
+	}
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+func (server *Server) listAPIKeys(ctx *gin.Context) {
+	user := ctx.MustGet(authorizationUserKey).(db.User)
+
+	apiKeys, err := server.store.ListAPIKeys(ctx, user.Username)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	rsp := make([]APIKeyResponse, len(apiKeys))
+	for i, apiKey := range apiKeys {
+		rsp[i] = newAPIKeyResponse(apiKey)
+	}
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+type RevokeAPIKeyRequest struct {
+	ID string `uri:"id" binding:"required,uuid"`
+}
+
+func (server *Server) revokeAPIKey(ctx *gin.Context) {
+	var req RevokeAPIKeyRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		ctx.Error(apperr.FromBinding(err))
+		return
+	}
+
+	user := ctx.MustGet(authorizationUserKey).(db.User)
+
+	apiKey, err := server.store.RevokeAPIKey(ctx, db.RevokeAPIKeyParams{
+		ID:       uuid.MustParse(req.ID),
+		Username: user.Username,
+	})
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			ctx.Error(errAPIKeyNotFound)
+			return
+		}
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newAPIKeyResponse(apiKey))
+}
+
+// verifyAPIKey is the middleware.APIKeyVerifier of the server. The payload
+// is issued when the key was created, so like access tokens, keys created
+// before the owner's last password change stop working.
+func (server *Server) verifyAPIKey(ctx *gin.Context, key string) (*token.Payload, error) {
+	payload, err := server.authenticator.VerifyAPIKey(ctx, key)
+	if err != nil {
+		return nil, authError(err)
+	}
+	return payload, nil
+}
+
+var apiKeyOperations = []apiOperation{
+	{
+		method:   http.MethodPost,
+		path:     "/users/me/api_keys",
+		tag:      "api keys",
+		summary:  "Issue an api key",
+		auth:     authToken,
+		scopes:   []string{token.ScopeUser},
+		body:     CreateAPIKeyRequest{},
+		response: CreateAPIKeyResponse{},
+	},
+	{
+		method:   http.MethodGet,
+		path:     "/users/me/api_keys",
+		tag:      "api keys",
+		summary:  "List the api keys of the authenticated user",
+		auth:     authToken,
+		scopes:   []string{token.ScopeUser},
+		response: []APIKeyResponse{},
+	},
+	{
+		method:   http.MethodDelete,
+		path:     "/users/me/api_keys/:id",
+		tag:      "api keys",
+		summary:  "Revoke an api key",
+		auth:     authToken,
+		scopes:   []string{token.ScopeUser},
+		uri:      RevokeAPIKeyRequest{},
+		response: APIKeyResponse{},
+	},
+}
+
+func (server *Server) setupAPIKeyRoutes(router gin.IRoutes) {
+	router.POST("/users/me/api_keys", server.createAPIKey)
+	router.GET("/users/me/api_keys", server.listAPIKeys)
+	router.DELETE("/users/me/api_keys/:id", server.revokeAPIKey)
+}
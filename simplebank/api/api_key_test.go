@@ -0,0 +1,783 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	mockdb "github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/db/mock"
+	db "github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/db/sqlc"
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/middleware"
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/token"
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/util"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/require"
+)
+
+// randomAPIKey returns a key and the row it is stored as
+func randomAPIKey(t *testing.T, username string, scopes ...string) (string, db.ApiKey) {
+	key, prefix, hashedSecret, err := util.GenerateAPIKey()
+	require.NoError(t, err)
+
+	return key, db.ApiKey{
+		ID:           uuid.New(),
+		Username:     username,
+		Name:         util.RandomString(8),
+		Prefix:       prefix,
+		HashedSecret: hashedSecret,
+		Scopes:       scopes,
+		ExpiredAt:    time.Now().Add(time.Hour),
+		CreatedAt:    time.Now().Add(-time.Minute),
+	}
+}
+
+func addAPIKeyAuthorization(request *http.Request, key string) {
+	request.Header.Set(middleware.AuthorizationHeaderKey, "ApiKey "+key)
+}
+
+type eqCreateAPIKeyParamsMatcher struct {
+	username string
+	name     string
+	scopes   []string
+	duration time.Duration
+}
+
+func (e eqCreateAPIKeyParamsMatcher) Matches(x interface{}) bool {
+	arg, ok := x.(db.CreateAPIKeyParams)
+	if !ok {
+		return false
+	}
+
+	expiresIn := time.Until(arg.ExpiredAt)
+	return arg.ID != uuid.Nil && arg.Username == e.username && arg.Name == e.name &&
+		fmt.Sprint(arg.Scopes) == fmt.Sprint(e.scopes) &&
+		arg.Prefix != "" && arg.HashedSecret != "" &&
+		expiresIn > e.duration-time.Minute && expiresIn <= e.duration
+}
+
+func (e eqCreateAPIKeyParamsMatcher) String() string {
+	return fmt.Sprintf("creates api key %v of %v with scopes %v for %v", e.name, e.username, e.scopes, e.duration)
+}
+
+func TestCreateAPIKeyAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	admin, _ := randomUser(t)
+	admin.Role = util.AdminRole
+
+	const maxDuration = 90 * 24 * time.Hour
+	name := util.RandomString(8)
+
+	// createStored makes CreateAPIKey return what it was asked to store
+	createStored := func(_ context.Context, arg db.CreateAPIKeyParams) (db.ApiKey, error) {
+		return db.ApiKey{
+			ID:           arg.ID,
+			Username:     arg.Username,
+			Name:         arg.Name,
+			Prefix:       arg.Prefix,
+			HashedSecret: arg.HashedSecret,
+			Scopes:       arg.Scopes,
+			ExpiredAt:    arg.ExpiredAt,
+			CreatedAt:    time.Now(),
+		}, nil
+	}
+
+	testCases := []struct {
+		name          string
+		user          db.User
+		body          gin.H
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			user: user,
+			body: gin.H{
+				"name":            name,
+				"scopes":          []string{token.ScopeAccountsRead},
+				"expires_in_days": 30,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateAPIKey(gomock.Any(), eqCreateAPIKeyParamsMatcher{user.Username, name, []string{token.ScopeAccountsRead}, 30 * 24 * time.Hour}).
+					Times(1).
+					DoAndReturn(createStored)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp CreateAPIKeyResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+				require.Equal(t, name, rsp.APIKey.Name)
+				require.Equal(t, []string{token.ScopeAccountsRead}, rsp.APIKey.Scopes)
+				require.Nil(t, rsp.APIKey.LastUsedAt)
+				require.Nil(t, rsp.APIKey.RevokedAt)
+
+				prefix, _, err := util.ParseAPIKey(rsp.Key)
+				require.NoError(t, err)
+				require.Equal(t, rsp.APIKey.Prefix, prefix)
+				require.NotContains(t, recorder.Body.String(), "hashed_secret")
+			},
+		},
+		{
+			name: "DefaultsToMaxDuration",
+			user: user,
+			body: gin.H{
+				"name":   name,
+				"scopes": []string{token.ScopeAccountsRead, token.ScopeTransfersWrite},
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateAPIKey(gomock.Any(), eqCreateAPIKeyParamsMatcher{user.Username, name, []string{token.ScopeAccountsRead, token.ScopeTransfersWrite}, maxDuration}).
+					Times(1).
+					DoAndReturn(createStored)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "AdminScope",
+			user: admin,
+			body: gin.H{
+				"name":   name,
+				"scopes": []string{token.ScopeAdmin},
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateAPIKey(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(createStored)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "AdminScopeNotAdmin",
+			user: user,
+			body: gin.H{
+				"name":   name,
+				"scopes": []string{token.ScopeAdmin},
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateAPIKey(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name: "TooLong",
+			user: user,
+			body: gin.H{
+				"name":            name,
+				"scopes":          []string{token.ScopeAccountsRead},
+				"expires_in_days": 91,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateAPIKey(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "UnsupportedScope",
+			user: user,
+			body: gin.H{
+				"name":   name,
+				"scopes": []string{"everything"},
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateAPIKey(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			// api keys never get to manage the user
+			name: "UserScope",
+			user: user,
+			body: gin.H{
+				"name":   name,
+				"scopes": []string{token.ScopeUser},
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateAPIKey(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "NoScopes",
+			user: user,
+			body: gin.H{
+				"name":   name,
+				"scopes": []string{},
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateAPIKey(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "WithAPIKey",
+			user: user,
+			body: gin.H{
+				"name":   name,
+				"scopes": []string{token.ScopeAccountsRead},
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				// api keys cannot mint more api keys
+				key, _ := randomAPIKey(t, user.Username, token.ScopeAccountsRead, token.ScopeAccountsWrite)
+				addAPIKeyAuthorization(request, key)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAPIKeyByPrefix(gomock.Any(), gomock.Any()).
+					Times(0)
+				store.EXPECT().
+					CreateAPIKey(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "InternalError",
+			user: user,
+			body: gin.H{
+				"name":   name,
+				"scopes": []string{token.ScopeAccountsRead},
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateAPIKey(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.ApiKey{}, pgx.ErrTxClosed)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			store.EXPECT().
+				GetUser(gomock.Any(), gomock.Eq(tc.user.Username)).
+				AnyTimes().
+				Return(tc.user, nil)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			server.config.APIKeyMaxDuration = maxDuration
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/users/me/api_keys", bytes.NewReader(data))
+			require.NoError(t, err)
+
+			if tc.setupAuth != nil {
+				tc.setupAuth(t, request, server.tokenMaker)
+			} else {
+				middleware.AddTestAuthorization(t, request, server.tokenMaker, middleware.AuthorizationTypeBearer, tc.user.Username, time.Minute)
+			}
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestListAPIKeysAPI(t *testing.T) {
+	user, _ := randomUser(t)
+
+	_, apiKey1 := randomAPIKey(t, user.Username, token.ScopeAccountsRead)
+	_, apiKey2 := randomAPIKey(t, user.Username, token.ScopeTransfersWrite)
+	apiKey2.RevokedAt = pgtype.Timestamptz{Time: time.Now(), Valid: true}
+	apiKeys := []db.ApiKey{apiKey2, apiKey1}
+
+	testCases := []struct {
+		name          string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ListAPIKeys(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(apiKeys, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp []APIKeyResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+				require.Len(t, rsp, 2)
+				require.Equal(t, apiKey2.ID, rsp[0].ID)
+				require.NotNil(t, rsp[0].RevokedAt)
+				require.Equal(t, apiKey1.ID, rsp[1].ID)
+				require.Nil(t, rsp[1].RevokedAt)
+				require.NotContains(t, recorder.Body.String(), apiKey1.HashedSecret)
+			},
+		},
+		{
+			name: "NoKeys",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ListAPIKeys(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return([]db.ApiKey{}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.JSONEq(t, `[]`, recorder.Body.String())
+			},
+		},
+		{
+			name: "InternalError",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ListAPIKeys(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(nil, pgx.ErrTxClosed)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			stubAuthUser(store)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			request, err := http.NewRequest(http.MethodGet, "/users/me/api_keys", nil)
+			require.NoError(t, err)
+
+			middleware.AddTestAuthorization(t, request, server.tokenMaker, middleware.AuthorizationTypeBearer, user.Username, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestRevokeAPIKeyAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	_, apiKey := randomAPIKey(t, user.Username, token.ScopeAccountsRead)
+
+	testCases := []struct {
+		name          string
+		id            string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			id:   apiKey.ID.String(),
+			buildStubs: func(store *mockdb.MockStore) {
+				revoked := apiKey
+				revoked.RevokedAt = pgtype.Timestamptz{Time: time.Now(), Valid: true}
+
+				store.EXPECT().
+					RevokeAPIKey(gomock.Any(), gomock.Eq(db.RevokeAPIKeyParams{
+						ID:       apiKey.ID,
+						Username: user.Username,
+					})).
+					Times(1).
+					Return(revoked, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp APIKeyResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+				require.Equal(t, apiKey.ID, rsp.ID)
+				require.NotNil(t, rsp.RevokedAt)
+			},
+		},
+		{
+			// unknown, already revoked and other users' keys look the same
+			name: "NotFound",
+			id:   apiKey.ID.String(),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					RevokeAPIKey(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.ApiKey{}, pgx.ErrNoRows)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name: "InvalidID",
+			id:   "not-a-uuid",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					RevokeAPIKey(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "InternalError",
+			id:   apiKey.ID.String(),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					RevokeAPIKey(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.ApiKey{}, pgx.ErrTxClosed)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			stubAuthUser(store)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			url := fmt.Sprintf("/users/me/api_keys/%s", tc.id)
+			request, err := http.NewRequest(http.MethodDelete, url, nil)
+			require.NoError(t, err)
+
+			middleware.AddTestAuthorization(t, request, server.tokenMaker, middleware.AuthorizationTypeBearer, user.Username, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestAPIKeyAuthorization(t *testing.T) {
+	user, _ := randomUser(t)
+	user.IsEmailVerified = true
+	account := randomAccount(user.Username)
+
+	admin, _ := randomUser(t)
+	admin.Role = util.AdminRole
+
+	key, apiKey := randomAPIKey(t, user.Username, token.ScopeAccountsRead)
+	adminKey, adminAPIKey := randomAPIKey(t, admin.Username, token.ScopeAdmin)
+	otherKey, _ := randomAPIKey(t, user.Username, token.ScopeAccountsRead)
+
+	// expectAPIKey stubs the lookup of a key and the owner it authorizes
+	expectAPIKey := func(store *mockdb.MockStore, apiKey db.ApiKey, owner db.User) {
+		store.EXPECT().
+			GetAPIKeyByPrefix(gomock.Any(), gomock.Eq(apiKey.Prefix)).
+			Times(1).
+			Return(apiKey, nil)
+		store.EXPECT().
+			TouchAPIKey(gomock.Any(), gomock.Eq(apiKey.ID)).
+			Times(1).
+			Return(nil)
+		store.EXPECT().
+			GetUser(gomock.Any(), gomock.Eq(owner.Username)).
+			Times(1).
+			Return(owner, nil)
+	}
+
+	testCases := []struct {
+		name          string
+		method        string
+		url           string
+		key           string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:   "OK",
+			method: http.MethodGet,
+			url:    fmt.Sprintf("/accounts/%d", account.ID),
+			key:    key,
+			buildStubs: func(store *mockdb.MockStore) {
+				expectAPIKey(store, apiKey, user)
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+					Times(1).
+					Return(account, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				requireBodyMatchAccount(t, recorder, account)
+			},
+		},
+		{
+			name:   "TouchFailureIgnored",
+			method: http.MethodGet,
+			url:    fmt.Sprintf("/accounts/%d", account.ID),
+			key:    key,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAPIKeyByPrefix(gomock.Any(), gomock.Eq(apiKey.Prefix)).
+					Times(1).
+					Return(apiKey, nil)
+				store.EXPECT().
+					TouchAPIKey(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(pgx.ErrTxClosed)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+					Times(1).
+					Return(account, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:   "MissingScope",
+			method: http.MethodPost,
+			url:    "/transfer",
+			key:    key,
+			buildStubs: func(store *mockdb.MockStore) {
+				expectAPIKey(store, apiKey, user)
+				store.EXPECT().
+					TransferTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name:   "WrongSecret",
+			method: http.MethodGet,
+			url:    fmt.Sprintf("/accounts/%d", account.ID),
+			// the prefix of one key with the secret of another
+			key: key[:len("sb_")+12] + otherKey[len("sb_")+12:],
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAPIKeyByPrefix(gomock.Any(), gomock.Eq(apiKey.Prefix)).
+					Times(1).
+					Return(apiKey, nil)
+				store.EXPECT().
+					TouchAPIKey(gomock.Any(), gomock.Any()).
+					Times(0)
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name:   "UnknownKey",
+			method: http.MethodGet,
+			url:    fmt.Sprintf("/accounts/%d", account.ID),
+			key:    key,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAPIKeyByPrefix(gomock.Any(), gomock.Eq(apiKey.Prefix)).
+					Times(1).
+					Return(db.ApiKey{}, pgx.ErrNoRows)
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name:   "MalformedKey",
+			method: http.MethodGet,
+			url:    fmt.Sprintf("/accounts/%d", account.ID),
+			key:    "not-an-api-key",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAPIKeyByPrefix(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name:   "Revoked",
+			method: http.MethodGet,
+			url:    fmt.Sprintf("/accounts/%d", account.ID),
+			key:    key,
+			buildStubs: func(store *mockdb.MockStore) {
+				revoked := apiKey
+				revoked.RevokedAt = pgtype.Timestamptz{Time: time.Now(), Valid: true}
+
+				store.EXPECT().
+					GetAPIKeyByPrefix(gomock.Any(), gomock.Eq(apiKey.Prefix)).
+					Times(1).
+					Return(revoked, nil)
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name:   "Expired",
+			method: http.MethodGet,
+			url:    fmt.Sprintf("/accounts/%d", account.ID),
+			key:    key,
+			buildStubs: func(store *mockdb.MockStore) {
+				expired := apiKey
+				expired.ExpiredAt = time.Now().Add(-time.Second)
+
+				store.EXPECT().
+					GetAPIKeyByPrefix(gomock.Any(), gomock.Eq(apiKey.Prefix)).
+					Times(1).
+					Return(expired, nil)
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name:   "PasswordChangedSinceCreated",
+			method: http.MethodGet,
+			url:    fmt.Sprintf("/accounts/%d", account.ID),
+			key:    key,
+			buildStubs: func(store *mockdb.MockStore) {
+				changed := user
+				changed.PasswordChangedAt = time.Now()
+
+				expectAPIKey(store, apiKey, changed)
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name:   "UserRoute",
+			method: http.MethodGet,
+			url:    "/users/me",
+			key:    key,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAPIKeyByPrefix(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name:   "AdminScope",
+			method: http.MethodPost,
+			url:    "/admin/login_locks/unlock",
+			key:    adminKey,
+			buildStubs: func(store *mockdb.MockStore) {
+				expectAPIKey(store, adminAPIKey, admin)
+				store.EXPECT().
+					DeleteLoginThrottle(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(int64(1), nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:   "AdminWithoutAdminScope",
+			method: http.MethodPost,
+			url:    "/admin/login_locks/unlock",
+			key:    adminKey,
+			buildStubs: func(store *mockdb.MockStore) {
+				readOnly := adminAPIKey
+				readOnly.Scopes = []string{token.ScopeAccountsRead}
+
+				expectAPIKey(store, readOnly, admin)
+				store.EXPECT().
+					DeleteLoginThrottle(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			body := bytes.NewReader([]byte(`{"username": "someone"}`))
+			request, err := http.NewRequest(tc.method, tc.url, body)
+			require.NoError(t, err)
+
+			addAPIKeyAuthorization(request, tc.key)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/apperr"
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/auth"
+	db "github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/db/sqlc"
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/util"
+)
+
+// authErrors are the responses to the errors of the authenticator
+var authErrors = []struct {
+	err    error
+	appErr *apperr.Error
+}{
+	{auth.ErrInvalidCredentials, errInvalidCredentials},
+	{auth.ErrUserNotFound, errUserNotFound},
+	{auth.ErrTokenRevoked, errTokenRevoked},
+	{auth.ErrOAuthConsentRevoked, errOAuthConsentRevoked},
+	{util.ErrInvalidAPIKey, errInvalidAPIKey},
+	{auth.ErrAPIKeyRevoked, errAPIKeyRevoked},
+	{auth.ErrAPIKeyExpired, errAPIKeyExpired},
+	{auth.ErrInvalidTOTPCode, errInvalidTOTPCode},
+	{auth.ErrTOTPRequired, errTOTPRequired},
+	{auth.ErrStepUpNotEnabled, errStepUpNotEnabled},
+	{auth.ErrAccountNotFound, errAccountNotFound},
+	{auth.ErrAccountNotOwned, errAccountNotOwned},
+	{db.ErrAccountFrozen, errAccountFrozen},
+}
+
+// authError returns the response to an error of the authenticator. Errors
+// of the store are classified as any other.
+func authError(err error) error {
+	for _, e := range authErrors {
+		if errors.Is(err, e.err) {
+			return e.appErr.WithCause(err)
+		}
+	}
+
+	if errors.Is(err, auth.ErrCurrencyMismatch) {
+		return apperr.New(http.StatusBadRequest, "currency_mismatch", err.Error())
+	}
+	return apperr.From(err)
+}
@@ -0,0 +1,83 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/apperr"
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/db/migration"
+	"github.com/gin-gonic/gin"
+)
+
+const codeNotReady = "not_ready"
+
+var errShuttingDown = apperr.New(http.StatusServiceUnavailable, codeNotReady, "server is shutting down")
+
+type HealthResponse struct {
+	Status string `json:"status"`
+}
+
+var healthResponse = HealthResponse{Status: "ok"}
+
+// healthz tells that the process is up. It checks nothing else, so a
+// database outage doesn't get every instance restarted.
+func (server *Server) healthz(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, healthResponse)
+}
+
+// readyz tells whether the server can take requests: it is not shutting
+// down, and the database can be reached and is migrated to the schema the
+// queries are written against.
+func (server *Server) readyz(ctx *gin.Context) {
+	if server.draining.Load() {
+		ctx.Error(errShuttingDown)
+		return
+	}
+
+	if err := server.store.Ping(ctx); err != nil {
+		ctx.Error(notReady("database is unreachable", err))
+		return
+	}
+
+	version, dirty, err := server.store.MigrationVersion(ctx)
+	if err != nil {
+		ctx.Error(notReady("database migrations cannot be read", err))
+		return
+	}
+	if dirty {
+		ctx.Error(notReady(fmt.Sprintf("database migration %d failed halfway", version), nil))
+		return
+	}
+	if latest := int64(migration.LatestVersion()); version < latest {
+		ctx.Error(notReady(fmt.Sprintf("database is migrated to version %d, expected %d", version, latest), nil))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, healthResponse)
+}
+
+func notReady(message string, err error) *apperr.Error {
+	return apperr.New(http.StatusServiceUnavailable, codeNotReady, message).WithCause(err)
+}
+
+var healthOperations = []apiOperation{
+	{
+		method:   http.MethodGet,
+		path:     "/healthz",
+		tag:      "health",
+		summary:  "Check that the server is alive",
+		response: HealthResponse{},
+	},
+	{
+		method:   http.MethodGet,
+		path:     "/readyz",
+		tag:      "health",
+		summary:  "Check that the server is ready for requests",
+		response: HealthResponse{},
+	},
+}
+
+func (server *Server) setupHealthRoutes(router gin.IRoutes) {
+	router.GET("/healthz", server.healthz)
+	router.GET("/readyz", server.readyz)
+}
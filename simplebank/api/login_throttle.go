@@ -0,0 +1,36 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/apperr"
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/auth"
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	errInvalidCredentials = apperr.New(http.StatusUnauthorized, "invalid_credentials", "incorrect username or password")
+	errLoginLocked        = apperr.New(http.StatusTooManyRequests, "login_locked", "too many failed login attempts, try again later")
+)
+
+// checkLoginLock responds with errLoginLocked and returns false while either
+// the username or the client ip is locked out
+func (server *Server) checkLoginLock(ctx *gin.Context, username string) bool {
+	err := server.authenticator.CheckLoginLock(ctx, username, ctx.ClientIP())
+
+	var locked *auth.LoginLockedError
+	if errors.As(err, &locked) {
+		ctx.Header("Retry-After", fmt.Sprint(int(math.Ceil(locked.RetryAfter.Seconds()))))
+		ctx.Error(errLoginLocked)
+		return false
+	}
+	if err != nil {
+		ctx.Error(err)
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,799 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	mockdb "github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/db/mock"
+	db "github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/db/sqlc"
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/middleware"
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/totp"
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/util"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const testClientIP = "192.0.2.1"
+
+type eqLockLoginMatcher struct {
+	kind    string
+	subject string
+	delay   time.Duration
+}
+
+func (e eqLockLoginMatcher) Matches(x interface{}) bool {
+	arg, ok := x.(db.LockLoginParams)
+	if !ok {
+		return false
+	}
+
+	lockedFor := time.Until(arg.LockedUntil.Time)
+	return arg.Kind == e.kind && arg.Subject == e.subject && arg.LockedUntil.Valid &&
+		lockedFor > e.delay-time.Second && lockedFor <= e.delay
+}
+
+func (e eqLockLoginMatcher) String() string {
+	return "locks " + e.kind + " " + e.subject + " for " + e.delay.String()
+}
+
+type eqRehashPasswordMatcher struct {
+	username string
+	password string
+}
+
+func (e eqRehashPasswordMatcher) Matches(x interface{}) bool {
+	arg, ok := x.(db.UpdateUserParams)
+	if !ok {
+		return false
+	}
+
+	// a rehash keeps the password, so it must not revoke tokens
+	if arg.Username != e.username || !arg.HashedPassword.Valid || arg.PasswordChangedAt.Valid {
+		return false
+	}
+
+	if arg.FullName.Valid || arg.Email.Valid || arg.IsEmailVerified.Valid {
+		return false
+	}
+
+	return util.CheckPassword(e.password, arg.HashedPassword.String) == nil &&
+		!util.DefaultPasswordHasher.NeedsRehash(arg.HashedPassword.String)
+}
+
+func (e eqRehashPasswordMatcher) String() string {
+	return fmt.Sprintf("rehashes the password of %v", e.username)
+}
+
+func TestLoginUserAPI(t *testing.T) {
+	user, password := randomUser(t)
+
+	bcryptHasher, err := util.NewPasswordHasher(util.PasswordHashBcrypt, util.Argon2idParams{}, bcrypt.MinCost)
+	require.NoError(t, err)
+	bcryptUser := user
+	bcryptUser.HashedPassword, err = bcryptHasher.Hash(password)
+	require.NoError(t, err)
+
+	// the minimum length may be configured below the default, so existing
+	// passwords of any length can log in
+	shortPassword := util.RandomString(4)
+	shortPasswordUser := user
+	shortPasswordUser.HashedPassword, err = util.HashPassword(shortPassword)
+	require.NoError(t, err)
+
+	lockParams := db.GetLoginLockedUntilParams{
+		Username: user.Username,
+		Ip:       testClientIP,
+	}
+
+	testCases := []struct {
+		name          string
+		body          gin.H
+		header        http.Header
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			body: gin.H{
+				"username": user.Username,
+				"password": password,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetLoginLockedUntil(gomock.Any(), gomock.Eq(lockParams)).
+					Times(1).
+					Return(pgtype.Timestamptz{}, nil)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					DeleteLoginThrottle(gomock.Any(), gomock.Eq(db.DeleteLoginThrottleParams{
+						Kind:    db.LoginThrottleKindUsername,
+						Subject: user.Username,
+					})).
+					Times(1).
+					Return(int64(1), nil)
+				store.EXPECT().
+					RecordLoginFailure(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp LoginUserResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+				require.NotEmpty(t, rsp.AccessToken)
+			},
+		},
+		{
+			name: "ShortPassword",
+			body: gin.H{
+				"username": user.Username,
+				"password": shortPassword,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetLoginLockedUntil(gomock.Any(), gomock.Eq(lockParams)).
+					Times(1).
+					Return(pgtype.Timestamptz{}, nil)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(shortPasswordUser, nil)
+				store.EXPECT().
+					DeleteLoginThrottle(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(int64(0), nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "IgnoresForwardedFor",
+			body: gin.H{
+				"username": user.Username,
+				"password": password,
+			},
+			header: http.Header{"X-Forwarded-For": {"198.51.100.7"}},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetLoginLockedUntil(gomock.Any(), gomock.Eq(lockParams)).
+					Times(1).
+					Return(pgtype.Timestamptz{}, nil)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					DeleteLoginThrottle(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(int64(0), nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "RehashesOutdatedPassword",
+			body: gin.H{
+				"username": user.Username,
+				"password": password,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetLoginLockedUntil(gomock.Any(), gomock.Eq(lockParams)).
+					Times(1).
+					Return(pgtype.Timestamptz{}, nil)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(bcryptUser, nil)
+				store.EXPECT().
+					UpdateUser(gomock.Any(), eqRehashPasswordMatcher{user.Username, password}).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					DeleteLoginThrottle(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(int64(0), nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "RehashFailureStillLogsIn",
+			body: gin.H{
+				"username": user.Username,
+				"password": password,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetLoginLockedUntil(gomock.Any(), gomock.Eq(lockParams)).
+					Times(1).
+					Return(pgtype.Timestamptz{}, nil)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(bcryptUser, nil)
+				store.EXPECT().
+					UpdateUser(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.User{}, pgx.ErrTxClosed)
+				store.EXPECT().
+					DeleteLoginThrottle(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(int64(0), nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp LoginUserResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+				require.Equal(t, user.Username, rsp.User.Username)
+			},
+		},
+		{
+			name: "UnsupportedScope",
+			body: gin.H{
+				"username": user.Username,
+				"password": password,
+				"scopes":   []string{"everything"},
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetLoginLockedUntil(gomock.Any(), gomock.Any()).
+					Times(0)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "WrongPassword",
+			body: gin.H{
+				"username": user.Username,
+				"password": "wrong-password",
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetLoginLockedUntil(gomock.Any(), gomock.Eq(lockParams)).
+					Times(1).
+					Return(pgtype.Timestamptz{}, nil)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				expectRecordLoginFailure(t, store, db.LoginThrottleKindUsername, user.Username, 1)
+				expectRecordLoginFailure(t, store, db.LoginThrottleKindIP, testClientIP, 1)
+				store.EXPECT().
+					LockLogin(gomock.Any(), gomock.Any()).
+					Times(0)
+				store.EXPECT().
+					DeleteLoginThrottle(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				problem := requireProblem(t, recorder, http.StatusUnauthorized, "invalid_credentials")
+				require.Equal(t, "incorrect username or password", problem.Detail)
+			},
+		},
+		{
+			name: "UnknownUser",
+			body: gin.H{
+				"username": user.Username,
+				"password": password,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetLoginLockedUntil(gomock.Any(), gomock.Eq(lockParams)).
+					Times(1).
+					Return(pgtype.Timestamptz{}, nil)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(db.User{}, pgx.ErrNoRows)
+				expectRecordLoginFailure(t, store, db.LoginThrottleKindUsername, user.Username, 1)
+				expectRecordLoginFailure(t, store, db.LoginThrottleKindIP, testClientIP, 1)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// indistinguishable from a wrong password
+				problem := requireProblem(t, recorder, http.StatusUnauthorized, "invalid_credentials")
+				require.Equal(t, "incorrect username or password", problem.Detail)
+			},
+		},
+		{
+			name: "LocksAfterFreeAttempts",
+			body: gin.H{
+				"username": user.Username,
+				"password": "wrong-password",
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetLoginLockedUntil(gomock.Any(), gomock.Eq(lockParams)).
+					Times(1).
+					Return(pgtype.Timestamptz{}, nil)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				expectRecordLoginFailure(t, store, db.LoginThrottleKindUsername, user.Username, 8)
+				expectRecordLoginFailure(t, store, db.LoginThrottleKindIP, testClientIP, 21)
+				store.EXPECT().
+					LockLogin(gomock.Any(), eqLockLoginMatcher{db.LoginThrottleKindUsername, user.Username, 4 * time.Second}).
+					Times(1)
+				store.EXPECT().
+					LockLogin(gomock.Any(), eqLockLoginMatcher{db.LoginThrottleKindIP, testClientIP, time.Second}).
+					Times(1)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "Locked",
+			body: gin.H{
+				"username": user.Username,
+				"password": password,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetLoginLockedUntil(gomock.Any(), gomock.Eq(lockParams)).
+					Times(1).
+					Return(pgtype.Timestamptz{Time: time.Now().Add(30 * time.Second), Valid: true}, nil)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Any()).
+					Times(0)
+				store.EXPECT().
+					RecordLoginFailure(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusTooManyRequests, recorder.Code)
+				require.Equal(t, "30", recorder.Header().Get("Retry-After"))
+			},
+		},
+		{
+			name: "LockExpired",
+			body: gin.H{
+				"username": user.Username,
+				"password": password,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetLoginLockedUntil(gomock.Any(), gomock.Eq(lockParams)).
+					Times(1).
+					Return(pgtype.Timestamptz{Time: time.Now().Add(-time.Second), Valid: true}, nil)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					DeleteLoginThrottle(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(int64(1), nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "InvalidUsername",
+			body: gin.H{
+				"username": "invalid-user#1",
+				"password": password,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetLoginLockedUntil(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "LockCheckError",
+			body: gin.H{
+				"username": user.Username,
+				"password": password,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetLoginLockedUntil(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(pgtype.Timestamptz{}, pgx.ErrTxClosed)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+		{
+			name: "InternalError",
+			body: gin.H{
+				"username": user.Username,
+				"password": password,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetLoginLockedUntil(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(pgtype.Timestamptz{}, nil)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.User{}, pgx.ErrTxClosed)
+				store.EXPECT().
+					RecordLoginFailure(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			config := newTestConfig()
+			config.LoginUserFreeAttempts = 5
+			config.LoginIPFreeAttempts = 20
+			config.LoginBackoffBase = time.Second
+			config.LoginLockoutDuration = 15 * time.Minute
+			config.LoginFailureWindow = time.Hour
+			server := newTestServerWithConfig(t, store, config)
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/users/login", bytes.NewReader(data))
+			require.NoError(t, err)
+			request.RemoteAddr = testClientIP + ":1234"
+			for key, values := range tc.header {
+				request.Header[key] = values
+			}
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+type recordLoginFailureMatcher struct {
+	kind    string
+	subject string
+}
+
+func (e recordLoginFailureMatcher) Matches(x interface{}) bool {
+	arg, ok := x.(db.RecordLoginFailureParams)
+	return ok && arg.Kind == e.kind && arg.Subject == e.subject
+}
+
+func (e recordLoginFailureMatcher) String() string {
+	return "records a failed login for " + e.kind + " " + e.subject
+}
+
+// expectRecordLoginFailure expects one failed login against the subject and
+// reports the given number of consecutive failures
+func expectRecordLoginFailure(t *testing.T, store *mockdb.MockStore, kind, subject string, failures int32) {
+	store.EXPECT().
+		RecordLoginFailure(gomock.Any(), recordLoginFailureMatcher{kind: kind, subject: subject}).
+		Times(1).
+		DoAndReturn(func(_ context.Context, arg db.RecordLoginFailureParams) (db.LoginThrottle, error) {
+			require.WithinDuration(t, time.Now().Add(-time.Hour), arg.ResetBefore, time.Second)
+			return db.LoginThrottle{Kind: kind, Subject: subject, FailedAttempts: failures}, nil
+		})
+}
+
+func TestLoginTOTPLockout(t *testing.T) {
+	user, _ := randomTOTPUser(t)
+
+	testCases := []struct {
+		name          string
+		code          func(t *testing.T) string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "WrongCodeCountsAsFailure",
+			code: func(t *testing.T) string {
+				code, err := totp.Code(user.TotpSecret, time.Now().Add(-5*totp.Period))
+				require.NoError(t, err)
+				return code
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetLoginLockedUntil(gomock.Any(), gomock.Eq(db.GetLoginLockedUntilParams{
+						Username: user.Username,
+						Ip:       testClientIP,
+					})).
+					Times(1).
+					Return(pgtype.Timestamptz{}, nil)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				expectRecordLoginFailure(t, store, db.LoginThrottleKindUsername, user.Username, 1)
+				expectRecordLoginFailure(t, store, db.LoginThrottleKindIP, testClientIP, 1)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "OKResetsFailures",
+			code: func(t *testing.T) string {
+				return currentTOTPCode(t, user.TotpSecret)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetLoginLockedUntil(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(pgtype.Timestamptz{}, nil)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					UseTOTPStep(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					DeleteLoginThrottle(gomock.Any(), gomock.Eq(db.DeleteLoginThrottleParams{
+						Kind:    db.LoginThrottleKindUsername,
+						Subject: user.Username,
+					})).
+					Times(1).
+					Return(int64(1), nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "Locked",
+			code: func(t *testing.T) string {
+				return currentTOTPCode(t, user.TotpSecret)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetLoginLockedUntil(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(pgtype.Timestamptz{Time: time.Now().Add(time.Minute), Valid: true}, nil)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Any()).
+					Times(0)
+				store.EXPECT().
+					UseTOTPStep(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusTooManyRequests, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			config := newTestConfig()
+			config.LoginFailureWindow = time.Hour
+			server := newTestServerWithConfig(t, store, config)
+			recorder := httptest.NewRecorder()
+
+			challengeToken, err := server.challengeTokenMaker.CreateToken(user.Username, time.Minute)
+			require.NoError(t, err)
+
+			data, err := json.Marshal(gin.H{
+				"challenge_token": challengeToken,
+				"code":            tc.code(t),
+			})
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/users/login/totp", bytes.NewReader(data))
+			require.NoError(t, err)
+			request.RemoteAddr = testClientIP + ":1234"
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestUnlockLoginAPI(t *testing.T) {
+	admin, _ := randomUser(t)
+	admin.Role = util.AdminRole
+
+	depositor, _ := randomUser(t)
+	depositor.Role = util.DepositorRole
+
+	testCases := []struct {
+		name          string
+		user          db.User
+		body          gin.H
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "UnlockUsername",
+			user: admin,
+			body: gin.H{
+				"username": depositor.Username,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					DeleteLoginThrottle(gomock.Any(), gomock.Eq(db.DeleteLoginThrottleParams{
+						Kind:    db.LoginThrottleKindUsername,
+						Subject: depositor.Username,
+					})).
+					Times(1).
+					Return(int64(1), nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.JSONEq(t, `{"unlocked": true}`, recorder.Body.String())
+			},
+		},
+		{
+			name: "UnlockUsernameAndIP",
+			user: admin,
+			body: gin.H{
+				"username": depositor.Username,
+				"ip":       testClientIP,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					DeleteLoginThrottle(gomock.Any(), gomock.Eq(db.DeleteLoginThrottleParams{
+						Kind:    db.LoginThrottleKindUsername,
+						Subject: depositor.Username,
+					})).
+					Times(1).
+					Return(int64(0), nil)
+				store.EXPECT().
+					DeleteLoginThrottle(gomock.Any(), gomock.Eq(db.DeleteLoginThrottleParams{
+						Kind:    db.LoginThrottleKindIP,
+						Subject: testClientIP,
+					})).
+					Times(1).
+					Return(int64(1), nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.JSONEq(t, `{"unlocked": true}`, recorder.Body.String())
+			},
+		},
+		{
+			name: "NotLocked",
+			user: admin,
+			body: gin.H{
+				"ip": testClientIP,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					DeleteLoginThrottle(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(int64(0), nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.JSONEq(t, `{"unlocked": false}`, recorder.Body.String())
+			},
+		},
+		{
+			name: "NotAdmin",
+			user: depositor,
+			body: gin.H{
+				"username": depositor.Username,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					DeleteLoginThrottle(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name: "NothingToUnlock",
+			user: admin,
+			body: gin.H{},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					DeleteLoginThrottle(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "InvalidIP",
+			user: admin,
+			body: gin.H{
+				"ip": "not-an-ip",
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					DeleteLoginThrottle(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "InternalError",
+			user: admin,
+			body: gin.H{
+				"username": depositor.Username,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					DeleteLoginThrottle(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(int64(0), pgx.ErrTxClosed)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			store.EXPECT().
+				GetUser(gomock.Any(), gomock.Eq(tc.user.Username)).
+				AnyTimes().
+				Return(tc.user, nil)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/admin/login_locks/unlock", bytes.NewReader(data))
+			require.NoError(t, err)
+
+			middleware.AddTestAuthorization(t, request, server.tokenMaker, middleware.AuthorizationTypeBearer, tc.user.Username, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
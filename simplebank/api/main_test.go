@@ -1,29 +1,90 @@
 package api
 
 import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
 	"os"
 	"testing"
 	"time"
 
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/apperr"
+	mockdb "github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/db/mock"
 	db "github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/db/sqlc"
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/metrics"
 	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/util"
 	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/jackc/pgx/v5/pgtype"
 	_ "github.com/lib/pq"
 	"github.com/stretchr/testify/require"
 )
 
 func newTestServer(t *testing.T, store db.Store) *Server {
-	config := util.Config{
-		TokenSymmetricKey:   util.RandomString(32),
-		AccessTokenDuration: time.Minute,
+	return newTestServerWithConfig(t, store, newTestConfig())
+}
+
+// newTestConfig returns the config of newTestServer, which has rate
+// limiting turned off
+func newTestConfig() util.Config {
+	return util.Config{
+		TokenSymmetricKey:     util.RandomString(32),
+		AccessTokenDuration:   time.Minute,
+		AccountNumberCountry:  util.DefaultAccountNumberCountry,
+		PasswordResetDuration: 15 * time.Minute,
+		TOTPIssuer:            "Simple Bank",
+		TOTPChallengeDuration: time.Minute,
 	}
+}
 
-	server, err := NewServer(config, store)
+func newTestServerWithConfig(t *testing.T, store db.Store, config util.Config) *Server {
+	server, err := NewServer(config, store, metrics.New())
 	require.NoError(t, err)
 
 	return server
 }
 
+// stubAuthUser lets the auth middleware load whichever user a test
+// authenticates as, with a verified email. Stubs set up before it take precedence.
+func stubAuthUser(store *mockdb.MockStore) {
+	store.EXPECT().
+		GetUser(gomock.Any(), gomock.Any()).
+		AnyTimes().
+		DoAndReturn(func(_ context.Context, username string) (db.User, error) {
+			return db.User{Username: username, IsEmailVerified: true}, nil
+		})
+}
+
+// stubLoginThrottle lets logins through and accepts any failed attempts
+// without locking. Stubs set up before it take precedence.
+func stubLoginThrottle(store *mockdb.MockStore) {
+	store.EXPECT().
+		GetLoginLockedUntil(gomock.Any(), gomock.Any()).
+		AnyTimes().
+		Return(pgtype.Timestamptz{}, nil)
+	store.EXPECT().
+		RecordLoginFailure(gomock.Any(), gomock.Any()).
+		AnyTimes().
+		Return(db.LoginThrottle{FailedAttempts: 1}, nil)
+	store.EXPECT().
+		DeleteLoginThrottle(gomock.Any(), gomock.Any()).
+		AnyTimes().
+		Return(int64(1), nil)
+}
+
+// requireProblem checks that the response holds the problem details of an
+// error with the given status and code, and returns them
+func requireProblem(t *testing.T, recorder *httptest.ResponseRecorder, status int, code string) apperr.Problem {
+	require.Equal(t, status, recorder.Code)
+	require.Equal(t, apperr.ProblemContentType, recorder.Header().Get("Content-Type"))
+
+	var problem apperr.Problem
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &problem))
+	require.Equal(t, status, problem.Status)
+	require.Equal(t, code, problem.Code)
+	return problem
+}
+
 func TestMain(m *testing.M) {
 	gin.SetMode(gin.TestMode)
 	os.Exit(m.Run())
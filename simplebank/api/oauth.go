@@ -0,0 +1,590 @@
+package api
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"net/url"
+	"slices"
+	"time"
+
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/apperr"
+	db "github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/db/sqlc"
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/oauth"
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/token"
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/util"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/jackc/pgx/v5"
+)
+
+// oauthClientIDBytes is the size of the random part of client ids
+const oauthClientIDBytes = 16
+
+var (
+	errOAuthConsentRevoked  = apperr.New(http.StatusUnauthorized, "oauth_consent_revoked", "the user has revoked the client's access")
+	errOAuthConsentNotFound = apperr.New(http.StatusNotFound, "oauth_consent_not_found", "oauth consent not found")
+)
+
+// OAuthErrorResponse is the error body of RFC 6749. RedirectURI is set once
+// the client and its redirect uri are known, for the consent screen to send
+// the user back to the client with the error.
+type OAuthErrorResponse struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description,omitempty"`
+	RedirectURI      string `json:"redirect_uri,omitempty"`
+}
+
+func oauthErrorResponse(code, description string) OAuthErrorResponse {
+	return OAuthErrorResponse{Error: code, ErrorDescription: description}
+}
+
+type CreateOAuthClientRequest struct {
+	Name         string   `json:"name" binding:"required,max=64"`
+	RedirectURIs []string `json:"redirect_uris" binding:"required,min=1,unique,dive,redirect_uri"`
+	// Scopes are the most a user can grant the client; users and admins
+	// cannot be managed by third parties
+	Scopes []string `json:"scopes" binding:"required,min=1,unique,dive,scope,ne=user,ne=admin"`
+	// Confidential clients can keep a secret, which they must present to
+	// exchange authorization codes. Public clients rely on PKCE alone.
+	Confidential bool `json:"confidential"`
+}
+
+type OAuthClientResponse struct {
+	ClientID       string    `json:"client_id"`
+	Name           string    `json:"name"`
+	RedirectURIs   []string  `json:"redirect_uris"`
+	Scopes         []string  `json:"scopes"`
+	IsConfidential bool      `json:"is_confidential"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+func newOAuthClientResponse(client db.OauthClient) OAuthClientResponse {
+	return OAuthClientResponse{
+		ClientID:       client.ID,
+		Name:           client.Name,
+		RedirectURIs:   client.RedirectUris,
+		Scopes:         client.Scopes,
+		IsConfidential: client.HashedSecret != "",
+		CreatedAt:      client.CreatedAt,
+	}
+}
+
+type CreateOAuthClientResponse struct {
+	// ClientSecret is only ever shown in this response
+	ClientSecret string              `json:"client_secret,omitempty"`
+	Client       OAuthClientResponse `json:"client"`
+}
+
+// createOAuthClient registers a third-party application with the bank
+func (server *Server) createOAuthClient(ctx *gin.Context) {
+	var req CreateOAuthClientRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.Error(apperr.FromBinding(err))
+		return
+	}
+
+	clientID, err := util.GenerateSecret(oauthClientIDBytes)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	var secret, hashedSecret string
+	if req.Confidential {
+		secret, hashedSecret, err = oauth.GenerateSecret()
+		if err != nil {
+			ctx.Error(err)
+			return
+		}
+	}
+
+	client, err := server.store.CreateOAuthClient(ctx, db.CreateOAuthClientParams{
+		ID:           clientID,
+		Name:         req.Name,
+		HashedSecret: hashedSecret,
+		RedirectUris: req.RedirectURIs,
+		Scopes:       req.Scopes,
+	})
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	rsp := CreateOAuthClientResponse{
+		ClientSecret: secret,
+		Client:       newOAuthClientResponse(client),
+	}
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+// OAuthAuthorizeRequest is the authorization request of a client, passed on
+// by the consent screen as it was received.
+type OAuthAuthorizeRequest struct {
+	ResponseType        string `form:"response_type" json:"response_type"`
+	ClientID            string `form:"client_id" json:"client_id" binding:"required"`
+	RedirectURI         string `form:"redirect_uri" json:"redirect_uri" binding:"required"`
+	Scope               string `form:"scope" json:"scope"`
+	State               string `form:"state" json:"state"`
+	CodeChallenge       string `form:"code_challenge" json:"code_challenge"`
+	CodeChallengeMethod string `form:"code_challenge_method" json:"code_challenge_method"`
+}
+
+type OAuthAuthorizeResponse struct {
+	ClientID   string   `json:"client_id"`
+	ClientName string   `json:"client_name"`
+	Scopes     []string `json:"scopes"`
+	// Consented is true when the user has already granted all the scopes
+	Consented bool `json:"consented"`
+}
+
+type ApproveOAuthRequest struct {
+	OAuthAuthorizeRequest
+	Approve bool `json:"approve"`
+}
+
+// OAuthRedirectResponse tells the consent screen where to send the user
+type OAuthRedirectResponse struct {
+	RedirectURI string `json:"redirect_uri"`
+}
+
+// checkAuthorizeRequest validates an authorization request and returns the
+// client and the requested scopes. Until the redirect uri is known to belong
+// to the client, errors must not send the user to it.
+func (server *Server) checkAuthorizeRequest(ctx *gin.Context, req OAuthAuthorizeRequest) (db.OauthClient, []string, bool) {
+	client, err := server.store.GetOAuthClient(ctx, req.ClientID)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			ctx.JSON(http.StatusBadRequest, oauthErrorResponse(oauth.ErrorInvalidClient, "unknown client"))
+			return client, nil, false
+		}
+		ctx.Error(err)
+		return client, nil, false
+	}
+
+	if !slices.Contains(client.RedirectUris, req.RedirectURI) {
+		ctx.JSON(http.StatusBadRequest, oauthErrorResponse(oauth.ErrorInvalidRequest, "redirect_uri is not registered for the client"))
+		return client, nil, false
+	}
+
+	fail := func(code, description string) (db.OauthClient, []string, bool) {
+		rsp := oauthErrorResponse(code, description)
+		rsp.RedirectURI = oauth.RedirectURL(req.RedirectURI, url.Values{
+			"error":             {code},
+			"error_description": {description},
+			"state":             {req.State},
+		})
+		ctx.JSON(http.StatusBadRequest, rsp)
+		return client, nil, false
+	}
+
+	if req.ResponseType != oauth.ResponseTypeCode {
+		return fail(oauth.ErrorUnsupportedResponseType, "response_type must be code")
+	}
+	if req.CodeChallengeMethod != oauth.CodeChallengeMethodS256 || !oauth.IsValidCodeChallenge(req.CodeChallenge) {
+		return fail(oauth.ErrorInvalidRequest, "an S256 code_challenge is required")
+	}
+
+	scopes := oauth.ParseScope(req.Scope)
+	if len(scopes) == 0 {
+		scopes = slices.Clone(client.Scopes)
+	}
+	for _, scope := range scopes {
+		if !slices.Contains(client.Scopes, scope) {
+			return fail(oauth.ErrorInvalidScope, "the client cannot be granted "+scope)
+		}
+	}
+	slices.Sort(scopes)
+
+	return client, slices.Compact(scopes), true
+}
+
+// getOAuthAuthorization describes an authorization request to the consent
+// screen, which asks the user to approve or deny it.
+func (server *Server) getOAuthAuthorization(ctx *gin.Context) {
+	var req OAuthAuthorizeRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, oauthErrorResponse(oauth.ErrorInvalidRequest, apperr.FromBinding(err).Error()))
+		return
+	}
+
+	client, scopes, ok := server.checkAuthorizeRequest(ctx, req)
+	if !ok {
+		return
+	}
+
+	user := ctx.MustGet(authorizationUserKey).(db.User)
+
+	consented := false
+	consent, err := server.store.GetOAuthConsent(ctx, db.GetOAuthConsentParams{
+		Username: user.Username,
+		ClientID: client.ID,
+	})
+	switch {
+	case err == nil:
+		consented = containsAll(consent.Scopes, scopes)
+	case err != pgx.ErrNoRows:
+		ctx.Error(err)
+		return
+	}
+
+	rsp := OAuthAuthorizeResponse{
+		ClientID:   client.ID,
+		ClientName: client.Name,
+		Scopes:     scopes,
+		Consented:  consented,
+	}
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+// approveOAuthAuthorization records the user's answer to an authorization
+// request. Approving it issues an authorization code for the client.
+func (server *Server) approveOAuthAuthorization(ctx *gin.Context) {
+	var req ApproveOAuthRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, oauthErrorResponse(oauth.ErrorInvalidRequest, apperr.FromBinding(err).Error()))
+		return
+	}
+
+	client, scopes, ok := server.checkAuthorizeRequest(ctx, req.OAuthAuthorizeRequest)
+	if !ok {
+		return
+	}
+
+	params := url.Values{}
+	if req.State != "" {
+		params.Set("state", req.State)
+	}
+
+	if !req.Approve {
+		params.Set("error", oauth.ErrorAccessDenied)
+		ctx.JSON(http.StatusOK, OAuthRedirectResponse{RedirectURI: oauth.RedirectURL(req.RedirectURI, params)})
+		return
+	}
+
+	code, hashedCode, err := oauth.GenerateSecret()
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	user := ctx.MustGet(authorizationUserKey).(db.User)
+
+	_, err = server.store.AuthorizeOAuthTx(ctx, db.AuthorizeOAuthTxParams{
+		Username:      user.Username,
+		ClientID:      client.ID,
+		Scopes:        scopes,
+		HashedCode:    hashedCode,
+		RedirectURI:   req.RedirectURI,
+		CodeChallenge: req.CodeChallenge,
+		ExpiredAt:     time.Now().Add(server.config.OAuthCodeDuration),
+	})
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	params.Set("code", code)
+	ctx.JSON(http.StatusOK, OAuthRedirectResponse{RedirectURI: oauth.RedirectURL(req.RedirectURI, params)})
+}
+
+type OAuthTokenRequest struct {
+	GrantType    string `form:"grant_type" binding:"required"`
+	Code         string `form:"code" binding:"required"`
+	RedirectURI  string `form:"redirect_uri" binding:"required"`
+	CodeVerifier string `form:"code_verifier" binding:"required"`
+	// the client can authenticate with http basic auth instead
+	ClientID     string `form:"client_id"`
+	ClientSecret string `form:"client_secret"`
+}
+
+type OAuthTokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int64  `json:"expires_in"`
+	Scope       string `json:"scope"`
+}
+
+// createOAuthToken is the token endpoint, where clients exchange an
+// authorization code for an access token limited to the granted scopes.
+func (server *Server) createOAuthToken(ctx *gin.Context) {
+	ctx.Header("Cache-Control", "no-store")
+	ctx.Header("Pragma", "no-cache")
+
+	if grantType := ctx.PostForm("grant_type"); grantType != "" && grantType != oauth.GrantTypeAuthorizationCode {
+		ctx.JSON(http.StatusBadRequest, oauthErrorResponse(oauth.ErrorUnsupportedGrantType, "only the authorization_code grant is supported"))
+		return
+	}
+
+	var req OAuthTokenRequest
+	if err := ctx.ShouldBindWith(&req, binding.FormPost); err != nil {
+		ctx.JSON(http.StatusBadRequest, oauthErrorResponse(oauth.ErrorInvalidRequest, apperr.FromBinding(err).Error()))
+		return
+	}
+
+	client, ok := server.authenticateOAuthClient(ctx, req)
+	if !ok {
+		return
+	}
+
+	invalidGrant := func(description string) {
+		ctx.JSON(http.StatusBadRequest, oauthErrorResponse(oauth.ErrorInvalidGrant, description))
+	}
+
+	code, err := server.store.UseOAuthAuthorizationCode(ctx, oauth.HashSecret(req.Code))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			invalidGrant("the code is invalid, expired or already used")
+			return
+		}
+		ctx.Error(err)
+		return
+	}
+
+	if code.ClientID != client.ID {
+		invalidGrant("the code was issued to another client")
+		return
+	}
+	if code.RedirectUri != req.RedirectURI {
+		invalidGrant("redirect_uri does not match the authorization request")
+		return
+	}
+	if !oauth.VerifyCodeChallenge(req.CodeVerifier, code.CodeChallenge) {
+		invalidGrant("code_verifier does not match the code_challenge")
+		return
+	}
+
+	// the consent may have been revoked since the code was issued
+	consent, err := server.store.GetOAuthConsent(ctx, db.GetOAuthConsentParams{
+		Username: code.Username,
+		ClientID: code.ClientID,
+	})
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			invalidGrant(errOAuthConsentRevoked.Error())
+			return
+		}
+		ctx.Error(err)
+		return
+	}
+	if consent.GrantedAt.After(code.CreatedAt) || len(code.Scopes) == 0 || !containsAll(consent.Scopes, code.Scopes) {
+		invalidGrant(errOAuthConsentRevoked.Error())
+		return
+	}
+
+	duration := server.config.AccessTokenDuration
+	accessToken, _, err := server.tokenMaker.CreateClientToken(code.Username, client.ID, duration, code.Scopes...)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	rsp := OAuthTokenResponse{
+		AccessToken: accessToken,
+		TokenType:   oauth.TokenTypeBearer,
+		ExpiresIn:   int64(duration.Seconds()),
+		Scope:       oauth.FormatScope(code.Scopes),
+	}
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+// authenticateOAuthClient identifies the client at the token endpoint.
+// Confidential clients must present their secret, in basic auth or the form.
+func (server *Server) authenticateOAuthClient(ctx *gin.Context, req OAuthTokenRequest) (db.OauthClient, bool) {
+	clientID, secret := req.ClientID, req.ClientSecret
+	if username, password, ok := ctx.Request.BasicAuth(); ok {
+		// credentials in basic auth are form-encoded first
+		basicID, err1 := url.QueryUnescape(username)
+		basicSecret, err2 := url.QueryUnescape(password)
+		if err1 != nil || err2 != nil || (clientID != "" && clientID != basicID) {
+			ctx.JSON(http.StatusUnauthorized, oauthErrorResponse(oauth.ErrorInvalidClient, "invalid client credentials"))
+			return db.OauthClient{}, false
+		}
+		clientID, secret = basicID, basicSecret
+	}
+
+	if clientID == "" {
+		ctx.JSON(http.StatusUnauthorized, oauthErrorResponse(oauth.ErrorInvalidClient, "client_id is required"))
+		return db.OauthClient{}, false
+	}
+
+	client, err := server.store.GetOAuthClient(ctx, clientID)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			ctx.JSON(http.StatusUnauthorized, oauthErrorResponse(oauth.ErrorInvalidClient, "unknown client"))
+			return client, false
+		}
+		ctx.Error(err)
+		return client, false
+	}
+
+	if client.HashedSecret != "" {
+		hashedSecret := oauth.HashSecret(secret)
+		if secret == "" || subtle.ConstantTimeCompare([]byte(hashedSecret), []byte(client.HashedSecret)) != 1 {
+			ctx.JSON(http.StatusUnauthorized, oauthErrorResponse(oauth.ErrorInvalidClient, "invalid client credentials"))
+			return client, false
+		}
+	}
+
+	return client, true
+}
+
+// checkTokenClient rejects tokens of oauth clients once the user has revoked
+// their consent, or when it does not cover the scopes of the token. It must
+// run after checkTokenUser.
+func (server *Server) checkTokenClient(ctx *gin.Context, payload *token.Payload) error {
+	if err := server.authenticator.CheckTokenClient(ctx, payload); err != nil {
+		return authError(err)
+	}
+	return nil
+}
+
+type OAuthConsentResponse struct {
+	ClientID   string    `json:"client_id"`
+	ClientName string    `json:"client_name"`
+	Scopes     []string  `json:"scopes"`
+	GrantedAt  time.Time `json:"granted_at"`
+}
+
+func (server *Server) listOAuthConsents(ctx *gin.Context) {
+	user := ctx.MustGet(authorizationUserKey).(db.User)
+
+	consents, err := server.store.ListOAuthConsents(ctx, user.Username)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	rsp := make([]OAuthConsentResponse, len(consents))
+	for i, consent := range consents {
+		rsp[i] = OAuthConsentResponse{
+			ClientID:   consent.ClientID,
+			ClientName: consent.ClientName,
+			Scopes:     consent.Scopes,
+			GrantedAt:  consent.GrantedAt,
+		}
+	}
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+type RevokeOAuthConsentRequest struct {
+	ClientID string `uri:"client_id" binding:"required"`
+}
+
+// revokeOAuthConsent withdraws the user's consent to a client, which stops
+// its access tokens and unused authorization codes from working.
+func (server *Server) revokeOAuthConsent(ctx *gin.Context) {
+	var req RevokeOAuthConsentRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		ctx.Error(apperr.FromBinding(err))
+		return
+	}
+
+	user := ctx.MustGet(authorizationUserKey).(db.User)
+
+	deleted, err := server.store.DeleteOAuthConsent(ctx, db.DeleteOAuthConsentParams{
+		Username: user.Username,
+		ClientID: req.ClientID,
+	})
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+	if deleted == 0 {
+		ctx.Error(errOAuthConsentNotFound)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+// containsAll reports whether every scope in scopes is also in granted
+func containsAll(granted, scopes []string) bool {
+	for _, scope := range scopes {
+		if !slices.Contains(granted, scope) {
+			return false
+		}
+	}
+	return true
+}
+
+var oauthTokenOperations = []apiOperation{
+	{
+		method:        http.MethodPost,
+		path:          "/oauth/token",
+		tag:           "oauth",
+		summary:       "Exchange an authorization code for an access token",
+		form:          OAuthTokenRequest{},
+		response:      OAuthTokenResponse{},
+		errorResponse: OAuthErrorResponse{},
+	},
+}
+
+func (server *Server) setupOAuthTokenRoutes(router gin.IRoutes) {
+	router.POST("/oauth/token", server.rateLimits.login, server.createOAuthToken)
+}
+
+var oauthOperations = []apiOperation{
+	{
+		method:   http.MethodGet,
+		path:     "/oauth/authorize",
+		tag:      "oauth",
+		summary:  "Check an authorization request for the consent screen",
+		auth:     authToken,
+		scopes:   []string{token.ScopeUser},
+		query:    OAuthAuthorizeRequest{},
+		response: OAuthAuthorizeResponse{},
+	},
+	{
+		method:   http.MethodPost,
+		path:     "/oauth/authorize",
+		tag:      "oauth",
+		summary:  "Approve or deny an authorization request",
+		auth:     authToken,
+		scopes:   []string{token.ScopeUser},
+		body:     ApproveOAuthRequest{},
+		response: OAuthRedirectResponse{},
+	},
+	{
+		method:   http.MethodGet,
+		path:     "/users/me/oauth/consents",
+		tag:      "oauth",
+		summary:  "List the clients the authenticated user has granted access",
+		auth:     authToken,
+		scopes:   []string{token.ScopeUser},
+		response: []OAuthConsentResponse{},
+	},
+	{
+		method:  http.MethodDelete,
+		path:    "/users/me/oauth/consents/:client_id",
+		tag:     "oauth",
+		summary: "Revoke the access of a client",
+		auth:    authToken,
+		scopes:  []string{token.ScopeUser},
+		uri:     RevokeOAuthConsentRequest{},
+		status:  http.StatusNoContent,
+	},
+}
+
+func (server *Server) setupOAuthRoutes(router gin.IRoutes) {
+	router.GET("/oauth/authorize", server.getOAuthAuthorization)
+	router.POST("/oauth/authorize", server.approveOAuthAuthorization)
+	router.GET("/users/me/oauth/consents", server.listOAuthConsents)
+	router.DELETE("/users/me/oauth/consents/:client_id", server.revokeOAuthConsent)
+}
+
+var oauthClientOperations = []apiOperation{
+	{
+		method:   http.MethodPost,
+		path:     "/admin/oauth/clients",
+		tag:      "admin",
+		summary:  "Register an oauth client",
+		auth:     authTokenOrAPIKey,
+		scopes:   []string{token.ScopeAdmin},
+		body:     CreateOAuthClientRequest{},
+		response: CreateOAuthClientResponse{},
+	},
+}
+
+func (server *Server) setupOAuthClientRoutes(router gin.IRoutes) {
+	router.POST("/admin/oauth/clients", server.createOAuthClient)
+}
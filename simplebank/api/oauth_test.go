@@ -0,0 +1,1543 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	mockdb "github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/db/mock"
+	db "github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/db/sqlc"
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/middleware"
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/oauth"
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/token"
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/util"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/require"
+)
+
+const testRedirectURI = "https://partner.example.com/callback"
+
+// randomOAuthClient returns a client and, if it is confidential, its secret
+func randomOAuthClient(t *testing.T, confidential bool) (db.OauthClient, string) {
+	client := db.OauthClient{
+		ID:           util.RandomString(32),
+		Name:         util.RandomString(8),
+		RedirectUris: []string{testRedirectURI, "http://localhost:9000/callback"},
+		Scopes:       []string{token.ScopeAccountsRead, token.ScopeTransfersWrite},
+		CreatedAt:    time.Now().Add(-time.Hour),
+	}
+
+	var secret string
+	if confidential {
+		var err error
+		secret, client.HashedSecret, err = oauth.GenerateSecret()
+		require.NoError(t, err)
+	}
+	return client, secret
+}
+
+// authorizeQuery returns the parameters of a valid authorization request
+func authorizeQuery(clientID, codeVerifier string) url.Values {
+	return url.Values{
+		"response_type":         {oauth.ResponseTypeCode},
+		"client_id":             {clientID},
+		"redirect_uri":          {testRedirectURI},
+		"scope":                 {token.ScopeAccountsRead},
+		"state":                 {"xyz"},
+		"code_challenge":        {oauth.CodeChallenge(codeVerifier)},
+		"code_challenge_method": {oauth.CodeChallengeMethodS256},
+	}
+}
+
+func requireOAuthError(t *testing.T, recorder *httptest.ResponseRecorder, status int, code string) OAuthErrorResponse {
+	require.Equal(t, status, recorder.Code)
+
+	var rsp OAuthErrorResponse
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+	require.Equal(t, code, rsp.Error)
+	return rsp
+}
+
+type eqAuthorizeOAuthTxParamsMatcher struct {
+	username    string
+	clientID    string
+	scopes      []string
+	challenge   string
+	redirectURI string
+}
+
+func (e eqAuthorizeOAuthTxParamsMatcher) Matches(x interface{}) bool {
+	arg, ok := x.(db.AuthorizeOAuthTxParams)
+	if !ok {
+		return false
+	}
+
+	expiresIn := time.Until(arg.ExpiredAt)
+	return arg.Username == e.username && arg.ClientID == e.clientID &&
+		fmt.Sprint(arg.Scopes) == fmt.Sprint(e.scopes) &&
+		arg.CodeChallenge == e.challenge && arg.RedirectURI == e.redirectURI &&
+		arg.HashedCode != "" && expiresIn > 0 && expiresIn <= time.Minute
+}
+
+func (e eqAuthorizeOAuthTxParamsMatcher) String() string {
+	return fmt.Sprintf("authorizes %v for %v with scopes %v", e.clientID, e.username, e.scopes)
+}
+
+func TestCreateOAuthClientAPI(t *testing.T) {
+	admin, _ := randomUser(t)
+	admin.Role = util.AdminRole
+	user, _ := randomUser(t)
+
+	name := util.RandomString(8)
+
+	// createStored makes CreateOAuthClient return what it was asked to store
+	createStored := func(_ context.Context, arg db.CreateOAuthClientParams) (db.OauthClient, error) {
+		return db.OauthClient{
+			ID:           arg.ID,
+			Name:         arg.Name,
+			HashedSecret: arg.HashedSecret,
+			RedirectUris: arg.RedirectUris,
+			Scopes:       arg.Scopes,
+			CreatedAt:    time.Now(),
+		}, nil
+	}
+
+	testCases := []struct {
+		name          string
+		user          db.User
+		body          gin.H
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "Public",
+			user: admin,
+			body: gin.H{
+				"name":          name,
+				"redirect_uris": []string{testRedirectURI},
+				"scopes":        []string{token.ScopeAccountsRead},
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateOAuthClient(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(ctx context.Context, arg db.CreateOAuthClientParams) (db.OauthClient, error) {
+						require.NotEmpty(t, arg.ID)
+						require.Empty(t, arg.HashedSecret)
+						return createStored(ctx, arg)
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp CreateOAuthClientResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+				require.Empty(t, rsp.ClientSecret)
+				require.NotEmpty(t, rsp.Client.ClientID)
+				require.Equal(t, name, rsp.Client.Name)
+				require.Equal(t, []string{testRedirectURI}, rsp.Client.RedirectURIs)
+				require.Equal(t, []string{token.ScopeAccountsRead}, rsp.Client.Scopes)
+				require.False(t, rsp.Client.IsConfidential)
+			},
+		},
+		{
+			name: "Confidential",
+			user: admin,
+			body: gin.H{
+				"name":          name,
+				"redirect_uris": []string{testRedirectURI},
+				"scopes":        []string{token.ScopeAccountsRead},
+				"confidential":  true,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateOAuthClient(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(createStored)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp CreateOAuthClientResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+				require.NotEmpty(t, rsp.ClientSecret)
+				require.True(t, rsp.Client.IsConfidential)
+				require.NotContains(t, recorder.Body.String(), "hashed_secret")
+			},
+		},
+		{
+			name: "InsecureRedirectURI",
+			user: admin,
+			body: gin.H{
+				"name":          name,
+				"redirect_uris": []string{"http://partner.example.com/callback"},
+				"scopes":        []string{token.ScopeAccountsRead},
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateOAuthClient(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "NoRedirectURIs",
+			user: admin,
+			body: gin.H{
+				"name":   name,
+				"scopes": []string{token.ScopeAccountsRead},
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateOAuthClient(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			// third parties never get to manage users or the bank
+			name: "AdminScope",
+			user: admin,
+			body: gin.H{
+				"name":          name,
+				"redirect_uris": []string{testRedirectURI},
+				"scopes":        []string{token.ScopeAdmin},
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateOAuthClient(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "UserScope",
+			user: admin,
+			body: gin.H{
+				"name":          name,
+				"redirect_uris": []string{testRedirectURI},
+				"scopes":        []string{token.ScopeUser},
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateOAuthClient(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "NotAdmin",
+			user: user,
+			body: gin.H{
+				"name":          name,
+				"redirect_uris": []string{testRedirectURI},
+				"scopes":        []string{token.ScopeAccountsRead},
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateOAuthClient(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name: "InternalError",
+			user: admin,
+			body: gin.H{
+				"name":          name,
+				"redirect_uris": []string{testRedirectURI},
+				"scopes":        []string{token.ScopeAccountsRead},
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateOAuthClient(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.OauthClient{}, pgx.ErrTxClosed)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			store.EXPECT().
+				GetUser(gomock.Any(), gomock.Eq(tc.user.Username)).
+				AnyTimes().
+				Return(tc.user, nil)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/admin/oauth/clients", bytes.NewReader(data))
+			require.NoError(t, err)
+
+			middleware.AddTestAuthorization(t, request, server.tokenMaker, middleware.AuthorizationTypeBearer, tc.user.Username, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestGetOAuthAuthorizationAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	client, _ := randomOAuthClient(t, false)
+	verifier := util.RandomString(43)
+
+	withQuery := func(change func(query url.Values)) url.Values {
+		query := authorizeQuery(client.ID, verifier)
+		change(query)
+		return query
+	}
+
+	testCases := []struct {
+		name          string
+		query         url.Values
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:  "OK",
+			query: authorizeQuery(client.ID, verifier),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetOAuthClient(gomock.Any(), gomock.Eq(client.ID)).
+					Times(1).
+					Return(client, nil)
+				store.EXPECT().
+					GetOAuthConsent(gomock.Any(), gomock.Eq(db.GetOAuthConsentParams{Username: user.Username, ClientID: client.ID})).
+					Times(1).
+					Return(db.OauthConsent{}, pgx.ErrNoRows)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp OAuthAuthorizeResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+				require.Equal(t, client.ID, rsp.ClientID)
+				require.Equal(t, client.Name, rsp.ClientName)
+				require.Equal(t, []string{token.ScopeAccountsRead}, rsp.Scopes)
+				require.False(t, rsp.Consented)
+			},
+		},
+		{
+			name: "DefaultsToClientScopes",
+			query: withQuery(func(query url.Values) {
+				query.Del("scope")
+			}),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetOAuthClient(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(client, nil)
+				store.EXPECT().
+					GetOAuthConsent(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.OauthConsent{Scopes: []string{token.ScopeAccountsRead}}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp OAuthAuthorizeResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+				require.Equal(t, []string{token.ScopeAccountsRead, token.ScopeTransfersWrite}, rsp.Scopes)
+				require.False(t, rsp.Consented)
+			},
+		},
+		{
+			name:  "Consented",
+			query: authorizeQuery(client.ID, verifier),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetOAuthClient(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(client, nil)
+				store.EXPECT().
+					GetOAuthConsent(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.OauthConsent{Scopes: client.Scopes}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp OAuthAuthorizeResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+				require.True(t, rsp.Consented)
+			},
+		},
+		{
+			name:  "UnknownClient",
+			query: authorizeQuery(client.ID, verifier),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetOAuthClient(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.OauthClient{}, pgx.ErrNoRows)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				rsp := requireOAuthError(t, recorder, http.StatusBadRequest, oauth.ErrorInvalidClient)
+				require.Empty(t, rsp.RedirectURI)
+			},
+		},
+		{
+			// the user must never be sent to a uri the client did not register
+			name: "UnregisteredRedirectURI",
+			query: withQuery(func(query url.Values) {
+				query.Set("redirect_uri", "https://attacker.example.com/callback")
+			}),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetOAuthClient(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(client, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				rsp := requireOAuthError(t, recorder, http.StatusBadRequest, oauth.ErrorInvalidRequest)
+				require.Empty(t, rsp.RedirectURI)
+			},
+		},
+		{
+			name: "UnsupportedResponseType",
+			query: withQuery(func(query url.Values) {
+				query.Set("response_type", "token")
+			}),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetOAuthClient(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(client, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireOAuthError(t, recorder, http.StatusBadRequest, oauth.ErrorUnsupportedResponseType)
+			},
+		},
+		{
+			name: "PlainCodeChallenge",
+			query: withQuery(func(query url.Values) {
+				query.Set("code_challenge", verifier)
+				query.Set("code_challenge_method", "plain")
+			}),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetOAuthClient(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(client, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				rsp := requireOAuthError(t, recorder, http.StatusBadRequest, oauth.ErrorInvalidRequest)
+
+				redirect, err := url.Parse(rsp.RedirectURI)
+				require.NoError(t, err)
+				require.True(t, strings.HasPrefix(rsp.RedirectURI, testRedirectURI))
+				require.Equal(t, oauth.ErrorInvalidRequest, redirect.Query().Get("error"))
+				require.Equal(t, "xyz", redirect.Query().Get("state"))
+			},
+		},
+		{
+			name: "NoCodeChallenge",
+			query: withQuery(func(query url.Values) {
+				query.Del("code_challenge")
+			}),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetOAuthClient(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(client, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireOAuthError(t, recorder, http.StatusBadRequest, oauth.ErrorInvalidRequest)
+			},
+		},
+		{
+			name: "ScopeNotAllowed",
+			query: withQuery(func(query url.Values) {
+				query.Set("scope", token.ScopeAccountsRead+" "+token.ScopeAccountsWrite)
+			}),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetOAuthClient(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(client, nil)
+				store.EXPECT().
+					GetOAuthConsent(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireOAuthError(t, recorder, http.StatusBadRequest, oauth.ErrorInvalidScope)
+			},
+		},
+		{
+			// clients cannot approve their own requests
+			name:  "ClientToken",
+			query: authorizeQuery(client.ID, verifier),
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				accessToken, _, err := tokenMaker.CreateClientToken(user.Username, client.ID, time.Minute, token.ScopeAccountsRead)
+				require.NoError(t, err)
+				request.Header.Set(middleware.AuthorizationHeaderKey, "Bearer "+accessToken)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetOAuthClient(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name:  "NoAuthorization",
+			query: authorizeQuery(client.ID, verifier),
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetOAuthClient(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+			stubAuthUser(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			request, err := http.NewRequest(http.MethodGet, "/oauth/authorize?"+tc.query.Encode(), nil)
+			require.NoError(t, err)
+
+			if tc.setupAuth != nil {
+				tc.setupAuth(t, request, server.tokenMaker)
+			} else {
+				middleware.AddTestAuthorization(t, request, server.tokenMaker, middleware.AuthorizationTypeBearer, user.Username, time.Minute)
+			}
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestApproveOAuthAuthorizationAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	client, _ := randomOAuthClient(t, false)
+	verifier := util.RandomString(43)
+
+	body := func(approve bool) gin.H {
+		body := gin.H{"approve": approve}
+		for key, values := range authorizeQuery(client.ID, verifier) {
+			body[key] = values[0]
+		}
+		return body
+	}
+
+	testCases := []struct {
+		name          string
+		body          gin.H
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "Approve",
+			body: body(true),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetOAuthClient(gomock.Any(), gomock.Eq(client.ID)).
+					Times(1).
+					Return(client, nil)
+				store.EXPECT().
+					AuthorizeOAuthTx(gomock.Any(), eqAuthorizeOAuthTxParamsMatcher{
+						username:    user.Username,
+						clientID:    client.ID,
+						scopes:      []string{token.ScopeAccountsRead},
+						challenge:   oauth.CodeChallenge(verifier),
+						redirectURI: testRedirectURI,
+					}).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg db.AuthorizeOAuthTxParams) (db.AuthorizeOAuthTxResult, error) {
+						return db.AuthorizeOAuthTxResult{
+							AuthorizationCode: db.OauthAuthorizationCode{HashedCode: arg.HashedCode},
+						}, nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp OAuthRedirectResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+				require.True(t, strings.HasPrefix(rsp.RedirectURI, testRedirectURI+"?"))
+
+				redirect, err := url.Parse(rsp.RedirectURI)
+				require.NoError(t, err)
+				require.NotEmpty(t, redirect.Query().Get("code"))
+				require.Equal(t, "xyz", redirect.Query().Get("state"))
+				require.Empty(t, redirect.Query().Get("error"))
+			},
+		},
+		{
+			name: "Deny",
+			body: body(false),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetOAuthClient(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(client, nil)
+				store.EXPECT().
+					AuthorizeOAuthTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp OAuthRedirectResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+
+				redirect, err := url.Parse(rsp.RedirectURI)
+				require.NoError(t, err)
+				require.Equal(t, oauth.ErrorAccessDenied, redirect.Query().Get("error"))
+				require.Equal(t, "xyz", redirect.Query().Get("state"))
+				require.Empty(t, redirect.Query().Get("code"))
+			},
+		},
+		{
+			name: "UnknownClient",
+			body: body(true),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetOAuthClient(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.OauthClient{}, pgx.ErrNoRows)
+				store.EXPECT().
+					AuthorizeOAuthTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireOAuthError(t, recorder, http.StatusBadRequest, oauth.ErrorInvalidClient)
+			},
+		},
+		{
+			name: "InternalError",
+			body: body(true),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetOAuthClient(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(client, nil)
+				store.EXPECT().
+					AuthorizeOAuthTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.AuthorizeOAuthTxResult{}, pgx.ErrTxClosed)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+			stubAuthUser(store)
+
+			server := newTestServer(t, store)
+			server.config.OAuthCodeDuration = time.Minute
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/oauth/authorize", bytes.NewReader(data))
+			require.NoError(t, err)
+
+			middleware.AddTestAuthorization(t, request, server.tokenMaker, middleware.AuthorizationTypeBearer, user.Username, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestCreateOAuthTokenAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	publicClient, _ := randomOAuthClient(t, false)
+	confidentialClient, secret := randomOAuthClient(t, true)
+
+	verifier := util.RandomString(64)
+	code, hashedCode, err := oauth.GenerateSecret()
+	require.NoError(t, err)
+
+	authorizationCode := func(client db.OauthClient) db.OauthAuthorizationCode {
+		return db.OauthAuthorizationCode{
+			HashedCode:    hashedCode,
+			ClientID:      client.ID,
+			Username:      user.Username,
+			RedirectUri:   testRedirectURI,
+			Scopes:        []string{token.ScopeAccountsRead},
+			CodeChallenge: oauth.CodeChallenge(verifier),
+			IsUsed:        true,
+			CreatedAt:     time.Now().Add(-time.Second),
+			ExpiredAt:     time.Now().Add(time.Minute),
+		}
+	}
+	consent := db.OauthConsent{
+		Username:  user.Username,
+		ClientID:  publicClient.ID,
+		Scopes:    []string{token.ScopeAccountsRead},
+		GrantedAt: time.Now().Add(-time.Second),
+	}
+
+	form := func(clientID string) url.Values {
+		return url.Values{
+			"grant_type":    {oauth.GrantTypeAuthorizationCode},
+			"code":          {code},
+			"redirect_uri":  {testRedirectURI},
+			"code_verifier": {verifier},
+			"client_id":     {clientID},
+		}
+	}
+	withForm := func(clientID string, change func(form url.Values)) url.Values {
+		form := form(clientID)
+		change(form)
+		return form
+	}
+
+	// stubExchange lets a code issued to the client through, up to the consent
+	stubExchange := func(store *mockdb.MockStore, client db.OauthClient) {
+		store.EXPECT().
+			GetOAuthClient(gomock.Any(), gomock.Eq(client.ID)).
+			Times(1).
+			Return(client, nil)
+		store.EXPECT().
+			UseOAuthAuthorizationCode(gomock.Any(), gomock.Eq(hashedCode)).
+			Times(1).
+			Return(authorizationCode(client), nil)
+	}
+
+	testCases := []struct {
+		name          string
+		form          url.Values
+		setupAuth     func(request *http.Request)
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder, tokenMaker token.Maker)
+	}{
+		{
+			name: "OK",
+			form: form(publicClient.ID),
+			buildStubs: func(store *mockdb.MockStore) {
+				stubExchange(store, publicClient)
+				store.EXPECT().
+					GetOAuthConsent(gomock.Any(), gomock.Eq(db.GetOAuthConsentParams{Username: user.Username, ClientID: publicClient.ID})).
+					Times(1).
+					Return(consent, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, tokenMaker token.Maker) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.Equal(t, "no-store", recorder.Header().Get("Cache-Control"))
+
+				var rsp OAuthTokenResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+				require.Equal(t, oauth.TokenTypeBearer, rsp.TokenType)
+				require.Equal(t, int64(60), rsp.ExpiresIn)
+				require.Equal(t, token.ScopeAccountsRead, rsp.Scope)
+
+				payload, err := tokenMaker.VerifyToken(rsp.AccessToken)
+				require.NoError(t, err)
+				require.Equal(t, user.Username, payload.Username)
+				require.Equal(t, publicClient.ID, payload.ClientID)
+				require.Equal(t, []string{token.ScopeAccountsRead}, payload.Scopes)
+			},
+		},
+		{
+			name: "ConfidentialClientBasicAuth",
+			form: withForm("", func(form url.Values) {}),
+			setupAuth: func(request *http.Request) {
+				request.SetBasicAuth(confidentialClient.ID, secret)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				stubExchange(store, confidentialClient)
+				store.EXPECT().
+					GetOAuthConsent(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(consent, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, tokenMaker token.Maker) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "ConfidentialClientFormSecret",
+			form: withForm(confidentialClient.ID, func(form url.Values) {
+				form.Set("client_secret", secret)
+			}),
+			buildStubs: func(store *mockdb.MockStore) {
+				stubExchange(store, confidentialClient)
+				store.EXPECT().
+					GetOAuthConsent(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(consent, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, tokenMaker token.Maker) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "ConfidentialClientNoSecret",
+			form: form(confidentialClient.ID),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetOAuthClient(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(confidentialClient, nil)
+				store.EXPECT().
+					UseOAuthAuthorizationCode(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, tokenMaker token.Maker) {
+				requireOAuthError(t, recorder, http.StatusUnauthorized, oauth.ErrorInvalidClient)
+			},
+		},
+		{
+			name: "ConfidentialClientWrongSecret",
+			form: withForm("", func(form url.Values) {}),
+			setupAuth: func(request *http.Request) {
+				request.SetBasicAuth(confidentialClient.ID, secret+"x")
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetOAuthClient(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(confidentialClient, nil)
+				store.EXPECT().
+					UseOAuthAuthorizationCode(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, tokenMaker token.Maker) {
+				requireOAuthError(t, recorder, http.StatusUnauthorized, oauth.ErrorInvalidClient)
+			},
+		},
+		{
+			name: "UnknownClient",
+			form: form(publicClient.ID),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetOAuthClient(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.OauthClient{}, pgx.ErrNoRows)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, tokenMaker token.Maker) {
+				requireOAuthError(t, recorder, http.StatusUnauthorized, oauth.ErrorInvalidClient)
+			},
+		},
+		{
+			name: "NoClientID",
+			form: form(""),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetOAuthClient(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, tokenMaker token.Maker) {
+				requireOAuthError(t, recorder, http.StatusUnauthorized, oauth.ErrorInvalidClient)
+			},
+		},
+		{
+			name: "UsedCode",
+			form: form(publicClient.ID),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetOAuthClient(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(publicClient, nil)
+				store.EXPECT().
+					UseOAuthAuthorizationCode(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.OauthAuthorizationCode{}, pgx.ErrNoRows)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, tokenMaker token.Maker) {
+				requireOAuthError(t, recorder, http.StatusBadRequest, oauth.ErrorInvalidGrant)
+			},
+		},
+		{
+			name: "CodeOfOtherClient",
+			form: form(publicClient.ID),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetOAuthClient(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(publicClient, nil)
+				store.EXPECT().
+					UseOAuthAuthorizationCode(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(authorizationCode(confidentialClient), nil)
+				store.EXPECT().
+					GetOAuthConsent(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, tokenMaker token.Maker) {
+				requireOAuthError(t, recorder, http.StatusBadRequest, oauth.ErrorInvalidGrant)
+			},
+		},
+		{
+			name: "RedirectURIMismatch",
+			form: withForm(publicClient.ID, func(form url.Values) {
+				form.Set("redirect_uri", "http://localhost:9000/callback")
+			}),
+			buildStubs: func(store *mockdb.MockStore) {
+				stubExchange(store, publicClient)
+				store.EXPECT().
+					GetOAuthConsent(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, tokenMaker token.Maker) {
+				requireOAuthError(t, recorder, http.StatusBadRequest, oauth.ErrorInvalidGrant)
+			},
+		},
+		{
+			name: "WrongCodeVerifier",
+			form: withForm(publicClient.ID, func(form url.Values) {
+				form.Set("code_verifier", util.RandomString(64))
+			}),
+			buildStubs: func(store *mockdb.MockStore) {
+				stubExchange(store, publicClient)
+				store.EXPECT().
+					GetOAuthConsent(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, tokenMaker token.Maker) {
+				requireOAuthError(t, recorder, http.StatusBadRequest, oauth.ErrorInvalidGrant)
+			},
+		},
+		{
+			name: "ConsentRevoked",
+			form: form(publicClient.ID),
+			buildStubs: func(store *mockdb.MockStore) {
+				stubExchange(store, publicClient)
+				store.EXPECT().
+					GetOAuthConsent(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.OauthConsent{}, pgx.ErrNoRows)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, tokenMaker token.Maker) {
+				requireOAuthError(t, recorder, http.StatusBadRequest, oauth.ErrorInvalidGrant)
+			},
+		},
+		{
+			// revoking and granting again does not revive codes from before
+			name: "ConsentGrantedAfterCode",
+			form: form(publicClient.ID),
+			buildStubs: func(store *mockdb.MockStore) {
+				stubExchange(store, publicClient)
+
+				regranted := consent
+				regranted.GrantedAt = time.Now()
+				store.EXPECT().
+					GetOAuthConsent(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(regranted, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, tokenMaker token.Maker) {
+				requireOAuthError(t, recorder, http.StatusBadRequest, oauth.ErrorInvalidGrant)
+			},
+		},
+		{
+			name: "ConsentNarrowed",
+			form: form(publicClient.ID),
+			buildStubs: func(store *mockdb.MockStore) {
+				stubExchange(store, publicClient)
+
+				narrowed := consent
+				narrowed.Scopes = []string{token.ScopeTransfersWrite}
+				store.EXPECT().
+					GetOAuthConsent(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(narrowed, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, tokenMaker token.Maker) {
+				requireOAuthError(t, recorder, http.StatusBadRequest, oauth.ErrorInvalidGrant)
+			},
+		},
+		{
+			name: "UnsupportedGrantType",
+			form: withForm(publicClient.ID, func(form url.Values) {
+				form.Set("grant_type", "password")
+			}),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetOAuthClient(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, tokenMaker token.Maker) {
+				requireOAuthError(t, recorder, http.StatusBadRequest, oauth.ErrorUnsupportedGrantType)
+			},
+		},
+		{
+			name: "NoCodeVerifier",
+			form: withForm(publicClient.ID, func(form url.Values) {
+				form.Del("code_verifier")
+			}),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetOAuthClient(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, tokenMaker token.Maker) {
+				requireOAuthError(t, recorder, http.StatusBadRequest, oauth.ErrorInvalidRequest)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			request, err := http.NewRequest(http.MethodPost, "/oauth/token", strings.NewReader(tc.form.Encode()))
+			require.NoError(t, err)
+			request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+			if tc.setupAuth != nil {
+				tc.setupAuth(request)
+			}
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder, server.tokenMaker)
+		})
+	}
+}
+
+func TestOAuthClientTokenAuthorization(t *testing.T) {
+	user, _ := randomUser(t)
+	account := randomAccount(user.Username)
+	client, _ := randomOAuthClient(t, false)
+
+	consent := db.OauthConsent{
+		Username:  user.Username,
+		ClientID:  client.ID,
+		Scopes:    []string{token.ScopeAccountsRead},
+		GrantedAt: time.Now().Add(-time.Minute),
+	}
+
+	testCases := []struct {
+		name          string
+		scopes        []string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:   "OK",
+			scopes: []string{token.ScopeAccountsRead},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetOAuthConsent(gomock.Any(), gomock.Eq(db.GetOAuthConsentParams{Username: user.Username, ClientID: client.ID})).
+					Times(1).
+					Return(consent, nil)
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+					Times(1).
+					Return(account, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				requireBodyMatchAccount(t, recorder, account)
+			},
+		},
+		{
+			name:   "ConsentRevoked",
+			scopes: []string{token.ScopeAccountsRead},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetOAuthConsent(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.OauthConsent{}, pgx.ErrNoRows)
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name:   "ConsentGrantedAfterToken",
+			scopes: []string{token.ScopeAccountsRead},
+			buildStubs: func(store *mockdb.MockStore) {
+				regranted := consent
+				regranted.GrantedAt = time.Now().Add(time.Second)
+				store.EXPECT().
+					GetOAuthConsent(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(regranted, nil)
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name:   "ConsentNarrowed",
+			scopes: []string{token.ScopeAccountsRead, token.ScopeTransfersWrite},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetOAuthConsent(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(consent, nil)
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name:   "ScopeNotGranted",
+			scopes: []string{token.ScopeTransfersWrite},
+			buildStubs: func(store *mockdb.MockStore) {
+				granted := consent
+				granted.Scopes = []string{token.ScopeTransfersWrite}
+				store.EXPECT().
+					GetOAuthConsent(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(granted, nil)
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+			stubAuthUser(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			request, err := http.NewRequest(http.MethodGet, fmt.Sprintf("/accounts/%d", account.ID), nil)
+			require.NoError(t, err)
+
+			accessToken, _, err := server.tokenMaker.CreateClientToken(user.Username, client.ID, time.Minute, tc.scopes...)
+			require.NoError(t, err)
+			request.Header.Set(middleware.AuthorizationHeaderKey, "Bearer "+accessToken)
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestListOAuthConsentsAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	client, _ := randomOAuthClient(t, false)
+
+	consents := []db.ListOAuthConsentsRow{
+		{
+			Username:   user.Username,
+			ClientID:   client.ID,
+			Scopes:     []string{token.ScopeAccountsRead},
+			GrantedAt:  time.Now().Truncate(time.Second),
+			ClientName: client.Name,
+		},
+	}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		ListOAuthConsents(gomock.Any(), gomock.Eq(user.Username)).
+		Times(1).
+		Return(consents, nil)
+	stubAuthUser(store)
+
+	server := newTestServer(t, store)
+	recorder := httptest.NewRecorder()
+
+	request, err := http.NewRequest(http.MethodGet, "/users/me/oauth/consents", nil)
+	require.NoError(t, err)
+
+	middleware.AddTestAuthorization(t, request, server.tokenMaker, middleware.AuthorizationTypeBearer, user.Username, time.Minute)
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	var rsp []OAuthConsentResponse
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+	require.Len(t, rsp, 1)
+	require.Equal(t, client.ID, rsp[0].ClientID)
+	require.Equal(t, client.Name, rsp[0].ClientName)
+	require.Equal(t, consents[0].Scopes, rsp[0].Scopes)
+	require.WithinDuration(t, consents[0].GrantedAt, rsp[0].GrantedAt, time.Second)
+}
+
+func TestRevokeOAuthConsentAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	client, _ := randomOAuthClient(t, false)
+
+	testCases := []struct {
+		name          string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					DeleteOAuthConsent(gomock.Any(), gomock.Eq(db.DeleteOAuthConsentParams{Username: user.Username, ClientID: client.ID})).
+					Times(1).
+					Return(int64(1), nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNoContent, recorder.Code)
+			},
+		},
+		{
+			name: "NotFound",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					DeleteOAuthConsent(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(int64(0), nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name: "InternalError",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					DeleteOAuthConsent(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(int64(0), pgx.ErrTxClosed)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+			stubAuthUser(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			request, err := http.NewRequest(http.MethodDelete, "/users/me/oauth/consents/"+client.ID, nil)
+			require.NoError(t, err)
+
+			middleware.AddTestAuthorization(t, request, server.tokenMaker, middleware.AuthorizationTypeBearer, user.Username, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+// fakeOAuthStore keeps the oauth rows of TestOAuthFlow in memory
+type fakeOAuthStore struct {
+	clients  map[string]db.OauthClient
+	consents map[string]db.OauthConsent
+	codes    map[string]db.OauthAuthorizationCode
+}
+
+func newFakeOAuthStore(store *mockdb.MockStore) *fakeOAuthStore {
+	fake := &fakeOAuthStore{
+		clients:  map[string]db.OauthClient{},
+		consents: map[string]db.OauthConsent{},
+		codes:    map[string]db.OauthAuthorizationCode{},
+	}
+
+	store.EXPECT().
+		CreateOAuthClient(gomock.Any(), gomock.Any()).
+		AnyTimes().
+		DoAndReturn(func(_ context.Context, arg db.CreateOAuthClientParams) (db.OauthClient, error) {
+			client := db.OauthClient{
+				ID:           arg.ID,
+				Name:         arg.Name,
+				HashedSecret: arg.HashedSecret,
+				RedirectUris: arg.RedirectUris,
+				Scopes:       arg.Scopes,
+				CreatedAt:    time.Now(),
+			}
+			fake.clients[client.ID] = client
+			return client, nil
+		})
+	store.EXPECT().
+		GetOAuthClient(gomock.Any(), gomock.Any()).
+		AnyTimes().
+		DoAndReturn(func(_ context.Context, id string) (db.OauthClient, error) {
+			client, ok := fake.clients[id]
+			if !ok {
+				return client, pgx.ErrNoRows
+			}
+			return client, nil
+		})
+	store.EXPECT().
+		AuthorizeOAuthTx(gomock.Any(), gomock.Any()).
+		AnyTimes().
+		DoAndReturn(func(_ context.Context, arg db.AuthorizeOAuthTxParams) (db.AuthorizeOAuthTxResult, error) {
+			now := time.Now()
+			consent, ok := fake.consents[arg.Username+"/"+arg.ClientID]
+			if !ok {
+				consent = db.OauthConsent{Username: arg.Username, ClientID: arg.ClientID, GrantedAt: now}
+			}
+			consent.Scopes = arg.Scopes
+			fake.consents[arg.Username+"/"+arg.ClientID] = consent
+
+			code := db.OauthAuthorizationCode{
+				HashedCode:    arg.HashedCode,
+				ClientID:      arg.ClientID,
+				Username:      arg.Username,
+				RedirectUri:   arg.RedirectURI,
+				Scopes:        arg.Scopes,
+				CodeChallenge: arg.CodeChallenge,
+				CreatedAt:     now,
+				ExpiredAt:     arg.ExpiredAt,
+			}
+			fake.codes[code.HashedCode] = code
+			return db.AuthorizeOAuthTxResult{Consent: consent, AuthorizationCode: code}, nil
+		})
+	store.EXPECT().
+		UseOAuthAuthorizationCode(gomock.Any(), gomock.Any()).
+		AnyTimes().
+		DoAndReturn(func(_ context.Context, hashedCode string) (db.OauthAuthorizationCode, error) {
+			code, ok := fake.codes[hashedCode]
+			if !ok || code.IsUsed || time.Now().After(code.ExpiredAt) {
+				return db.OauthAuthorizationCode{}, pgx.ErrNoRows
+			}
+			code.IsUsed = true
+			fake.codes[hashedCode] = code
+			return code, nil
+		})
+	store.EXPECT().
+		GetOAuthConsent(gomock.Any(), gomock.Any()).
+		AnyTimes().
+		DoAndReturn(func(_ context.Context, arg db.GetOAuthConsentParams) (db.OauthConsent, error) {
+			consent, ok := fake.consents[arg.Username+"/"+arg.ClientID]
+			if !ok {
+				return consent, pgx.ErrNoRows
+			}
+			return consent, nil
+		})
+	store.EXPECT().
+		DeleteOAuthConsent(gomock.Any(), gomock.Any()).
+		AnyTimes().
+		DoAndReturn(func(_ context.Context, arg db.DeleteOAuthConsentParams) (int64, error) {
+			key := arg.Username + "/" + arg.ClientID
+			if _, ok := fake.consents[key]; !ok {
+				return 0, nil
+			}
+			delete(fake.consents, key)
+			return 1, nil
+		})
+
+	return fake
+}
+
+// TestOAuthFlow plays a third-party client through the whole flow: it is
+// registered, gets the user's consent, exchanges the code for a token, reads
+// an account with it, and loses access when the user revokes the consent.
+func TestOAuthFlow(t *testing.T) {
+	admin, _ := randomUser(t)
+	admin.Role = util.AdminRole
+	user, _ := randomUser(t)
+	account := randomAccount(user.Username)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	newFakeOAuthStore(store)
+	store.EXPECT().
+		GetUser(gomock.Any(), gomock.Eq(admin.Username)).
+		AnyTimes().
+		Return(admin, nil)
+	store.EXPECT().
+		GetUser(gomock.Any(), gomock.Eq(user.Username)).
+		AnyTimes().
+		Return(user, nil)
+	store.EXPECT().
+		GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+		AnyTimes().
+		Return(account, nil)
+
+	server := newTestServer(t, store)
+	server.config.OAuthCodeDuration = time.Minute
+
+	serve := func(request *http.Request) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		server.router.ServeHTTP(recorder, request)
+		return recorder
+	}
+	asUser := func(request *http.Request, username string) *http.Request {
+		middleware.AddTestAuthorization(t, request, server.tokenMaker, middleware.AuthorizationTypeBearer, username, time.Minute)
+		return request
+	}
+	jsonRequest := func(method, url string, body any) *http.Request {
+		data, err := json.Marshal(body)
+		require.NoError(t, err)
+		request, err := http.NewRequest(method, url, bytes.NewReader(data))
+		require.NoError(t, err)
+		return request
+	}
+
+	// an admin registers the client
+	recorder := serve(asUser(jsonRequest(http.MethodPost, "/admin/oauth/clients", gin.H{
+		"name":          "Budgeting App",
+		"redirect_uris": []string{testRedirectURI},
+		"scopes":        []string{token.ScopeAccountsRead, token.ScopeTransfersWrite},
+		"confidential":  true,
+	}), admin.Username))
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	var registered CreateOAuthClientResponse
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &registered))
+	clientID, clientSecret := registered.Client.ClientID, registered.ClientSecret
+
+	// the client sends the user to the consent screen, which shows the request
+	verifier := util.RandomString(64)
+	query := authorizeQuery(clientID, verifier)
+
+	request, err := http.NewRequest(http.MethodGet, "/oauth/authorize?"+query.Encode(), nil)
+	require.NoError(t, err)
+	recorder = serve(asUser(request, user.Username))
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	var authorization OAuthAuthorizeResponse
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &authorization))
+	require.Equal(t, "Budgeting App", authorization.ClientName)
+	require.Equal(t, []string{token.ScopeAccountsRead}, authorization.Scopes)
+	require.False(t, authorization.Consented)
+
+	// the user approves and is sent back to the client with a code
+	approval := gin.H{"approve": true}
+	for key := range query {
+		approval[key] = query.Get(key)
+	}
+	recorder = serve(asUser(jsonRequest(http.MethodPost, "/oauth/authorize", approval), user.Username))
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	var redirect OAuthRedirectResponse
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &redirect))
+	callback, err := url.Parse(redirect.RedirectURI)
+	require.NoError(t, err)
+	require.Equal(t, "xyz", callback.Query().Get("state"))
+	code := callback.Query().Get("code")
+	require.NotEmpty(t, code)
+
+	// the client exchanges the code for an access token
+	exchange := func() *httptest.ResponseRecorder {
+		form := url.Values{
+			"grant_type":    {oauth.GrantTypeAuthorizationCode},
+			"code":          {code},
+			"redirect_uri":  {testRedirectURI},
+			"code_verifier": {verifier},
+		}
+		request, err := http.NewRequest(http.MethodPost, "/oauth/token", strings.NewReader(form.Encode()))
+		require.NoError(t, err)
+		request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		request.SetBasicAuth(clientID, clientSecret)
+		return serve(request)
+	}
+
+	recorder = exchange()
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	var issued OAuthTokenResponse
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &issued))
+	require.Equal(t, token.ScopeAccountsRead, issued.Scope)
+
+	// codes work once
+	requireOAuthError(t, exchange(), http.StatusBadRequest, oauth.ErrorInvalidGrant)
+
+	asClient := func(request *http.Request) *http.Request {
+		request.Header.Set(middleware.AuthorizationHeaderKey, issued.TokenType+" "+issued.AccessToken)
+		return request
+	}
+
+	// the token reads accounts, but cannot move money or act as the user
+	request, err = http.NewRequest(http.MethodGet, fmt.Sprintf("/accounts/%d", account.ID), nil)
+	require.NoError(t, err)
+	recorder = serve(asClient(request))
+	require.Equal(t, http.StatusOK, recorder.Code)
+	requireBodyMatchAccount(t, recorder, account)
+
+	recorder = serve(asClient(jsonRequest(http.MethodPost, "/transfer", gin.H{})))
+	require.Equal(t, http.StatusForbidden, recorder.Code)
+
+	request, err = http.NewRequest(http.MethodGet, "/users/me/oauth/consents", nil)
+	require.NoError(t, err)
+	recorder = serve(asClient(request))
+	require.Equal(t, http.StatusForbidden, recorder.Code)
+
+	// the user revokes the consent, which stops the token working
+	request, err = http.NewRequest(http.MethodDelete, "/users/me/oauth/consents/"+clientID, nil)
+	require.NoError(t, err)
+	recorder = serve(asUser(request, user.Username))
+	require.Equal(t, http.StatusNoContent, recorder.Code)
+
+	request, err = http.NewRequest(http.MethodGet, fmt.Sprintf("/accounts/%d", account.ID), nil)
+	require.NoError(t, err)
+	recorder = serve(asClient(request))
+	require.Equal(t, http.StatusUnauthorized, recorder.Code)
+}
@@ -0,0 +1,171 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/apperr"
+	mockdb "github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/db/mock"
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/util"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+// TestOpenAPIDocumentsAllRoutes fails when a route is registered without an
+// apiOperation documenting it, or an apiOperation outlives its route
+func TestOpenAPIDocumentsAllRoutes(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// with single sign-on configured, so that every route is registered
+	server, _ := newTestSSOServer(t, mockdb.NewMockStore(ctrl), nil)
+	routes := server.router.Routes()
+
+	require.Empty(t, undocumentedRoutes(routes, apiOperations), "routes missing from apiOperations")
+
+	registered := make(map[string]bool)
+	for _, route := range routes {
+		registered[route.Method+" "+route.Path] = true
+	}
+	for _, op := range apiOperations {
+		require.True(t, registered[op.method+" "+op.path], "%s %s is documented but not registered", op.method, op.path)
+	}
+}
+
+func TestUndocumentedRoutes(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	server := newTestServer(t, mockdb.NewMockStore(ctrl))
+	server.router.GET("/accounts/:id/owners", server.getAccount)
+
+	missing := undocumentedRoutes(server.router.Routes(), apiOperations)
+	require.Equal(t, []string{"GET /accounts/:id/owners"}, missing)
+}
+
+func TestOpenAPIDocumentAPI(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	server := newTestServer(t, mockdb.NewMockStore(ctrl))
+
+	recorder := httptest.NewRecorder()
+	request, err := http.NewRequest(http.MethodGet, "/openapi.json", nil)
+	require.NoError(t, err)
+
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	doc, err := openapi3.NewLoader().LoadFromData(recorder.Body.Bytes())
+	require.NoError(t, err)
+	require.NoError(t, doc.Validate(context.Background()))
+
+	// the routes of disabled features are left out
+	require.Nil(t, doc.Paths.Find("/sso/login"))
+
+	transfer := doc.Paths.Find("/transfer").Post
+	require.NotNil(t, transfer)
+	require.Len(t, *transfer.Security, 2)
+	require.Contains(t, transfer.Responses.Default().Value.Content, apperr.ProblemContentType)
+	require.Contains(t, doc.Components.Schemas["Problem"].Value.Properties, "errors")
+
+	// the token endpoint answers in the format of RFC 6749, but for internal errors
+	oauthToken := doc.Paths.Find("/oauth/token").Post
+	require.NotNil(t, oauthToken)
+	require.Contains(t, oauthToken.Responses.Default().Value.Content, apperr.ProblemContentType)
+	require.Contains(t, oauthToken.Responses.Default().Value.Content, "application/json")
+
+	schema := doc.Components.Schemas["CreateTransferRequest"].Value
+	require.ElementsMatch(t, []string{"amount", "currency"}, schema.Required)
+	require.Equal(t, stringEnum(util.SupportedCurrencies), schema.Properties["currency"].Value.Enum)
+	require.Equal(t, float64(1), *schema.Properties["amount"].Value.Min)
+	require.Contains(t, schema.Properties["from_account_id"].Value.Description, "from_account_number")
+	require.Equal(t, uint64(6), schema.Properties["totp_code"].Value.MinLength)
+
+	listAccounts := doc.Paths.Find("/accounts").Get
+	require.NotNil(t, listAccounts)
+	pageSize := listAccounts.Parameters.GetByInAndName(openapi3.ParameterInQuery, "page_size")
+	require.NotNil(t, pageSize)
+	require.True(t, pageSize.Required)
+	require.Equal(t, float64(5), *pageSize.Schema.Value.Min)
+	require.Equal(t, float64(10), *pageSize.Schema.Value.Max)
+
+	statement := doc.Paths.Find("/accounts/{id}/statements").Get
+	require.NotNil(t, statement)
+	require.Contains(t, statement.Responses.Status(http.StatusOK).Value.Content, "application/pdf")
+}
+
+func TestOpenAPIUndocumentedBindingRule(t *testing.T) {
+	type request struct {
+		Name string `json:"name" binding:"required,iban"`
+	}
+
+	_, err := newOpenAPIDocument([]apiOperation{{
+		method: http.MethodPost,
+		path:   "/test",
+		tag:    "test",
+		body:   request{},
+	}})
+	require.ErrorContains(t, err, `undocumented binding rule "iban"`)
+}
+
+func TestOpenAPIUnsupportedType(t *testing.T) {
+	_, err := (&schemaGenerator{schemas: make(openapi3.Schemas), types: make(map[string]reflect.Type)}).schemaRef(reflect.TypeOf(map[string]int{}))
+	require.Error(t, err)
+}
+
+func TestSwaggerUI(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	server := newTestServer(t, mockdb.NewMockStore(ctrl))
+
+	testCases := []struct {
+		name     string
+		path     string
+		code     int
+		contains string
+	}{
+		{
+			name:     "Index",
+			path:     "/swagger/",
+			code:     http.StatusOK,
+			contains: "swagger-ui",
+		},
+		{
+			name:     "Initializer",
+			path:     "/swagger/swagger-initializer.js",
+			code:     http.StatusOK,
+			contains: `"/openapi.json"`,
+		},
+		{
+			name:     "Bundle",
+			path:     "/swagger/swagger-ui-bundle.js",
+			code:     http.StatusOK,
+			contains: "SwaggerUIBundle",
+		},
+		{
+			name: "NotFound",
+			path: "/swagger/missing.js",
+			code: http.StatusNotFound,
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			request, err := http.NewRequest(http.MethodGet, tc.path, nil)
+			require.NoError(t, err)
+
+			server.router.ServeHTTP(recorder, request)
+			require.Equal(t, tc.code, recorder.Code)
+			require.Contains(t, recorder.Body.String(), tc.contains)
+		})
+	}
+}
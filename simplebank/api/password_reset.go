@@ -0,0 +1,149 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/apperr"
+	db "github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/db/sqlc"
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/mail"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+const forgotPasswordMessage = "if an account with this email exists, a password reset link has been sent to it"
+
+var errInvalidResetToken = apperr.New(http.StatusUnauthorized, "invalid_reset_token", "password reset token is invalid, expired or already used")
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type ForgotPasswordResponse struct {
+	Message string `json:"message"`
+}
+
+// forgotPassword mails a password reset link to the owner of the email. The
+// response is the same whether or not the email belongs to a user, and so is
+// its timing: the reset is recorded and mailed after responding.
+func (server *Server) forgotPassword(ctx *gin.Context) {
+	var req ForgotPasswordRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.Error(apperr.FromBinding(err))
+		return
+	}
+
+	user, err := server.store.GetUserByEmail(ctx, req.Email)
+	if err != nil && err != pgx.ErrNoRows {
+		ctx.Error(err)
+		return
+	}
+
+	if err == nil {
+		server.goBackground(ctx.Request.Context(), func(ctx context.Context) {
+			if err := server.sendPasswordResetEmail(ctx, user); err != nil {
+				slog.ErrorContext(ctx, "cannot send password reset email", "username", user.Username, "error", err)
+			}
+		})
+	}
+
+	ctx.JSON(http.StatusOK, ForgotPasswordResponse{Message: forgotPasswordMessage})
+}
+
+// sendPasswordResetEmail signs a short-lived reset token, records it so it
+// can only be used once, and mails a link containing it.
+func (server *Server) sendPasswordResetEmail(ctx context.Context, user db.User) error {
+	resetToken, payload, err := server.resetTokenMaker.CreateScopedToken(user.Username, server.config.PasswordResetDuration)
+	if err != nil {
+		return err
+	}
+
+	_, err = server.store.CreatePasswordReset(ctx, db.CreatePasswordResetParams{
+		ID:        payload.ID,
+		Username:  user.Username,
+		ExpiredAt: payload.ExpiredAt,
+	})
+	if err != nil {
+		return fmt.Errorf("cannot create password reset: %w", err)
+	}
+
+	link := fmt.Sprintf("%s?%s", server.config.PasswordResetURL, url.Values{"token": {resetToken}}.Encode())
+
+	return server.mailer.SendEmail(ctx, mail.Email{
+		To:      []string{user.Email},
+		Subject: "Reset your Simple Bank password",
+		Body: fmt.Sprintf("Hello %s,\n\nSomeone asked to reset the password of your Simple Bank account. If it was you, open the link below to choose a new password:\n\n%s\n\nThe link can be used once and expires in %s. If you did not ask for a reset, you can ignore this email.\n",
+			user.FullName, link, server.config.PasswordResetDuration),
+	})
+}
+
+type ResetPasswordRequest struct {
+	Token           string `json:"token" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required,password"`
+	ConfirmPassword string `json:"confirm_password" binding:"required,eqfield=NewPassword"`
+}
+
+// resetPassword sets a new password using a reset token. All tokens issued
+// before the reset, including other reset links, stop working.
+func (server *Server) resetPassword(ctx *gin.Context) {
+	var req ResetPasswordRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.Error(apperr.FromBinding(err))
+		return
+	}
+
+	payload, err := server.resetTokenMaker.VerifyToken(req.Token)
+	if err != nil {
+		ctx.Error(errInvalidResetToken)
+		return
+	}
+
+	hashedPassword, err := server.passwordHasher.Hash(req.NewPassword)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	user, err := server.store.ResetPasswordTx(ctx, db.ResetPasswordTxParams{
+		ResetID:        payload.ID,
+		Username:       payload.Username,
+		HashedPassword: hashedPassword,
+	})
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			ctx.Error(errInvalidResetToken)
+			return
+		}
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newUserResponse(user))
+}
+
+var passwordResetOperations = []apiOperation{
+	{
+		method:   http.MethodPost,
+		path:     "/users/password/forgot",
+		tag:      "users",
+		summary:  "Email a password reset link",
+		body:     ForgotPasswordRequest{},
+		response: ForgotPasswordResponse{},
+	},
+	{
+		method:   http.MethodPost,
+		path:     "/users/password/reset",
+		tag:      "users",
+		summary:  "Set a new password with a password reset token",
+		body:     ResetPasswordRequest{},
+		response: UserResponse{},
+	},
+}
+
+func (server *Server) setupPasswordResetRoutes(router gin.IRoutes) {
+	router.POST("/users/password/forgot", server.rateLimits.login, server.forgotPassword)
+	router.POST("/users/password/reset", server.rateLimits.login, server.resetPassword)
+}
@@ -0,0 +1,433 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	mockdb "github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/db/mock"
+	db "github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/db/sqlc"
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/mail"
+	mockmail "github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/mail/mock"
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/token"
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/util"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/require"
+)
+
+type passwordResetEmailMatcher struct {
+	to string
+}
+
+func (e passwordResetEmailMatcher) Matches(x interface{}) bool {
+	email, ok := x.(mail.Email)
+	if !ok {
+		return false
+	}
+
+	return len(email.To) == 1 && email.To[0] == e.to && strings.Contains(email.Body, "?token=")
+}
+
+func (e passwordResetEmailMatcher) String() string {
+	return fmt.Sprintf("is a password reset email to %v", e.to)
+}
+
+func TestForgotPasswordAPI(t *testing.T) {
+	user, _ := randomUser(t)
+
+	testCases := []struct {
+		name          string
+		body          gin.H
+		buildStubs    func(store *mockdb.MockStore, mailer *mockmail.MockMailer)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			body: gin.H{
+				"email": user.Email,
+			},
+			buildStubs: func(store *mockdb.MockStore, mailer *mockmail.MockMailer) {
+				store.EXPECT().
+					GetUserByEmail(gomock.Any(), gomock.Eq(user.Email)).
+					Times(1).
+					Return(user, nil)
+
+				store.EXPECT().
+					CreatePasswordReset(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg db.CreatePasswordResetParams) (db.PasswordReset, error) {
+						require.Equal(t, user.Username, arg.Username)
+						require.NotZero(t, arg.ID)
+						require.WithinDuration(t, time.Now().Add(15*time.Minute), arg.ExpiredAt, time.Second)
+						return db.PasswordReset{ID: arg.ID, Username: arg.Username, ExpiredAt: arg.ExpiredAt}, nil
+					})
+
+				mailer.EXPECT().
+					SendEmail(gomock.Any(), passwordResetEmailMatcher{to: user.Email}).
+					Times(1).
+					Return(nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				requireBodyMatchForgotPassword(t, recorder)
+			},
+		},
+		{
+			name: "UnknownEmail",
+			body: gin.H{
+				"email": user.Email,
+			},
+			buildStubs: func(store *mockdb.MockStore, mailer *mockmail.MockMailer) {
+				store.EXPECT().
+					GetUserByEmail(gomock.Any(), gomock.Eq(user.Email)).
+					Times(1).
+					Return(db.User{}, pgx.ErrNoRows)
+
+				store.EXPECT().
+					CreatePasswordReset(gomock.Any(), gomock.Any()).
+					Times(0)
+
+				mailer.EXPECT().
+					SendEmail(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				requireBodyMatchForgotPassword(t, recorder)
+			},
+		},
+		{
+			name: "MailerFailure",
+			body: gin.H{
+				"email": user.Email,
+			},
+			buildStubs: func(store *mockdb.MockStore, mailer *mockmail.MockMailer) {
+				store.EXPECT().
+					GetUserByEmail(gomock.Any(), gomock.Eq(user.Email)).
+					Times(1).
+					Return(user, nil)
+
+				store.EXPECT().
+					CreatePasswordReset(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.PasswordReset{}, nil)
+
+				mailer.EXPECT().
+					SendEmail(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(errors.New("connection refused"))
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				requireBodyMatchForgotPassword(t, recorder)
+			},
+		},
+		{
+			name: "InvalidEmail",
+			body: gin.H{
+				"email": "petegeorge20005@gmailcom",
+			},
+			buildStubs: func(store *mockdb.MockStore, mailer *mockmail.MockMailer) {
+				store.EXPECT().
+					GetUserByEmail(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "InternalError",
+			body: gin.H{
+				"email": user.Email,
+			},
+			buildStubs: func(store *mockdb.MockStore, mailer *mockmail.MockMailer) {
+				store.EXPECT().
+					GetUserByEmail(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.User{}, pgx.ErrTxClosed)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			mailer := mockmail.NewMockMailer(ctrl)
+			tc.buildStubs(store, mailer)
+
+			server := newTestServer(t, store)
+			server.mailer = mailer
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/users/password/forgot", bytes.NewReader(data))
+			require.NoError(t, err)
+
+			server.router.ServeHTTP(recorder, request)
+			server.background.Wait()
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestForgotPasswordLinkResetsPassword(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	user, _ := randomUser(t)
+	newPassword := util.RandomString(12)
+
+	store := mockdb.NewMockStore(ctrl)
+	mailer := mockmail.NewMockMailer(ctrl)
+
+	server := newTestServer(t, store)
+	server.mailer = mailer
+
+	var reset db.CreatePasswordResetParams
+	var email mail.Email
+
+	store.EXPECT().
+		GetUserByEmail(gomock.Any(), gomock.Eq(user.Email)).
+		Times(1).
+		Return(user, nil)
+	store.EXPECT().
+		CreatePasswordReset(gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(_ context.Context, arg db.CreatePasswordResetParams) (db.PasswordReset, error) {
+			reset = arg
+			return db.PasswordReset{ID: arg.ID, Username: arg.Username, ExpiredAt: arg.ExpiredAt}, nil
+		})
+	mailer.EXPECT().
+		SendEmail(gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(_ context.Context, arg mail.Email) error {
+			email = arg
+			return nil
+		})
+
+	recorder := httptest.NewRecorder()
+	request, err := http.NewRequest(http.MethodPost, "/users/password/forgot", strings.NewReader(fmt.Sprintf(`{"email": %q}`, user.Email)))
+	require.NoError(t, err)
+	server.router.ServeHTTP(recorder, request)
+	server.background.Wait()
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	// follow the emailed link
+	resetToken := extractQueryParam(t, email.Body, "token")
+
+	store.EXPECT().
+		ResetPasswordTx(gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(_ context.Context, arg db.ResetPasswordTxParams) (db.User, error) {
+			require.Equal(t, reset.ID, arg.ResetID)
+			require.Equal(t, user.Username, arg.Username)
+			require.NoError(t, util.CheckPassword(newPassword, arg.HashedPassword))
+			return user, nil
+		})
+
+	data, err := json.Marshal(gin.H{
+		"token":            resetToken,
+		"new_password":     newPassword,
+		"confirm_password": newPassword,
+	})
+	require.NoError(t, err)
+
+	recorder = httptest.NewRecorder()
+	request, err = http.NewRequest(http.MethodPost, "/users/password/reset", bytes.NewReader(data))
+	require.NoError(t, err)
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+}
+
+func TestResetPasswordAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	newPassword := util.RandomString(12)
+
+	testCases := []struct {
+		name          string
+		body          func(server *Server) gin.H
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			body: func(server *Server) gin.H {
+				return gin.H{
+					"token":            createTestResetToken(t, server.resetTokenMaker.CreateScopedToken, user.Username, time.Minute),
+					"new_password":     newPassword,
+					"confirm_password": newPassword,
+				}
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ResetPasswordTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(user, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				requireBodyMatchUser(t, recorder, user)
+			},
+		},
+		{
+			name: "AlreadyUsed",
+			body: func(server *Server) gin.H {
+				return gin.H{
+					"token":            createTestResetToken(t, server.resetTokenMaker.CreateScopedToken, user.Username, time.Minute),
+					"new_password":     newPassword,
+					"confirm_password": newPassword,
+				}
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ResetPasswordTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.User{}, pgx.ErrNoRows)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "ExpiredToken",
+			body: func(server *Server) gin.H {
+				return gin.H{
+					"token":            createTestResetToken(t, server.resetTokenMaker.CreateScopedToken, user.Username, -time.Minute),
+					"new_password":     newPassword,
+					"confirm_password": newPassword,
+				}
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ResetPasswordTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "AccessTokenRejected",
+			body: func(server *Server) gin.H {
+				return gin.H{
+					"token":            createTestResetToken(t, server.tokenMaker.CreateScopedToken, user.Username, time.Minute),
+					"new_password":     newPassword,
+					"confirm_password": newPassword,
+				}
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ResetPasswordTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "PasswordMismatch",
+			body: func(server *Server) gin.H {
+				return gin.H{
+					"token":            createTestResetToken(t, server.resetTokenMaker.CreateScopedToken, user.Username, time.Minute),
+					"new_password":     newPassword,
+					"confirm_password": "wrong-password",
+				}
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ResetPasswordTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "InternalError",
+			body: func(server *Server) gin.H {
+				return gin.H{
+					"token":            createTestResetToken(t, server.resetTokenMaker.CreateScopedToken, user.Username, time.Minute),
+					"new_password":     newPassword,
+					"confirm_password": newPassword,
+				}
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ResetPasswordTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.User{}, pgx.ErrTxClosed)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(tc.body(server))
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/users/password/reset", bytes.NewReader(data))
+			require.NoError(t, err)
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func createTestResetToken(
+	t *testing.T,
+	createToken func(string, time.Duration, ...string) (string, *token.Payload, error),
+	username string,
+	duration time.Duration,
+) string {
+	resetToken, _, err := createToken(username, duration)
+	require.NoError(t, err)
+	return resetToken
+}
+
+func extractQueryParam(t *testing.T, body, name string) string {
+	for _, field := range strings.Fields(body) {
+		if link, err := url.Parse(field); err == nil && link.Query().Has(name) {
+			return link.Query().Get(name)
+		}
+	}
+	t.Fatalf("no link with a %q parameter in %q", name, body)
+	return ""
+}
+
+func requireBodyMatchForgotPassword(t *testing.T, recorder *httptest.ResponseRecorder) {
+	var rsp ForgotPasswordResponse
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+	require.Equal(t, forgotPasswordMessage, rsp.Message)
+}
@@ -0,0 +1,62 @@
+package api
+
+import (
+	"fmt"
+
+	db "github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/db/sqlc"
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/middleware"
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/ratelimit"
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/util"
+	"github.com/gin-gonic/gin"
+)
+
+// rateLimits are the rate limiting middleware of the route groups. Each
+// lets everything through when its limit is turned off.
+type rateLimits struct {
+	// public limits the routes open to anyone by client ip
+	public gin.HandlerFunc
+	// login limits logins and password resets by client ip, on top of public
+	login gin.HandlerFunc
+	// ip limits authenticated routes by client ip before their credentials
+	// are checked, so invalid tokens and api keys are limited too
+	ip gin.HandlerFunc
+	// user limits authenticated routes by user, on top of ip
+	user gin.HandlerFunc
+	// transfer limits transfers by user, on top of user
+	transfer gin.HandlerFunc
+}
+
+func newRateLimits(config util.Config, store db.Store) (rateLimits, error) {
+	var limits rateLimits
+
+	buckets, err := ratelimit.NewStore(config.RateLimitBackend, store)
+	if err != nil {
+		return limits, err
+	}
+
+	for _, group := range []struct {
+		name    string
+		limit   string
+		key     middleware.RateLimitKey
+		handler *gin.HandlerFunc
+	}{
+		{name: "public", limit: config.RateLimitPublic, key: middleware.RateLimitByIP, handler: &limits.public},
+		{name: "login", limit: config.RateLimitLogin, key: middleware.RateLimitByIP, handler: &limits.login},
+		{name: "ip", limit: config.RateLimitIP, key: middleware.RateLimitByIP, handler: &limits.ip},
+		{name: "user", limit: config.RateLimitUser, key: middleware.RateLimitByUser, handler: &limits.user},
+		{name: "transfer", limit: config.RateLimitTransfer, key: middleware.RateLimitByUser, handler: &limits.transfer},
+	} {
+		limit, err := ratelimit.ParseLimit(group.limit)
+		if err != nil {
+			return limits, fmt.Errorf("%s rate limit: %w", group.name, err)
+		}
+
+		if buckets == nil || limit.IsZero() {
+			*group.handler = func(*gin.Context) {}
+			continue
+		}
+		*group.handler = middleware.RateLimit(buckets, group.name, limit, group.key)
+	}
+
+	return limits, nil
+}
@@ -0,0 +1,187 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	mockdb "github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/db/mock"
+	db "github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/db/sqlc"
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/metrics"
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/middleware"
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/ratelimit"
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/util"
+	"github.com/golang/mock/gomock"
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/require"
+)
+
+func newRateLimitedTestServer(t *testing.T, store *mockdb.MockStore) *Server {
+	config := newTestConfig()
+	config.RateLimitBackend = ratelimit.BackendMemory
+	config.RateLimitPublic = "5/m"
+	config.RateLimitLogin = "2/m"
+	config.RateLimitIP = "3/m"
+	config.RateLimitUser = "5/m"
+	config.RateLimitTransfer = "1/m"
+	return newTestServerWithConfig(t, store, config)
+}
+
+func TestRateLimitLoginAPI(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	server := newRateLimitedTestServer(t, mockdb.NewMockStore(ctrl))
+
+	// an empty body is rejected before the store is used, but still counts
+	login := func(remoteAddr string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		request := httptest.NewRequest(http.MethodPost, "/users/login", strings.NewReader("{}"))
+		request.RemoteAddr = remoteAddr
+		server.router.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	for _, remaining := range []string{"1", "0"} {
+		recorder := login("192.0.2.1:1234")
+		require.Equal(t, http.StatusBadRequest, recorder.Code)
+		require.Equal(t, "2", recorder.Header().Get(middleware.RateLimitLimitHeader))
+		require.Equal(t, remaining, recorder.Header().Get(middleware.RateLimitRemainingHeader))
+	}
+
+	recorder := login("192.0.2.1:1234")
+	requireProblem(t, recorder, http.StatusTooManyRequests, "rate_limited")
+	require.Equal(t, "30", recorder.Header().Get(middleware.RetryAfterHeader))
+	require.Equal(t, "60", recorder.Header().Get(middleware.RateLimitResetHeader))
+
+	// other clients have their own limit
+	recorder = login("192.0.2.2:1234")
+	require.Equal(t, http.StatusBadRequest, recorder.Code)
+
+	// the public limit still has room for other routes
+	recorder = httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodPost, "/users", strings.NewReader("{}"))
+	request.RemoteAddr = "192.0.2.1:1234"
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusBadRequest, recorder.Code)
+	require.Equal(t, "5", recorder.Header().Get(middleware.RateLimitLimitHeader))
+	require.Equal(t, "1", recorder.Header().Get(middleware.RateLimitRemainingHeader))
+}
+
+func TestRateLimitTransferAPI(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	stubAuthUser(store)
+	server := newRateLimitedTestServer(t, store)
+
+	// transfer sends an invalid transfer as username from changing client
+	// ips, which does not matter for authenticated routes
+	transfer := func(username string, i int) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		request := httptest.NewRequest(http.MethodPost, "/transfer", strings.NewReader("{}"))
+		request.RemoteAddr = fmt.Sprintf("192.0.2.%d:1234", i+1)
+		middleware.AddTestAuthorization(t, request, server.tokenMaker, middleware.AuthorizationTypeBearer, username, time.Minute)
+		server.router.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	username := util.RandomOwner()
+	recorder := transfer(username, 0)
+	require.Equal(t, http.StatusBadRequest, recorder.Code)
+
+	recorder = transfer(username, 1)
+	requireProblem(t, recorder, http.StatusTooManyRequests, "rate_limited")
+	require.Equal(t, "1", recorder.Header().Get(middleware.RateLimitLimitHeader))
+	require.Equal(t, "60", recorder.Header().Get(middleware.RetryAfterHeader))
+
+	recorder = transfer(util.RandomOwner(), 2)
+	require.Equal(t, http.StatusBadRequest, recorder.Code)
+}
+
+func TestRateLimitIPAPI(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	key, prefix, _, err := util.GenerateAPIKey()
+	require.NoError(t, err)
+
+	// only requests within the ip limit get to look up the unknown key
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		GetAPIKeyByPrefix(gomock.Any(), gomock.Eq(prefix)).
+		Times(3).
+		Return(db.ApiKey{}, pgx.ErrNoRows)
+	server := newRateLimitedTestServer(t, store)
+
+	listAccounts := func(remoteAddr string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		request := httptest.NewRequest(http.MethodGet, "/accounts", nil)
+		request.RemoteAddr = remoteAddr
+		request.Header.Set(middleware.AuthorizationHeaderKey, middleware.AuthorizationTypeAPIKey+" "+key)
+		server.router.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	for range 2 {
+		recorder := listAccounts("192.0.2.1:1234")
+		requireProblem(t, recorder, http.StatusUnauthorized, "invalid_api_key")
+	}
+
+	recorder := listAccounts("192.0.2.1:1234")
+	requireProblem(t, recorder, http.StatusUnauthorized, "invalid_api_key")
+	require.Equal(t, "0", recorder.Header().Get(middleware.RateLimitRemainingHeader))
+
+	recorder = listAccounts("192.0.2.1:1234")
+	requireProblem(t, recorder, http.StatusTooManyRequests, "rate_limited")
+	require.Equal(t, "20", recorder.Header().Get(middleware.RetryAfterHeader))
+}
+
+func TestRateLimitSkipsProbes(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	server := newRateLimitedTestServer(t, mockdb.NewMockStore(ctrl))
+
+	for range 10 {
+		recorder := httptest.NewRecorder()
+		server.router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+		require.Equal(t, http.StatusOK, recorder.Code)
+		require.Empty(t, recorder.Header().Get(middleware.RateLimitLimitHeader))
+	}
+}
+
+func TestNewServerRateLimitConfig(t *testing.T) {
+	testCases := []struct {
+		name   string
+		modify func(config *util.Config)
+	}{
+		{
+			name: "UnknownBackend",
+			modify: func(config *util.Config) {
+				config.RateLimitBackend = "redis"
+			},
+		},
+		{
+			name: "InvalidLimit",
+			modify: func(config *util.Config) {
+				config.RateLimitBackend = ratelimit.BackendMemory
+				config.RateLimitLogin = "10 per minute"
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			config := newTestConfig()
+			tc.modify(&config)
+
+			_, err := NewServer(config, nil, metrics.New())
+			require.Error(t, err)
+		})
+	}
+}
@@ -1,8 +1,23 @@
 package api
 
 import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/apperr"
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/auth"
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/core"
 	db "github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/db/sqlc"
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/mail"
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/metrics"
 	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/middleware"
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/oidc"
 	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/token"
 	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/util"
 	"github.com/gin-gonic/gin"
@@ -11,48 +26,216 @@ import (
 )
 
 type Server struct {
-	config     util.Config
-	router     *gin.Engine
-	store      db.Store
-	tokenMaker token.Maker
+	config              util.Config
+	router              *gin.Engine
+	store               db.Store
+	tokenMaker          token.Maker
+	resetTokenMaker     token.Maker
+	challengeTokenMaker token.Maker
+	ssoStateMaker       token.Maker
+	mailer              mail.Mailer
+	passwordHasher      util.PasswordHasher
+	authenticator       *auth.Authenticator
+	metrics             *metrics.Metrics
+	rateLimits          rateLimits
+	// draining is set once the server shuts down, failing its readiness
+	draining atomic.Bool
+	// background tracks the work requests leave running after they are
+	// answered, which Serve waits for when shutting down
+	background sync.WaitGroup
+	// ssoClient is nil unless staff log in through an identity provider
+	ssoClient *oidc.Client
+	// openAPIDocument is the JSON document of the registered routes
+	openAPIDocument []byte
 }
 
-func NewServer(config util.Config, store db.Store) (*Server, error) {
-	maker, err := token.NewPasetoMaker(config.TokenSymmetricKey)
+// NewServer creates the HTTP server. It records request metrics, which are
+// served apart from the API so they are not public.
+func NewServer(config util.Config, store db.Store, metrics *metrics.Metrics) (*Server, error) {
+	shared, err := core.New(config, store)
+	if err != nil {
+		return nil, err
+	}
+
+	resetMaker, err := token.NewPasetoMaker(token.DeriveKey(config.TokenSymmetricKey, token.PurposePasswordReset))
+	if err != nil {
+		return nil, err
+	}
+
+	ssoStateMaker, err := token.NewPasetoMaker(token.DeriveKey(config.TokenSymmetricKey, token.PurposeSSOState))
+	if err != nil {
+		return nil, err
+	}
+
+	rateLimits, err := newRateLimits(config, store)
 	if err != nil {
 		return nil, err
 	}
 
 	server := &Server{
-		config:     config,
-		store:      store,
-		tokenMaker: maker,
+		config:              config,
+		store:               store,
+		tokenMaker:          shared.TokenMaker,
+		resetTokenMaker:     resetMaker,
+		challengeTokenMaker: shared.ChallengeTokenMaker,
+		ssoStateMaker:       ssoStateMaker,
+		mailer:              shared.Mailer,
+		passwordHasher:      shared.PasswordHasher,
+		authenticator:       shared.Authenticator,
+		metrics:             metrics,
+		rateLimits:          rateLimits,
 	}
-	router := gin.Default()
+	if config.OIDCIssuerURL != "" {
+		server.ssoClient = oidc.NewClient(oidc.Config{
+			IssuerURL:    config.OIDCIssuerURL,
+			ClientID:     config.OIDCClientID,
+			ClientSecret: config.OIDCClientSecret,
+			RedirectURL:  config.OIDCRedirectURL,
+			Scopes:       config.OIDCScopes,
+		}, &http.Client{Timeout: ssoProviderTimeout})
+	}
+	router := gin.New()
+	if err := router.SetTrustedProxies(config.TrustedProxies); err != nil {
+		return nil, err
+	}
+	// Handlers pass the gin context on to the store, so queries see the
+	// request id and cancellation of the request
+	router.ContextWithFallback = true
+	router.Use(
+		middleware.RequestID(),
+		middleware.Tracing(),
+		middleware.Logger(),
+		metrics.HTTPMiddleware(),
+		middleware.Recovery(),
+		middleware.ErrorHandler(),
+	)
 
 	// Initialize router
 	server.router = router
 
 	// Register validations
 	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterTagNameFunc(apperr.FieldName)
 		v.RegisterValidation("currency", validCurrency)
+		v.RegisterValidation("account_number", validAccountNumber)
+		v.RegisterValidation("account_ref", validAccountRef)
+		v.RegisterValidation("password", validPassword(shared.PasswordPolicy))
+		v.RegisterValidation("scope", validScope)
+		v.RegisterValidation("redirect_uri", validRedirectURI)
+	}
+
+	// Setup routes; probes are not rate limited
+	server.setupHealthRoutes(server.router)
+
+	publicRoutes := server.router.Group("/").Use(server.rateLimits.public)
+
+	server.setupUserRoutes(publicRoutes)
+	server.setupVerifyEmailRoutes(publicRoutes)
+	server.setupPasswordResetRoutes(publicRoutes)
+	server.setupTOTPLoginRoutes(publicRoutes)
+	server.setupOAuthTokenRoutes(publicRoutes)
+	if server.ssoClient != nil {
+		server.setupSSORoutes(publicRoutes)
 	}
 
-	// Setup routes
-	server.setupUserRoutes(server.router)
+	// routes managing the user itself cannot be used with api keys
+	userRoutes := server.router.Group("/").Use(
+		server.rateLimits.ip,
+		middleware.AuthMiddleware(server.tokenMaker, nil, server.checkTokenUser),
+		server.rateLimits.user,
+		middleware.RequireScopes(token.ScopeUser),
+	)
+
+	server.setupCurrentUserRoutes(userRoutes)
+	server.setupResendVerifyEmailRoutes(userRoutes)
+	server.setupTOTPRoutes(userRoutes)
+	server.setupAPIKeyRoutes(userRoutes)
+	server.setupOAuthRoutes(userRoutes)
 
-	authRoutes := server.router.Group("/").Use(middleware.AuthMiddleware(server.tokenMaker))
+	authRoutes := server.router.Group("/").Use(
+		server.rateLimits.ip,
+		middleware.AuthMiddleware(server.tokenMaker, server.verifyAPIKey, server.checkTokenUser, server.checkTokenClient),
+		server.rateLimits.user,
+	)
 
 	server.setupAccountRoutes(authRoutes)
 	server.setupTransferRoutes(authRoutes)
+	server.setupStatementRoutes(authRoutes)
+
+	adminRoutes := server.router.Group("/").Use(
+		server.rateLimits.ip,
+		middleware.AuthMiddleware(server.tokenMaker, server.verifyAPIKey, server.checkTokenUser),
+		server.rateLimits.user,
+		server.requireRole(util.AdminRole),
+		middleware.RequireScopes(token.ScopeAdmin),
+	)
+
+	server.setupAdminRoutes(adminRoutes)
+	server.setupOAuthClientRoutes(adminRoutes)
+
+	if err := server.setupOpenAPIRoutes(server.router); err != nil {
+		return nil, err
+	}
 
 	return server, nil
 }
 
-func (server *Server) Start(address string) error {
-	return server.router.Run(address)
+// Start serves HTTP at address until ctx is done, then shuts down as Serve does
+func (server *Server) Start(ctx context.Context, address string) error {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return err
+	}
+	return server.Serve(ctx, listener)
+}
+
+// Serve serves HTTP on listener until ctx is done. It then fails readiness
+// for the drain period, so load balancers stop sending requests, and waits
+// for the requests in flight until the shutdown timeout.
+func (server *Server) Serve(ctx context.Context, listener net.Listener) error {
+	httpServer := &http.Server{
+		Handler:      server.router,
+		ReadTimeout:  server.config.HTTPReadTimeout,
+		WriteTimeout: server.config.HTTPWriteTimeout,
+		IdleTimeout:  server.config.HTTPIdleTimeout,
+	}
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- httpServer.Serve(listener)
+	}()
+	slog.Info("serving HTTP", "address", listener.Addr().String())
+
+	select {
+	case err := <-serveErr:
+		return err
+	case <-ctx.Done():
+	}
+
+	server.draining.Store(true)
+	slog.Info("draining HTTP server", "period", server.config.ShutdownDrainPeriod)
+	select {
+	case err := <-serveErr:
+		return err
+	case <-time.After(server.config.ShutdownDrainPeriod):
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), server.config.ShutdownTimeout)
+	defer cancel()
+	if err := httpServer.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("cannot shut down HTTP server: %w", err)
+	}
+	server.background.Wait()
+	slog.Info("HTTP server stopped")
+	return nil
 }
 
-func errorsResponse(err error) gin.H {
-	return gin.H{"error": err.Error()}
+// goBackground runs f without holding up the response of the request
+// whose context is given. f gets a context with the same values that is
+// not canceled when the request ends.
+func (server *Server) goBackground(ctx context.Context, f func(ctx context.Context)) {
+	ctx = context.WithoutCancel(ctx)
+	server.background.Go(func() {
+		f(ctx)
+	})
 }
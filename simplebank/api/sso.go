@@ -0,0 +1,212 @@
+package api
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/apperr"
+	db "github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/db/sqlc"
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/oauth"
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/token"
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/util"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// ssoProviderTimeout bounds each request to the identity provider
+const ssoProviderTimeout = 10 * time.Second
+
+// ssoFallbackUsername is used when the provider's claims give nothing to
+// base a username on
+const ssoFallbackUsername = "staff"
+
+var (
+	errSSOUnavailable      = apperr.New(http.StatusBadGateway, "sso_unavailable", "the identity provider cannot be reached")
+	errInvalidSSOState     = apperr.New(http.StatusUnauthorized, "invalid_sso_state", "login state is invalid or has expired")
+	errSSOCodeRejected     = apperr.New(http.StatusUnauthorized, "sso_code_rejected", "the identity provider did not accept the code")
+	errSSONonceMismatch    = apperr.New(http.StatusUnauthorized, "sso_nonce_mismatch", "id token was not issued for this login")
+	errSSOEmailNotVerified = apperr.New(http.StatusForbidden, "sso_email_not_verified", "the identity provider has not verified your email")
+	errSSONotAllowed       = apperr.New(http.StatusForbidden, "sso_not_allowed", "you are not allowed to log in here")
+	errSSOUserNotLinkable  = apperr.New(http.StatusConflict, "sso_user_not_linkable", "an account with your email already exists; ask an admin to link it")
+)
+
+type SSOLoginResponse struct {
+	AuthorizationURL string `json:"authorization_url"`
+	// State comes back with the code. The frontend should check that it is
+	// the state of a login it started before passing the code on.
+	State     string    `json:"state"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// startSSOLogin begins a login at the identity provider. The state is a
+// token, and the nonce and PKCE verifier are derived from it, so nothing
+// needs to be stored until the user comes back.
+func (server *Server) startSSOLogin(ctx *gin.Context) {
+	state, payload, err := server.ssoStateMaker.CreateScopedToken("", server.config.OIDCLoginDuration)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	nonce := server.ssoSecret(token.PurposeSSONonce, payload.ID)
+	verifier := server.ssoSecret(token.PurposeSSOVerifier, payload.ID)
+
+	authURL, err := server.ssoClient.AuthCodeURL(ctx, state, nonce, oauth.CodeChallenge(verifier))
+	if err != nil {
+		ctx.Error(errSSOUnavailable.WithCause(err))
+		return
+	}
+
+	rsp := SSOLoginResponse{
+		AuthorizationURL: authURL,
+		State:            state,
+		ExpiresAt:        payload.ExpiredAt,
+	}
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+type SSOCallbackRequest struct {
+	Code  string `json:"code" binding:"required"`
+	State string `json:"state" binding:"required"`
+}
+
+// finishSSOLogin exchanges the code the provider sent the user back with
+// and logs in the user its ID token identifies. Two-factor authentication
+// is left to the provider.
+func (server *Server) finishSSOLogin(ctx *gin.Context) {
+	var req SSOCallbackRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.Error(apperr.FromBinding(err))
+		return
+	}
+
+	payload, err := server.ssoStateMaker.VerifyToken(req.State)
+	if err != nil {
+		ctx.Error(errInvalidSSOState.WithCause(err))
+		return
+	}
+
+	verifier := server.ssoSecret(token.PurposeSSOVerifier, payload.ID)
+	idToken, err := server.ssoClient.Exchange(ctx, req.Code, verifier)
+	if err != nil {
+		ctx.Error(errSSOCodeRejected.WithCause(err))
+		return
+	}
+
+	nonce := server.ssoSecret(token.PurposeSSONonce, payload.ID)
+	if subtle.ConstantTimeCompare([]byte(idToken.Nonce), []byte(nonce)) != 1 {
+		ctx.Error(errSSONonceMismatch)
+		return
+	}
+
+	// the email links the identity to an existing user, so it must be the user's
+	if idToken.Email == "" || !idToken.EmailVerified {
+		ctx.Error(errSSOEmailNotVerified)
+		return
+	}
+
+	groups := idToken.Strings(server.config.OIDCGroupsClaim)
+	if len(server.config.OIDCAllowedGroups) > 0 && !containsAny(server.config.OIDCAllowedGroups, groups) {
+		ctx.Error(errSSONotAllowed)
+		return
+	}
+
+	role := util.DepositorRole
+	if containsAny(server.config.OIDCAdminGroups, groups) {
+		role = util.AdminRole
+	}
+
+	fullName := idToken.Name
+	if fullName == "" {
+		fullName = idToken.Email
+	}
+
+	result, err := server.store.SSOLoginTx(ctx, db.SSOLoginTxParams{
+		Issuer:   idToken.Issuer,
+		Subject:  idToken.Subject,
+		Username: ssoUsername(idToken.PreferredUsername, idToken.Email),
+		FullName: fullName,
+		Email:    idToken.Email,
+		Role:     role,
+	})
+	if errors.Is(err, db.ErrSSOUserNotLinkable) {
+		ctx.Error(errSSOUserNotLinkable.WithCause(err))
+		return
+	}
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	server.issueAccessToken(ctx, result.User)
+}
+
+// ssoSecret derives a secret of one login from its state token id. Only the
+// server can derive it, and the base64url encoding of the 32 byte mac is a
+// valid PKCE code verifier.
+func (server *Server) ssoSecret(purpose string, stateID uuid.UUID) string {
+	mac := hmac.New(sha256.New, []byte(token.DeriveKey(server.config.TokenSymmetricKey, purpose)))
+	mac.Write(stateID[:])
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// ssoUsername suggests a username for a new user from the preferred
+// username or else the local part of the email, keeping what the username
+// validation accepts.
+func ssoUsername(preferredUsername, email string) string {
+	name := preferredUsername
+	if name == "" {
+		name, _, _ = strings.Cut(email, "@")
+	}
+
+	var username strings.Builder
+	for _, r := range strings.ToLower(name) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			username.WriteRune(r)
+		}
+	}
+	if username.Len() == 0 {
+		return ssoFallbackUsername
+	}
+	return username.String()
+}
+
+// containsAny reports whether any of values is in set
+func containsAny(set, values []string) bool {
+	for _, value := range values {
+		if slices.Contains(set, value) {
+			return true
+		}
+	}
+	return false
+}
+
+var ssoOperations = []apiOperation{
+	{
+		method:   http.MethodGet,
+		path:     "/sso/login",
+		tag:      "sso",
+		summary:  "Start a staff login at the identity provider",
+		response: SSOLoginResponse{},
+	},
+	{
+		method:   http.MethodPost,
+		path:     "/sso/callback",
+		tag:      "sso",
+		summary:  "Complete a staff login with the code from the identity provider",
+		body:     SSOCallbackRequest{},
+		response: LoginUserResponse{},
+	},
+}
+
+func (server *Server) setupSSORoutes(router gin.IRoutes) {
+	router.GET("/sso/login", server.startSSOLogin)
+	router.POST("/sso/callback", server.rateLimits.login, server.finishSSOLogin)
+}
@@ -0,0 +1,413 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	mockdb "github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/db/mock"
+	db "github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/db/sqlc"
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/metrics"
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/oidc/oidctest"
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/util"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+const testSSORedirectURL = "http://localhost:3000/sso/callback"
+
+var testSSOUser = oidctest.User{
+	Subject:           "00u1staff",
+	Email:             "jane.doe@bank.example.com",
+	EmailVerified:     true,
+	Name:              "Jane Doe",
+	PreferredUsername: "Jane.Doe",
+	Groups:            []string{"staff"},
+}
+
+// newTestSSOServer returns a server logging staff in through a mock provider
+func newTestSSOServer(t *testing.T, store db.Store, configure func(config *util.Config)) (*Server, *oidctest.Provider) {
+	idp, provider, err := oidctest.NewServer("simplebank", "secret", testSSORedirectURL)
+	require.NoError(t, err)
+	t.Cleanup(idp.Close)
+
+	config := util.Config{
+		TokenSymmetricKey:     util.RandomString(32),
+		AccessTokenDuration:   time.Minute,
+		AccountNumberCountry:  util.DefaultAccountNumberCountry,
+		PasswordResetDuration: 15 * time.Minute,
+		TOTPIssuer:            "Simple Bank",
+		TOTPChallengeDuration: time.Minute,
+		OIDCIssuerURL:         idp.URL,
+		OIDCClientID:          "simplebank",
+		OIDCClientSecret:      "secret",
+		OIDCRedirectURL:       testSSORedirectURL,
+		OIDCGroupsClaim:       "groups",
+		OIDCLoginDuration:     time.Minute,
+	}
+	if configure != nil {
+		configure(&config)
+	}
+
+	server, err := NewServer(config, store, metrics.New())
+	require.NoError(t, err)
+	return server, provider
+}
+
+func startSSOLogin(t *testing.T, server *Server) SSOLoginResponse {
+	recorder := httptest.NewRecorder()
+	request, err := http.NewRequest(http.MethodGet, "/sso/login", nil)
+	require.NoError(t, err)
+
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	var rsp SSOLoginResponse
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+	return rsp
+}
+
+// loginAtProvider follows the authorization url and returns the code the
+// provider sends the user back with
+func loginAtProvider(t *testing.T, authURL string) string {
+	httpClient := &http.Client{
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	response, err := httpClient.Get(authURL)
+	require.NoError(t, err)
+	defer response.Body.Close()
+	require.Equal(t, http.StatusFound, response.StatusCode)
+
+	location, err := url.Parse(response.Header.Get("Location"))
+	require.NoError(t, err)
+	require.NotEmpty(t, location.Query().Get("code"))
+	return location.Query().Get("code")
+}
+
+type eqSSOLoginTxParamsMatcher struct {
+	arg db.SSOLoginTxParams
+}
+
+func (e eqSSOLoginTxParamsMatcher) Matches(x interface{}) bool {
+	arg, ok := x.(db.SSOLoginTxParams)
+	return ok && arg == e.arg
+}
+
+func (e eqSSOLoginTxParamsMatcher) String() string {
+	return fmt.Sprintf("matches arg %v", e.arg)
+}
+
+func TestSSOLoginAPI(t *testing.T) {
+	testCases := []struct {
+		name      string
+		user      oidctest.User
+		configure func(config *util.Config)
+		// callback returns the request the frontend makes for a login
+		callback      func(t *testing.T, server *Server) SSOCallbackRequest
+		buildStubs    func(store *mockdb.MockStore, issuer string)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			user: testSSOUser,
+			buildStubs: func(store *mockdb.MockStore, issuer string) {
+				arg := db.SSOLoginTxParams{
+					Issuer:   issuer,
+					Subject:  testSSOUser.Subject,
+					Username: "janedoe",
+					FullName: testSSOUser.Name,
+					Email:    testSSOUser.Email,
+					Role:     util.DepositorRole,
+				}
+				store.EXPECT().
+					SSOLoginTx(gomock.Any(), eqSSOLoginTxParamsMatcher{arg}).
+					Times(1).
+					Return(db.SSOLoginTxResult{
+						User: db.User{Username: "janedoe", Email: arg.Email, IsEmailVerified: true, Role: arg.Role},
+					}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp LoginUserResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+				require.NotEmpty(t, rsp.AccessToken)
+				require.Equal(t, "janedoe", rsp.User.Username)
+			},
+		},
+		{
+			name: "AdminGroup",
+			user: oidctest.User{
+				Subject:       testSSOUser.Subject,
+				Email:         testSSOUser.Email,
+				EmailVerified: true,
+				Groups:        []string{"staff", "bank-admins"},
+			},
+			configure: func(config *util.Config) {
+				config.OIDCAllowedGroups = []string{"staff"}
+				config.OIDCAdminGroups = []string{"bank-admins"}
+			},
+			buildStubs: func(store *mockdb.MockStore, issuer string) {
+				arg := db.SSOLoginTxParams{
+					Issuer:   issuer,
+					Subject:  testSSOUser.Subject,
+					Username: "janedoe",
+					FullName: testSSOUser.Email,
+					Email:    testSSOUser.Email,
+					Role:     util.AdminRole,
+				}
+				store.EXPECT().
+					SSOLoginTx(gomock.Any(), eqSSOLoginTxParamsMatcher{arg}).
+					Times(1).
+					Return(db.SSOLoginTxResult{
+						User: db.User{Username: "janedoe", Email: arg.Email, IsEmailVerified: true, Role: arg.Role},
+					}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "NotInAllowedGroup",
+			user: testSSOUser,
+			configure: func(config *util.Config) {
+				config.OIDCAllowedGroups = []string{"bank-admins"}
+			},
+			buildStubs: func(store *mockdb.MockStore, issuer string) {
+				store.EXPECT().
+					SSOLoginTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name: "EmailNotVerified",
+			user: oidctest.User{
+				Subject: testSSOUser.Subject,
+				Email:   testSSOUser.Email,
+				Groups:  testSSOUser.Groups,
+			},
+			buildStubs: func(store *mockdb.MockStore, issuer string) {
+				store.EXPECT().
+					SSOLoginTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name: "InvalidState",
+			user: testSSOUser,
+			callback: func(t *testing.T, server *Server) SSOCallbackRequest {
+				login := startSSOLogin(t, server)
+				return SSOCallbackRequest{
+					Code:  loginAtProvider(t, login.AuthorizationURL),
+					State: util.RandomString(32),
+				}
+			},
+			buildStubs: func(store *mockdb.MockStore, issuer string) {
+				store.EXPECT().
+					SSOLoginTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			// the code was issued for another login, so its verifier is wrong
+			name: "StateOfOtherLogin",
+			user: testSSOUser,
+			callback: func(t *testing.T, server *Server) SSOCallbackRequest {
+				login1 := startSSOLogin(t, server)
+				login2 := startSSOLogin(t, server)
+				return SSOCallbackRequest{
+					Code:  loginAtProvider(t, login1.AuthorizationURL),
+					State: login2.State,
+				}
+			},
+			buildStubs: func(store *mockdb.MockStore, issuer string) {
+				store.EXPECT().
+					SSOLoginTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "NonceMismatch",
+			user: testSSOUser,
+			callback: func(t *testing.T, server *Server) SSOCallbackRequest {
+				login := startSSOLogin(t, server)
+
+				authURL, err := url.Parse(login.AuthorizationURL)
+				require.NoError(t, err)
+				query := authURL.Query()
+				query.Set("nonce", util.RandomString(43))
+				authURL.RawQuery = query.Encode()
+
+				return SSOCallbackRequest{
+					Code:  loginAtProvider(t, authURL.String()),
+					State: login.State,
+				}
+			},
+			buildStubs: func(store *mockdb.MockStore, issuer string) {
+				store.EXPECT().
+					SSOLoginTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "CodeUsedTwice",
+			user: testSSOUser,
+			callback: func(t *testing.T, server *Server) SSOCallbackRequest {
+				login := startSSOLogin(t, server)
+				req := SSOCallbackRequest{
+					Code:  loginAtProvider(t, login.AuthorizationURL),
+					State: login.State,
+				}
+
+				recorder := httptest.NewRecorder()
+				data, err := json.Marshal(req)
+				require.NoError(t, err)
+				request, err := http.NewRequest(http.MethodPost, "/sso/callback", bytes.NewReader(data))
+				require.NoError(t, err)
+				server.router.ServeHTTP(recorder, request)
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				return req
+			},
+			buildStubs: func(store *mockdb.MockStore, issuer string) {
+				store.EXPECT().
+					SSOLoginTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.SSOLoginTxResult{User: db.User{Username: "janedoe"}}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "UserNotLinkable",
+			user: testSSOUser,
+			buildStubs: func(store *mockdb.MockStore, issuer string) {
+				store.EXPECT().
+					SSOLoginTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.SSOLoginTxResult{}, db.ErrSSOUserNotLinkable)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireProblem(t, recorder, http.StatusConflict, "sso_user_not_linkable")
+			},
+		},
+		{
+			name: "InternalError",
+			user: testSSOUser,
+			buildStubs: func(store *mockdb.MockStore, issuer string) {
+				store.EXPECT().
+					SSOLoginTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.SSOLoginTxResult{}, db.ErrSSOUsernameTaken)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			server, provider := newTestSSOServer(t, store, tc.configure)
+			provider.SetUser(tc.user)
+			tc.buildStubs(store, provider.Issuer)
+
+			var req SSOCallbackRequest
+			if tc.callback != nil {
+				req = tc.callback(t, server)
+			} else {
+				login := startSSOLogin(t, server)
+				req = SSOCallbackRequest{
+					Code:  loginAtProvider(t, login.AuthorizationURL),
+					State: login.State,
+				}
+			}
+
+			recorder := httptest.NewRecorder()
+			data, err := json.Marshal(req)
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/sso/callback", bytes.NewReader(data))
+			require.NoError(t, err)
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestSSOLoginProviderUnreachable(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	server, _ := newTestSSOServer(t, store, func(config *util.Config) {
+		config.OIDCIssuerURL = "http://127.0.0.1:1"
+	})
+
+	recorder := httptest.NewRecorder()
+	request, err := http.NewRequest(http.MethodGet, "/sso/login", nil)
+	require.NoError(t, err)
+
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusBadGateway, recorder.Code)
+}
+
+func TestSSORoutesDisabled(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	server := newTestServer(t, mockdb.NewMockStore(ctrl))
+
+	recorder := httptest.NewRecorder()
+	request, err := http.NewRequest(http.MethodGet, "/sso/login", nil)
+	require.NoError(t, err)
+
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusNotFound, recorder.Code)
+}
+
+func TestSSOUsername(t *testing.T) {
+	testCases := []struct {
+		preferredUsername string
+		email             string
+		expect            string
+	}{
+		{"Jane.Doe", "jane@bank.example.com", "janedoe"},
+		{"", "j.doe+staff@bank.example.com", "jdoestaff"},
+		{"jd_42", "jane@bank.example.com", "jd42"},
+		{"", "...@bank.example.com", ssoFallbackUsername},
+		{"Zoë", "", "zo"},
+	}
+
+	for _, tc := range testCases {
+		require.Equal(t, tc.expect, ssoUsername(tc.preferredUsername, tc.email))
+	}
+}
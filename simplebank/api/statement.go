@@ -0,0 +1,147 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/apperr"
+	db "github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/db/sqlc"
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/middleware"
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/statement"
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/token"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+// maxStatementPeriod is the longest period a single statement may cover
+const maxStatementPeriod = 366 * 24 * time.Hour
+
+var errStatementPeriod = apperr.InvalidArgument(fmt.Sprintf("statement period must not exceed %d days", int(maxStatementPeriod.Hours()/24)))
+
+var statementContentTypes = map[string]string{
+	statement.FormatCSV: "text/csv",
+	statement.FormatOFX: "application/x-ofx",
+	statement.FormatPDF: "application/pdf",
+}
+
+// GetStatementRequest selects the statement period; both dates are inclusive
+type GetStatementRequest struct {
+	From   time.Time `form:"from" binding:"required" time_format:"2006-01-02" time_utc:"1"`
+	To     time.Time `form:"to" binding:"required,gtefield=From" time_format:"2006-01-02" time_utc:"1"`
+	Format string    `form:"format" binding:"omitempty,oneof=csv ofx pdf"`
+}
+
+func (server *Server) getStatement(ctx *gin.Context) {
+	var uri GetAccountRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.Error(apperr.FromBinding(err))
+		return
+	}
+
+	var req GetStatementRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.Error(apperr.FromBinding(err))
+		return
+	}
+
+	// The end date is inclusive, so the period ends at the start of the next day
+	from := req.From
+	to := req.To.AddDate(0, 0, 1)
+	if to.Sub(from) > maxStatementPeriod {
+		ctx.Error(errStatementPeriod)
+		return
+	}
+
+	format := req.Format
+	if format == "" {
+		format = statement.FormatCSV
+	}
+
+	account, err := server.getOwnAccount(ctx, uri.ID)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	stmt, err := server.buildStatement(ctx, account.ID, from, to)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	var buf bytes.Buffer
+	switch format {
+	case statement.FormatOFX:
+		err = stmt.WriteOFX(&buf)
+	case statement.FormatPDF:
+		err = stmt.WritePDF(&buf)
+	default:
+		err = stmt.WriteCSV(&buf)
+	}
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	filename := fmt.Sprintf("statement-%s-%s-%s.%s",
+		account.AccountNumber, req.From.Format("20060102"), req.To.Format("20060102"), format)
+	ctx.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	ctx.Data(http.StatusOK, statementContentTypes[format], buf.Bytes())
+}
+
+// buildStatement returns the stored statement when the period is a month the
+// statement generator has stored one for, and builds it from the entries
+// otherwise
+func (server *Server) buildStatement(ctx *gin.Context, accountID int64, from, to time.Time) (*statement.Statement, error) {
+	if monthFrom, monthTo := statement.Month(from); from.Equal(monthFrom) && to.Equal(monthTo) {
+		stored, err := server.store.GetStatement(ctx, db.GetStatementParams{
+			AccountID:   accountID,
+			PeriodStart: from,
+			PeriodEnd:   to,
+		})
+		if err == nil {
+			var stmt statement.Statement
+			if err := json.Unmarshal(stored.Content, &stmt); err != nil {
+				return nil, fmt.Errorf("cannot read stored statement %d: %w", stored.ID, err)
+			}
+			return &stmt, nil
+		}
+		if !errors.Is(err, pgx.ErrNoRows) {
+			return nil, err
+		}
+	}
+
+	result, err := server.store.StatementTx(ctx, db.StatementTxParams{
+		AccountID: accountID,
+		From:      from,
+		To:        to,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return statement.New(result.Account, from, to, result.OpeningBalance, result.Entries), nil
+}
+
+var statementOperations = []apiOperation{
+	{
+		method:       http.MethodGet,
+		path:         "/accounts/:id/statements",
+		tag:          "accounts",
+		summary:      "Download the statement of an account for a period",
+		auth:         authTokenOrAPIKey,
+		scopes:       []string{token.ScopeAccountsRead},
+		uri:          GetAccountRequest{},
+		query:        GetStatementRequest{},
+		contentTypes: slices.Sorted(maps.Values(statementContentTypes)),
+	},
+}
+
+func (server *Server) setupStatementRoutes(router gin.IRoutes) {
+	router.GET("/accounts/:id/statements", middleware.RequireScopes(token.ScopeAccountsRead), server.getStatement)
+}
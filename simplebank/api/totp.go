@@ -0,0 +1,315 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/apperr"
+	db "github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/db/sqlc"
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/token"
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/totp"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	errTOTPAlreadyEnabled    = apperr.New(http.StatusBadRequest, "totp_already_enabled", "two-factor authentication is already enabled")
+	errTOTPNotEnabled        = apperr.New(http.StatusBadRequest, "totp_not_enabled", "two-factor authentication is not enabled")
+	errTOTPNotEnrolled       = apperr.New(http.StatusBadRequest, "totp_not_enrolled", "no authenticator is being enrolled")
+	errInvalidTOTPCode       = apperr.New(http.StatusUnauthorized, "invalid_totp_code", "two-factor authentication code is invalid or was already used")
+	errInvalidChallengeToken = apperr.New(http.StatusUnauthorized, "invalid_challenge_token", "login challenge is invalid or has expired")
+	errTOTPRequired          = apperr.New(http.StatusUnauthorized, "totp_required", "a two-factor authentication code is required for this transfer")
+	errStepUpNotEnabled      = apperr.New(http.StatusForbidden, "totp_step_up_not_enabled", "two-factor authentication must be enabled for transfers of this amount")
+)
+
+// SecondFactorRequest carries either a code from the authenticator app or a
+// one-time recovery code.
+type SecondFactorRequest struct {
+	Code         string `json:"code" binding:"required_without=RecoveryCode,omitempty,numeric,len=6"`
+	RecoveryCode string `json:"recovery_code" binding:"omitempty,max=16"`
+}
+
+// checkSecondFactor accepts a TOTP code, whose time step can only be used
+// once, or an unused recovery code, which is then used up. It returns
+// errInvalidTOTPCode when neither is valid.
+func (server *Server) checkSecondFactor(ctx *gin.Context, user db.User, req SecondFactorRequest) error {
+	err := server.authenticator.CheckSecondFactor(ctx, user, req.Code, req.RecoveryCode)
+	if err != nil {
+		return authError(err)
+	}
+	return nil
+}
+
+type LoginChallengeResponse struct {
+	TOTPRequired       bool      `json:"totp_required"`
+	ChallengeToken     string    `json:"challenge_token"`
+	ChallengeExpiresAt time.Time `json:"challenge_expires_at"`
+}
+
+type LoginTOTPRequest struct {
+	ChallengeToken string `json:"challenge_token" binding:"required"`
+	SecondFactorRequest
+}
+
+// loginTOTP completes the login of a user with two-factor authentication by
+// exchanging the challenge token from loginUser and a code for an access token.
+func (server *Server) loginTOTP(ctx *gin.Context) {
+	var req LoginTOTPRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.Error(apperr.FromBinding(err))
+		return
+	}
+
+	payload, err := server.challengeTokenMaker.VerifyToken(req.ChallengeToken)
+	if err != nil {
+		ctx.Error(errInvalidChallengeToken)
+		return
+	}
+
+	if !server.checkLoginLock(ctx, payload.Username) {
+		return
+	}
+
+	user, err := server.store.GetUser(ctx, payload.Username)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			ctx.Error(errInvalidChallengeToken)
+			return
+		}
+		ctx.Error(err)
+		return
+	}
+
+	if payload.IssuedAt.Before(user.PasswordChangedAt) {
+		ctx.Error(errTokenRevoked)
+		return
+	}
+	if !user.IsTotpEnabled {
+		ctx.Error(errInvalidChallengeToken)
+		return
+	}
+
+	if err := server.checkSecondFactor(ctx, user, req.SecondFactorRequest); err != nil {
+		if errors.Is(err, errInvalidTOTPCode) {
+			server.authenticator.RecordLoginFailure(ctx, user.Username, ctx.ClientIP())
+		}
+		ctx.Error(err)
+		return
+	}
+
+	server.authenticator.ResetLoginFailures(ctx, user.Username)
+	server.issueAccessToken(ctx, user, payload.Scopes...)
+}
+
+type EnrollTOTPResponse struct {
+	Secret     string `json:"secret"`
+	OTPAuthURI string `json:"otpauth_uri"`
+}
+
+// enrollTOTP starts enrolling an authenticator app. The secret stays pending,
+// and login keeps working without a code, until it is confirmed.
+func (server *Server) enrollTOTP(ctx *gin.Context) {
+	user := ctx.MustGet(authorizationUserKey).(db.User)
+	if user.IsTotpEnabled {
+		ctx.Error(errTOTPAlreadyEnabled)
+		return
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	_, err = server.store.SetUserTOTPSecret(ctx, db.SetUserTOTPSecretParams{
+		Username:   user.Username,
+		TotpSecret: secret,
+	})
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			ctx.Error(errTOTPAlreadyEnabled)
+			return
+		}
+		ctx.Error(err)
+		return
+	}
+
+	rsp := EnrollTOTPResponse{
+		Secret:     secret,
+		OTPAuthURI: totp.URI(server.config.TOTPIssuer, user.Username, secret),
+	}
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+type ConfirmTOTPRequest struct {
+	Code string `json:"code" binding:"required,numeric,len=6"`
+}
+
+type ConfirmTOTPResponse struct {
+	User UserResponse `json:"user"`
+	// RecoveryCodes are only ever shown here; just their hashes are stored
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// confirmTOTP enables two-factor authentication once the user proves their
+// authenticator produces codes for the pending secret.
+func (server *Server) confirmTOTP(ctx *gin.Context) {
+	var req ConfirmTOTPRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.Error(apperr.FromBinding(err))
+		return
+	}
+
+	user := ctx.MustGet(authorizationUserKey).(db.User)
+	if user.IsTotpEnabled {
+		ctx.Error(errTOTPAlreadyEnabled)
+		return
+	}
+	if user.TotpSecret == "" {
+		ctx.Error(errTOTPNotEnrolled)
+		return
+	}
+
+	step, err := totp.Validate(user.TotpSecret, req.Code, time.Now())
+	if err != nil {
+		ctx.Error(errInvalidTOTPCode)
+		return
+	}
+
+	recoveryCodes, err := totp.GenerateRecoveryCodes(totp.RecoveryCodeCount)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	hashedCodes := make([]string, len(recoveryCodes))
+	for i, code := range recoveryCodes {
+		hashedCodes[i] = totp.HashRecoveryCode(code)
+	}
+
+	user, err = server.store.EnableTOTPTx(ctx, db.EnableTOTPTxParams{
+		Username:            user.Username,
+		Secret:              user.TotpSecret,
+		Step:                step,
+		HashedRecoveryCodes: hashedCodes,
+	})
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			ctx.Error(errTOTPNotEnrolled)
+			return
+		}
+		ctx.Error(err)
+		return
+	}
+
+	rsp := ConfirmTOTPResponse{
+		User:          newUserResponse(user),
+		RecoveryCodes: recoveryCodes,
+	}
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+type DisableTOTPRequest struct {
+	Password string `json:"password" binding:"required"`
+	SecondFactorRequest
+}
+
+// disableTOTP turns off two-factor authentication. It needs both the password
+// and a second factor, so a stolen access token alone can't remove it.
+func (server *Server) disableTOTP(ctx *gin.Context) {
+	var req DisableTOTPRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.Error(apperr.FromBinding(err))
+		return
+	}
+
+	user := ctx.MustGet(authorizationUserKey).(db.User)
+	if !user.IsTotpEnabled {
+		ctx.Error(errTOTPNotEnabled)
+		return
+	}
+
+	err := server.passwordHasher.Check(req.Password, user.HashedPassword)
+	if err != nil {
+		ctx.Error(errIncorrectPassword.WithCause(err))
+		return
+	}
+
+	if err := server.checkSecondFactor(ctx, user, req.SecondFactorRequest); err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	user, err = server.store.DisableTOTPTx(ctx, user.Username)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newUserResponse(user))
+}
+
+// checkStepUp asks for a TOTP code on transfers of at least the configured
+// step-up amount. It adds the error to the context and returns false when
+// the transfer must not go ahead.
+func (server *Server) checkStepUp(ctx *gin.Context, user db.User, amount int64, code string) bool {
+	if err := server.authenticator.CheckStepUp(ctx, user, amount, code); err != nil {
+		ctx.Error(authError(err))
+		return false
+	}
+	return true
+}
+
+var totpLoginOperations = []apiOperation{
+	{
+		method:   http.MethodPost,
+		path:     "/users/login/totp",
+		tag:      "users",
+		summary:  "Complete a login with a two-factor authentication or recovery code",
+		body:     LoginTOTPRequest{},
+		response: LoginUserResponse{},
+	},
+}
+
+func (server *Server) setupTOTPLoginRoutes(router gin.IRoutes) {
+	router.POST("/users/login/totp", server.rateLimits.login, server.loginTOTP)
+}
+
+var totpOperations = []apiOperation{
+	{
+		method:   http.MethodPost,
+		path:     "/users/me/totp",
+		tag:      "users",
+		summary:  "Start enrolling an authenticator",
+		auth:     authToken,
+		scopes:   []string{token.ScopeUser},
+		response: EnrollTOTPResponse{},
+	},
+	{
+		method:   http.MethodPost,
+		path:     "/users/me/totp/confirm",
+		tag:      "users",
+		summary:  "Enable two-factor authentication with a code from the authenticator",
+		auth:     authToken,
+		scopes:   []string{token.ScopeUser},
+		body:     ConfirmTOTPRequest{},
+		response: ConfirmTOTPResponse{},
+	},
+	{
+		method:   http.MethodDelete,
+		path:     "/users/me/totp",
+		tag:      "users",
+		summary:  "Disable two-factor authentication",
+		auth:     authToken,
+		scopes:   []string{token.ScopeUser},
+		body:     DisableTOTPRequest{},
+		response: UserResponse{},
+	},
+}
+
+func (server *Server) setupTOTPRoutes(router gin.IRoutes) {
+	router.POST("/users/me/totp", server.enrollTOTP)
+	router.POST("/users/me/totp/confirm", server.confirmTOTP)
+	router.DELETE("/users/me/totp", server.disableTOTP)
+}
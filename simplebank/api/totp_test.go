@@ -0,0 +1,905 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	mockdb "github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/db/mock"
+	db "github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/db/sqlc"
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/middleware"
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/token"
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/totp"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/require"
+)
+
+// randomTOTPUser returns a user with two-factor authentication enabled
+func randomTOTPUser(t *testing.T) (user db.User, password string) {
+	user, password = randomUser(t)
+
+	secret, err := totp.GenerateSecret()
+	require.NoError(t, err)
+
+	user.TotpSecret = secret
+	user.IsTotpEnabled = true
+	user.IsEmailVerified = true
+	return
+}
+
+func currentTOTPCode(t *testing.T, secret string) string {
+	code, err := totp.Code(secret, time.Now())
+	require.NoError(t, err)
+	return code
+}
+
+func TestLoginUserTOTPAPI(t *testing.T) {
+	user, password := randomUser(t)
+	totpUser, totpPassword := randomTOTPUser(t)
+
+	testCases := []struct {
+		name          string
+		user          db.User
+		password      string
+		scopes        []string
+		checkResponse func(t *testing.T, server *Server, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:     "WithoutTOTP",
+			user:     user,
+			password: password,
+			checkResponse: func(t *testing.T, server *Server, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp LoginUserResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+				require.NotEmpty(t, rsp.AccessToken)
+				require.Equal(t, user.Username, rsp.User.Username)
+			},
+		},
+		{
+			name:     "WithTOTP",
+			user:     totpUser,
+			password: totpPassword,
+			checkResponse: func(t *testing.T, server *Server, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp map[string]interface{}
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+				require.NotContains(t, rsp, "access_token")
+				require.Equal(t, true, rsp["totp_required"])
+
+				challengeToken := rsp["challenge_token"].(string)
+				payload, err := server.challengeTokenMaker.VerifyToken(challengeToken)
+				require.NoError(t, err)
+				require.Equal(t, totpUser.Username, payload.Username)
+
+				// the challenge is no substitute for an access token
+				_, err = server.tokenMaker.VerifyToken(challengeToken)
+				require.Error(t, err)
+			},
+		},
+		{
+			name:     "WithScopes",
+			user:     user,
+			password: password,
+			scopes:   []string{token.ScopeAccountsRead},
+			checkResponse: func(t *testing.T, server *Server, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp LoginUserResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+				require.Equal(t, []string{token.ScopeAccountsRead}, rsp.Scopes)
+
+				payload, err := server.tokenMaker.VerifyToken(rsp.AccessToken)
+				require.NoError(t, err)
+				require.Equal(t, []string{token.ScopeAccountsRead}, payload.Scopes)
+			},
+		},
+		{
+			name:     "WithTOTPAndScopes",
+			user:     totpUser,
+			password: totpPassword,
+			scopes:   []string{token.ScopeAccountsRead},
+			checkResponse: func(t *testing.T, server *Server, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp LoginChallengeResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+
+				// loginTOTP issues the access token with the scopes of the challenge
+				payload, err := server.challengeTokenMaker.VerifyToken(rsp.ChallengeToken)
+				require.NoError(t, err)
+				require.Equal(t, []string{token.ScopeAccountsRead}, payload.Scopes)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			store.EXPECT().
+				GetUser(gomock.Any(), gomock.Eq(tc.user.Username)).
+				Times(1).
+				Return(tc.user, nil)
+			stubLoginThrottle(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(gin.H{
+				"username": tc.user.Username,
+				"password": tc.password,
+				"scopes":   tc.scopes,
+			})
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/users/login", bytes.NewReader(data))
+			require.NoError(t, err)
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, server, recorder)
+		})
+	}
+}
+
+func TestLoginTOTPAPI(t *testing.T) {
+	user, _ := randomTOTPUser(t)
+	recoveryCode := "abcde-fgh23"
+
+	challenge := func(t *testing.T, server *Server) string {
+		challengeToken, err := server.challengeTokenMaker.CreateToken(user.Username, time.Minute)
+		require.NoError(t, err)
+		return challengeToken
+	}
+
+	testCases := []struct {
+		name          string
+		body          func(t *testing.T, server *Server) gin.H
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			body: func(t *testing.T, server *Server) gin.H {
+				return gin.H{
+					"challenge_token": challenge(t, server),
+					"code":            currentTOTPCode(t, user.TotpSecret),
+				}
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					UseTOTPStep(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg db.UseTOTPStepParams) (db.User, error) {
+						require.Equal(t, user.Username, arg.Username)
+						require.InDelta(t, totp.Step(time.Now()), arg.TotpLastStep, 1)
+						return user, nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp LoginUserResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+				require.NotEmpty(t, rsp.AccessToken)
+				require.True(t, rsp.User.IsTOTPEnabled)
+			},
+		},
+		{
+			name: "KeepsRequestedScopes",
+			body: func(t *testing.T, server *Server) gin.H {
+				challengeToken, _, err := server.challengeTokenMaker.CreateScopedToken(user.Username, time.Minute, token.ScopeAccountsRead)
+				require.NoError(t, err)
+				return gin.H{
+					"challenge_token": challengeToken,
+					"code":            currentTOTPCode(t, user.TotpSecret),
+				}
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					UseTOTPStep(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(user, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp LoginUserResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+				require.Equal(t, []string{token.ScopeAccountsRead}, rsp.Scopes)
+			},
+		},
+		{
+			name: "OKWithRecoveryCode",
+			body: func(t *testing.T, server *Server) gin.H {
+				return gin.H{
+					"challenge_token": challenge(t, server),
+					"recovery_code":   recoveryCode,
+				}
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					UseRecoveryCode(gomock.Any(), gomock.Eq(db.UseRecoveryCodeParams{
+						Username:   user.Username,
+						HashedCode: totp.HashRecoveryCode(recoveryCode),
+					})).
+					Times(1).
+					Return(db.RecoveryCode{Username: user.Username}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "WrongCode",
+			body: func(t *testing.T, server *Server) gin.H {
+				code, err := totp.Code(user.TotpSecret, time.Now().Add(-5*totp.Period))
+				require.NoError(t, err)
+				return gin.H{
+					"challenge_token": challenge(t, server),
+					"code":            code,
+				}
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					UseTOTPStep(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "ReplayedCode",
+			body: func(t *testing.T, server *Server) gin.H {
+				return gin.H{
+					"challenge_token": challenge(t, server),
+					"code":            currentTOTPCode(t, user.TotpSecret),
+				}
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					UseTOTPStep(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.User{}, pgx.ErrNoRows)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "UsedRecoveryCode",
+			body: func(t *testing.T, server *Server) gin.H {
+				return gin.H{
+					"challenge_token": challenge(t, server),
+					"recovery_code":   recoveryCode,
+				}
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					UseRecoveryCode(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.RecoveryCode{}, pgx.ErrNoRows)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "MissingCode",
+			body: func(t *testing.T, server *Server) gin.H {
+				return gin.H{
+					"challenge_token": challenge(t, server),
+				}
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "AccessTokenAsChallenge",
+			body: func(t *testing.T, server *Server) gin.H {
+				accessToken, err := server.tokenMaker.CreateToken(user.Username, time.Minute)
+				require.NoError(t, err)
+				return gin.H{
+					"challenge_token": accessToken,
+					"code":            currentTOTPCode(t, user.TotpSecret),
+				}
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "ExpiredChallenge",
+			body: func(t *testing.T, server *Server) gin.H {
+				challengeToken, err := server.challengeTokenMaker.CreateToken(user.Username, -time.Minute)
+				require.NoError(t, err)
+				return gin.H{
+					"challenge_token": challengeToken,
+					"code":            currentTOTPCode(t, user.TotpSecret),
+				}
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "PasswordChangedSinceChallenge",
+			body: func(t *testing.T, server *Server) gin.H {
+				return gin.H{
+					"challenge_token": challenge(t, server),
+					"code":            currentTOTPCode(t, user.TotpSecret),
+				}
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				changedUser := user
+				changedUser.PasswordChangedAt = time.Now().Add(time.Second)
+
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(changedUser, nil)
+				store.EXPECT().
+					UseTOTPStep(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "TOTPDisabledSinceChallenge",
+			body: func(t *testing.T, server *Server) gin.H {
+				return gin.H{
+					"challenge_token": challenge(t, server),
+					"code":            currentTOTPCode(t, user.TotpSecret),
+				}
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				disabledUser := user
+				disabledUser.IsTotpEnabled = false
+
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(disabledUser, nil)
+				store.EXPECT().
+					UseTOTPStep(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "InternalError",
+			body: func(t *testing.T, server *Server) gin.H {
+				return gin.H{
+					"challenge_token": challenge(t, server),
+					"code":            currentTOTPCode(t, user.TotpSecret),
+				}
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					UseTOTPStep(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.User{}, pgx.ErrTxClosed)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+			stubLoginThrottle(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(tc.body(t, server))
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/users/login/totp", bytes.NewReader(data))
+			require.NoError(t, err)
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestEnrollTOTPAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	totpUser, _ := randomTOTPUser(t)
+
+	testCases := []struct {
+		name          string
+		user          db.User
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			user: user,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					SetUserTOTPSecret(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg db.SetUserTOTPSecretParams) (db.User, error) {
+						require.Equal(t, user.Username, arg.Username)
+						require.Len(t, arg.TotpSecret, 32)
+
+						enrolled := user
+						enrolled.TotpSecret = arg.TotpSecret
+						return enrolled, nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp EnrollTOTPResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+				require.Len(t, rsp.Secret, 32)
+
+				uri, err := url.Parse(rsp.OTPAuthURI)
+				require.NoError(t, err)
+				require.Equal(t, "otpauth", uri.Scheme)
+				require.Equal(t, "/Simple Bank:"+user.Username, uri.Path)
+				require.Equal(t, rsp.Secret, uri.Query().Get("secret"))
+			},
+		},
+		{
+			name: "AlreadyEnabled",
+			user: totpUser,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					SetUserTOTPSecret(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "EnabledConcurrently",
+			user: user,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					SetUserTOTPSecret(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.User{}, pgx.ErrNoRows)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "InternalError",
+			user: user,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					SetUserTOTPSecret(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.User{}, pgx.ErrTxClosed)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			store.EXPECT().
+				GetUser(gomock.Any(), gomock.Eq(tc.user.Username)).
+				AnyTimes().
+				Return(tc.user, nil)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			request, err := http.NewRequest(http.MethodPost, "/users/me/totp", nil)
+			require.NoError(t, err)
+
+			middleware.AddTestAuthorization(t, request, server.tokenMaker, middleware.AuthorizationTypeBearer, tc.user.Username, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+type eqEnableTOTPTxParamsMatcher struct {
+	username string
+	secret   string
+	// codes receives the hashes the handler stored, to compare with the response
+	codes *[]string
+}
+
+func (e eqEnableTOTPTxParamsMatcher) Matches(x interface{}) bool {
+	arg, ok := x.(db.EnableTOTPTxParams)
+	if !ok {
+		return false
+	}
+
+	if arg.Username != e.username || arg.Secret != e.secret {
+		return false
+	}
+
+	now := totp.Step(time.Now())
+	if arg.Step < now-totp.Skew || arg.Step > now+totp.Skew {
+		return false
+	}
+
+	*e.codes = arg.HashedRecoveryCodes
+	return len(arg.HashedRecoveryCodes) == totp.RecoveryCodeCount
+}
+
+func (e eqEnableTOTPTxParamsMatcher) String() string {
+	return fmt.Sprintf("enables totp for %v with secret %v", e.username, e.secret)
+}
+
+func TestConfirmTOTPAPI(t *testing.T) {
+	user, _ := randomUser(t)
+
+	pendingUser := user
+	pendingUser.TotpSecret, _ = totp.GenerateSecret()
+
+	enabledUser := pendingUser
+	enabledUser.IsTotpEnabled = true
+
+	var hashedCodes []string
+
+	testCases := []struct {
+		name          string
+		user          db.User
+		code          func(t *testing.T) string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			user: pendingUser,
+			code: func(t *testing.T) string {
+				return currentTOTPCode(t, pendingUser.TotpSecret)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					EnableTOTPTx(gomock.Any(), eqEnableTOTPTxParamsMatcher{
+						username: user.Username,
+						secret:   pendingUser.TotpSecret,
+						codes:    &hashedCodes,
+					}).
+					Times(1).
+					Return(enabledUser, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp ConfirmTOTPResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+				require.True(t, rsp.User.IsTOTPEnabled)
+				require.Len(t, rsp.RecoveryCodes, totp.RecoveryCodeCount)
+
+				for i, code := range rsp.RecoveryCodes {
+					require.Equal(t, totp.HashRecoveryCode(code), hashedCodes[i])
+				}
+			},
+		},
+		{
+			name: "WrongCode",
+			user: pendingUser,
+			code: func(t *testing.T) string {
+				code, err := totp.Code(pendingUser.TotpSecret, time.Now().Add(-5*totp.Period))
+				require.NoError(t, err)
+				return code
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					EnableTOTPTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "NotEnrolled",
+			user: user,
+			code: func(t *testing.T) string {
+				return "123456"
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					EnableTOTPTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "AlreadyEnabled",
+			user: enabledUser,
+			code: func(t *testing.T) string {
+				return currentTOTPCode(t, enabledUser.TotpSecret)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					EnableTOTPTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "SecretReplaced",
+			user: pendingUser,
+			code: func(t *testing.T) string {
+				return currentTOTPCode(t, pendingUser.TotpSecret)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					EnableTOTPTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.User{}, pgx.ErrNoRows)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "InvalidCode",
+			user: pendingUser,
+			code: func(t *testing.T) string {
+				return "12ab56"
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					EnableTOTPTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "InternalError",
+			user: pendingUser,
+			code: func(t *testing.T) string {
+				return currentTOTPCode(t, pendingUser.TotpSecret)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					EnableTOTPTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.User{}, pgx.ErrTxClosed)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			store.EXPECT().
+				GetUser(gomock.Any(), gomock.Eq(tc.user.Username)).
+				AnyTimes().
+				Return(tc.user, nil)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(gin.H{"code": tc.code(t)})
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/users/me/totp/confirm", bytes.NewReader(data))
+			require.NoError(t, err)
+
+			middleware.AddTestAuthorization(t, request, server.tokenMaker, middleware.AuthorizationTypeBearer, tc.user.Username, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestDisableTOTPAPI(t *testing.T) {
+	user, password := randomTOTPUser(t)
+
+	disabledUser := user
+	disabledUser.TotpSecret = ""
+	disabledUser.IsTotpEnabled = false
+
+	testCases := []struct {
+		name          string
+		user          db.User
+		body          func(t *testing.T) gin.H
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			user: user,
+			body: func(t *testing.T) gin.H {
+				return gin.H{
+					"password": password,
+					"code":     currentTOTPCode(t, user.TotpSecret),
+				}
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					UseTOTPStep(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					DisableTOTPTx(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(disabledUser, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				requireBodyMatchUser(t, recorder, disabledUser)
+			},
+		},
+		{
+			name: "WrongPassword",
+			user: user,
+			body: func(t *testing.T) gin.H {
+				return gin.H{
+					"password": "wrong-password",
+					"code":     currentTOTPCode(t, user.TotpSecret),
+				}
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					UseTOTPStep(gomock.Any(), gomock.Any()).
+					Times(0)
+				store.EXPECT().
+					DisableTOTPTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "WrongCode",
+			user: user,
+			body: func(t *testing.T) gin.H {
+				code, err := totp.Code(user.TotpSecret, time.Now().Add(-5*totp.Period))
+				require.NoError(t, err)
+				return gin.H{
+					"password": password,
+					"code":     code,
+				}
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					DisableTOTPTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "NotEnabled",
+			user: disabledUser,
+			body: func(t *testing.T) gin.H {
+				return gin.H{
+					"password": password,
+					"code":     "123456",
+				}
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					DisableTOTPTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			store.EXPECT().
+				GetUser(gomock.Any(), gomock.Eq(tc.user.Username)).
+				AnyTimes().
+				Return(tc.user, nil)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(tc.body(t))
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodDelete, "/users/me/totp", bytes.NewReader(data))
+			require.NoError(t, err)
+
+			middleware.AddTestAuthorization(t, request, server.tokenMaker, middleware.AuthorizationTypeBearer, tc.user.Username, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
@@ -1,72 +1,99 @@
 package api
 
 import (
-	"fmt"
+	"errors"
 	"net/http"
 
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/apperr"
 	db "github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/db/sqlc"
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/middleware"
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/token"
 	"github.com/gin-gonic/gin"
-	"github.com/jackc/pgx/v5"
 )
 
+// CreateTransferRequest identifies each account either by id or by account number
 type CreateTransferRequest struct {
-	FromAccountID int64  `json:"from_account_id" binding:"required"`
-	ToAccountID   int64  `json:"to_account_id" binding:"required"`
-	Amount        int64  `json:"amount" binding:"required,min=1"`
-	Currency      string `json:"currency" binding:"required,currency"`
+	FromAccountID     int64  `json:"from_account_id" binding:"required_without=FromAccountNumber,excluded_with=FromAccountNumber"`
+	FromAccountNumber string `json:"from_account_number" binding:"omitempty,account_number"`
+	ToAccountID       int64  `json:"to_account_id" binding:"required_without=ToAccountNumber,excluded_with=ToAccountNumber"`
+	ToAccountNumber   string `json:"to_account_number" binding:"omitempty,account_number"`
+	Amount            int64  `json:"amount" binding:"required,min=1"`
+	Currency          string `json:"currency" binding:"required,currency"`
+	// TOTPCode is required for transfers of at least the step-up amount
+	TOTPCode string `json:"totp_code" binding:"omitempty,numeric,len=6"`
 }
 
 func (server *Server) CreateTransfer(ctx *gin.Context) {
 	var req CreateTransferRequest
 	if err := ctx.ShouldBindJSON(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, errorsResponse(err))
+		ctx.Error(apperr.FromBinding(err))
 		return
 	}
 
-	if !server.validAccount(ctx, req.FromAccountID, req.Currency) {
+	user := ctx.MustGet(authorizationUserKey).(db.User)
+	if !user.IsEmailVerified {
+		ctx.Error(errEmailNotVerified)
 		return
 	}
-	if !server.validAccount(ctx, req.ToAccountID, req.Currency) {
+
+	fromAccount, valid := server.validAccount(ctx, user.Username, req.FromAccountID, req.FromAccountNumber, req.Currency)
+	if !valid {
+		return
+	}
+	toAccount, valid := server.validAccount(ctx, "", req.ToAccountID, req.ToAccountNumber, req.Currency)
+	if !valid {
+		return
+	}
+
+	if !server.checkStepUp(ctx, user, req.Amount, req.TOTPCode) {
 		return
 	}
 
 	arg := db.TransferTxParams{
-		FromAccountID: req.FromAccountID,
-		ToAccountID:   req.ToAccountID,
+		FromAccountID: fromAccount.ID,
+		ToAccountID:   toAccount.ID,
 		Amount:        req.Amount,
 	}
 
 	result, err := server.store.TransferTx(ctx, arg)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, errorsResponse(err))
+		// an account was frozen since it was checked
+		if errors.Is(err, db.ErrAccountFrozen) {
+			err = errAccountFrozen.WithCause(err)
+		}
+		ctx.Error(err)
 		return
 	}
 
 	ctx.JSON(http.StatusOK, result)
 }
 
-func (server *Server) validAccount(ctx *gin.Context, accountID int64, currency string) bool {
-	account, err := server.store.GetAccount(ctx, accountID)
-
+// validAccount looks up the account by number when one is given and by id
+// otherwise, and checks that it belongs to owner when one is given. It adds
+// the error to the context and returns false when the transfer can't use the
+// account.
+func (server *Server) validAccount(ctx *gin.Context, owner string, accountID int64, accountNumber string, currency string) (db.Account, bool) {
+	account, err := server.authenticator.TransferAccount(ctx, owner, accountID, accountNumber, currency)
 	if err != nil {
-		if err == pgx.ErrNoRows {
-			ctx.JSON(http.StatusNotFound, errorsResponse(err))
-			return false
-		}
-		ctx.JSON(http.StatusInternalServerError, errorsResponse(err))
-		return false
-	}
-
-	// Check account currency
-	if account.Currency != currency {
-		err = fmt.Errorf("account [%d] currency mismatch: %v vs %v", accountID, currency, account.Currency)
-		ctx.JSON(http.StatusBadRequest, errorsResponse(err))
-		return false
+		ctx.Error(authError(err))
+		return account, false
 	}
+	return account, true
+}
 
-	return true
+var transferOperations = []apiOperation{
+	{
+		method:   http.MethodPost,
+		path:     "/transfer",
+		tag:      "transfers",
+		summary:  "Transfer money between two accounts in the same currency",
+		auth:     authTokenOrAPIKey,
+		scopes:   []string{token.ScopeTransfersWrite},
+		body:     CreateTransferRequest{},
+		response: db.TransferTxResult{},
+	},
 }
 
 func (server *Server) setupTransferRoutes(router gin.IRoutes) {
-	router.POST("/transfer", server.CreateTransfer)
+	router.POST("/transfer", server.rateLimits.transfer, middleware.RequireScopes(token.ScopeTransfersWrite), server.CreateTransfer)
 }
@@ -3,6 +3,7 @@ package api
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
@@ -11,10 +12,12 @@ import (
 
 	mockdb "github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/db/mock"
 	db "github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/db/sqlc"
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/middleware"
 	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/util"
 	"github.com/gin-gonic/gin"
 	"github.com/golang/mock/gomock"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/stretchr/testify/require"
 )
 
@@ -105,7 +108,69 @@ func TestCreateTransferAPI(t *testing.T) {
 			},
 		},
 
+		{
+			name: "OKByAccountNumber",
+			body: gin.H{
+				"from_account_number": fromAccount.AccountNumber,
+				"to_account_number":   toAccount.AccountNumber,
+				"amount":              amount,
+				"currency":            toAccount.Currency,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAccountByNumber(gomock.Any(), gomock.Eq(fromAccount.AccountNumber)).
+					Times(1).
+					Return(fromAccount, nil)
+
+				store.EXPECT().
+					GetAccountByNumber(gomock.Any(), gomock.Eq(toAccount.AccountNumber)).
+					Times(1).
+					Return(toAccount, nil)
+
+				arg := db.TransferTxParams{
+					FromAccountID: fromAccount.ID,
+					ToAccountID:   toAccount.ID,
+					Amount:        amount,
+				}
+				store.EXPECT().
+					TransferTx(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(transferTxResult, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				requireBodyMatchTransferTxResponse(t, recorder, transferTxResult)
+			},
+		},
+
 		// Gin Validation Errors (400 Bad Request)
+		{
+			name: "InvalidAccountNumber",
+			body: gin.H{
+				"from_account_number": "NG000000000000",
+				"to_account_id":       transfer.ToAccountID,
+				"amount":              amount,
+				"currency":            toAccount.Currency,
+			},
+			buildStubs: expectNoAction,
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "BothAccountIDAndNumber",
+			body: gin.H{
+				"from_account_id":     transfer.FromAccountID,
+				"from_account_number": fromAccount.AccountNumber,
+				"to_account_id":       transfer.ToAccountID,
+				"amount":              amount,
+				"currency":            toAccount.Currency,
+			},
+			buildStubs: expectNoAction,
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
 		{
 			name: "InvalidCurrency",
 			body: gin.H{
@@ -206,6 +271,31 @@ func TestCreateTransferAPI(t *testing.T) {
 				require.Equal(t, http.StatusNotFound, recorder.Code)
 			},
 		},
+		{
+			name: "FromAccountNotOwned",
+			body: gin.H{
+				"from_account_id": toAccount.ID,
+				"to_account_id":   fromAccount.ID,
+				"amount":          amount,
+				"currency":        toAccount.Currency,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(toAccount.ID)).
+					Times(1).
+					Return(toAccount, nil)
+
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(fromAccount.ID)).
+					Times(0)
+				store.EXPECT().
+					TransferTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireProblem(t, recorder, http.StatusForbidden, "account_not_owned")
+			},
+		},
 		{
 			name: "ToAccountNotFound",
 			body: gin.H{
@@ -297,6 +387,105 @@ func TestCreateTransferAPI(t *testing.T) {
 				require.Equal(t, http.StatusBadRequest, recorder.Code)
 			},
 		},
+		{
+			name: "FromAccountFrozen",
+			body: gin.H{
+				"from_account_id": fromAccount.ID,
+				"to_account_id":   toAccount.ID,
+				"amount":          amount,
+				"currency":        fromAccount.Currency,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				frozenAccount := fromAccount
+				frozenAccount.FrozenAt = pgtype.Timestamptz{Time: time.Now(), Valid: true}
+
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(fromAccount.ID)).
+					Times(1).
+					Return(frozenAccount, nil)
+				store.EXPECT().
+					TransferTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireProblem(t, recorder, http.StatusConflict, "account_frozen")
+			},
+		},
+		{
+			name: "ToAccountFrozen",
+			body: gin.H{
+				"from_account_id": fromAccount.ID,
+				"to_account_id":   toAccount.ID,
+				"amount":          amount,
+				"currency":        fromAccount.Currency,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				frozenAccount := toAccount
+				frozenAccount.FrozenAt = pgtype.Timestamptz{Time: time.Now(), Valid: true}
+
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(fromAccount.ID)).
+					Times(1).
+					Return(fromAccount, nil)
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(toAccount.ID)).
+					Times(1).
+					Return(frozenAccount, nil)
+				store.EXPECT().
+					TransferTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireProblem(t, recorder, http.StatusConflict, "account_frozen")
+			},
+		},
+		{
+			name: "FrozenDuringTransfer",
+			body: gin.H{
+				"from_account_id": fromAccount.ID,
+				"to_account_id":   toAccount.ID,
+				"amount":          amount,
+				"currency":        fromAccount.Currency,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(fromAccount.ID)).
+					Times(1).
+					Return(fromAccount, nil)
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(toAccount.ID)).
+					Times(1).
+					Return(toAccount, nil)
+				store.EXPECT().
+					TransferTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.TransferTxResult{}, fmt.Errorf("%w: account %d", db.ErrAccountFrozen, toAccount.ID))
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireProblem(t, recorder, http.StatusConflict, "account_frozen")
+			},
+		},
+
+		{
+			name: "EmailNotVerified",
+			body: gin.H{
+				"from_account_id": transfer.FromAccountID,
+				"to_account_id":   transfer.ToAccountID,
+				"amount":          amount,
+				"currency":        toAccount.Currency,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user1.Username)).
+					Times(1).
+					Return(user1, nil)
+
+				expectNoAction(store)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
 
 		// TODO: Transaction / Internal Errors (500)
 	}
@@ -308,6 +497,7 @@ func TestCreateTransferAPI(t *testing.T) {
 
 			store := mockdb.NewMockStore(ctrl)
 			tc.buildStubs(store)
+			stubAuthUser(store)
 
 			server := newTestServer(t, store)
 			recorder := httptest.NewRecorder()
@@ -320,6 +510,175 @@ func TestCreateTransferAPI(t *testing.T) {
 			request, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(data))
 			require.NoError(t, err)
 
+			middleware.AddTestAuthorization(t, request, server.tokenMaker, middleware.AuthorizationTypeBearer, user1.Username, time.Minute)
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestCreateTransferStepUpAPI(t *testing.T) {
+	const stepUpAmount = 1000
+
+	user, _ := randomTOTPUser(t)
+	userWithoutTOTP, _ := randomUser(t)
+	userWithoutTOTP.IsEmailVerified = true
+
+	fromAccount := randomAccount(user.Username)
+	toAccount := randomAccount(userWithoutTOTP.Username)
+	fromAccount.Currency = util.USD
+	toAccount.Currency = util.USD
+
+	// expectAccounts finds the accounts, with the from account owned by owner
+	expectAccounts := func(store *mockdb.MockStore, owner string) {
+		from := fromAccount
+		from.Owner = owner
+		store.EXPECT().
+			GetAccount(gomock.Any(), gomock.Eq(fromAccount.ID)).
+			Times(1).
+			Return(from, nil)
+		store.EXPECT().
+			GetAccount(gomock.Any(), gomock.Eq(toAccount.ID)).
+			Times(1).
+			Return(toAccount, nil)
+	}
+
+	testCases := []struct {
+		name          string
+		user          db.User
+		amount        int64
+		code          func(t *testing.T) string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:   "BelowStepUpAmount",
+			user:   user,
+			amount: stepUpAmount - 1,
+			code: func(t *testing.T) string {
+				return ""
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				expectAccounts(store, user.Username)
+				store.EXPECT().
+					TransferTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.TransferTxResult{}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:   "OK",
+			user:   user,
+			amount: stepUpAmount,
+			code: func(t *testing.T) string {
+				return currentTOTPCode(t, user.TotpSecret)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				expectAccounts(store, user.Username)
+				store.EXPECT().
+					UseTOTPStep(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					TransferTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.TransferTxResult{}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:   "MissingCode",
+			user:   user,
+			amount: stepUpAmount,
+			code: func(t *testing.T) string {
+				return ""
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				expectAccounts(store, user.Username)
+				store.EXPECT().
+					TransferTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name:   "ReplayedCode",
+			user:   user,
+			amount: stepUpAmount,
+			code: func(t *testing.T) string {
+				return currentTOTPCode(t, user.TotpSecret)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				expectAccounts(store, user.Username)
+				store.EXPECT().
+					UseTOTPStep(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.User{}, pgx.ErrNoRows)
+				store.EXPECT().
+					TransferTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name:   "TOTPNotEnabled",
+			user:   userWithoutTOTP,
+			amount: stepUpAmount,
+			code: func(t *testing.T) string {
+				return ""
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				expectAccounts(store, userWithoutTOTP.Username)
+				store.EXPECT().
+					TransferTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			store.EXPECT().
+				GetUser(gomock.Any(), gomock.Eq(tc.user.Username)).
+				AnyTimes().
+				Return(tc.user, nil)
+			tc.buildStubs(store)
+
+			config := newTestConfig()
+			config.TOTPStepUpAmount = stepUpAmount
+			server := newTestServerWithConfig(t, store, config)
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(gin.H{
+				"from_account_id": fromAccount.ID,
+				"to_account_id":   toAccount.ID,
+				"amount":          tc.amount,
+				"currency":        util.USD,
+				"totp_code":       tc.code(t),
+			})
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/transfer", bytes.NewReader(data))
+			require.NoError(t, err)
+
+			middleware.AddTestAuthorization(t, request, server.tokenMaker, middleware.AuthorizationTypeBearer, tc.user.Username, time.Minute)
 			server.router.ServeHTTP(recorder, request)
 			tc.checkResponse(t, recorder)
 		})
@@ -1,19 +1,30 @@
 package api
 
 import (
+	"log/slog"
 	"net/http"
 	"time"
 
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/apperr"
 	db "github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/db/sqlc"
-	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/util"
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/middleware"
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/token"
 	"github.com/gin-gonic/gin"
-	"github.com/jackc/pgx/v5"
-	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// authorizationUserKey holds the authenticated user loaded by checkTokenUser
+const authorizationUserKey = "authorization_user"
+
+var (
+	errUserNotFound      = apperr.New(http.StatusUnauthorized, "user_not_found", "user no longer exists")
+	errTokenRevoked      = apperr.New(http.StatusUnauthorized, "token_revoked", "token was issued before the password was last changed")
+	errIncorrectPassword = apperr.New(http.StatusUnauthorized, "incorrect_password", "password is incorrect")
 )
 
 type CreateUserRequest struct {
 	Username        string `json:"username" binding:"required,alphanum"`
-	Password        string `json:"password" binding:"required,min=8"`
+	Password        string `json:"password" binding:"required,password"`
 	ConfirmPassword string `json:"confirm_password" binding:"required,eqfield=Password"`
 	FullName        string `json:"full_name" binding:"required"`
 	Email           string `json:"email" binding:"required,email"`
@@ -23,6 +34,9 @@ type UserResponse struct {
 	Username          string    `json:"username"`
 	FullName          string    `json:"full_name"`
 	Email             string    `json:"email"`
+	IsEmailVerified   bool      `json:"is_email_verified"`
+	IsTOTPEnabled     bool      `json:"is_totp_enabled"`
+	Role              string    `json:"role"`
 	PasswordChangedAt time.Time `json:"password_changed_at"`
 	CreatedAt         time.Time `json:"created_at"`
 }
@@ -32,6 +46,9 @@ func newUserResponse(user db.User) UserResponse {
 		Username:          user.Username,
 		FullName:          user.FullName,
 		Email:             user.Email,
+		IsEmailVerified:   user.IsEmailVerified,
+		IsTOTPEnabled:     user.IsTotpEnabled,
+		Role:              user.Role,
 		PasswordChangedAt: user.PasswordChangedAt,
 		CreatedAt:         user.CreatedAt,
 	}
@@ -40,13 +57,13 @@ func newUserResponse(user db.User) UserResponse {
 func (server *Server) createUser(ctx *gin.Context) {
 	var req CreateUserRequest
 	if err := ctx.ShouldBindJSON(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, errorsResponse(err))
+		ctx.Error(apperr.FromBinding(err))
 		return
 	}
 
-	hashedPassword, err := util.HashPassword(req.Password)
+	hashedPassword, err := server.passwordHasher.Hash(req.Password)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, errorsResponse(err))
+		ctx.Error(err)
 		return
 	}
 
@@ -59,73 +76,258 @@ func (server *Server) createUser(ctx *gin.Context) {
 
 	user, err := server.store.CreateUser(ctx, arg)
 	if err != nil {
-		if pqErr, ok := err.(*pgconn.PgError); ok {
-			switch pqErr.Code {
-			case "23505":
-				ctx.JSON(http.StatusForbidden, errorsResponse(err))
-				return
-			}
-		}
-		ctx.JSON(http.StatusInternalServerError, errorsResponse(err))
+		ctx.Error(err)
 		return
 	}
 
+	// the user can ask for a new link, so a mail failure doesn't fail the signup
+	if err := server.sendVerifyEmail(ctx, user); err != nil {
+		slog.ErrorContext(ctx, "cannot send verification email", "username", user.Username, "error", err)
+	}
+
 	rsp := newUserResponse(user)
 	ctx.JSON(http.StatusOK, rsp)
 }
 
 type LoginUserRequest struct {
 	Username string `json:"username" binding:"required,alphanum"`
-	Password string `json:"password" binding:"required,min=8"`
+	Password string `json:"password" binding:"required"`
+	// Scopes limit the access token, e.g. to read-only access for
+	// integrations. Without any the token grants full access.
+	Scopes []string `json:"scopes" binding:"omitempty,unique,dive,scope"`
 }
 
 type LoginUserResponse struct {
 	AccessToken string       `json:"access_token"`
+	Scopes      []string     `json:"scopes,omitempty"`
 	User        UserResponse `json:"user"`
 }
 
 func (server *Server) loginUser(ctx *gin.Context) {
 	var req LoginUserRequest
 	if err := ctx.ShouldBindJSON(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, errorsResponse(err))
+		ctx.Error(apperr.FromBinding(err))
 		return
 	}
 
-	user, err := server.store.GetUser(ctx, req.Username)
-	if err != nil {
-		if err == pgx.ErrNoRows {
-			ctx.JSON(http.StatusNotFound, errorsResponse(err))
-			return
-		}
-		ctx.JSON(http.StatusInternalServerError, errorsResponse(err))
+	if !server.checkLoginLock(ctx, req.Username) {
 		return
 	}
 
-	err = util.CheckPassword(req.Password, user.HashedPassword)
+	user, err := server.authenticator.CheckPassword(ctx, req.Username, req.Password, ctx.ClientIP())
 	if err != nil {
-		ctx.JSON(http.StatusUnauthorized, errorsResponse(err))
+		ctx.Error(authError(err))
 		return
 	}
 
-	accessToken, err := server.tokenMaker.CreateToken(
+	// users with two-factor authentication get an access token from loginTOTP,
+	// limited to the scopes requested here
+	if user.IsTotpEnabled {
+		challengeToken, payload, err := server.challengeTokenMaker.CreateScopedToken(
+			user.Username,
+			server.config.TOTPChallengeDuration,
+			req.Scopes...,
+		)
+		if err != nil {
+			ctx.Error(err)
+			return
+		}
+
+		rsp := LoginChallengeResponse{
+			TOTPRequired:       true,
+			ChallengeToken:     challengeToken,
+			ChallengeExpiresAt: payload.ExpiredAt,
+		}
+		ctx.JSON(http.StatusOK, rsp)
+		return
+	}
+
+	server.authenticator.ResetLoginFailures(ctx, user.Username)
+	server.issueAccessToken(ctx, user, req.Scopes...)
+}
+
+// issueAccessToken responds with a new access token for the user, limited
+// to the given scopes
+func (server *Server) issueAccessToken(ctx *gin.Context, user db.User, scopes ...string) {
+	accessToken, payload, err := server.tokenMaker.CreateScopedToken(
 		user.Username,
 		server.config.AccessTokenDuration,
+		scopes...,
 	)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, errorsResponse(err))
+		ctx.Error(err)
 		return
 	}
 
 	rsp := LoginUserResponse{
 		AccessToken: accessToken,
+		Scopes:      payload.Scopes,
 		User:        newUserResponse(user),
 	}
 	ctx.JSON(http.StatusOK, rsp)
 }
 
+// checkTokenUser loads the user a token was issued to for the handlers. It
+// rejects tokens of deleted users and tokens issued before the user's last
+// password change.
+func (server *Server) checkTokenUser(ctx *gin.Context, payload *token.Payload) error {
+	user, err := server.authenticator.TokenUser(ctx, payload)
+	if err != nil {
+		return authError(err)
+	}
+
+	ctx.Set(authorizationUserKey, user)
+	return nil
+}
+
+func (server *Server) getCurrentUser(ctx *gin.Context) {
+	user := ctx.MustGet(authorizationUserKey).(db.User)
+	ctx.JSON(http.StatusOK, newUserResponse(user))
+}
+
+type UpdateCurrentUserRequest struct {
+	FullName string `json:"full_name" binding:"required_without=Email"`
+	Email    string `json:"email" binding:"omitempty,email"`
+}
+
+func (server *Server) updateCurrentUser(ctx *gin.Context) {
+	var req UpdateCurrentUserRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.Error(apperr.FromBinding(err))
+		return
+	}
+
+	user := ctx.MustGet(authorizationUserKey).(db.User)
+
+	arg := db.UpdateUserParams{
+		Username: user.Username,
+		FullName: pgtype.Text{String: req.FullName, Valid: req.FullName != ""},
+	}
+
+	// a new email address has to be verified again
+	emailChanged := req.Email != "" && req.Email != user.Email
+	if emailChanged {
+		arg.Email = pgtype.Text{String: req.Email, Valid: true}
+		arg.IsEmailVerified = pgtype.Bool{Bool: false, Valid: true}
+	}
+
+	user, err := server.store.UpdateUser(ctx, arg)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	if emailChanged {
+		if err := server.sendVerifyEmail(ctx, user); err != nil {
+			slog.ErrorContext(ctx, "cannot send verification email", "username", user.Username, "error", err)
+		}
+	}
+
+	ctx.JSON(http.StatusOK, newUserResponse(user))
+}
+
+type UpdatePasswordRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required,password,nefield=CurrentPassword"`
+	ConfirmPassword string `json:"confirm_password" binding:"required,eqfield=NewPassword"`
+}
+
+// updatePassword changes the password of the authenticated user. Tokens issued
+// before the change stop working, so a fresh access token is returned.
+func (server *Server) updatePassword(ctx *gin.Context) {
+	var req UpdatePasswordRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.Error(apperr.FromBinding(err))
+		return
+	}
+
+	user := ctx.MustGet(authorizationUserKey).(db.User)
+
+	err := server.passwordHasher.Check(req.CurrentPassword, user.HashedPassword)
+	if err != nil {
+		ctx.Error(errIncorrectPassword.WithCause(err))
+		return
+	}
+
+	hashedPassword, err := server.passwordHasher.Hash(req.NewPassword)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	user, err = server.store.UpdateUser(ctx, db.UpdateUserParams{
+		Username:          user.Username,
+		HashedPassword:    pgtype.Text{String: hashedPassword, Valid: true},
+		PasswordChangedAt: pgtype.Timestamptz{Time: time.Now(), Valid: true},
+	})
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	// the new token replaces the revoked one, with the same scopes
+	authPayload := ctx.MustGet(middleware.AuthorizationPayloadKey).(*token.Payload)
+	server.issueAccessToken(ctx, user, authPayload.Scopes...)
+}
+
+var userOperations = []apiOperation{
+	{
+		method:   http.MethodPost,
+		path:     "/users",
+		tag:      "users",
+		summary:  "Sign up",
+		body:     CreateUserRequest{},
+		response: UserResponse{},
+	},
+	{
+		method:   http.MethodPost,
+		path:     "/users/login",
+		tag:      "users",
+		summary:  "Log in, or get a challenge token when two-factor authentication is enabled",
+		body:     LoginUserRequest{},
+		response: oneOf{LoginUserResponse{}, LoginChallengeResponse{}},
+	},
+}
+
 func (server *Server) setupUserRoutes(router gin.IRoutes) {
 	router.POST("/users", server.createUser)
-	router.POST("/users/login", server.loginUser)
-	// router.GET("/accounts/:id", server.getUser)
-	// router.GET("/accounts", server.listUsers)
+	router.POST("/users/login", server.rateLimits.login, server.loginUser)
+}
+
+var currentUserOperations = []apiOperation{
+	{
+		method:   http.MethodGet,
+		path:     "/users/me",
+		tag:      "users",
+		summary:  "Get the authenticated user",
+		auth:     authToken,
+		scopes:   []string{token.ScopeUser},
+		response: UserResponse{},
+	},
+	{
+		method:   http.MethodPatch,
+		path:     "/users/me",
+		tag:      "users",
+		summary:  "Update the name or email of the authenticated user",
+		auth:     authToken,
+		scopes:   []string{token.ScopeUser},
+		body:     UpdateCurrentUserRequest{},
+		response: UserResponse{},
+	},
+	{
+		method:   http.MethodPut,
+		path:     "/users/me/password",
+		tag:      "users",
+		summary:  "Change the password, revoking earlier tokens",
+		auth:     authToken,
+		scopes:   []string{token.ScopeUser},
+		body:     UpdatePasswordRequest{},
+		response: LoginUserResponse{},
+	},
+}
+
+func (server *Server) setupCurrentUserRoutes(router gin.IRoutes) {
+	router.GET("/users/me", server.getCurrentUser)
+	router.PATCH("/users/me", server.updateCurrentUser)
+	router.PUT("/users/me/password", server.updatePassword)
 }
@@ -1,6 +1,10 @@
 package api
 
 import (
+	"strconv"
+
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/oauth"
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/token"
 	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/util"
 	"github.com/go-playground/validator/v10"
 )
@@ -11,3 +15,46 @@ var validCurrency validator.Func = func(fl validator.FieldLevel) bool {
 	}
 	return false
 }
+
+var validAccountNumber validator.Func = func(fl validator.FieldLevel) bool {
+	if accountNumber, ok := fl.Field().Interface().(string); ok {
+		return util.IsValidAccountNumber(accountNumber)
+	}
+	return false
+}
+
+// validAccountRef accepts either a positive numeric account id or an account number
+var validAccountRef validator.Func = func(fl validator.FieldLevel) bool {
+	if ref, ok := fl.Field().Interface().(string); ok {
+		if util.IsValidAccountNumber(ref) {
+			return true
+		}
+		id, err := strconv.ParseInt(ref, 10, 64)
+		return err == nil && id > 0
+	}
+	return false
+}
+
+// validPassword accepts new passwords that satisfy the password policy
+func validPassword(policy *util.PasswordPolicy) validator.Func {
+	return func(fl validator.FieldLevel) bool {
+		if password, ok := fl.Field().Interface().(string); ok {
+			return policy.Check(password) == nil
+		}
+		return false
+	}
+}
+
+var validScope validator.Func = func(fl validator.FieldLevel) bool {
+	if scope, ok := fl.Field().Interface().(string); ok {
+		return token.IsSupportedScope(scope)
+	}
+	return false
+}
+
+var validRedirectURI validator.Func = func(fl validator.FieldLevel) bool {
+	if uri, ok := fl.Field().Interface().(string); ok {
+		return oauth.IsValidRedirectURI(uri)
+	}
+	return false
+}
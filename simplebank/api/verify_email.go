@@ -0,0 +1,139 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/apperr"
+	db "github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/db/sqlc"
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/mail"
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/token"
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/util"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+const verifyEmailSecretBytes = 32
+
+var (
+	errEmailNotVerified       = apperr.New(http.StatusForbidden, "email_not_verified", "email address has not been verified")
+	errEmailAlreadyVerified   = apperr.New(http.StatusBadRequest, "email_already_verified", "email address is already verified")
+	errInvalidVerifyEmailCode = apperr.New(http.StatusBadRequest, "invalid_verify_email_code", "verification code is invalid or has expired")
+)
+
+// sendVerifyEmail creates a single-use verification code for the user's
+// current email address and mails a link to it.
+func (server *Server) sendVerifyEmail(ctx context.Context, user db.User) error {
+	secretCode, err := util.GenerateSecret(verifyEmailSecretBytes)
+	if err != nil {
+		return err
+	}
+
+	verifyEmail, err := server.store.CreateVerifyEmail(ctx, db.CreateVerifyEmailParams{
+		Username:   user.Username,
+		Email:      user.Email,
+		SecretCode: secretCode,
+		ExpiredAt:  time.Now().Add(server.config.VerifyEmailDuration),
+	})
+	if err != nil {
+		return fmt.Errorf("cannot create verification code: %w", err)
+	}
+
+	link := fmt.Sprintf("%s?%s", server.config.VerifyEmailURL, url.Values{
+		"email_id":    {fmt.Sprint(verifyEmail.ID)},
+		"secret_code": {verifyEmail.SecretCode},
+	}.Encode())
+
+	return server.mailer.SendEmail(ctx, mail.Email{
+		To:      []string{user.Email},
+		Subject: "Verify your Simple Bank email address",
+		Body: fmt.Sprintf("Hello %s,\n\nPlease verify your email address by opening the link below:\n\n%s\n\nThe link expires at %s.\n",
+			user.FullName, link, verifyEmail.ExpiredAt.UTC().Format(time.RFC1123)),
+	})
+}
+
+type VerifyEmailRequest struct {
+	EmailID    int64  `form:"email_id" binding:"required,min=1"`
+	SecretCode string `form:"secret_code" binding:"required,hexadecimal"`
+}
+
+type VerifyEmailResponse struct {
+	IsVerified bool `json:"is_verified"`
+}
+
+func (server *Server) verifyEmail(ctx *gin.Context) {
+	var req VerifyEmailRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.Error(apperr.FromBinding(err))
+		return
+	}
+
+	result, err := server.store.VerifyEmailTx(ctx, db.VerifyEmailTxParams{
+		EmailID:    req.EmailID,
+		SecretCode: req.SecretCode,
+	})
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			ctx.Error(errInvalidVerifyEmailCode)
+			return
+		}
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, VerifyEmailResponse{IsVerified: result.User.IsEmailVerified})
+}
+
+type ResendVerifyEmailResponse struct {
+	Email string `json:"email"`
+}
+
+// resendVerifyEmail sends a new verification link, e.g. after the previous one expired
+func (server *Server) resendVerifyEmail(ctx *gin.Context) {
+	user := ctx.MustGet(authorizationUserKey).(db.User)
+	if user.IsEmailVerified {
+		ctx.Error(errEmailAlreadyVerified)
+		return
+	}
+
+	if err := server.sendVerifyEmail(ctx, user); err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, ResendVerifyEmailResponse{Email: user.Email})
+}
+
+var verifyEmailOperations = []apiOperation{
+	{
+		method:   http.MethodGet,
+		path:     "/verify_email",
+		tag:      "users",
+		summary:  "Verify an email address with the link from the verification email",
+		query:    VerifyEmailRequest{},
+		response: VerifyEmailResponse{},
+	},
+}
+
+func (server *Server) setupVerifyEmailRoutes(router gin.IRoutes) {
+	router.GET("/verify_email", server.verifyEmail)
+}
+
+var resendVerifyEmailOperations = []apiOperation{
+	{
+		method:   http.MethodPost,
+		path:     "/users/me/verify_email",
+		tag:      "users",
+		summary:  "Send a new verification email",
+		auth:     authToken,
+		scopes:   []string{token.ScopeUser},
+		response: ResendVerifyEmailResponse{},
+	},
+}
+
+func (server *Server) setupResendVerifyEmailRoutes(router gin.IRoutes) {
+	router.POST("/users/me/verify_email", server.resendVerifyEmail)
+}
@@ -0,0 +1,127 @@
+// Package apperr describes the errors the API returns to clients. Each error
+// has an http status and a stable machine-readable code, and only its message
+// is shown to clients; the error that caused it is kept for the logs.
+package apperr
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Codes of errors that are not specific to an endpoint. Endpoints define
+// their own codes for errors clients are expected to handle.
+const (
+	CodeInvalidRequest      = "invalid_request"
+	CodeValidationFailed    = "validation_failed"
+	CodeInvalidArgument     = "invalid_argument"
+	CodeUnauthenticated     = "unauthenticated"
+	CodePermissionDenied    = "permission_denied"
+	CodeNotFound            = "not_found"
+	CodeAlreadyExists       = "already_exists"
+	CodeConstraintViolation = "constraint_violation"
+	CodeConflict            = "conflict"
+	CodeInternal            = "internal"
+)
+
+// Postgres error codes mapped by From
+const (
+	pgUniqueViolation      = "23505"
+	pgForeignKeyViolation  = "23503"
+	pgCheckViolation       = "23514"
+	pgSerializationFailure = "40001"
+	pgDeadlockDetected     = "40P01"
+)
+
+type Error struct {
+	Status  int
+	Code    string
+	Message string
+	// Fields are set on validation errors, one for each invalid field
+	Fields []FieldError
+	// Err is the cause of the error. It is logged but never sent to clients.
+	Err error
+}
+
+func New(status int, code, message string) *Error {
+	return &Error{Status: status, Code: code, Message: message}
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether target is an *Error with the same status and code, so
+// errors.Is matches sentinel errors that have since been given a cause.
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Status == e.Status && t.Code == e.Code
+}
+
+// WithCause returns a copy of the error caused by err
+func (e *Error) WithCause(err error) *Error {
+	copied := *e
+	copied.Err = err
+	return &copied
+}
+
+func InvalidArgument(message string) *Error {
+	return New(http.StatusBadRequest, CodeInvalidArgument, message)
+}
+
+func Unauthenticated(message string) *Error {
+	return New(http.StatusUnauthorized, CodeUnauthenticated, message)
+}
+
+func PermissionDenied(message string) *Error {
+	return New(http.StatusForbidden, CodePermissionDenied, message)
+}
+
+func NotFound(message string) *Error {
+	return New(http.StatusNotFound, CodeNotFound, message)
+}
+
+// Internal hides err from clients behind a generic message
+func Internal(err error) *Error {
+	return New(http.StatusInternalServerError, CodeInternal, "internal server error").WithCause(err)
+}
+
+// From classifies err. Errors of this package are returned as they are,
+// validation and database errors are mapped to their client errors, and
+// everything else is an internal error.
+func From(err error) *Error {
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		return appErr
+	}
+
+	var validationErrs validator.ValidationErrors
+	if errors.As(err, &validationErrs) {
+		return validationError(validationErrs)
+	}
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return NotFound("resource not found").WithCause(err)
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch pgErr.Code {
+		case pgUniqueViolation:
+			return New(http.StatusConflict, CodeAlreadyExists, "resource already exists").WithCause(err)
+		case pgForeignKeyViolation, pgCheckViolation:
+			return New(http.StatusConflict, CodeConstraintViolation, "request conflicts with existing data").WithCause(err)
+		case pgSerializationFailure, pgDeadlockDetected:
+			return New(http.StatusConflict, CodeConflict, "request conflicted with a concurrent request, try again").WithCause(err)
+		}
+	}
+
+	return Internal(err)
+}
@@ -0,0 +1,116 @@
+package apperr
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFrom(t *testing.T) {
+	errTest := New(http.StatusTeapot, "teapot", "i'm a teapot")
+
+	testCases := []struct {
+		name    string
+		err     error
+		status  int
+		code    string
+		message string
+	}{
+		{
+			name:    "Error",
+			err:     errTest,
+			status:  http.StatusTeapot,
+			code:    "teapot",
+			message: "i'm a teapot",
+		},
+		{
+			name:    "WrappedError",
+			err:     fmt.Errorf("brewing: %w", errTest),
+			status:  http.StatusTeapot,
+			code:    "teapot",
+			message: "i'm a teapot",
+		},
+		{
+			name:    "NoRows",
+			err:     fmt.Errorf("get account: %w", pgx.ErrNoRows),
+			status:  http.StatusNotFound,
+			code:    CodeNotFound,
+			message: "resource not found",
+		},
+		{
+			name:    "UniqueViolation",
+			err:     &pgconn.PgError{Code: pgUniqueViolation, Message: `duplicate key value violates unique constraint "users_pkey"`},
+			status:  http.StatusConflict,
+			code:    CodeAlreadyExists,
+			message: "resource already exists",
+		},
+		{
+			name:    "ForeignKeyViolation",
+			err:     &pgconn.PgError{Code: pgForeignKeyViolation},
+			status:  http.StatusConflict,
+			code:    CodeConstraintViolation,
+			message: "request conflicts with existing data",
+		},
+		{
+			name:    "SerializationFailure",
+			err:     &pgconn.PgError{Code: pgSerializationFailure},
+			status:  http.StatusConflict,
+			code:    CodeConflict,
+			message: "request conflicted with a concurrent request, try again",
+		},
+		{
+			name:    "OtherPgError",
+			err:     &pgconn.PgError{Code: "42P01", Message: `relation "accounts" does not exist`},
+			status:  http.StatusInternalServerError,
+			code:    CodeInternal,
+			message: "internal server error",
+		},
+		{
+			name:    "OtherError",
+			err:     errors.New("connection refused"),
+			status:  http.StatusInternalServerError,
+			code:    CodeInternal,
+			message: "internal server error",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			appErr := From(tc.err)
+			require.Equal(t, tc.status, appErr.Status)
+			require.Equal(t, tc.code, appErr.Code)
+			require.Equal(t, tc.message, appErr.Error())
+		})
+	}
+}
+
+func TestWithCause(t *testing.T) {
+	errTest := Unauthenticated("token is invalid")
+	cause := errors.New("bad signature")
+
+	err := errTest.WithCause(cause)
+	require.ErrorIs(t, err, errTest)
+	require.ErrorIs(t, err, cause)
+	require.Nil(t, errTest.Err, "the sentinel error must not change")
+
+	require.NotErrorIs(t, err, PermissionDenied("token is invalid"))
+}
+
+func TestProblem(t *testing.T) {
+	err := InvalidArgument("statement period must not exceed 366 days").WithCause(errors.New("hidden"))
+
+	problem := err.Problem("/accounts/1/statements")
+	require.Equal(t, Problem{
+		Type:     "about:blank",
+		Title:    "Bad Request",
+		Status:   http.StatusBadRequest,
+		Detail:   "statement period must not exceed 366 days",
+		Instance: "/accounts/1/statements",
+		Code:     CodeInvalidArgument,
+	}, problem)
+}
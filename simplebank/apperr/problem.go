@@ -0,0 +1,35 @@
+package apperr
+
+import "net/http"
+
+// ProblemContentType is the media type of problem details, RFC 7807
+const ProblemContentType = "application/problem+json"
+
+// Problem is the RFC 7807 problem details body of error responses. Clients
+// should tell errors apart by Code; Detail is meant for people.
+type Problem struct {
+	// Type is about:blank, as the problem is identified by Code
+	Type     string       `json:"type"`
+	Title    string       `json:"title"`
+	Status   int          `json:"status"`
+	Detail   string       `json:"detail"`
+	Instance string       `json:"instance,omitempty"`
+	Code     string       `json:"code"`
+	Errors   []FieldError `json:"errors,omitempty"`
+	// TraceID identifies the trace of the request, to quote to support
+	TraceID string `json:"trace_id,omitempty"`
+}
+
+// Problem returns the problem details of the error, which occurred at the
+// request path instance
+func (e *Error) Problem(instance string) Problem {
+	return Problem{
+		Type:     "about:blank",
+		Title:    http.StatusText(e.Status),
+		Status:   e.Status,
+		Detail:   e.Message,
+		Instance: instance,
+		Code:     e.Code,
+		Errors:   e.Fields,
+	}
+}
@@ -0,0 +1,190 @@
+package apperr
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// FieldError describes why one field of a request is invalid
+type FieldError struct {
+	// Field is the path of the field as the client sent it, e.g. scopes[0]
+	Field string `json:"field"`
+	// Code is the binding rule the field failed, e.g. required
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// fieldMessages describe the binding rules; %s is the parameter of the rule.
+// Rules comparing fields name the other field in their parameter.
+var fieldMessages = map[string]string{
+	"required":         "is required",
+	"required_without": "is required unless %s is set",
+	"excluded_with":    "must not be set together with %s",
+	"eqfield":          "must match %s",
+	"nefield":          "must differ from %s",
+	"gtefield":         "must not be before %s",
+	"ne":               "must not be %s",
+	"oneof":            "must be one of %s",
+	"unique":           "must not contain duplicates",
+	"email":            "must be a valid email address",
+	"alphanum":         "must contain only letters and digits",
+	"numeric":          "must contain only digits",
+	"hexadecimal":      "must be hexadecimal",
+	"uuid":             "must be a uuid",
+	"ip":               "must be an ip address",
+	"currency":         "must be a supported currency",
+	"account_number":   "must be a valid account number",
+	"account_ref":      "must be an account id or account number",
+	"password":         "does not satisfy the password policy",
+	"scope":            "must be a supported scope",
+	"redirect_uri":     "must be an absolute https uri without a fragment, or http on the loopback interface",
+}
+
+// sizeMessages describe the size rules for strings, collections and
+// numbers, in that order
+var sizeMessages = map[string][3]string{
+	"min": {"must be at least %s characters", "must have at least %s items", "must be at least %s"},
+	"max": {"must be at most %s characters", "must have at most %s items", "must be at most %s"},
+	"len": {"must be exactly %s characters", "must have exactly %s items", "must be %s"},
+}
+
+// FieldName names struct fields after their json, form or uri key. It is
+// registered as the tag name function of the validator, so validation
+// errors refer to fields the way clients know them.
+func FieldName(field reflect.StructField) string {
+	for _, tag := range []string{"json", "form", "uri"} {
+		name, _, _ := strings.Cut(field.Tag.Get(tag), ",")
+		if name != "" {
+			return name
+		}
+	}
+	return ""
+}
+
+// FromBinding classifies an error binding a request. Failed binding rules
+// become field errors; requests that cannot be decoded at all are invalid.
+func FromBinding(err error) *Error {
+	var validationErrs validator.ValidationErrors
+	if errors.As(err, &validationErrs) {
+		return validationError(validationErrs)
+	}
+
+	message := "request could not be decoded"
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	var numErr *strconv.NumError
+	var timeErr *time.ParseError
+	switch {
+	case errors.Is(err, io.EOF):
+		message = "request body is empty"
+	case errors.As(err, &syntaxErr):
+		message = fmt.Sprintf("request body is not valid json at offset %d", syntaxErr.Offset)
+	case errors.As(err, &typeErr) && typeErr.Field != "":
+		message = fmt.Sprintf("%s must be of type %s", typeErr.Field, typeErr.Type)
+	case errors.As(err, &numErr):
+		message = fmt.Sprintf("%q is not a valid number", numErr.Num)
+	case errors.As(err, &timeErr):
+		message = fmt.Sprintf("%q is not a valid time, expected the layout %s", timeErr.Value, timeErr.Layout)
+	}
+	return New(http.StatusBadRequest, CodeInvalidRequest, message).WithCause(err)
+}
+
+func validationError(errs validator.ValidationErrors) *Error {
+	fields := make([]FieldError, len(errs))
+	messages := make([]string, len(errs))
+	for i, fe := range errs {
+		field := fieldPath(fe.Namespace(), fe.StructNamespace())
+		fields[i] = FieldError{
+			Field:   field,
+			Code:    fe.Tag(),
+			Message: field + " " + fieldMessage(fe),
+		}
+		messages[i] = fields[i].Message
+	}
+
+	return &Error{
+		Status:  http.StatusBadRequest,
+		Code:    CodeValidationFailed,
+		Message: strings.Join(messages, "; "),
+		Fields:  fields,
+		Err:     errs,
+	}
+}
+
+func fieldMessage(fe validator.FieldError) string {
+	param := fe.Param()
+	switch fe.Tag() {
+	case "required_without", "excluded_with", "eqfield", "nefield", "gtefield":
+		param = snakeCase(param)
+	case "oneof":
+		param = strings.ReplaceAll(param, " ", ", ")
+	}
+
+	if messages, ok := sizeMessages[fe.Tag()]; ok {
+		switch fe.Kind() {
+		case reflect.String:
+			return fmt.Sprintf(messages[0], param)
+		case reflect.Slice, reflect.Array, reflect.Map:
+			return fmt.Sprintf(messages[1], param)
+		default:
+			return fmt.Sprintf(messages[2], param)
+		}
+	}
+
+	message, ok := fieldMessages[fe.Tag()]
+	if !ok {
+		return "is invalid"
+	}
+	if strings.Contains(message, "%s") {
+		return fmt.Sprintf(message, param)
+	}
+	return message
+}
+
+// fieldPath returns the path of a field from its namespaces with and without
+// FieldName. Segments named the same in both have no key in requests: they
+// are the request type, and embedded structs whose fields are part of the
+// enclosing object.
+func fieldPath(namespace, structNamespace string) string {
+	segments := strings.Split(namespace, ".")
+	structSegments := strings.Split(structNamespace, ".")
+
+	var path []string
+	for i, segment := range segments {
+		if i < len(structSegments) && segment == structSegments[i] {
+			continue
+		}
+		path = append(path, segment)
+	}
+	return strings.Join(path, ".")
+}
+
+// snakeCase turns the Go name of a field into its key in requests, which
+// follow the same naming, e.g. FromAccountNumber into from_account_number
+// and TOTPCode into totp_code.
+func snakeCase(name string) string {
+	runes := []rune(name)
+
+	var b strings.Builder
+	for i, r := range runes {
+		if i > 0 && unicode.IsUpper(r) {
+			prevLower := unicode.IsLower(runes[i-1]) || unicode.IsDigit(runes[i-1])
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if prevLower || (unicode.IsUpper(runes[i-1]) && nextLower) {
+				b.WriteByte('_')
+			}
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
@@ -0,0 +1,112 @@
+package apperr
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/stretchr/testify/require"
+)
+
+type testSecondFactor struct {
+	Code string `json:"code" binding:"required_without=RecoveryCode,omitempty,numeric,len=6"`
+	// RecoveryCode only fails required_without along with Code
+	RecoveryCode string `json:"recovery_code"`
+}
+
+type testRequest struct {
+	Name            string   `json:"name" binding:"required,max=8"`
+	Scopes          []string `json:"scopes" binding:"required,min=1,dive,oneof=read write"`
+	Page            int32    `form:"page" binding:"min=1"`
+	NewPassword     string   `json:"new_password"`
+	ConfirmPassword string   `json:"confirm_password" binding:"eqfield=NewPassword"`
+	testSecondFactor
+}
+
+func newTestValidator() *validator.Validate {
+	v := validator.New()
+	v.SetTagName("binding")
+	v.RegisterTagNameFunc(FieldName)
+	return v
+}
+
+func TestFromBindingValidation(t *testing.T) {
+	err := newTestValidator().Struct(testRequest{
+		Name:            "a very long name",
+		Scopes:          []string{"read", "delete"},
+		NewPassword:     "secret",
+		ConfirmPassword: "other",
+	})
+	require.Error(t, err)
+
+	appErr := FromBinding(err)
+	require.Equal(t, http.StatusBadRequest, appErr.Status)
+	require.Equal(t, CodeValidationFailed, appErr.Code)
+	require.Equal(t, []FieldError{
+		{Field: "name", Code: "max", Message: "name must be at most 8 characters"},
+		{Field: "scopes[1]", Code: "oneof", Message: "scopes[1] must be one of read, write"},
+		{Field: "page", Code: "min", Message: "page must be at least 1"},
+		{Field: "confirm_password", Code: "eqfield", Message: "confirm_password must match new_password"},
+		{Field: "code", Code: "required_without", Message: "code is required unless recovery_code is set"},
+	}, appErr.Fields)
+	require.True(t, strings.HasPrefix(appErr.Message, "name must be at most 8 characters; scopes[1]"))
+
+	// From classifies validation errors the same way
+	require.Equal(t, appErr.Fields, From(err).Fields)
+}
+
+func TestFromBindingDecoding(t *testing.T) {
+	var req testRequest
+	syntaxErr := json.Unmarshal([]byte(`{"name": }`), &req)
+	typeErr := json.Unmarshal([]byte(`{"name": 1}`), &req)
+	_, numErr := strconv.ParseInt("ten", 10, 32)
+
+	testCases := []struct {
+		name    string
+		err     error
+		message string
+	}{
+		{
+			name:    "Syntax",
+			err:     syntaxErr,
+			message: "request body is not valid json at offset 10",
+		},
+		{
+			name:    "Type",
+			err:     typeErr,
+			message: "name must be of type string",
+		},
+		{
+			name:    "Number",
+			err:     numErr,
+			message: `"ten" is not a valid number`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			appErr := FromBinding(tc.err)
+			require.Equal(t, http.StatusBadRequest, appErr.Status)
+			require.Equal(t, CodeInvalidRequest, appErr.Code)
+			require.Equal(t, tc.message, appErr.Message)
+			require.ErrorIs(t, appErr, tc.err)
+		})
+	}
+}
+
+func TestSnakeCase(t *testing.T) {
+	testCases := map[string]string{
+		"Password":          "password",
+		"FromAccountNumber": "from_account_number",
+		"TOTPCode":          "totp_code",
+		"IP":                "ip",
+		"Argon2Memory":      "argon2_memory",
+	}
+
+	for name, want := range testCases {
+		require.Equal(t, want, snakeCase(name), name)
+	}
+}
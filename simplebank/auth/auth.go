@@ -0,0 +1,47 @@
+// Package auth implements the authentication rules the HTTP API and the gRPC
+// server share: login throttling, password and second factor checks, token
+// and api key checks and the step-up of large transfers. Its errors say what
+// went wrong; each server turns them into its own responses.
+package auth
+
+import (
+	"errors"
+	"sync"
+
+	db "github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/db/sqlc"
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/util"
+)
+
+var (
+	ErrInvalidCredentials  = errors.New("incorrect username or password")
+	ErrUserNotFound        = errors.New("user no longer exists")
+	ErrTokenRevoked        = errors.New("token was issued before the password was last changed")
+	ErrOAuthConsentRevoked = errors.New("the user has revoked the client's access")
+	ErrAPIKeyRevoked       = errors.New("api key has been revoked")
+	ErrAPIKeyExpired       = errors.New("api key has expired")
+	ErrInvalidTOTPCode     = errors.New("two-factor authentication code is invalid or was already used")
+	ErrTOTPRequired        = errors.New("a two-factor authentication code is required for this transfer")
+	ErrStepUpNotEnabled    = errors.New("two-factor authentication must be enabled for transfers of this amount")
+	ErrAccountNotFound     = errors.New("account not found")
+	ErrAccountNotOwned     = errors.New("account doesn't belong to the authenticated user")
+	ErrCurrencyMismatch    = errors.New("currency mismatch")
+)
+
+// Authenticator checks credentials against the store. Both servers create
+// one from the same config, so a login over either counts the same way.
+type Authenticator struct {
+	config            util.Config
+	store             db.Store
+	passwordHasher    util.PasswordHasher
+	dummyPasswordHash func() string
+}
+
+func NewAuthenticator(config util.Config, store db.Store, passwordHasher util.PasswordHasher) *Authenticator {
+	authenticator := &Authenticator{
+		config:         config,
+		store:          store,
+		passwordHasher: passwordHasher,
+	}
+	authenticator.dummyPasswordHash = sync.OnceValue(authenticator.newDummyPasswordHash)
+	return authenticator
+}
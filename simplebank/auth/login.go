@@ -0,0 +1,168 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	db "github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/db/sqlc"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// LoginLockedError is returned while logins of a username or from an ip are
+// locked out after too many failures
+type LoginLockedError struct {
+	RetryAfter time.Duration
+}
+
+func (err *LoginLockedError) Error() string {
+	return fmt.Sprintf("too many failed login attempts, try again in %s", err.RetryAfter.Round(time.Second))
+}
+
+// newDummyPasswordHash returns the hash checked against when a username
+// doesn't exist, so unknown users take as long to turn away as wrong passwords.
+func (authenticator *Authenticator) newDummyPasswordHash() string {
+	hash, err := authenticator.passwordHasher.Hash("not the password of any user")
+	if err != nil {
+		panic(err)
+	}
+	return hash
+}
+
+// loginBackoff returns how long to lock logins after the given number of
+// consecutive failures: nothing during the free attempts, then base doubling
+// with every further failure, capped at max.
+func loginBackoff(failures, freeAttempts int32, base, max time.Duration) time.Duration {
+	if failures <= freeAttempts || base <= 0 {
+		return 0
+	}
+
+	delay := base
+	for i := freeAttempts + 1; i < failures && delay < max; i++ {
+		delay *= 2
+	}
+	return min(delay, max)
+}
+
+// CheckLoginLock returns a *LoginLockedError while either the username or
+// the client ip is locked out. Unknown usernames are locked the same way, so
+// a lock says nothing about whether a user exists.
+func (authenticator *Authenticator) CheckLoginLock(ctx context.Context, username, ip string) error {
+	lockedUntil, err := authenticator.store.GetLoginLockedUntil(ctx, db.GetLoginLockedUntilParams{
+		Username: username,
+		Ip:       ip,
+	})
+	if err != nil {
+		return err
+	}
+
+	if wait := time.Until(lockedUntil.Time); lockedUntil.Valid && wait > 0 {
+		return &LoginLockedError{RetryAfter: wait}
+	}
+	return nil
+}
+
+// CheckPassword returns the user when the password is theirs. Otherwise it
+// records the failure and returns ErrInvalidCredentials, after the same work
+// whether or not the user exists. A hash made with outdated parameters is
+// upgraded while the plain password is at hand.
+func (authenticator *Authenticator) CheckPassword(ctx context.Context, username, password, ip string) (db.User, error) {
+	user, err := authenticator.store.GetUser(ctx, username)
+	if err != nil && err != pgx.ErrNoRows {
+		return user, err
+	}
+
+	userFound := err == nil
+	hashedPassword := user.HashedPassword
+	if !userFound {
+		hashedPassword = authenticator.dummyPasswordHash()
+	}
+
+	err = authenticator.passwordHasher.Check(password, hashedPassword)
+	if err != nil || !userFound {
+		authenticator.RecordLoginFailure(ctx, username, ip)
+		return db.User{}, ErrInvalidCredentials
+	}
+
+	if authenticator.passwordHasher.NeedsRehash(user.HashedPassword) {
+		user = authenticator.rehashPassword(ctx, user, password)
+	}
+	return user, nil
+}
+
+// rehashPassword stores a new hash of the password. The password itself is
+// unchanged, so existing tokens stay valid and failures don't fail the login.
+func (authenticator *Authenticator) rehashPassword(ctx context.Context, user db.User, password string) db.User {
+	hashedPassword, err := authenticator.passwordHasher.Hash(password)
+	if err != nil {
+		slog.ErrorContext(ctx, "cannot rehash password", "username", user.Username, "error", err)
+		return user
+	}
+
+	updated, err := authenticator.store.UpdateUser(ctx, db.UpdateUserParams{
+		Username:       user.Username,
+		HashedPassword: pgtype.Text{String: hashedPassword, Valid: true},
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "cannot rehash password", "username", user.Username, "error", err)
+		return user
+	}
+	return updated
+}
+
+// RecordLoginFailure counts a failed login against both the username and the
+// client ip, and locks whichever has used up its free attempts.
+func (authenticator *Authenticator) RecordLoginFailure(ctx context.Context, username, ip string) {
+	config := authenticator.config
+	resetBefore := time.Now().Add(-config.LoginFailureWindow)
+
+	subjects := []struct {
+		kind         string
+		subject      string
+		freeAttempts int32
+	}{
+		{db.LoginThrottleKindUsername, username, config.LoginUserFreeAttempts},
+		{db.LoginThrottleKindIP, ip, config.LoginIPFreeAttempts},
+	}
+
+	for _, s := range subjects {
+		throttle, err := authenticator.store.RecordLoginFailure(ctx, db.RecordLoginFailureParams{
+			Kind:        s.kind,
+			Subject:     s.subject,
+			ResetBefore: resetBefore,
+		})
+		if err != nil {
+			slog.ErrorContext(ctx, "cannot record failed login", "kind", s.kind, "subject", s.subject, "error", err)
+			continue
+		}
+
+		delay := loginBackoff(throttle.FailedAttempts, s.freeAttempts, config.LoginBackoffBase, config.LoginLockoutDuration)
+		if delay == 0 {
+			continue
+		}
+
+		_, err = authenticator.store.LockLogin(ctx, db.LockLoginParams{
+			Kind:        s.kind,
+			Subject:     s.subject,
+			LockedUntil: pgtype.Timestamptz{Time: time.Now().Add(delay), Valid: true},
+		})
+		if err != nil {
+			slog.ErrorContext(ctx, "cannot lock login", "kind", s.kind, "subject", s.subject, "error", err)
+		}
+	}
+}
+
+// ResetLoginFailures forgets the failures of a username once its user logs
+// in. Failures from the client ip are kept, so an attacker can't clear them
+// by logging into an account of their own.
+func (authenticator *Authenticator) ResetLoginFailures(ctx context.Context, username string) {
+	_, err := authenticator.store.DeleteLoginThrottle(ctx, db.DeleteLoginThrottleParams{
+		Kind:    db.LoginThrottleKindUsername,
+		Subject: username,
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "cannot reset failed logins", "username", username, "error", err)
+	}
+}
@@ -0,0 +1,195 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	mockdb "github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/db/mock"
+	db "github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/db/sqlc"
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/util"
+	"github.com/golang/mock/gomock"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/require"
+)
+
+const testIP = "192.0.2.1"
+
+func TestLoginBackoff(t *testing.T) {
+	testCases := []struct {
+		failures int32
+		delay    time.Duration
+	}{
+		{0, 0},
+		{5, 0},
+		{6, time.Second},
+		{7, 2 * time.Second},
+		{10, 16 * time.Second},
+		{15, 512 * time.Second},
+		{16, 15 * time.Minute},
+		{1000, 15 * time.Minute},
+	}
+
+	for _, tc := range testCases {
+		require.Equal(t, tc.delay, loginBackoff(tc.failures, 5, time.Second, 15*time.Minute), "failures: %d", tc.failures)
+	}
+
+	// backoff is off without a base delay
+	require.Zero(t, loginBackoff(100, 5, 0, 15*time.Minute))
+}
+
+func TestCheckLoginLock(t *testing.T) {
+	testCases := []struct {
+		name        string
+		lockedUntil pgtype.Timestamptz
+		err         error
+		check       func(t *testing.T, err error)
+	}{
+		{
+			name: "NotLocked",
+			check: func(t *testing.T, err error) {
+				require.NoError(t, err)
+			},
+		},
+		{
+			name:        "LockExpired",
+			lockedUntil: pgtype.Timestamptz{Time: time.Now().Add(-time.Second), Valid: true},
+			check: func(t *testing.T, err error) {
+				require.NoError(t, err)
+			},
+		},
+		{
+			name:        "Locked",
+			lockedUntil: pgtype.Timestamptz{Time: time.Now().Add(time.Minute), Valid: true},
+			check: func(t *testing.T, err error) {
+				var locked *LoginLockedError
+				require.ErrorAs(t, err, &locked)
+				require.InDelta(t, time.Minute, locked.RetryAfter, float64(time.Second))
+			},
+		},
+		{
+			name: "InternalError",
+			err:  errors.New("connection refused"),
+			check: func(t *testing.T, err error) {
+				require.Error(t, err)
+				require.NotErrorAs(t, err, new(*LoginLockedError))
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			store.EXPECT().
+				GetLoginLockedUntil(gomock.Any(), gomock.Eq(db.GetLoginLockedUntilParams{Username: "alice", Ip: testIP})).
+				Times(1).
+				Return(tc.lockedUntil, tc.err)
+
+			authenticator := NewAuthenticator(util.Config{}, store, util.DefaultPasswordHasher)
+			tc.check(t, authenticator.CheckLoginLock(context.Background(), "alice", testIP))
+		})
+	}
+}
+
+func TestCheckPassword(t *testing.T) {
+	password := util.RandomString(12)
+	hashedPassword, err := util.HashPassword(password)
+	require.NoError(t, err)
+	user := db.User{Username: util.RandomOwner(), HashedPassword: hashedPassword}
+
+	expectFailure := func(store *mockdb.MockStore) {
+		for _, kind := range []string{db.LoginThrottleKindUsername, db.LoginThrottleKindIP} {
+			store.EXPECT().
+				RecordLoginFailure(gomock.Any(), recordLoginFailureMatcher{kind: kind}).
+				Times(1).
+				Return(db.LoginThrottle{FailedAttempts: 1}, nil)
+		}
+	}
+
+	testCases := []struct {
+		name       string
+		password   string
+		buildStubs func(store *mockdb.MockStore)
+		check      func(t *testing.T, user db.User, err error)
+	}{
+		{
+			name:     "OK",
+			password: password,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				store.EXPECT().RecordLoginFailure(gomock.Any(), gomock.Any()).Times(0)
+			},
+			check: func(t *testing.T, got db.User, err error) {
+				require.NoError(t, err)
+				require.Equal(t, user, got)
+			},
+		},
+		{
+			name:     "WrongPassword",
+			password: "wrong password",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				expectFailure(store)
+			},
+			check: func(t *testing.T, _ db.User, err error) {
+				require.ErrorIs(t, err, ErrInvalidCredentials)
+			},
+		},
+		{
+			name:     "UserNotFound",
+			password: password,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(db.User{}, pgx.ErrNoRows)
+				expectFailure(store)
+			},
+			check: func(t *testing.T, _ db.User, err error) {
+				require.ErrorIs(t, err, ErrInvalidCredentials)
+			},
+		},
+		{
+			name:     "InternalError",
+			password: password,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(1).Return(db.User{}, errors.New("connection refused"))
+				store.EXPECT().RecordLoginFailure(gomock.Any(), gomock.Any()).Times(0)
+			},
+			check: func(t *testing.T, _ db.User, err error) {
+				require.Error(t, err)
+				require.NotErrorIs(t, err, ErrInvalidCredentials)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			authenticator := NewAuthenticator(util.Config{}, store, util.DefaultPasswordHasher)
+			got, err := authenticator.CheckPassword(context.Background(), user.Username, tc.password, testIP)
+			tc.check(t, got, err)
+		})
+	}
+}
+
+// recordLoginFailureMatcher matches the failures recorded for a kind of subject
+type recordLoginFailureMatcher struct {
+	kind string
+}
+
+func (m recordLoginFailureMatcher) Matches(x interface{}) bool {
+	arg, ok := x.(db.RecordLoginFailureParams)
+	return ok && arg.Kind == m.kind
+}
+
+func (m recordLoginFailureMatcher) String() string {
+	return "records a login failure of kind " + m.kind
+}
@@ -0,0 +1,105 @@
+package auth
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"time"
+
+	db "github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/db/sqlc"
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/token"
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/util"
+	"github.com/jackc/pgx/v5"
+)
+
+// TokenUser loads the user a token was issued to. It rejects tokens of
+// deleted users and tokens issued before the user's last password change.
+func (authenticator *Authenticator) TokenUser(ctx context.Context, payload *token.Payload) (db.User, error) {
+	user, err := authenticator.store.GetUser(ctx, payload.Username)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return user, ErrUserNotFound
+		}
+		return user, err
+	}
+
+	if payload.IssuedAt.Before(user.PasswordChangedAt) {
+		return user, ErrTokenRevoked
+	}
+	return user, nil
+}
+
+// CheckTokenClient rejects tokens of oauth clients once the user has revoked
+// their consent, or when it does not cover the scopes of the token. Consents
+// only ever gain scopes, so a token stays valid until the consent is revoked.
+func (authenticator *Authenticator) CheckTokenClient(ctx context.Context, payload *token.Payload) error {
+	if payload.ClientID == "" {
+		return nil
+	}
+
+	consent, err := authenticator.store.GetOAuthConsent(ctx, db.GetOAuthConsentParams{
+		Username: payload.Username,
+		ClientID: payload.ClientID,
+	})
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return ErrOAuthConsentRevoked
+		}
+		return err
+	}
+
+	// a payload without scopes would grant full access
+	if len(payload.Scopes) == 0 || payload.IssuedAt.Before(consent.GrantedAt) {
+		return ErrOAuthConsentRevoked
+	}
+	for _, scope := range payload.Scopes {
+		if !slices.Contains(consent.Scopes, scope) {
+			return ErrOAuthConsentRevoked
+		}
+	}
+	return nil
+}
+
+// VerifyAPIKey returns the payload of an api key. The payload is issued when
+// the key was created, so like access tokens, keys created before the
+// owner's last password change stop working.
+func (authenticator *Authenticator) VerifyAPIKey(ctx context.Context, key string) (*token.Payload, error) {
+	prefix, hashedSecret, err := util.ParseAPIKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	apiKey, err := authenticator.store.GetAPIKeyByPrefix(ctx, prefix)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, util.ErrInvalidAPIKey
+		}
+		return nil, err
+	}
+
+	if err := util.CheckAPIKeySecret(hashedSecret, apiKey.HashedSecret); err != nil {
+		return nil, err
+	}
+	// a payload without scopes would grant full access
+	if len(apiKey.Scopes) == 0 {
+		return nil, util.ErrInvalidAPIKey
+	}
+	if apiKey.RevokedAt.Valid {
+		return nil, ErrAPIKeyRevoked
+	}
+	if time.Now().After(apiKey.ExpiredAt) {
+		return nil, ErrAPIKeyExpired
+	}
+
+	if err := authenticator.store.TouchAPIKey(ctx, apiKey.ID); err != nil {
+		slog.ErrorContext(ctx, "cannot record use of api key", "api_key_id", apiKey.ID, "error", err)
+	}
+
+	return &token.Payload{
+		ID:        apiKey.ID,
+		Username:  apiKey.Username,
+		IssuedAt:  apiKey.CreatedAt,
+		ExpiredAt: apiKey.ExpiredAt,
+		Scopes:    apiKey.Scopes,
+	}, nil
+}
@@ -0,0 +1,96 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	db "github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/db/sqlc"
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/totp"
+	"github.com/jackc/pgx/v5"
+)
+
+// TransferAccount looks up an account taking part in a transfer, by number
+// when one is given and by id otherwise. When owner is set, as it is for the
+// account money is taken from, the account must be theirs. It returns
+// ErrAccountNotFound, ErrAccountNotOwned, ErrCurrencyMismatch or
+// db.ErrAccountFrozen when the transfer can't use it. Ownership is checked
+// first, so nothing else is told about the accounts of other users.
+func (authenticator *Authenticator) TransferAccount(ctx context.Context, owner string, accountID int64, accountNumber string, currency string) (db.Account, error) {
+	var account db.Account
+	var err error
+	if accountNumber != "" {
+		account, err = authenticator.store.GetAccountByNumber(ctx, accountNumber)
+	} else {
+		account, err = authenticator.store.GetAccount(ctx, accountID)
+	}
+
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return account, ErrAccountNotFound
+		}
+		return account, err
+	}
+
+	if owner != "" && account.Owner != owner {
+		return account, ErrAccountNotOwned
+	}
+
+	if account.Currency != currency {
+		return account, fmt.Errorf("account [%d] %w: %v vs %v", account.ID, ErrCurrencyMismatch, currency, account.Currency)
+	}
+
+	if account.FrozenAt.Valid {
+		return account, db.ErrAccountFrozen
+	}
+
+	return account, nil
+}
+
+// CheckSecondFactor accepts a TOTP code, whose time step can only be used
+// once, or else an unused recovery code, which is then used up. It returns
+// ErrInvalidTOTPCode when neither is valid.
+func (authenticator *Authenticator) CheckSecondFactor(ctx context.Context, user db.User, code, recoveryCode string) error {
+	var err error
+	if code != "" {
+		var step int64
+		step, err = totp.Validate(user.TotpSecret, code, time.Now())
+		if err != nil {
+			return ErrInvalidTOTPCode
+		}
+
+		_, err = authenticator.store.UseTOTPStep(ctx, db.UseTOTPStepParams{
+			Username:     user.Username,
+			TotpLastStep: step,
+		})
+	} else {
+		_, err = authenticator.store.UseRecoveryCode(ctx, db.UseRecoveryCodeParams{
+			Username:   user.Username,
+			HashedCode: totp.HashRecoveryCode(recoveryCode),
+		})
+	}
+
+	if err == pgx.ErrNoRows {
+		return ErrInvalidTOTPCode
+	}
+	return err
+}
+
+// CheckStepUp asks for a TOTP code on transfers of at least the configured
+// step-up amount. Recovery codes are for getting back into an account, not
+// for step-up, so only authenticator codes are accepted.
+func (authenticator *Authenticator) CheckStepUp(ctx context.Context, user db.User, amount int64, code string) error {
+	stepUpAmount := authenticator.config.TOTPStepUpAmount
+	if stepUpAmount <= 0 || amount < stepUpAmount {
+		return nil
+	}
+
+	if !user.IsTotpEnabled {
+		return ErrStepUpNotEnabled
+	}
+	if code == "" {
+		return ErrTOTPRequired
+	}
+
+	return authenticator.CheckSecondFactor(ctx, user, code, "")
+}
@@ -0,0 +1,196 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	mockdb "github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/db/mock"
+	db "github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/db/sqlc"
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/totp"
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/util"
+	"github.com/golang/mock/gomock"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransferAccount(t *testing.T) {
+	account := db.Account{ID: 1, Owner: "owner", AccountNumber: "ACCOUNT", Currency: util.USD}
+
+	testCases := []struct {
+		name       string
+		owner      string
+		number     string
+		currency   string
+		buildStubs func(store *mockdb.MockStore)
+		err        error
+	}{
+		{
+			name:     "ByID",
+			currency: util.USD,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+			},
+		},
+		{
+			name:     "ByNumber",
+			number:   account.AccountNumber,
+			currency: util.USD,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Eq(account.AccountNumber)).Times(1).Return(account, nil)
+			},
+		},
+		{
+			name:     "NotFound",
+			currency: util.USD,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(1).Return(db.Account{}, pgx.ErrNoRows)
+			},
+			err: ErrAccountNotFound,
+		},
+		{
+			name:     "Owned",
+			owner:    account.Owner,
+			currency: util.USD,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+			},
+		},
+		{
+			// other users are not told the currency of the account
+			name:     "NotOwned",
+			owner:    "someone else",
+			currency: util.EUR,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(1).Return(account, nil)
+			},
+			err: ErrAccountNotOwned,
+		},
+		{
+			name:     "CurrencyMismatch",
+			currency: util.EUR,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(1).Return(account, nil)
+			},
+			err: ErrCurrencyMismatch,
+		},
+		{
+			name:     "Frozen",
+			currency: util.USD,
+			buildStubs: func(store *mockdb.MockStore) {
+				frozen := account
+				frozen.FrozenAt = pgtype.Timestamptz{Time: time.Now(), Valid: true}
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(1).Return(frozen, nil)
+			},
+			err: db.ErrAccountFrozen,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			authenticator := NewAuthenticator(util.Config{}, store, util.DefaultPasswordHasher)
+			got, err := authenticator.TransferAccount(context.Background(), tc.owner, account.ID, tc.number, tc.currency)
+			if tc.err != nil {
+				require.ErrorIs(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, account, got)
+		})
+	}
+}
+
+func TestCheckStepUp(t *testing.T) {
+	secret, err := totp.GenerateSecret()
+	require.NoError(t, err)
+	user := db.User{Username: util.RandomOwner(), IsTotpEnabled: true, TotpSecret: secret}
+
+	code, err := totp.Code(secret, time.Now())
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name       string
+		user       db.User
+		amount     int64
+		code       string
+		buildStubs func(store *mockdb.MockStore)
+		err        error
+	}{
+		{
+			name:   "BelowStepUpAmount",
+			user:   db.User{Username: user.Username},
+			amount: 99,
+		},
+		{
+			name:   "OK",
+			user:   user,
+			amount: 100,
+			code:   code,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().UseTOTPStep(gomock.Any(), gomock.Any()).Times(1).Return(user, nil)
+			},
+		},
+		{
+			name:   "NotEnabled",
+			user:   db.User{Username: user.Username},
+			amount: 100,
+			code:   code,
+			err:    ErrStepUpNotEnabled,
+		},
+		{
+			name:   "MissingCode",
+			user:   user,
+			amount: 100,
+			err:    ErrTOTPRequired,
+		},
+		{
+			name:   "ReplayedCode",
+			user:   user,
+			amount: 100,
+			code:   code,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().UseTOTPStep(gomock.Any(), gomock.Any()).Times(1).Return(db.User{}, pgx.ErrNoRows)
+			},
+			err: ErrInvalidTOTPCode,
+		},
+		{
+			name:   "InternalError",
+			user:   user,
+			amount: 100,
+			code:   code,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().UseTOTPStep(gomock.Any(), gomock.Any()).Times(1).Return(db.User{}, errors.New("connection refused"))
+			},
+			err: errors.New("connection refused"),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			if tc.buildStubs != nil {
+				tc.buildStubs(store)
+			}
+
+			authenticator := NewAuthenticator(util.Config{TOTPStepUpAmount: 100}, store, util.DefaultPasswordHasher)
+			err := authenticator.CheckStepUp(context.Background(), tc.user, tc.amount, tc.code)
+			if tc.err != nil {
+				require.EqualError(t, err, tc.err.Error())
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
@@ -0,0 +1,63 @@
+// Command mockidp serves a mock OpenID Connect provider at OIDC_ISSUER_URL
+// for trying staff single sign-on locally. It logs in the user given by the
+// flags without asking for credentials, so it is a program of its own and
+// never part of the server.
+package main
+
+import (
+	"flag"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/oidc/oidctest"
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/util"
+)
+
+func main() {
+	config, err := util.LoadConfig(".")
+	if err != nil {
+		fatal("cannot load config", "error", err)
+	}
+
+	flags := flag.NewFlagSet("mockidp", flag.ExitOnError)
+	subject := flags.String("subject", "00u1staff", "subject of the user logged in")
+	email := flags.String("email", "staff@simplebank.local", "verified email of the user")
+	name := flags.String("name", "Local Staff", "full name of the user")
+	groups := flags.String("groups", "", "comma separated groups of the user")
+	_ = flags.Parse(os.Args[1:])
+
+	issuer, err := url.Parse(config.OIDCIssuerURL)
+	if err != nil || issuer.Host == "" {
+		fatal("OIDC_ISSUER_URL is not a url to serve the provider at", "issuer", config.OIDCIssuerURL)
+	}
+
+	provider, err := oidctest.NewProvider(config.OIDCIssuerURL, config.OIDCClientID, config.OIDCClientSecret, config.OIDCRedirectURL)
+	if err != nil {
+		fatal("cannot create provider", "error", err)
+	}
+
+	user := oidctest.User{
+		Subject:       *subject,
+		Email:         *email,
+		EmailVerified: true,
+		Name:          *name,
+	}
+	if *groups != "" {
+		user.Groups = strings.Split(*groups, ",")
+	}
+	provider.SetUser(user)
+
+	slog.Info("serving mock identity provider", "issuer", config.OIDCIssuerURL, "email", user.Email)
+	if err := http.ListenAndServe(issuer.Host, provider); err != nil {
+		fatal("cannot serve mock identity provider", "error", err)
+	}
+}
+
+// fatal logs why the program cannot go on and exits with status 1
+func fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
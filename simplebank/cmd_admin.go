@@ -0,0 +1,157 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/admin"
+	db "github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/db/sqlc"
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/util"
+)
+
+// runAdmin runs an operations task and prints its result as JSON to stdout.
+// Accounts are given by id or account number, and tasks that write take
+// -dry-run to only check what they would do:
+//
+//	admin create-user -username U -full-name N -email E [-role R] [-password-stdin]
+//	admin reset-password -username U [-password-stdin]
+//	admin create-account -owner U -currency C
+//	admin credit|debit -account A -contra-account A -amount N -reason R
+//	admin freeze|unfreeze -account A
+//	admin ledger -account A [-limit N] [-offset N]
+//	admin reconcile
+//
+// Passwords are read from the first line of stdin with -password-stdin and
+// generated otherwise; a generated password is printed once.
+func runAdmin(config util.Config, store db.Store, args []string) {
+	if len(args) == 0 {
+		fatal("admin takes a command: create-user, reset-password, create-account, credit, debit, freeze, unfreeze, ledger or reconcile")
+	}
+
+	if args[0] == "reconcile" {
+		runReconcile(store, args[1:])
+		return
+	}
+
+	operator, err := admin.New(config, store)
+	if err != nil {
+		fatal("cannot create admin", "error", err)
+	}
+
+	ctx := context.Background()
+	flags := flag.NewFlagSet("admin "+args[0], flag.ExitOnError)
+
+	var result any
+	switch args[0] {
+	case "create-user":
+		arg := admin.CreateUserParams{}
+		flags.StringVar(&arg.Username, "username", "", "username of the user")
+		flags.StringVar(&arg.FullName, "full-name", "", "full name of the user")
+		flags.StringVar(&arg.Email, "email", "", "email address of the user")
+		flags.StringVar(&arg.Role, "role", util.DepositorRole, "role of the user: depositor or admin")
+		passwordStdin := flags.Bool("password-stdin", false, "read the password from stdin instead of generating one")
+		flags.BoolVar(&arg.DryRun, "dry-run", false, "check the user without creating it")
+		parseAdminFlags(flags, args[1:])
+
+		arg.Password = readPassword(*passwordStdin)
+		result, err = operator.CreateUser(ctx, arg)
+	case "reset-password":
+		arg := admin.ResetPasswordParams{}
+		flags.StringVar(&arg.Username, "username", "", "username of the user")
+		passwordStdin := flags.Bool("password-stdin", false, "read the password from stdin instead of generating one")
+		flags.BoolVar(&arg.DryRun, "dry-run", false, "check the user without changing its password")
+		parseAdminFlags(flags, args[1:])
+
+		arg.Password = readPassword(*passwordStdin)
+		result, err = operator.ResetPassword(ctx, arg)
+	case "create-account":
+		arg := admin.CreateAccountParams{}
+		flags.StringVar(&arg.Owner, "owner", "", "username of the owner")
+		flags.StringVar(&arg.Currency, "currency", "", "currency of the account")
+		flags.BoolVar(&arg.DryRun, "dry-run", false, "check the account without creating it")
+		parseAdminFlags(flags, args[1:])
+
+		result, err = operator.CreateAccount(ctx, arg)
+	case "credit", "debit":
+		arg := admin.AdjustParams{Type: args[0]}
+		flags.StringVar(&arg.Account, "account", "", "account to "+args[0])
+		flags.StringVar(&arg.ContraAccount, "contra-account", "", "account taking the other side of the adjustment")
+		flags.Int64Var(&arg.Amount, "amount", 0, "amount in minor units, e.g. cents")
+		flags.StringVar(&arg.Reason, "reason", "", "why the adjustment is made, recorded on the journal")
+		flags.BoolVar(&arg.DryRun, "dry-run", false, "show the entries and balances without posting them")
+		parseAdminFlags(flags, args[1:])
+
+		result, err = operator.Adjust(ctx, arg)
+	case "freeze", "unfreeze":
+		arg := admin.FreezeAccountParams{}
+		flags.StringVar(&arg.Account, "account", "", "account to "+args[0])
+		flags.BoolVar(&arg.DryRun, "dry-run", false, "show the account without changing it")
+		parseAdminFlags(flags, args[1:])
+
+		if args[0] == "freeze" {
+			result, err = operator.FreezeAccount(ctx, arg)
+		} else {
+			result, err = operator.UnfreezeAccount(ctx, arg)
+		}
+	case "ledger":
+		arg := admin.LedgerParams{}
+		flags.StringVar(&arg.Account, "account", "", "account to show")
+		limit := flags.Int("limit", 50, "number of entries to show")
+		offset := flags.Int("offset", 0, "number of entries to skip, oldest first")
+		parseAdminFlags(flags, args[1:])
+
+		if *limit > math.MaxInt32 || *offset > math.MaxInt32 {
+			fatal("limit and offset must fit in 32 bits")
+		}
+		arg.Limit, arg.Offset = int32(*limit), int32(*offset)
+		result, err = operator.Ledger(ctx, arg)
+	default:
+		fatal("unknown admin command", "command", args[0])
+	}
+	if err != nil {
+		fatal("admin command failed", "command", args[0], "error", err)
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(result); err != nil {
+		fatal("cannot write result", "error", err)
+	}
+}
+
+// parseAdminFlags parses the flags of an admin command, which takes no
+// positional arguments
+func parseAdminFlags(flags *flag.FlagSet, args []string) {
+	_ = flags.Parse(args)
+	if flags.NArg() > 0 {
+		fatal(fmt.Sprintf("%s takes no arguments", flags.Name()), "args", flags.Args())
+	}
+}
+
+// readPassword reads the first line of stdin when fromStdin is set, and
+// returns an empty password, to be generated, otherwise
+func readPassword(fromStdin bool) string {
+	if !fromStdin {
+		return ""
+	}
+
+	scanner := bufio.NewScanner(os.Stdin)
+	if !scanner.Scan() {
+		if err := scanner.Err(); err != nil {
+			fatal("cannot read password", "error", err)
+		}
+		fatal("no password on stdin")
+	}
+
+	password := strings.TrimSuffix(scanner.Text(), "\r")
+	if password == "" {
+		fatal("no password on stdin")
+	}
+	return password
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/db/migration"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// runMigrate applies or reverts the migrations embedded in the binary:
+//
+//	migrate up              applies the missing migrations
+//	migrate down [-all] [N] reverts the last N migrations, 1 by default
+//	migrate status          lists the migrations and whether they are applied
+//	migrate version         prints the last migration applied
+func runMigrate(conn *pgxpool.Pool, args []string) {
+	if len(args) == 0 {
+		fatal("migrate takes a command: up, down, status or version")
+	}
+
+	migrator, err := migration.New(conn)
+	if err != nil {
+		fatal("cannot create migrator", "error", err)
+	}
+	defer migrator.Close()
+
+	switch args[0] {
+	case "up":
+		err = migrator.Up()
+	case "down":
+		err = migrateDown(migrator, args[1:])
+	case "status":
+		err = printMigrationStatus(migrator)
+	case "version":
+		err = printMigrationVersion(migrator)
+	default:
+		fatal("unknown migrate command", "command", args[0])
+	}
+	if err != nil {
+		fatal("cannot migrate", "command", args[0], "error", err)
+	}
+}
+
+func migrateDown(migrator *migration.Migrator, args []string) error {
+	flags := flag.NewFlagSet("migrate down", flag.ExitOnError)
+	all := flags.Bool("all", false, "revert every migration")
+	_ = flags.Parse(args)
+
+	if *all {
+		return migrator.DownAll()
+	}
+
+	steps := 1
+	if flags.NArg() > 0 {
+		n, err := strconv.Atoi(flags.Arg(0))
+		if err != nil || n < 1 {
+			return fmt.Errorf("the number of migrations to revert must be positive, got %q", flags.Arg(0))
+		}
+		steps = n
+	}
+	return migrator.Down(steps)
+}
+
+func printMigrationStatus(migrator *migration.Migrator) error {
+	version, dirty, err := migrator.Version()
+	if err != nil {
+		return err
+	}
+	migrations, err := migration.List()
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, m := range migrations {
+		status := "pending"
+		switch {
+		case m.Version == version && dirty:
+			status = "dirty"
+		case m.Version <= version:
+			status = "applied"
+		}
+		fmt.Fprintf(w, "%06d\t%s\t%s\n", m.Version, m.Name, status)
+	}
+	return w.Flush()
+}
+
+func printMigrationVersion(migrator *migration.Migrator) error {
+	version, dirty, err := migrator.Version()
+	if err != nil {
+		return err
+	}
+
+	if dirty {
+		fmt.Printf("%d (dirty)\n", version)
+		return nil
+	}
+	fmt.Println(version)
+	return nil
+}
+
+// autoMigrate applies the missing migrations before the server starts
+func autoMigrate(conn *pgxpool.Pool) {
+	migrator, err := migration.New(conn)
+	if err != nil {
+		fatal("cannot create migrator", "error", err)
+	}
+	defer migrator.Close()
+
+	if err := migrator.Up(); err != nil {
+		fatal("cannot migrate db", "error", err)
+	}
+}
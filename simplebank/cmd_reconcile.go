@@ -0,0 +1,33 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+
+	db "github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/db/sqlc"
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/reconcile"
+)
+
+// runReconcile prints the reconciliation report as JSON to stdout and exits
+// with status 1 when the ledger has any discrepancies.
+func runReconcile(store db.Store, args []string) {
+	if len(args) > 0 {
+		fatal("reconcile takes no arguments", "args", args)
+	}
+
+	report, err := reconcile.Run(context.Background(), store)
+	if err != nil {
+		fatal("cannot reconcile ledger", "error", err)
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		fatal("cannot write reconciliation report", "error", err)
+	}
+
+	if !report.OK {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,64 @@
+// Package core sets up what the HTTP API and the gRPC server share from the
+// config, so both make tokens, hash passwords and send mail the same way.
+package core
+
+import (
+	"fmt"
+
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/auth"
+	db "github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/db/sqlc"
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/mail"
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/token"
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/util"
+)
+
+// Core holds what both servers are built from
+type Core struct {
+	TokenMaker token.Maker
+	// ChallengeTokenMaker makes the tokens of logins waiting for a second factor
+	ChallengeTokenMaker token.Maker
+	Mailer              mail.Mailer
+	PasswordHasher      util.PasswordHasher
+	PasswordPolicy      *util.PasswordPolicy
+	Authenticator       *auth.Authenticator
+}
+
+func New(config util.Config, store db.Store) (*Core, error) {
+	maker, err := token.NewPasetoMaker(config.TokenSymmetricKey)
+	if err != nil {
+		return nil, err
+	}
+
+	challengeMaker, err := token.NewPasetoMaker(token.DeriveKey(config.TokenSymmetricKey, token.PurposeTOTPChallenge))
+	if err != nil {
+		return nil, err
+	}
+
+	if !util.IsValidCountryCode(config.AccountNumberCountry) {
+		return nil, fmt.Errorf("invalid account number country prefix: %q", config.AccountNumberCountry)
+	}
+
+	mailer, err := mail.NewMailer(config)
+	if err != nil {
+		return nil, err
+	}
+
+	passwordHasher, err := util.NewConfiguredPasswordHasher(config)
+	if err != nil {
+		return nil, err
+	}
+
+	passwordPolicy, err := util.NewConfiguredPasswordPolicy(config)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Core{
+		TokenMaker:          maker,
+		ChallengeTokenMaker: challengeMaker,
+		Mailer:              mailer,
+		PasswordHasher:      passwordHasher,
+		PasswordPolicy:      passwordPolicy,
+		Authenticator:       auth.NewAuthenticator(config, store, passwordHasher),
+	}, nil
+}
@@ -0,0 +1,69 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestConfig() util.Config {
+	return util.Config{
+		TokenSymmetricKey:    util.RandomString(32),
+		AccountNumberCountry: util.DefaultAccountNumberCountry,
+	}
+}
+
+func TestNew(t *testing.T) {
+	shared, err := New(newTestConfig(), nil)
+	require.NoError(t, err)
+	require.NotNil(t, shared.TokenMaker)
+	require.NotNil(t, shared.ChallengeTokenMaker)
+	require.NotNil(t, shared.Mailer)
+	require.NotNil(t, shared.PasswordHasher)
+	require.NotNil(t, shared.PasswordPolicy)
+	require.NotNil(t, shared.Authenticator)
+}
+
+func TestNewInvalidConfig(t *testing.T) {
+	testCases := []struct {
+		name   string
+		modify func(config *util.Config)
+	}{
+		{
+			name: "ShortTokenKey",
+			modify: func(config *util.Config) {
+				config.TokenSymmetricKey = "short"
+			},
+		},
+		{
+			name: "InvalidCountry",
+			modify: func(config *util.Config) {
+				config.AccountNumberCountry = "gb"
+			},
+		},
+		{
+			name: "UnknownHashAlgorithm",
+			modify: func(config *util.Config) {
+				config.PasswordHashAlgorithm = "md5"
+			},
+		},
+		{
+			name: "InvalidPasswordLengths",
+			modify: func(config *util.Config) {
+				config.PasswordMinLength = 20
+				config.PasswordMaxLength = 10
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			config := newTestConfig()
+			tc.modify(&config)
+
+			_, err := New(config, nil)
+			require.Error(t, err)
+		})
+	}
+}
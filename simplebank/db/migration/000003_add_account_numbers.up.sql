@@ -0,0 +1,16 @@
+ALTER TABLE "accounts" ADD COLUMN "account_number" varchar;
+
+-- Backfill existing accounts with an IBAN-style number using the default NG prefix.
+-- The basic account number scrambles the id with a prime multiplier so it stays
+-- unique without exposing how many accounts exist.
+UPDATE "accounts" AS a
+SET "account_number" = 'NG' || lpad((98 - mod((b.bban || '231600')::numeric, 97))::text, 2, '0') || b.bban
+FROM (
+  SELECT "id", lpad(mod("id"::numeric * 4294967291, 10000000000)::text, 10, '0') AS bban
+  FROM "accounts"
+) AS b
+WHERE a."id" = b."id";
+
+ALTER TABLE "accounts" ALTER COLUMN "account_number" SET NOT NULL;
+
+ALTER TABLE "accounts" ADD CONSTRAINT "accounts_account_number_key" UNIQUE ("account_number");
@@ -0,0 +1,18 @@
+CREATE TABLE "statements" (
+  "id" bigserial PRIMARY KEY,
+  "account_id" bigint NOT NULL,
+  "period_start" timestamptz NOT NULL,
+  "period_end" timestamptz NOT NULL,
+  "content" jsonb NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX ON "entries" ("account_id", "created_at");
+
+CREATE UNIQUE INDEX ON "statements" ("account_id", "period_start", "period_end");
+
+COMMENT ON COLUMN "statements"."period_end" IS 'exclusive';
+
+COMMENT ON COLUMN "statements"."content" IS 'the statement with its lines, rendered into each format on download';
+
+ALTER TABLE "statements" ADD FOREIGN KEY ("account_id") REFERENCES "accounts" ("id");
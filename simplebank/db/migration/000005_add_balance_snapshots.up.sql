@@ -0,0 +1,11 @@
+CREATE TABLE "account_balance_snapshots" (
+  "account_id" bigint NOT NULL,
+  "snapshot_date" date NOT NULL,
+  "balance" bigint NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  PRIMARY KEY ("account_id", "snapshot_date")
+);
+
+COMMENT ON COLUMN "account_balance_snapshots"."balance" IS 'balance at the end of snapshot_date (UTC)';
+
+ALTER TABLE "account_balance_snapshots" ADD FOREIGN KEY ("account_id") REFERENCES "accounts" ("id");
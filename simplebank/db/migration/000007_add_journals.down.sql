@@ -0,0 +1,4 @@
+ALTER TABLE "transfers" DROP COLUMN IF EXISTS "journal_id";
+ALTER TABLE "entries" DROP COLUMN IF EXISTS "leg_type";
+ALTER TABLE "entries" DROP COLUMN IF EXISTS "journal_id";
+DROP TABLE IF EXISTS "journals";
@@ -0,0 +1,44 @@
+CREATE TABLE "journals" (
+  "id" bigserial PRIMARY KEY,
+  "kind" varchar NOT NULL,
+  "description" varchar NOT NULL DEFAULT '',
+  "reverses_journal_id" bigint UNIQUE,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+COMMENT ON COLUMN "journals"."kind" IS 'transfer, fee, interest or reversal';
+
+COMMENT ON COLUMN "journals"."reverses_journal_id" IS 'set on reversals, a journal can only be reversed once';
+
+ALTER TABLE "journals" ADD FOREIGN KEY ("reverses_journal_id") REFERENCES "journals" ("id");
+
+ALTER TABLE "entries" ADD COLUMN "journal_id" bigint;
+
+ALTER TABLE "entries" ADD COLUMN "leg_type" varchar NOT NULL
+  GENERATED ALWAYS AS (CASE WHEN "amount" < 0 THEN 'debit' ELSE 'credit' END) STORED;
+
+COMMENT ON COLUMN "entries"."journal_id" IS 'null for entries posted outside of a journal';
+
+COMMENT ON COLUMN "entries"."leg_type" IS 'debit for negative amounts, credit otherwise';
+
+ALTER TABLE "transfers" ADD COLUMN "journal_id" bigint;
+
+-- every existing transfer becomes a journal with the same id
+INSERT INTO "journals" ("id", "kind", "description", "created_at")
+SELECT "id", 'transfer', 'transfer ' || "id", "created_at"
+FROM "transfers";
+
+SELECT setval(pg_get_serial_sequence('journals', 'id'), COALESCE(MAX("id"), 0) + 1, false)
+FROM "journals";
+
+UPDATE "transfers" SET "journal_id" = "id";
+
+UPDATE "entries" SET "journal_id" = "transfer_id" WHERE "transfer_id" IS NOT NULL;
+
+CREATE INDEX ON "entries" ("journal_id");
+
+CREATE UNIQUE INDEX ON "transfers" ("journal_id");
+
+ALTER TABLE "entries" ADD FOREIGN KEY ("journal_id") REFERENCES "journals" ("id");
+
+ALTER TABLE "transfers" ADD FOREIGN KEY ("journal_id") REFERENCES "journals" ("id");
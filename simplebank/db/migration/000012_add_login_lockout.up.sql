@@ -0,0 +1,13 @@
+ALTER TABLE "users" ADD COLUMN "role" varchar NOT NULL DEFAULT 'depositor';
+
+CREATE TABLE "login_throttles" (
+  "kind" varchar NOT NULL,
+  "subject" varchar NOT NULL,
+  "failed_attempts" integer NOT NULL DEFAULT 0,
+  "last_failed_at" timestamptz NOT NULL DEFAULT (now()),
+  "locked_until" timestamptz,
+  PRIMARY KEY ("kind", "subject")
+);
+
+COMMENT ON COLUMN "login_throttles"."kind" IS 'username or ip';
+COMMENT ON COLUMN "login_throttles"."subject" IS 'the username or client ip failing to log in';
@@ -0,0 +1,39 @@
+CREATE TABLE "oauth_clients" (
+  "id" varchar PRIMARY KEY,
+  "name" varchar NOT NULL,
+  "hashed_secret" varchar NOT NULL DEFAULT '',
+  "redirect_uris" varchar[] NOT NULL,
+  "scopes" varchar[] NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE TABLE "oauth_consents" (
+  "username" varchar NOT NULL,
+  "client_id" varchar NOT NULL,
+  "scopes" varchar[] NOT NULL,
+  "granted_at" timestamptz NOT NULL DEFAULT (now()),
+  PRIMARY KEY ("username", "client_id")
+);
+
+CREATE TABLE "oauth_authorization_codes" (
+  "hashed_code" varchar PRIMARY KEY,
+  "client_id" varchar NOT NULL,
+  "username" varchar NOT NULL,
+  "redirect_uri" varchar NOT NULL,
+  "scopes" varchar[] NOT NULL,
+  "code_challenge" varchar NOT NULL,
+  "is_used" boolean NOT NULL DEFAULT false,
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  "expired_at" timestamptz NOT NULL
+);
+
+CREATE INDEX ON "oauth_consents" ("client_id");
+
+COMMENT ON COLUMN "oauth_clients"."hashed_secret" IS 'sha-256 of the client secret, empty for public clients';
+COMMENT ON COLUMN "oauth_clients"."scopes" IS 'the most a user can grant the client';
+COMMENT ON COLUMN "oauth_authorization_codes"."code_challenge" IS 'S256 PKCE code challenge';
+
+ALTER TABLE "oauth_consents" ADD FOREIGN KEY ("username") REFERENCES "users" ("username");
+ALTER TABLE "oauth_consents" ADD FOREIGN KEY ("client_id") REFERENCES "oauth_clients" ("id");
+ALTER TABLE "oauth_authorization_codes" ADD FOREIGN KEY ("username") REFERENCES "users" ("username");
+ALTER TABLE "oauth_authorization_codes" ADD FOREIGN KEY ("client_id") REFERENCES "oauth_clients" ("id");
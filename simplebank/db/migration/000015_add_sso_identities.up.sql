@@ -0,0 +1,15 @@
+CREATE TABLE "sso_identities" (
+  "issuer" varchar NOT NULL,
+  "subject" varchar NOT NULL,
+  "username" varchar NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  "last_login_at" timestamptz NOT NULL DEFAULT (now()),
+  PRIMARY KEY ("issuer", "subject")
+);
+
+CREATE INDEX ON "sso_identities" ("username");
+
+COMMENT ON COLUMN "sso_identities"."issuer" IS 'the OpenID Connect provider';
+COMMENT ON COLUMN "sso_identities"."subject" IS 'the sub claim, stable for the user at the provider';
+
+ALTER TABLE "sso_identities" ADD FOREIGN KEY ("username") REFERENCES "users" ("username");
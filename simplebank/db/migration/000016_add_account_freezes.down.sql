@@ -0,0 +1,3 @@
+COMMENT ON COLUMN "journals"."kind" IS 'transfer, fee, interest or reversal';
+
+ALTER TABLE "accounts" DROP COLUMN IF EXISTS "frozen_at";
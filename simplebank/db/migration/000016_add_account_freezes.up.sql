@@ -0,0 +1,5 @@
+ALTER TABLE "accounts" ADD COLUMN "frozen_at" timestamptz;
+
+COMMENT ON COLUMN "accounts"."frozen_at" IS 'set while the account is frozen, which blocks transfers from and to it';
+
+COMMENT ON COLUMN "journals"."kind" IS 'transfer, fee, interest, reversal or adjustment';
@@ -0,0 +1,10 @@
+CREATE TABLE "rate_limit_buckets" (
+  "key" varchar PRIMARY KEY,
+  "tokens" double precision NOT NULL,
+  "updated_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX ON "rate_limit_buckets" ("updated_at");
+
+COMMENT ON COLUMN "rate_limit_buckets"."key" IS 'the route group and the client ip or username being limited';
+COMMENT ON COLUMN "rate_limit_buckets"."tokens" IS 'requests left at updated_at, refilled at the rate of the limit';
@@ -0,0 +1 @@
+ALTER TABLE "sso_identities" DROP COLUMN IF EXISTS "manages_role";
@@ -0,0 +1,10 @@
+ALTER TABLE "sso_identities" ADD COLUMN "manages_role" boolean NOT NULL DEFAULT false;
+
+COMMENT ON COLUMN "sso_identities"."manages_role" IS 'set when the identity created the user, whose role then follows the provider groups';
+
+-- only logins through the provider create users without a password
+UPDATE "sso_identities"
+SET "manages_role" = true
+FROM "users"
+WHERE "users"."username" = "sso_identities"."username"
+  AND "users"."hashed_password" = '';
@@ -0,0 +1,149 @@
+// Package migration embeds the database migrations and applies them with
+// migrate, recording them in its schema_migrations table as the migrate CLI
+// does.
+package migration
+
+import (
+	"cmp"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/golang-migrate/migrate/v4"
+	pgxmigrate "github.com/golang-migrate/migrate/v4/database/pgx/v5"
+	"github.com/golang-migrate/migrate/v4/source"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jackc/pgx/v5/stdlib"
+)
+
+//go:embed *.sql
+var files embed.FS
+
+// lockTimeout bounds the wait for another instance migrating the database
+const lockTimeout = 5 * time.Minute
+
+// Migration is one of the embedded migrations
+type Migration struct {
+	Version uint
+	Name    string
+}
+
+// List returns the embedded migrations in the order they are applied
+func List() ([]Migration, error) {
+	names, err := fs.Glob(files, "*.up.sql")
+	if err != nil {
+		return nil, err
+	}
+
+	migrations := make([]Migration, 0, len(names))
+	for _, name := range names {
+		m, err := source.Parse(name)
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration file %q: %w", name, err)
+		}
+		migrations = append(migrations, Migration{Version: m.Version, Name: m.Identifier})
+	}
+	slices.SortFunc(migrations, func(a, b Migration) int {
+		return cmp.Compare(a.Version, b.Version)
+	})
+	return migrations, nil
+}
+
+// LatestVersion returns the version of the last embedded migration, which
+// the queries are written against
+func LatestVersion() uint {
+	migrations, err := List()
+	if err != nil || len(migrations) == 0 {
+		return 0
+	}
+	return migrations[len(migrations)-1].Version
+}
+
+// Migrator applies the embedded migrations to a database. It holds a
+// Postgres advisory lock while it migrates, so instances started together
+// migrate the database one at a time.
+type Migrator struct {
+	migrate *migrate.Migrate
+}
+
+// New creates a migrator for the database of pool, using one of its
+// connections. Closing the migrator doesn't close the pool.
+func New(pool *pgxpool.Pool) (*Migrator, error) {
+	sourceDriver, err := iofs.New(files, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	dbDriver, err := pgxmigrate.WithInstance(stdlib.OpenDBFromPool(pool), &pgxmigrate.Config{})
+	if err != nil {
+		return nil, fmt.Errorf("cannot connect to db: %w", err)
+	}
+
+	m, err := migrate.NewWithInstance("iofs", sourceDriver, "pgx5", dbDriver)
+	if err != nil {
+		return nil, err
+	}
+	m.LockTimeout = lockTimeout
+	m.Log = logger{}
+
+	return &Migrator{migrate: m}, nil
+}
+
+// Up applies the migrations the database is missing
+func (migrator *Migrator) Up() error {
+	err := migrator.migrate.Up()
+	if errors.Is(err, migrate.ErrNoChange) {
+		return nil
+	}
+	return err
+}
+
+// Down reverts the last steps migrations
+func (migrator *Migrator) Down(steps int) error {
+	err := migrator.migrate.Steps(-steps)
+	if errors.Is(err, migrate.ErrNoChange) {
+		return nil
+	}
+	return err
+}
+
+// DownAll reverts every migration
+func (migrator *Migrator) DownAll() error {
+	err := migrator.migrate.Down()
+	if errors.Is(err, migrate.ErrNoChange) {
+		return nil
+	}
+	return err
+}
+
+// Version returns the last migration applied, zero if none is, and whether
+// it failed halfway and left the schema dirty
+func (migrator *Migrator) Version() (version uint, dirty bool, err error) {
+	version, dirty, err = migrator.migrate.Version()
+	if errors.Is(err, migrate.ErrNilVersion) {
+		return 0, false, nil
+	}
+	return version, dirty, err
+}
+
+func (migrator *Migrator) Close() error {
+	sourceErr, dbErr := migrator.migrate.Close()
+	return errors.Join(sourceErr, dbErr)
+}
+
+// logger logs the migrations applied
+type logger struct{}
+
+func (logger) Printf(format string, v ...any) {
+	slog.Info("migrate: " + strings.TrimSpace(fmt.Sprintf(format, v...)))
+}
+
+func (logger) Verbose() bool {
+	return false
+}
@@ -0,0 +1,26 @@
+package migration
+
+import (
+	"fmt"
+	"io/fs"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestList(t *testing.T) {
+	migrations, err := List()
+	require.NoError(t, err)
+	require.NotEmpty(t, migrations)
+
+	for i, m := range migrations {
+		require.Equal(t, uint(i+1), m.Version, "migrations are numbered without gaps")
+		require.NotEmpty(t, m.Name)
+
+		down := fmt.Sprintf("%06d_%s.down.sql", m.Version, m.Name)
+		_, err := fs.Stat(files, down)
+		require.NoError(t, err, "%s is missing", down)
+	}
+
+	require.Equal(t, migrations[len(migrations)-1].Version, LatestVersion())
+}
@@ -2,15 +2,20 @@
 INSERT INTO accounts (
     owner,
     balance,
-    currency
+    currency,
+    account_number
 ) VALUES (
-    $1, $2, $3
+    $1, $2, $3, $4
 ) RETURNING *;
 
 -- name: GetAccount :one
 SELECT * FROM accounts
 WHERE id = $1 LIMIT 1;
 
+-- name: GetAccountByNumber :one
+SELECT * FROM accounts
+WHERE account_number = $1 LIMIT 1;
+
 -- name: ListAccounts :many
 SELECT * FROM accounts
 ORDER BY id
@@ -38,4 +43,17 @@ RETURNING *;
 
 -- name: DeleteAccount :exec
 DELETE FROM accounts
-WHERE id = $1;
\ No newline at end of file
+WHERE id = $1;
+
+-- name: FreezeAccount :one
+-- Keeps the time the account was first frozen when it already is.
+UPDATE accounts
+SET frozen_at = COALESCE(frozen_at, now())
+WHERE id = $1
+RETURNING *;
+
+-- name: UnfreezeAccount :one
+UPDATE accounts
+SET frozen_at = NULL
+WHERE id = $1
+RETURNING *;
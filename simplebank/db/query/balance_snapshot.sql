@@ -0,0 +1,57 @@
+-- name: CreateBalanceSnapshots :execrows
+-- Snapshots every account at the end of the given UTC day, starting from each
+-- account's latest earlier snapshot so only that day's entries are summed. The
+-- day must be over long enough for every entry dated in it to have committed:
+-- existing snapshots are kept as they are.
+INSERT INTO account_balance_snapshots (
+    account_id,
+    snapshot_date,
+    balance
+)
+SELECT
+    a.id,
+    sqlc.arg(snapshot_date)::date,
+    COALESCE(s.balance, 0) + COALESCE((
+        SELECT SUM(e.amount)
+        FROM entries e
+        WHERE e.account_id = a.id
+          AND e.created_at >= COALESCE((s.snapshot_date + 1)::timestamp AT TIME ZONE 'UTC', '-infinity')
+          AND e.created_at < (sqlc.arg(snapshot_date)::date + 1)::timestamp AT TIME ZONE 'UTC'
+    ), 0)::bigint
+FROM accounts a
+LEFT JOIN LATERAL (
+    SELECT snapshot_date, balance
+    FROM account_balance_snapshots
+    WHERE account_id = a.id
+      AND snapshot_date < sqlc.arg(snapshot_date)::date
+    ORDER BY snapshot_date DESC
+    LIMIT 1
+) s ON true
+ON CONFLICT (account_id, snapshot_date) DO NOTHING;
+
+-- name: GetAccountBalanceAt :one
+-- Computes the balance just before the given instant from the latest snapshot
+-- that ends at or before it plus the entries posted since.
+WITH snapshot AS (
+    SELECT snapshot_date, balance
+    FROM account_balance_snapshots
+    WHERE account_id = sqlc.arg(account_id)
+      AND snapshot_date < (sqlc.arg(at)::timestamptz AT TIME ZONE 'UTC')::date
+    ORDER BY snapshot_date DESC
+    LIMIT 1
+)
+SELECT (
+    COALESCE((SELECT balance FROM snapshot), 0) + COALESCE((
+        SELECT SUM(amount)
+        FROM entries
+        WHERE account_id = sqlc.arg(account_id)
+          AND created_at >= COALESCE(((SELECT snapshot_date FROM snapshot) + 1)::timestamp AT TIME ZONE 'UTC', '-infinity')
+          AND created_at < sqlc.arg(at)::timestamptz
+    ), 0)
+)::bigint AS balance;
+
+-- name: GetLatestBalanceSnapshot :one
+SELECT * FROM account_balance_snapshots
+WHERE account_id = $1
+ORDER BY snapshot_date DESC
+LIMIT 1;
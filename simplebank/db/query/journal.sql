@@ -0,0 +1,29 @@
+-- name: CreateJournal :one
+INSERT INTO journals (
+    kind,
+    description,
+    reverses_journal_id
+) VALUES (
+    $1, $2, $3
+) RETURNING *;
+
+-- name: GetJournal :one
+SELECT * FROM journals
+WHERE id = $1 LIMIT 1;
+
+-- name: ListEntriesForJournal :many
+SELECT * FROM entries
+WHERE journal_id = $1
+ORDER BY id;
+
+-- name: ListUnbalancedJournals :many
+SELECT
+    e.journal_id::bigint AS journal_id,
+    a.currency,
+    SUM(e.amount)::bigint AS total
+FROM entries e
+JOIN accounts a ON a.id = e.account_id
+WHERE e.journal_id IS NOT NULL
+GROUP BY e.journal_id, a.currency
+HAVING SUM(e.amount) <> 0
+ORDER BY e.journal_id, a.currency;
@@ -0,0 +1,35 @@
+-- name: DeleteLoginThrottle :execrows
+DELETE FROM login_throttles
+WHERE kind = $1 AND subject = $2;
+
+-- name: GetLoginLockedUntil :one
+-- The latest lock on either the username or the client ip, null when neither is locked.
+SELECT max(locked_until)::timestamptz AS locked_until
+FROM login_throttles
+WHERE (kind = 'username' AND subject = sqlc.arg(username))
+   OR (kind = 'ip' AND subject = sqlc.arg(ip));
+
+-- name: LockLogin :one
+UPDATE login_throttles
+SET locked_until = $3
+WHERE kind = $1 AND subject = $2
+RETURNING *;
+
+-- name: RecordLoginFailure :one
+-- Counting starts again when the previous failure is older than reset_before.
+INSERT INTO login_throttles (
+  kind,
+  subject,
+  failed_attempts,
+  last_failed_at
+) VALUES (
+  sqlc.arg(kind), sqlc.arg(subject), 1, now()
+)
+ON CONFLICT (kind, subject) DO UPDATE
+SET
+  failed_attempts = CASE
+    WHEN login_throttles.last_failed_at < sqlc.arg(reset_before) THEN 1
+    ELSE login_throttles.failed_attempts + 1
+  END,
+  last_failed_at = now()
+RETURNING *;
@@ -0,0 +1,68 @@
+-- name: CreateOAuthAuthorizationCode :one
+INSERT INTO oauth_authorization_codes (
+    hashed_code,
+    client_id,
+    username,
+    redirect_uri,
+    scopes,
+    code_challenge,
+    expired_at
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7
+) RETURNING *;
+
+-- name: CreateOAuthClient :one
+INSERT INTO oauth_clients (
+    id,
+    name,
+    hashed_secret,
+    redirect_uris,
+    scopes
+) VALUES (
+    $1, $2, $3, $4, $5
+) RETURNING *;
+
+-- name: DeleteOAuthConsent :execrows
+DELETE FROM oauth_consents
+WHERE username = $1 AND client_id = $2;
+
+-- name: GetOAuthClient :one
+SELECT * FROM oauth_clients
+WHERE id = $1 LIMIT 1;
+
+-- name: GetOAuthConsent :one
+SELECT * FROM oauth_consents
+WHERE username = $1 AND client_id = $2 LIMIT 1;
+
+-- name: ListOAuthConsents :many
+SELECT oauth_consents.*, oauth_clients.name AS client_name
+FROM oauth_consents
+JOIN oauth_clients ON oauth_clients.id = oauth_consents.client_id
+WHERE oauth_consents.username = $1
+ORDER BY oauth_consents.granted_at DESC;
+
+-- name: UpsertOAuthConsent :one
+-- A new consent adds its scopes to those of an earlier one and keeps its grant
+-- time, so approving a narrower request does not revoke tokens issued before.
+INSERT INTO oauth_consents (
+    username,
+    client_id,
+    scopes
+) VALUES (
+    $1, $2, $3
+)
+ON CONFLICT (username, client_id) DO UPDATE
+SET scopes = ARRAY(
+    SELECT DISTINCT scope
+    FROM unnest(oauth_consents.scopes || EXCLUDED.scopes) AS scope
+    ORDER BY scope
+)
+RETURNING *;
+
+-- name: UseOAuthAuthorizationCode :one
+UPDATE oauth_authorization_codes
+SET is_used = TRUE
+WHERE hashed_code = $1
+  AND is_used = FALSE
+  AND expired_at > now()
+RETURNING *;
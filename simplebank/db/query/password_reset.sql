@@ -0,0 +1,19 @@
+-- name: CreatePasswordReset :one
+INSERT INTO password_resets (
+    id,
+    username,
+    expired_at
+) VALUES (
+    $1, $2, $3
+) RETURNING *;
+
+-- name: UsePasswordReset :one
+-- Resets created before the password last changed are no longer valid.
+UPDATE password_resets
+SET is_used = TRUE
+WHERE id = $1
+  AND username = $2
+  AND is_used = FALSE
+  AND expired_at > now()
+  AND created_at > (SELECT password_changed_at FROM users WHERE username = $2)
+RETURNING *;
@@ -0,0 +1,30 @@
+-- name: DeleteRateLimitBuckets :execrows
+DELETE FROM rate_limit_buckets
+WHERE updated_at < $1;
+
+-- name: GetRateLimitBucket :one
+SELECT * FROM rate_limit_buckets
+WHERE key = $1 LIMIT 1;
+
+-- name: TakeRateLimitToken :one
+-- Refills the bucket for the time since it was last updated and takes a token
+-- from it. Returns no row, and leaves the bucket alone, when it has no token.
+INSERT INTO rate_limit_buckets (
+  key,
+  tokens,
+  updated_at
+) VALUES (
+  sqlc.arg(key), sqlc.arg(burst)::float8 - 1, now()
+)
+ON CONFLICT (key) DO UPDATE
+SET
+  tokens = LEAST(
+    sqlc.arg(burst)::float8,
+    rate_limit_buckets.tokens + sqlc.arg(rate)::float8 * EXTRACT(EPOCH FROM now() - rate_limit_buckets.updated_at)::float8
+  ) - 1,
+  updated_at = now()
+WHERE LEAST(
+  sqlc.arg(burst)::float8,
+  rate_limit_buckets.tokens + sqlc.arg(rate)::float8 * EXTRACT(EPOCH FROM now() - rate_limit_buckets.updated_at)::float8
+) >= 1
+RETURNING *;
@@ -0,0 +1,46 @@
+-- name: ListAccountBalanceMismatches :many
+SELECT
+    a.id AS account_id,
+    a.currency,
+    a.balance,
+    COALESCE(SUM(e.amount), 0)::bigint AS entries_total
+FROM accounts a
+LEFT JOIN entries e ON e.account_id = a.id
+GROUP BY a.id
+HAVING a.balance <> COALESCE(SUM(e.amount), 0)
+ORDER BY a.id;
+
+-- name: ListUnbalancedTransfers :many
+SELECT
+    t.id AS transfer_id,
+    t.from_account_id,
+    t.to_account_id,
+    t.amount,
+    COUNT(e.id) FILTER (WHERE e.account_id = t.from_account_id AND e.amount = -t.amount)::bigint AS debit_entries,
+    COUNT(e.id) FILTER (WHERE e.account_id = t.to_account_id AND e.amount = t.amount)::bigint AS credit_entries,
+    COUNT(e.id)::bigint AS total_entries
+FROM transfers t
+LEFT JOIN entries e ON e.transfer_id = t.id
+GROUP BY t.id
+HAVING COUNT(e.id) FILTER (WHERE e.account_id = t.from_account_id AND e.amount = -t.amount) <> 1
+    OR COUNT(e.id) FILTER (WHERE e.account_id = t.to_account_id AND e.amount = t.amount) <> 1
+    OR COUNT(e.id) <> 2
+ORDER BY t.id;
+
+-- name: ListCurrencyTotals :many
+SELECT
+    a.currency,
+    SUM(a.balance)::bigint AS balance_total,
+    COALESCE(SUM(e.entries_total), 0)::bigint AS entries_total,
+    COALESCE(SUM(e.transfer_entries_total), 0)::bigint AS transfer_entries_total
+FROM accounts a
+LEFT JOIN (
+    SELECT
+        account_id,
+        SUM(amount) AS entries_total,
+        SUM(amount) FILTER (WHERE transfer_id IS NOT NULL) AS transfer_entries_total
+    FROM entries
+    GROUP BY account_id
+) e ON e.account_id = a.id
+GROUP BY a.currency
+ORDER BY a.currency;
@@ -0,0 +1,19 @@
+-- name: CreateSSOIdentity :one
+INSERT INTO sso_identities (
+    issuer,
+    subject,
+    username,
+    manages_role
+) VALUES (
+    $1, $2, $3, $4
+) RETURNING *;
+
+-- name: GetSSOIdentity :one
+SELECT * FROM sso_identities
+WHERE issuer = $1 AND subject = $2 LIMIT 1;
+
+-- name: TouchSSOIdentity :one
+UPDATE sso_identities
+SET last_login_at = now()
+WHERE issuer = $1 AND subject = $2
+RETURNING *;
@@ -0,0 +1,19 @@
+-- name: CreateStatement :execrows
+-- Statements are only created for periods that are over, so an existing one
+-- is kept as it is.
+INSERT INTO statements (
+    account_id,
+    period_start,
+    period_end,
+    content
+) VALUES (
+    $1, $2, $3, $4
+)
+ON CONFLICT (account_id, period_start, period_end) DO NOTHING;
+
+-- name: GetStatement :one
+SELECT * FROM statements
+WHERE account_id = $1
+  AND period_start = $2
+  AND period_end = $3
+LIMIT 1;
@@ -0,0 +1,55 @@
+-- name: CreateRecoveryCode :one
+INSERT INTO recovery_codes (
+  username,
+  hashed_code
+) VALUES (
+  $1, $2
+) RETURNING *;
+
+-- name: DeleteRecoveryCodes :exec
+DELETE FROM recovery_codes
+WHERE username = $1;
+
+-- name: DisableUserTOTP :one
+UPDATE users
+SET
+  totp_secret = '',
+  is_totp_enabled = FALSE,
+  totp_last_step = 0
+WHERE username = $1
+RETURNING *;
+
+-- name: EnableUserTOTP :one
+-- Only succeeds for the pending secret the confirmation code was checked against.
+UPDATE users
+SET
+  is_totp_enabled = TRUE,
+  totp_last_step = $3
+WHERE username = $1
+  AND totp_secret = $2
+  AND is_totp_enabled = FALSE
+RETURNING *;
+
+-- name: SetUserTOTPSecret :one
+-- Stores a pending secret. It cannot replace the secret of an enabled authenticator.
+UPDATE users
+SET totp_secret = $2
+WHERE username = $1 AND is_totp_enabled = FALSE
+RETURNING *;
+
+-- name: UseRecoveryCode :one
+UPDATE recovery_codes
+SET used_at = now()
+WHERE username = $1
+  AND hashed_code = $2
+  AND used_at IS NULL
+RETURNING *;
+
+-- name: UseTOTPStep :one
+-- Each time step can be used once, so an intercepted code cannot be replayed.
+UPDATE users
+SET totp_last_step = $2
+WHERE username = $1
+  AND is_totp_enabled = TRUE
+  AND totp_last_step < $2
+RETURNING *;
@@ -27,10 +27,24 @@ SET
   hashed_password = COALESCE(sqlc.narg(hashed_password), hashed_password),
   password_changed_at = COALESCE(sqlc.narg(password_changed_at), password_changed_at),
   full_name = COALESCE(sqlc.narg(full_name), full_name),
-  email = COALESCE(sqlc.narg(email), email)
+  email = COALESCE(sqlc.narg(email), email),
+  is_email_verified = COALESCE(sqlc.narg(is_email_verified), is_email_verified)
 WHERE username = sqlc.arg(username)
 RETURNING *;
 
 -- name: DeleteUser :exec
 DELETE FROM users
-WHERE username = $1;
\ No newline at end of file
+WHERE username = $1;
+
+-- name: MarkUserEmailVerified :one
+-- Only succeeds while the user still has the given email address.
+UPDATE users
+SET is_email_verified = TRUE
+WHERE username = $1 AND email = $2
+RETURNING *;
+
+-- name: SetUserRole :one
+UPDATE users
+SET role = $2
+WHERE username = $1
+RETURNING *;
@@ -0,0 +1,45 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/util"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const (
+	// AccountNumberConstraint is violated when a generated account number is taken
+	AccountNumberConstraint = "accounts_account_number_key"
+
+	// MaxAccountNumberAttempts bounds how often a colliding account number is regenerated
+	MaxAccountNumberAttempts = 3
+)
+
+// CreateAccountWithNumber creates the account under a newly generated account
+// number of the country, replacing the number of arg. A number that is taken
+// is generated again, and running out of attempts is an internal error rather
+// than a conflict with the caller's data.
+func CreateAccountWithNumber(ctx context.Context, q Querier, country string, arg CreateAccountParams) (Account, error) {
+	for attempt := 1; ; attempt++ {
+		accountNumber, err := util.GenerateAccountNumber(country)
+		if err != nil {
+			return Account{}, err
+		}
+
+		arg.AccountNumber = accountNumber
+		account, err := q.CreateAccount(ctx, arg)
+		if err == nil {
+			return account, nil
+		}
+
+		var pgErr *pgconn.PgError
+		if !errors.As(err, &pgErr) || pgErr.ConstraintName != AccountNumberConstraint {
+			return account, err
+		}
+		if attempt == MaxAccountNumberAttempts {
+			return account, fmt.Errorf("no unused account number after %d attempts: %s", attempt, err)
+		}
+	}
+}
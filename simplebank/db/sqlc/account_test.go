@@ -17,6 +17,7 @@ func createAccountFromArg(t *testing.T, arg CreateAccountParams) Account {
 	require.Equal(t, arg.Owner, account.Owner)
 	require.Equal(t, arg.Balance, account.Balance)
 	require.Equal(t, arg.Currency, account.Currency)
+	require.Equal(t, arg.AccountNumber, account.AccountNumber)
 
 	require.NotZero(t, account.ID)
 	require.NotZero(t, account.CreatedAt)
@@ -26,9 +27,10 @@ func createAccountFromArg(t *testing.T, arg CreateAccountParams) Account {
 
 func createRandomAccount(t *testing.T, owner User) Account {
 	arg := CreateAccountParams{
-		Owner:    owner.Username,
-		Balance:  util.RandomMoney(),
-		Currency: util.RandomCurrency(),
+		Owner:         owner.Username,
+		Balance:       util.RandomMoney(),
+		Currency:      util.RandomCurrency(),
+		AccountNumber: util.RandomAccountNumber(),
 	}
 
 	account := createAccountFromArg(t, arg)
@@ -55,6 +57,19 @@ func TestGetAccount(t *testing.T) {
 	require.WithinDuration(t, account1.CreatedAt, account2.CreatedAt, 0)
 }
 
+func TestGetAccountByNumber(t *testing.T) {
+	user := createRandomUser(t)
+	account1 := createRandomAccount(t, user)
+
+	account2, err := testQueries.GetAccountByNumber(context.Background(), account1.AccountNumber)
+	require.NoError(t, err)
+	require.NotEmpty(t, account2)
+
+	require.Equal(t, account1.ID, account2.ID)
+	require.Equal(t, account1.AccountNumber, account2.AccountNumber)
+	require.WithinDuration(t, account1.CreatedAt, account2.CreatedAt, 0)
+}
+
 func TestUpdateAccount(t *testing.T) {
 	user := createRandomUser(t)
 	account1 := createRandomAccount(t, user)
@@ -112,19 +127,22 @@ func TestListAccountsForUser(t *testing.T) {
 	user := createRandomUser(t)
 
 	accountUSD := createAccountFromArg(t, CreateAccountParams{
-		Owner:    user.Username,
-		Balance:  util.RandomAmount(),
-		Currency: util.USD,
+		Owner:         user.Username,
+		Balance:       util.RandomAmount(),
+		Currency:      util.USD,
+		AccountNumber: util.RandomAccountNumber(),
 	})
 	accountEUR := createAccountFromArg(t, CreateAccountParams{
-		Owner:    user.Username,
-		Balance:  util.RandomAmount(),
-		Currency: util.EUR,
+		Owner:         user.Username,
+		Balance:       util.RandomAmount(),
+		Currency:      util.EUR,
+		AccountNumber: util.RandomAccountNumber(),
 	})
 	accountCAD := createAccountFromArg(t, CreateAccountParams{
-		Owner:    user.Username,
-		Balance:  util.RandomAmount(),
-		Currency: util.CAD,
+		Owner:         user.Username,
+		Balance:       util.RandomAmount(),
+		Currency:      util.CAD,
+		AccountNumber: util.RandomAccountNumber(),
 	})
 
 	arg := ListAccountsForUserParams{
@@ -150,3 +168,23 @@ func TestListAccountsForUser(t *testing.T) {
 		}
 	}
 }
+
+func TestFreezeAccount(t *testing.T) {
+	user := createRandomUser(t)
+	account := createRandomAccount(t, user)
+	require.False(t, account.FrozenAt.Valid)
+
+	frozen, err := testQueries.FreezeAccount(context.Background(), account.ID)
+	require.NoError(t, err)
+	require.True(t, frozen.FrozenAt.Valid)
+	require.Equal(t, account.Balance, frozen.Balance)
+
+	// freezing again keeps the original time
+	refrozen, err := testQueries.FreezeAccount(context.Background(), account.ID)
+	require.NoError(t, err)
+	require.WithinDuration(t, frozen.FrozenAt.Time, refrozen.FrozenAt.Time, 0)
+
+	unfrozen, err := testQueries.UnfreezeAccount(context.Background(), account.ID)
+	require.NoError(t, err)
+	require.False(t, unfrozen.FrozenAt.Valid)
+}
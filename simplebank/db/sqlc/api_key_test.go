@@ -0,0 +1,110 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/util"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomAPIKey(t *testing.T, user User) ApiKey {
+	_, prefix, hashedSecret, err := util.GenerateAPIKey()
+	require.NoError(t, err)
+
+	arg := CreateAPIKeyParams{
+		ID:           uuid.New(),
+		Username:     user.Username,
+		Name:         util.RandomString(8),
+		Prefix:       prefix,
+		HashedSecret: hashedSecret,
+		Scopes:       []string{"accounts:read", "transfers:write"},
+		ExpiredAt:    time.Now().Add(time.Hour),
+	}
+
+	apiKey, err := testQueries.CreateAPIKey(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, arg.ID, apiKey.ID)
+	require.Equal(t, arg.Username, apiKey.Username)
+	require.Equal(t, arg.Name, apiKey.Name)
+	require.Equal(t, arg.Prefix, apiKey.Prefix)
+	require.Equal(t, arg.HashedSecret, apiKey.HashedSecret)
+	require.Equal(t, arg.Scopes, apiKey.Scopes)
+	require.WithinDuration(t, arg.ExpiredAt, apiKey.ExpiredAt, time.Second)
+	require.False(t, apiKey.LastUsedAt.Valid)
+	require.False(t, apiKey.RevokedAt.Valid)
+	require.NotZero(t, apiKey.CreatedAt)
+
+	return apiKey
+}
+
+func TestGetAPIKeyByPrefix(t *testing.T) {
+	apiKey1 := createRandomAPIKey(t, createRandomUser(t))
+
+	apiKey2, err := testQueries.GetAPIKeyByPrefix(context.Background(), apiKey1.Prefix)
+	require.NoError(t, err)
+	require.Equal(t, apiKey1.ID, apiKey2.ID)
+	require.Equal(t, apiKey1.HashedSecret, apiKey2.HashedSecret)
+
+	_, err = testQueries.GetAPIKeyByPrefix(context.Background(), util.RandomString(12))
+	require.ErrorIs(t, err, pgx.ErrNoRows)
+}
+
+func TestListAPIKeys(t *testing.T) {
+	user := createRandomUser(t)
+	for i := 0; i < 3; i++ {
+		createRandomAPIKey(t, user)
+	}
+	createRandomAPIKey(t, createRandomUser(t))
+
+	apiKeys, err := testQueries.ListAPIKeys(context.Background(), user.Username)
+	require.NoError(t, err)
+	require.Len(t, apiKeys, 3)
+
+	for i, apiKey := range apiKeys {
+		require.Equal(t, user.Username, apiKey.Username)
+		if i > 0 {
+			require.False(t, apiKey.CreatedAt.After(apiKeys[i-1].CreatedAt))
+		}
+	}
+}
+
+func TestRevokeAPIKey(t *testing.T) {
+	user := createRandomUser(t)
+	apiKey := createRandomAPIKey(t, user)
+
+	// only the owner can revoke a key
+	_, err := testQueries.RevokeAPIKey(context.Background(), RevokeAPIKeyParams{
+		ID:       apiKey.ID,
+		Username: createRandomUser(t).Username,
+	})
+	require.ErrorIs(t, err, pgx.ErrNoRows)
+
+	arg := RevokeAPIKeyParams{
+		ID:       apiKey.ID,
+		Username: user.Username,
+	}
+
+	revoked, err := testQueries.RevokeAPIKey(context.Background(), arg)
+	require.NoError(t, err)
+	require.True(t, revoked.RevokedAt.Valid)
+	require.WithinDuration(t, time.Now(), revoked.RevokedAt.Time, time.Second)
+
+	_, err = testQueries.RevokeAPIKey(context.Background(), arg)
+	require.ErrorIs(t, err, pgx.ErrNoRows)
+}
+
+func TestTouchAPIKey(t *testing.T) {
+	apiKey := createRandomAPIKey(t, createRandomUser(t))
+
+	err := testQueries.TouchAPIKey(context.Background(), apiKey.ID)
+	require.NoError(t, err)
+
+	touched, err := testQueries.GetAPIKeyByPrefix(context.Background(), apiKey.Prefix)
+	require.NoError(t, err)
+	require.True(t, touched.LastUsedAt.Valid)
+	require.WithinDuration(t, time.Now(), touched.LastUsedAt.Time, time.Second)
+}
@@ -0,0 +1,68 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetAccountBalanceAt(t *testing.T) {
+	user := createRandomUser(t)
+	account := createRandomAccount(t, user)
+
+	var total int64
+	for range 5 {
+		entry := createRandomEntry(t, account)
+		total += entry.Amount
+	}
+
+	balance, err := testQueries.GetAccountBalanceAt(context.Background(), GetAccountBalanceAtParams{
+		AccountID: account.ID,
+		At:        time.Now().Add(time.Second),
+	})
+	require.NoError(t, err)
+	require.Equal(t, total, balance)
+
+	balance, err = testQueries.GetAccountBalanceAt(context.Background(), GetAccountBalanceAtParams{
+		AccountID: account.ID,
+		At:        account.CreatedAt,
+	})
+	require.NoError(t, err)
+	require.Zero(t, balance)
+}
+
+func TestCreateBalanceSnapshots(t *testing.T) {
+	user := createRandomUser(t)
+	account := createRandomAccount(t, user)
+	total := createRandomEntry(t, account).Amount
+
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	yesterday := today.AddDate(0, 0, -1)
+
+	_, err := testQueries.CreateBalanceSnapshots(context.Background(), yesterday)
+	require.NoError(t, err)
+
+	// The entry was posted today, so yesterday's snapshot does not include it
+	snapshot, err := testQueries.GetLatestBalanceSnapshot(context.Background(), account.ID)
+	require.NoError(t, err)
+	require.True(t, yesterday.Equal(snapshot.SnapshotDate))
+	require.Zero(t, snapshot.Balance)
+
+	// Snapshotting the same day again leaves the existing snapshot untouched
+	_, err = testQueries.CreateBalanceSnapshots(context.Background(), yesterday)
+	require.NoError(t, err)
+
+	for range 3 {
+		entry := createRandomEntry(t, account)
+		total += entry.Amount
+	}
+
+	balance, err := testQueries.GetAccountBalanceAt(context.Background(), GetAccountBalanceAtParams{
+		AccountID: account.ID,
+		At:        time.Now().Add(time.Second),
+	})
+	require.NoError(t, err)
+	require.Equal(t, total, balance)
+}
@@ -0,0 +1,15 @@
+package db
+
+import "context"
+
+// Ping checks that the database can be reached
+func (store *SQLStore) Ping(ctx context.Context) error {
+	return store.db.Ping(ctx)
+}
+
+// MigrationVersion returns the last migration applied by migrate, and
+// whether it failed halfway and left the schema dirty
+func (store *SQLStore) MigrationVersion(ctx context.Context) (version int64, dirty bool, err error) {
+	err = store.db.QueryRow(ctx, "SELECT version, dirty FROM schema_migrations").Scan(&version, &dirty)
+	return
+}
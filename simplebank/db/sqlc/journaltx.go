@@ -0,0 +1,302 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Kinds of journals
+const (
+	JournalKindTransfer   = "transfer"
+	JournalKindFee        = "fee"
+	JournalKindInterest   = "interest"
+	JournalKindReversal   = "reversal"
+	JournalKindAdjustment = "adjustment"
+)
+
+// Types of journal legs. A debit takes money out of an account and a credit
+// puts money into it, so debits are stored as negative entries.
+const (
+	LegTypeDebit  = "debit"
+	LegTypeCredit = "credit"
+)
+
+var (
+	ErrInvalidJournal         = errors.New("invalid journal")
+	ErrJournalUnbalanced      = errors.New("journal debits and credits do not balance")
+	ErrJournalAlreadyReversed = errors.New("journal has already been reversed")
+)
+
+// JournalLeg moves Amount, which must be positive, out of or into a single
+// account. TransferID links the leg to the transfer it is part of, if any.
+type JournalLeg struct {
+	AccountID  int64       `json:"account_id"`
+	Type       string      `json:"type"`
+	Amount     int64       `json:"amount"`
+	TransferID pgtype.Int8 `json:"transfer_id"`
+}
+
+func (leg JournalLeg) signedAmount() int64 {
+	if leg.Type == LegTypeDebit {
+		return -leg.Amount
+	}
+	return leg.Amount
+}
+
+type PostJournalParams struct {
+	Kind              string       `json:"kind"`
+	Description       string       `json:"description"`
+	ReversesJournalID pgtype.Int8  `json:"reverses_journal_id"`
+	Legs              []JournalLeg `json:"legs"`
+}
+
+type PostJournalResult struct {
+	Journal Journal `json:"journal"`
+	// Entries holds one entry per leg, in the order of the legs
+	Entries []Entry `json:"entries"`
+	// Accounts holds every account touched by the journal, ordered by id
+	Accounts []Account `json:"accounts"`
+}
+
+// Account returns the updated account with the given id
+func (result PostJournalResult) Account(id int64) Account {
+	for _, account := range result.Accounts {
+		if account.ID == id {
+			return account
+		}
+	}
+	return Account{}
+}
+
+// FeeJournal charges a fee to an account and credits it to the account that collects fees
+func FeeJournal(accountID, feeAccountID, amount int64, description string) PostJournalParams {
+	return PostJournalParams{
+		Kind:        JournalKindFee,
+		Description: description,
+		Legs: []JournalLeg{
+			{AccountID: accountID, Type: LegTypeDebit, Amount: amount},
+			{AccountID: feeAccountID, Type: LegTypeCredit, Amount: amount},
+		},
+	}
+}
+
+// InterestJournal pays interest into an account out of the account that funds interest
+func InterestJournal(accountID, interestAccountID, amount int64, description string) PostJournalParams {
+	return PostJournalParams{
+		Kind:        JournalKindInterest,
+		Description: description,
+		Legs: []JournalLeg{
+			{AccountID: interestAccountID, Type: LegTypeDebit, Amount: amount},
+			{AccountID: accountID, Type: LegTypeCredit, Amount: amount},
+		},
+	}
+}
+
+// AdjustmentJournal corrects the balance of an account by hand. legType says
+// whether money goes out of (debit) or into (credit) the account; the opposite
+// leg is posted to the contra account, so the ledger stays balanced.
+func AdjustmentJournal(accountID, contraAccountID int64, legType string, amount int64, description string) PostJournalParams {
+	contraType := LegTypeDebit
+	if legType == LegTypeDebit {
+		contraType = LegTypeCredit
+	}
+
+	return PostJournalParams{
+		Kind:        JournalKindAdjustment,
+		Description: description,
+		Legs: []JournalLeg{
+			{AccountID: accountID, Type: legType, Amount: amount},
+			{AccountID: contraAccountID, Type: contraType, Amount: amount},
+		},
+	}
+}
+
+// ValidateJournalLegs checks that a journal has at least two well-formed legs
+// whose debits and credits balance. Per-currency balancing needs the accounts
+// and is checked again when the journal is posted.
+func ValidateJournalLegs(legs []JournalLeg) error {
+	if len(legs) < 2 {
+		return fmt.Errorf("%w: a journal needs at least two legs", ErrInvalidJournal)
+	}
+
+	var total int64
+	for i, leg := range legs {
+		if leg.AccountID <= 0 {
+			return fmt.Errorf("%w: leg %d has no account", ErrInvalidJournal, i)
+		}
+		if leg.Type != LegTypeDebit && leg.Type != LegTypeCredit {
+			return fmt.Errorf("%w: leg %d has unknown type %q", ErrInvalidJournal, i, leg.Type)
+		}
+		if leg.Amount <= 0 {
+			return fmt.Errorf("%w: leg %d amount must be positive", ErrInvalidJournal, i)
+		}
+		total += leg.signedAmount()
+	}
+
+	if total != 0 {
+		return fmt.Errorf("%w: legs sum to %d", ErrJournalUnbalanced, total)
+	}
+
+	return nil
+}
+
+// PostJournal atomically records a journal, one entry per leg and the
+// resulting account balances. It fails without writing anything unless the
+// legs balance within every currency.
+func (store *SQLStore) PostJournal(ctx context.Context, arg PostJournalParams) (PostJournalResult, error) {
+	var result PostJournalResult
+
+	switch arg.Kind {
+	case JournalKindTransfer, JournalKindFee, JournalKindInterest, JournalKindReversal, JournalKindAdjustment:
+	default:
+		return result, fmt.Errorf("%w: unknown kind %q", ErrInvalidJournal, arg.Kind)
+	}
+
+	if err := ValidateJournalLegs(arg.Legs); err != nil {
+		return result, err
+	}
+
+	err := store.execTx(ctx, "PostJournal", func(ctx context.Context, q *Queries) error {
+		var err error
+
+		result.Journal, err = insertJournal(ctx, q, CreateJournalParams{
+			Kind:              arg.Kind,
+			Description:       arg.Description,
+			ReversesJournalID: arg.ReversesJournalID,
+		})
+		if err != nil {
+			return err
+		}
+
+		result.Entries, result.Accounts, err = postJournalLegs(ctx, q, result.Journal.ID, arg.Legs)
+		return err
+	})
+
+	return result, err
+}
+
+type ReverseJournalParams struct {
+	JournalID   int64  `json:"journal_id"`
+	Description string `json:"description"`
+}
+
+// ReverseJournal posts a reversal journal with the opposite of every leg of
+// an existing journal. A journal can only be reversed once.
+func (store *SQLStore) ReverseJournal(ctx context.Context, arg ReverseJournalParams) (PostJournalResult, error) {
+	var result PostJournalResult
+
+	err := store.execTx(ctx, "ReverseJournal", func(ctx context.Context, q *Queries) error {
+		original, err := q.GetJournal(ctx, arg.JournalID)
+		if err != nil {
+			return err
+		}
+
+		entries, err := q.ListEntriesForJournal(ctx, pgtype.Int8{Int64: original.ID, Valid: true})
+		if err != nil {
+			return err
+		}
+
+		// reversal legs are not linked to the original transfer, which keeps
+		// exactly one debit and one credit
+		legs := make([]JournalLeg, len(entries))
+		for i, entry := range entries {
+			legs[i] = JournalLeg{AccountID: entry.AccountID, Type: LegTypeDebit, Amount: entry.Amount}
+			if entry.Amount < 0 {
+				legs[i] = JournalLeg{AccountID: entry.AccountID, Type: LegTypeCredit, Amount: -entry.Amount}
+			}
+		}
+		if err := ValidateJournalLegs(legs); err != nil {
+			return err
+		}
+
+		description := arg.Description
+		if description == "" {
+			description = fmt.Sprintf("reversal of journal %d", original.ID)
+		}
+
+		result.Journal, err = insertJournal(ctx, q, CreateJournalParams{
+			Kind:              JournalKindReversal,
+			Description:       description,
+			ReversesJournalID: pgtype.Int8{Int64: original.ID, Valid: true},
+		})
+		if err != nil {
+			return err
+		}
+
+		result.Entries, result.Accounts, err = postJournalLegs(ctx, q, result.Journal.ID, legs)
+		return err
+	})
+
+	return result, err
+}
+
+func insertJournal(ctx context.Context, q *Queries, arg CreateJournalParams) (Journal, error) {
+	journal, err := q.CreateJournal(ctx, arg)
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" && arg.ReversesJournalID.Valid {
+		return journal, ErrJournalAlreadyReversed
+	}
+
+	return journal, err
+}
+
+// postJournalLegs writes the entries of a journal and applies them to the
+// account balances. Balances are updated in ascending account id order so
+// concurrent journals touching the same accounts cannot deadlock.
+func postJournalLegs(ctx context.Context, q *Queries, journalID int64, legs []JournalLeg) ([]Entry, []Account, error) {
+	entries := make([]Entry, len(legs))
+	changes := make(map[int64]int64)
+
+	for i, leg := range legs {
+		var err error
+
+		entries[i], err = q.CreateEntry(ctx, CreateEntryParams{
+			AccountID:  leg.AccountID,
+			Amount:     leg.signedAmount(),
+			TransferID: leg.TransferID,
+			JournalID:  pgtype.Int8{Int64: journalID, Valid: true},
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+
+		changes[leg.AccountID] += leg.signedAmount()
+	}
+
+	accountIDs := make([]int64, 0, len(changes))
+	for id := range changes {
+		accountIDs = append(accountIDs, id)
+	}
+	sort.Slice(accountIDs, func(i, j int) bool { return accountIDs[i] < accountIDs[j] })
+
+	accounts := make([]Account, len(accountIDs))
+	totals := make(map[string]int64)
+
+	for i, id := range accountIDs {
+		var err error
+
+		accounts[i], err = q.AddAccountBalance(ctx, AddAccountBalanceParams{
+			ID:     id,
+			Amount: changes[id],
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+
+		totals[accounts[i].Currency] += changes[id]
+	}
+
+	for currency, total := range totals {
+		if total != 0 {
+			return nil, nil, fmt.Errorf("%w: %s legs sum to %d", ErrJournalUnbalanced, currency, total)
+		}
+	}
+
+	return entries, accounts, nil
+}
@@ -0,0 +1,207 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/util"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomAccountInCurrency(t *testing.T, currency string) Account {
+	return createAccountFromArg(t, CreateAccountParams{
+		Owner:         createRandomUser(t).Username,
+		Balance:       util.RandomMoney(),
+		Currency:      currency,
+		AccountNumber: util.RandomAccountNumber(),
+	})
+}
+
+func TestValidateJournalLegs(t *testing.T) {
+	testCases := []struct {
+		name string
+		legs []JournalLeg
+		err  error
+	}{
+		{
+			name: "OK",
+			legs: []JournalLeg{
+				{AccountID: 1, Type: LegTypeDebit, Amount: 15},
+				{AccountID: 2, Type: LegTypeCredit, Amount: 10},
+				{AccountID: 3, Type: LegTypeCredit, Amount: 5},
+			},
+		},
+		{
+			name: "SingleLeg",
+			legs: []JournalLeg{{AccountID: 1, Type: LegTypeDebit, Amount: 10}},
+			err:  ErrInvalidJournal,
+		},
+		{
+			name: "UnknownType",
+			legs: []JournalLeg{
+				{AccountID: 1, Type: "withdrawal", Amount: 10},
+				{AccountID: 2, Type: LegTypeCredit, Amount: 10},
+			},
+			err: ErrInvalidJournal,
+		},
+		{
+			name: "NonPositiveAmount",
+			legs: []JournalLeg{
+				{AccountID: 1, Type: LegTypeDebit, Amount: 0},
+				{AccountID: 2, Type: LegTypeCredit, Amount: 0},
+			},
+			err: ErrInvalidJournal,
+		},
+		{
+			name: "Unbalanced",
+			legs: []JournalLeg{
+				{AccountID: 1, Type: LegTypeDebit, Amount: 10},
+				{AccountID: 2, Type: LegTypeCredit, Amount: 9},
+			},
+			err: ErrJournalUnbalanced,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := ValidateJournalLegs(tc.legs)
+			if tc.err == nil {
+				require.NoError(t, err)
+			} else {
+				require.ErrorIs(t, err, tc.err)
+			}
+		})
+	}
+}
+
+func TestPostJournal(t *testing.T) {
+	store := NewStore(testDB)
+
+	account := createRandomAccountInCurrency(t, util.USD)
+	feeAccount := createRandomAccountInCurrency(t, util.USD)
+
+	result, err := store.PostJournal(context.Background(), FeeJournal(account.ID, feeAccount.ID, 7, "monthly fee"))
+	require.NoError(t, err)
+	require.Equal(t, JournalKindFee, result.Journal.Kind)
+	require.Equal(t, "monthly fee", result.Journal.Description)
+
+	require.Len(t, result.Entries, 2)
+	require.Equal(t, int64(-7), result.Entries[0].Amount)
+	require.Equal(t, LegTypeDebit, result.Entries[0].LegType)
+	require.Equal(t, int64(7), result.Entries[1].Amount)
+	require.Equal(t, LegTypeCredit, result.Entries[1].LegType)
+	for _, entry := range result.Entries {
+		require.Equal(t, result.Journal.ID, entry.JournalID.Int64)
+		require.False(t, entry.TransferID.Valid)
+	}
+
+	require.Equal(t, account.Balance-7, result.Account(account.ID).Balance)
+	require.Equal(t, feeAccount.Balance+7, result.Account(feeAccount.ID).Balance)
+}
+
+func TestPostJournalMixedCurrencies(t *testing.T) {
+	store := NewStore(testDB)
+
+	usdAccount := createRandomAccountInCurrency(t, util.USD)
+	eurAccount := createRandomAccountInCurrency(t, util.EUR)
+
+	_, err := store.PostJournal(context.Background(), InterestJournal(usdAccount.ID, eurAccount.ID, 10, "interest"))
+	require.ErrorIs(t, err, ErrJournalUnbalanced)
+
+	// nothing was written
+	updated, err := store.GetAccount(context.Background(), usdAccount.ID)
+	require.NoError(t, err)
+	require.Equal(t, usdAccount.Balance, updated.Balance)
+}
+
+func TestPostJournalAdjustment(t *testing.T) {
+	store := NewStore(testDB)
+
+	account := createRandomAccountInCurrency(t, util.USD)
+	suspenseAccount := createRandomAccountInCurrency(t, util.USD)
+
+	result, err := store.PostJournal(context.Background(), AdjustmentJournal(account.ID, suspenseAccount.ID, LegTypeCredit, 25, "refund"))
+	require.NoError(t, err)
+	require.Equal(t, JournalKindAdjustment, result.Journal.Kind)
+	require.Equal(t, "refund", result.Journal.Description)
+
+	require.Equal(t, account.Balance+25, result.Account(account.ID).Balance)
+	require.Equal(t, suspenseAccount.Balance-25, result.Account(suspenseAccount.ID).Balance)
+}
+
+func TestTransferTxFrozenAccount(t *testing.T) {
+	store := NewStore(testDB)
+
+	account1 := createRandomAccountInCurrency(t, util.USD)
+	account2 := createRandomAccountInCurrency(t, util.USD)
+
+	_, err := store.FreezeAccount(context.Background(), account2.ID)
+	require.NoError(t, err)
+
+	_, err = store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        10,
+	})
+	require.ErrorIs(t, err, ErrAccountFrozen)
+
+	// nothing was written
+	updated, err := store.GetAccount(context.Background(), account1.ID)
+	require.NoError(t, err)
+	require.Equal(t, account1.Balance, updated.Balance)
+}
+
+func TestTransferTxWithFee(t *testing.T) {
+	store := NewStore(testDB)
+
+	account1 := createRandomAccountInCurrency(t, util.USD)
+	account2 := createRandomAccountInCurrency(t, util.USD)
+	feeAccount := createRandomAccountInCurrency(t, util.USD)
+
+	result, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        10,
+		Fee:           2,
+		FeeAccountID:  feeAccount.ID,
+	})
+	require.NoError(t, err)
+	require.Equal(t, JournalKindTransfer, result.Journal.Kind)
+	require.Equal(t, result.Journal.ID, result.Transfer.JournalID.Int64)
+	require.NotNil(t, result.FeeEntry)
+	require.Equal(t, int64(-2), result.FeeEntry.Amount)
+	require.False(t, result.FeeEntry.TransferID.Valid)
+
+	require.Equal(t, account1.Balance-12, result.FromAccount.Balance)
+	require.Equal(t, account2.Balance+10, result.ToAccount.Balance)
+
+	entries, err := store.ListEntriesForJournal(context.Background(), pgtype.Int8{Int64: result.Journal.ID, Valid: true})
+	require.NoError(t, err)
+	require.Len(t, entries, 4)
+}
+
+func TestReverseJournal(t *testing.T) {
+	store := NewStore(testDB)
+
+	account1 := createRandomAccountInCurrency(t, util.USD)
+	account2 := createRandomAccountInCurrency(t, util.USD)
+
+	transfer, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        10,
+	})
+	require.NoError(t, err)
+
+	reversal, err := store.ReverseJournal(context.Background(), ReverseJournalParams{JournalID: transfer.Journal.ID})
+	require.NoError(t, err)
+	require.Equal(t, JournalKindReversal, reversal.Journal.Kind)
+	require.Equal(t, transfer.Journal.ID, reversal.Journal.ReversesJournalID.Int64)
+	require.Len(t, reversal.Entries, 2)
+	require.Equal(t, account1.Balance, reversal.Account(account1.ID).Balance)
+	require.Equal(t, account2.Balance, reversal.Account(account2.ID).Balance)
+
+	_, err = store.ReverseJournal(context.Background(), ReverseJournalParams{JournalID: transfer.Journal.ID})
+	require.ErrorIs(t, err, ErrJournalAlreadyReversed)
+}
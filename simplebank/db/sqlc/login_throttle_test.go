@@ -0,0 +1,94 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/util"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecordLoginFailure(t *testing.T) {
+	username := util.RandomOwner()
+
+	arg := RecordLoginFailureParams{
+		Kind:        LoginThrottleKindUsername,
+		Subject:     username,
+		ResetBefore: time.Now().Add(-time.Hour),
+	}
+
+	for i := int32(1); i <= 3; i++ {
+		throttle, err := testQueries.RecordLoginFailure(context.Background(), arg)
+		require.NoError(t, err)
+		require.Equal(t, i, throttle.FailedAttempts)
+		require.False(t, throttle.LockedUntil.Valid)
+		require.WithinDuration(t, time.Now(), throttle.LastFailedAt, time.Second)
+	}
+
+	// failures older than reset_before no longer count
+	arg.ResetBefore = time.Now().Add(time.Second)
+	throttle, err := testQueries.RecordLoginFailure(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, int32(1), throttle.FailedAttempts)
+}
+
+func TestGetLoginLockedUntil(t *testing.T) {
+	username := util.RandomOwner()
+	ip := fmt.Sprintf("2001:db8::%x", util.RandomInt(0, 1<<30))
+
+	params := GetLoginLockedUntilParams{
+		Username: username,
+		Ip:       ip,
+	}
+
+	lockedUntil, err := testQueries.GetLoginLockedUntil(context.Background(), params)
+	require.NoError(t, err)
+	require.False(t, lockedUntil.Valid)
+
+	userLock := time.Now().Add(time.Minute)
+	ipLock := time.Now().Add(time.Hour)
+
+	for _, lock := range []struct {
+		kind    string
+		subject string
+		until   time.Time
+	}{
+		{LoginThrottleKindUsername, username, userLock},
+		{LoginThrottleKindIP, ip, ipLock},
+	} {
+		_, err := testQueries.RecordLoginFailure(context.Background(), RecordLoginFailureParams{
+			Kind:        lock.kind,
+			Subject:     lock.subject,
+			ResetBefore: time.Now().Add(-time.Hour),
+		})
+		require.NoError(t, err)
+
+		throttle, err := testQueries.LockLogin(context.Background(), LockLoginParams{
+			Kind:        lock.kind,
+			Subject:     lock.subject,
+			LockedUntil: pgtype.Timestamptz{Time: lock.until, Valid: true},
+		})
+		require.NoError(t, err)
+		require.WithinDuration(t, lock.until, throttle.LockedUntil.Time, time.Millisecond)
+	}
+
+	// the later of the two locks applies
+	lockedUntil, err = testQueries.GetLoginLockedUntil(context.Background(), params)
+	require.NoError(t, err)
+	require.True(t, lockedUntil.Valid)
+	require.WithinDuration(t, ipLock, lockedUntil.Time, time.Millisecond)
+
+	deleted, err := testQueries.DeleteLoginThrottle(context.Background(), DeleteLoginThrottleParams{
+		Kind:    LoginThrottleKindIP,
+		Subject: ip,
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(1), deleted)
+
+	lockedUntil, err = testQueries.GetLoginLockedUntil(context.Background(), params)
+	require.NoError(t, err)
+	require.WithinDuration(t, userLock, lockedUntil.Time, time.Millisecond)
+}
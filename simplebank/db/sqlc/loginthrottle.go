@@ -0,0 +1,7 @@
+package db
+
+// Kinds of login throttles. GetLoginLockedUntil relies on these values.
+const (
+	LoginThrottleKindUsername = "username"
+	LoginThrottleKindIP       = "ip"
+)
@@ -6,6 +6,7 @@ import (
 	"os"
 	"testing"
 
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/db/migration"
 	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/util"
 	"github.com/jackc/pgx/v5/pgxpool"
 	_ "github.com/lib/pq"
@@ -27,6 +28,15 @@ func TestMain(m *testing.M) {
 		log.Fatal("cannot connect to db:", err)
 	}
 
+	migrator, err := migration.New(testDB)
+	if err != nil {
+		log.Fatal("cannot create migrator:", err)
+	}
+	if err := migrator.Up(); err != nil {
+		log.Fatal("cannot migrate db:", err)
+	}
+	migrator.Close()
+
 	testQueries = New(testDB)
 
 	os.Exit(m.Run())
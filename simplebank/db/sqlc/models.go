@@ -6,22 +6,156 @@ package db
 
 import (
 	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
 type Account struct {
-	ID        int64     `json:"id"`
-	Owner     string    `json:"owner"`
+	ID            int64     `json:"id"`
+	Owner         string    `json:"owner"`
+	Balance       int64     `json:"balance"`
+	Currency      string    `json:"currency"`
+	CreatedAt     time.Time `json:"created_at"`
+	AccountNumber string    `json:"account_number"`
+	// set while the account is frozen, which blocks transfers from and to it
+	FrozenAt pgtype.Timestamptz `json:"frozen_at"`
+}
+
+type AccountBalanceSnapshot struct {
+	AccountID    int64     `json:"account_id"`
+	SnapshotDate time.Time `json:"snapshot_date"`
+	// balance at the end of snapshot_date (UTC)
 	Balance   int64     `json:"balance"`
-	Currency  string    `json:"currency"`
 	CreatedAt time.Time `json:"created_at"`
 }
 
+type ApiKey struct {
+	ID       uuid.UUID `json:"id"`
+	Username string    `json:"username"`
+	Name     string    `json:"name"`
+	// public part of the key, used to look it up
+	Prefix string `json:"prefix"`
+	// sha-256 of the secret part of the key
+	HashedSecret string             `json:"hashed_secret"`
+	Scopes       []string           `json:"scopes"`
+	ExpiredAt    time.Time          `json:"expired_at"`
+	LastUsedAt   pgtype.Timestamptz `json:"last_used_at"`
+	RevokedAt    pgtype.Timestamptz `json:"revoked_at"`
+	CreatedAt    time.Time          `json:"created_at"`
+}
+
 type Entry struct {
 	ID        int64 `json:"id"`
 	AccountID int64 `json:"account_id"`
 	// can be negative or positive
 	Amount    int64     `json:"amount"`
 	CreatedAt time.Time `json:"created_at"`
+	// null for entries not created by a transfer
+	TransferID pgtype.Int8 `json:"transfer_id"`
+	// null for entries posted outside of a journal
+	JournalID pgtype.Int8 `json:"journal_id"`
+	// debit for negative amounts, credit otherwise
+	LegType string `json:"leg_type"`
+}
+
+type Journal struct {
+	ID int64 `json:"id"`
+	// transfer, fee, interest, reversal or adjustment
+	Kind        string `json:"kind"`
+	Description string `json:"description"`
+	// set on reversals, a journal can only be reversed once
+	ReversesJournalID pgtype.Int8 `json:"reverses_journal_id"`
+	CreatedAt         time.Time   `json:"created_at"`
+}
+
+type LoginThrottle struct {
+	// username or ip
+	Kind string `json:"kind"`
+	// the username or client ip failing to log in
+	Subject        string             `json:"subject"`
+	FailedAttempts int32              `json:"failed_attempts"`
+	LastFailedAt   time.Time          `json:"last_failed_at"`
+	LockedUntil    pgtype.Timestamptz `json:"locked_until"`
+}
+
+type OauthAuthorizationCode struct {
+	HashedCode  string   `json:"hashed_code"`
+	ClientID    string   `json:"client_id"`
+	Username    string   `json:"username"`
+	RedirectUri string   `json:"redirect_uri"`
+	Scopes      []string `json:"scopes"`
+	// S256 PKCE code challenge
+	CodeChallenge string    `json:"code_challenge"`
+	IsUsed        bool      `json:"is_used"`
+	CreatedAt     time.Time `json:"created_at"`
+	ExpiredAt     time.Time `json:"expired_at"`
+}
+
+type OauthClient struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	// sha-256 of the client secret, empty for public clients
+	HashedSecret string   `json:"hashed_secret"`
+	RedirectUris []string `json:"redirect_uris"`
+	// the most a user can grant the client
+	Scopes    []string  `json:"scopes"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type OauthConsent struct {
+	Username  string    `json:"username"`
+	ClientID  string    `json:"client_id"`
+	Scopes    []string  `json:"scopes"`
+	GrantedAt time.Time `json:"granted_at"`
+}
+
+type PasswordReset struct {
+	// id of the signed reset token payload
+	ID        uuid.UUID `json:"id"`
+	Username  string    `json:"username"`
+	IsUsed    bool      `json:"is_used"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiredAt time.Time `json:"expired_at"`
+}
+
+type RateLimitBucket struct {
+	// the route group and the client ip or username being limited
+	Key string `json:"key"`
+	// requests left at updated_at, refilled at the rate of the limit
+	Tokens    float64   `json:"tokens"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type RecoveryCode struct {
+	ID         int64              `json:"id"`
+	Username   string             `json:"username"`
+	HashedCode string             `json:"hashed_code"`
+	UsedAt     pgtype.Timestamptz `json:"used_at"`
+	CreatedAt  time.Time          `json:"created_at"`
+}
+
+type SsoIdentity struct {
+	// the OpenID Connect provider
+	Issuer string `json:"issuer"`
+	// the sub claim, stable for the user at the provider
+	Subject     string    `json:"subject"`
+	Username    string    `json:"username"`
+	CreatedAt   time.Time `json:"created_at"`
+	LastLoginAt time.Time `json:"last_login_at"`
+	// set when the identity created the user, whose role then follows the provider groups
+	ManagesRole bool `json:"manages_role"`
+}
+
+type Statement struct {
+	ID          int64     `json:"id"`
+	AccountID   int64     `json:"account_id"`
+	PeriodStart time.Time `json:"period_start"`
+	// exclusive
+	PeriodEnd time.Time `json:"period_end"`
+	// the statement with its lines, rendered into each format on download
+	Content   []byte    `json:"content"`
+	CreatedAt time.Time `json:"created_at"`
 }
 
 type Transfer struct {
@@ -29,8 +163,9 @@ type Transfer struct {
 	FromAccountID int64 `json:"from_account_id"`
 	ToAccountID   int64 `json:"to_account_id"`
 	// must be positive
-	Amount    int64     `json:"amount"`
-	CreatedAt time.Time `json:"created_at"`
+	Amount    int64       `json:"amount"`
+	CreatedAt time.Time   `json:"created_at"`
+	JournalID pgtype.Int8 `json:"journal_id"`
 }
 
 type User struct {
@@ -40,4 +175,22 @@ type User struct {
 	Email             string    `json:"email"`
 	PasswordChangedAt time.Time `json:"password_changed_at"`
 	CreatedAt         time.Time `json:"created_at"`
+	IsEmailVerified   bool      `json:"is_email_verified"`
+	// base32 secret, pending until is_totp_enabled
+	TotpSecret    string `json:"totp_secret"`
+	IsTotpEnabled bool   `json:"is_totp_enabled"`
+	// time step of the last accepted code, to stop replays
+	TotpLastStep int64  `json:"totp_last_step"`
+	Role         string `json:"role"`
+}
+
+type VerifyEmail struct {
+	ID       int64  `json:"id"`
+	Username string `json:"username"`
+	// the address the code was sent to
+	Email      string    `json:"email"`
+	SecretCode string    `json:"secret_code"`
+	IsUsed     bool      `json:"is_used"`
+	CreatedAt  time.Time `json:"created_at"`
+	ExpiredAt  time.Time `json:"expired_at"`
 }
@@ -0,0 +1,177 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/util"
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomOAuthClient(t *testing.T) OauthClient {
+	arg := CreateOAuthClientParams{
+		ID:           util.RandomString(32),
+		Name:         util.RandomString(8),
+		HashedSecret: util.RandomString(64),
+		RedirectUris: []string{"https://partner.example.com/callback"},
+		Scopes:       []string{"accounts:read", "transfers:write"},
+	}
+
+	client, err := testQueries.CreateOAuthClient(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, arg.ID, client.ID)
+	require.Equal(t, arg.Name, client.Name)
+	require.Equal(t, arg.HashedSecret, client.HashedSecret)
+	require.Equal(t, arg.RedirectUris, client.RedirectUris)
+	require.Equal(t, arg.Scopes, client.Scopes)
+	require.NotZero(t, client.CreatedAt)
+
+	return client
+}
+
+func authorizeRandomOAuth(t *testing.T, user User, client OauthClient, scopes ...string) AuthorizeOAuthTxResult {
+	arg := AuthorizeOAuthTxParams{
+		Username:      user.Username,
+		ClientID:      client.ID,
+		Scopes:        scopes,
+		HashedCode:    util.RandomString(64),
+		RedirectURI:   client.RedirectUris[0],
+		CodeChallenge: util.RandomString(43),
+		ExpiredAt:     time.Now().Add(time.Minute),
+	}
+
+	store := NewStore(testDB)
+
+	result, err := store.AuthorizeOAuthTx(context.Background(), arg)
+	require.NoError(t, err)
+
+	require.Equal(t, arg.Username, result.Consent.Username)
+	require.Equal(t, arg.ClientID, result.Consent.ClientID)
+	require.Equal(t, arg.Scopes, result.Consent.Scopes)
+
+	code := result.AuthorizationCode
+	require.Equal(t, arg.HashedCode, code.HashedCode)
+	require.Equal(t, arg.ClientID, code.ClientID)
+	require.Equal(t, arg.Username, code.Username)
+	require.Equal(t, arg.RedirectURI, code.RedirectUri)
+	require.Equal(t, arg.Scopes, code.Scopes)
+	require.Equal(t, arg.CodeChallenge, code.CodeChallenge)
+	require.False(t, code.IsUsed)
+	require.WithinDuration(t, arg.ExpiredAt, code.ExpiredAt, time.Second)
+	require.False(t, code.CreatedAt.Before(result.Consent.GrantedAt))
+
+	return result
+}
+
+func TestGetOAuthClient(t *testing.T) {
+	client1 := createRandomOAuthClient(t)
+
+	client2, err := testQueries.GetOAuthClient(context.Background(), client1.ID)
+	require.NoError(t, err)
+	require.Equal(t, client1.ID, client2.ID)
+	require.Equal(t, client1.HashedSecret, client2.HashedSecret)
+	require.Equal(t, client1.RedirectUris, client2.RedirectUris)
+
+	_, err = testQueries.GetOAuthClient(context.Background(), util.RandomString(32))
+	require.ErrorIs(t, err, pgx.ErrNoRows)
+}
+
+func TestAuthorizeOAuthTxKeepsGrantTime(t *testing.T) {
+	user := createRandomUser(t)
+	client := createRandomOAuthClient(t)
+
+	result1 := authorizeRandomOAuth(t, user, client, "transfers:write", "accounts:read")
+	result2 := authorizeRandomOAuth(t, user, client, "accounts:read")
+	result3 := authorizeRandomOAuth(t, user, client, "accounts:write")
+
+	// a narrower request keeps the scopes of the first grant, so tokens
+	// issued under it remain valid, and a different one adds its scopes
+	require.Equal(t, []string{"accounts:read", "transfers:write"}, result2.Consent.Scopes)
+	require.Equal(t, []string{"accounts:read", "accounts:write", "transfers:write"}, result3.Consent.Scopes)
+	require.WithinDuration(t, result1.Consent.GrantedAt, result3.Consent.GrantedAt, 0)
+
+	consent, err := testQueries.GetOAuthConsent(context.Background(), GetOAuthConsentParams{
+		Username: user.Username,
+		ClientID: client.ID,
+	})
+	require.NoError(t, err)
+	require.Equal(t, result3.Consent.Scopes, consent.Scopes)
+}
+
+func TestUseOAuthAuthorizationCode(t *testing.T) {
+	result := authorizeRandomOAuth(t, createRandomUser(t), createRandomOAuthClient(t), "accounts:read")
+	hashedCode := result.AuthorizationCode.HashedCode
+
+	code, err := testQueries.UseOAuthAuthorizationCode(context.Background(), hashedCode)
+	require.NoError(t, err)
+	require.Equal(t, hashedCode, code.HashedCode)
+	require.True(t, code.IsUsed)
+
+	// codes can only be used once
+	_, err = testQueries.UseOAuthAuthorizationCode(context.Background(), hashedCode)
+	require.ErrorIs(t, err, pgx.ErrNoRows)
+}
+
+func TestUseExpiredOAuthAuthorizationCode(t *testing.T) {
+	user := createRandomUser(t)
+	client := createRandomOAuthClient(t)
+
+	code, err := testQueries.CreateOAuthAuthorizationCode(context.Background(), CreateOAuthAuthorizationCodeParams{
+		HashedCode:    util.RandomString(64),
+		ClientID:      client.ID,
+		Username:      user.Username,
+		RedirectUri:   client.RedirectUris[0],
+		Scopes:        client.Scopes,
+		CodeChallenge: util.RandomString(43),
+		ExpiredAt:     time.Now().Add(-time.Second),
+	})
+	require.NoError(t, err)
+
+	_, err = testQueries.UseOAuthAuthorizationCode(context.Background(), code.HashedCode)
+	require.ErrorIs(t, err, pgx.ErrNoRows)
+}
+
+func TestListOAuthConsents(t *testing.T) {
+	user := createRandomUser(t)
+	clients := []OauthClient{createRandomOAuthClient(t), createRandomOAuthClient(t)}
+	for _, client := range clients {
+		authorizeRandomOAuth(t, user, client, "accounts:read")
+	}
+	authorizeRandomOAuth(t, createRandomUser(t), clients[0], "accounts:read")
+
+	consents, err := testQueries.ListOAuthConsents(context.Background(), user.Username)
+	require.NoError(t, err)
+	require.Len(t, consents, 2)
+
+	for i, consent := range consents {
+		require.Equal(t, user.Username, consent.Username)
+		require.NotEmpty(t, consent.ClientName)
+		if i > 0 {
+			require.False(t, consent.GrantedAt.After(consents[i-1].GrantedAt))
+		}
+	}
+}
+
+func TestDeleteOAuthConsent(t *testing.T) {
+	user := createRandomUser(t)
+	client := createRandomOAuthClient(t)
+	authorizeRandomOAuth(t, user, client, "accounts:read")
+
+	arg := DeleteOAuthConsentParams{
+		Username: user.Username,
+		ClientID: client.ID,
+	}
+
+	deleted, err := testQueries.DeleteOAuthConsent(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), deleted)
+
+	deleted, err = testQueries.DeleteOAuthConsent(context.Background(), arg)
+	require.NoError(t, err)
+	require.Zero(t, deleted)
+
+	_, err = testQueries.GetOAuthConsent(context.Background(), GetOAuthConsentParams(arg))
+	require.ErrorIs(t, err, pgx.ErrNoRows)
+}
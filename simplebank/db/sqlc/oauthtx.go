@@ -0,0 +1,55 @@
+package db
+
+import (
+	"context"
+	"time"
+)
+
+type AuthorizeOAuthTxParams struct {
+	Username    string   `json:"username"`
+	ClientID    string   `json:"client_id"`
+	Scopes      []string `json:"scopes"`
+	HashedCode  string   `json:"hashed_code"`
+	RedirectURI string   `json:"redirect_uri"`
+	// CodeChallenge is the S256 PKCE challenge the code verifier must match
+	CodeChallenge string    `json:"code_challenge"`
+	ExpiredAt     time.Time `json:"expired_at"`
+}
+
+type AuthorizeOAuthTxResult struct {
+	Consent           OauthConsent           `json:"consent"`
+	AuthorizationCode OauthAuthorizationCode `json:"authorization_code"`
+}
+
+// AuthorizeOAuthTx records the user's consent to the client and creates an
+// authorization code for it. Both get the same creation time, so a code is
+// never older than the consent it was issued under.
+func (store *SQLStore) AuthorizeOAuthTx(ctx context.Context, arg AuthorizeOAuthTxParams) (AuthorizeOAuthTxResult, error) {
+	var result AuthorizeOAuthTxResult
+
+	err := store.execTx(ctx, "AuthorizeOAuthTx", func(ctx context.Context, q *Queries) error {
+		var err error
+
+		result.Consent, err = q.UpsertOAuthConsent(ctx, UpsertOAuthConsentParams{
+			Username: arg.Username,
+			ClientID: arg.ClientID,
+			Scopes:   arg.Scopes,
+		})
+		if err != nil {
+			return err
+		}
+
+		result.AuthorizationCode, err = q.CreateOAuthAuthorizationCode(ctx, CreateOAuthAuthorizationCodeParams{
+			HashedCode:    arg.HashedCode,
+			ClientID:      arg.ClientID,
+			Username:      arg.Username,
+			RedirectUri:   arg.RedirectURI,
+			Scopes:        arg.Scopes,
+			CodeChallenge: arg.CodeChallenge,
+			ExpiredAt:     arg.ExpiredAt,
+		})
+		return err
+	})
+
+	return result, err
+}
@@ -0,0 +1,105 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/util"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomPasswordReset(t *testing.T, user User, expiredAt time.Time) PasswordReset {
+	arg := CreatePasswordResetParams{
+		ID:        uuid.New(),
+		Username:  user.Username,
+		ExpiredAt: expiredAt,
+	}
+
+	reset, err := testQueries.CreatePasswordReset(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, arg.ID, reset.ID)
+	require.Equal(t, arg.Username, reset.Username)
+	require.False(t, reset.IsUsed)
+	require.NotZero(t, reset.CreatedAt)
+
+	return reset
+}
+
+func TestResetPasswordTx(t *testing.T) {
+	store := NewStore(testDB)
+
+	user := createRandomUser(t)
+	reset := createRandomPasswordReset(t, user, time.Now().Add(time.Hour))
+
+	hashedPassword, err := util.HashPassword(util.RandomString(8))
+	require.NoError(t, err)
+
+	arg := ResetPasswordTxParams{
+		ResetID:        reset.ID,
+		Username:       user.Username,
+		HashedPassword: hashedPassword,
+	}
+
+	updated, err := store.ResetPasswordTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, hashedPassword, updated.HashedPassword)
+	require.True(t, updated.PasswordChangedAt.After(user.PasswordChangedAt))
+
+	// resets are single-use
+	_, err = store.ResetPasswordTx(context.Background(), arg)
+	require.ErrorIs(t, err, pgx.ErrNoRows)
+}
+
+func TestResetPasswordTxExpired(t *testing.T) {
+	store := NewStore(testDB)
+
+	user := createRandomUser(t)
+	reset := createRandomPasswordReset(t, user, time.Now().Add(-time.Minute))
+
+	_, err := store.ResetPasswordTx(context.Background(), ResetPasswordTxParams{
+		ResetID:        reset.ID,
+		Username:       user.Username,
+		HashedPassword: user.HashedPassword,
+	})
+	require.ErrorIs(t, err, pgx.ErrNoRows)
+}
+
+func TestResetPasswordTxPasswordChanged(t *testing.T) {
+	store := NewStore(testDB)
+
+	user := createRandomUser(t)
+	reset := createRandomPasswordReset(t, user, time.Now().Add(time.Hour))
+
+	_, err := testQueries.UpdateUser(context.Background(), UpdateUserParams{
+		Username:          user.Username,
+		PasswordChangedAt: pgtype.Timestamptz{Time: time.Now(), Valid: true},
+	})
+	require.NoError(t, err)
+
+	// a reset requested before the password changed cannot be used
+	_, err = store.ResetPasswordTx(context.Background(), ResetPasswordTxParams{
+		ResetID:        reset.ID,
+		Username:       user.Username,
+		HashedPassword: user.HashedPassword,
+	})
+	require.ErrorIs(t, err, pgx.ErrNoRows)
+}
+
+func TestResetPasswordTxWrongUser(t *testing.T) {
+	store := NewStore(testDB)
+
+	user := createRandomUser(t)
+	other := createRandomUser(t)
+	reset := createRandomPasswordReset(t, user, time.Now().Add(time.Hour))
+
+	_, err := store.ResetPasswordTx(context.Background(), ResetPasswordTxParams{
+		ResetID:        reset.ID,
+		Username:       other.Username,
+		HashedPassword: other.HashedPassword,
+	})
+	require.ErrorIs(t, err, pgx.ErrNoRows)
+}
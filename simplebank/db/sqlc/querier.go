@@ -6,30 +6,106 @@ package db
 
 import (
 	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
 type Querier interface {
 	AddAccountBalance(ctx context.Context, arg AddAccountBalanceParams) (Account, error)
+	CreateAPIKey(ctx context.Context, arg CreateAPIKeyParams) (ApiKey, error)
 	CreateAccount(ctx context.Context, arg CreateAccountParams) (Account, error)
+	// Snapshots every account at the end of the given UTC day, starting from each
+	// account's latest earlier snapshot so only that day's entries are summed. The
+	// day must be over long enough for every entry dated in it to have committed:
+	// existing snapshots are kept as they are.
+	CreateBalanceSnapshots(ctx context.Context, snapshotDate time.Time) (int64, error)
 	CreateEntry(ctx context.Context, arg CreateEntryParams) (Entry, error)
+	CreateJournal(ctx context.Context, arg CreateJournalParams) (Journal, error)
+	CreateOAuthAuthorizationCode(ctx context.Context, arg CreateOAuthAuthorizationCodeParams) (OauthAuthorizationCode, error)
+	CreateOAuthClient(ctx context.Context, arg CreateOAuthClientParams) (OauthClient, error)
+	CreatePasswordReset(ctx context.Context, arg CreatePasswordResetParams) (PasswordReset, error)
+	CreateRecoveryCode(ctx context.Context, arg CreateRecoveryCodeParams) (RecoveryCode, error)
+	CreateSSOIdentity(ctx context.Context, arg CreateSSOIdentityParams) (SsoIdentity, error)
+	// Statements are only created for periods that are over, so an existing one
+	// is kept as it is.
+	CreateStatement(ctx context.Context, arg CreateStatementParams) (int64, error)
 	CreateTransfer(ctx context.Context, arg CreateTransferParams) (Transfer, error)
 	CreateUser(ctx context.Context, arg CreateUserParams) (User, error)
+	CreateVerifyEmail(ctx context.Context, arg CreateVerifyEmailParams) (VerifyEmail, error)
 	DeleteAccount(ctx context.Context, id int64) error
+	DeleteLoginThrottle(ctx context.Context, arg DeleteLoginThrottleParams) (int64, error)
+	DeleteOAuthConsent(ctx context.Context, arg DeleteOAuthConsentParams) (int64, error)
+	DeleteRateLimitBuckets(ctx context.Context, updatedAt time.Time) (int64, error)
+	DeleteRecoveryCodes(ctx context.Context, username string) error
 	DeleteUser(ctx context.Context, username string) error
+	DisableUserTOTP(ctx context.Context, username string) (User, error)
+	// Only succeeds for the pending secret the confirmation code was checked against.
+	EnableUserTOTP(ctx context.Context, arg EnableUserTOTPParams) (User, error)
+	// Keeps the time the account was first frozen when it already is.
+	FreezeAccount(ctx context.Context, id int64) (Account, error)
+	GetAPIKeyByPrefix(ctx context.Context, prefix string) (ApiKey, error)
 	GetAccount(ctx context.Context, id int64) (Account, error)
+	// Computes the balance just before the given instant from the latest snapshot
+	// that ends at or before it plus the entries posted since.
+	GetAccountBalanceAt(ctx context.Context, arg GetAccountBalanceAtParams) (int64, error)
+	GetAccountByNumber(ctx context.Context, accountNumber string) (Account, error)
 	GetEntry(ctx context.Context, id int64) (Entry, error)
+	GetJournal(ctx context.Context, id int64) (Journal, error)
+	GetLatestBalanceSnapshot(ctx context.Context, accountID int64) (AccountBalanceSnapshot, error)
+	// The latest lock on either the username or the client ip, null when neither is locked.
+	GetLoginLockedUntil(ctx context.Context, arg GetLoginLockedUntilParams) (pgtype.Timestamptz, error)
+	GetOAuthClient(ctx context.Context, id string) (OauthClient, error)
+	GetOAuthConsent(ctx context.Context, arg GetOAuthConsentParams) (OauthConsent, error)
+	GetRateLimitBucket(ctx context.Context, key string) (RateLimitBucket, error)
+	GetSSOIdentity(ctx context.Context, arg GetSSOIdentityParams) (SsoIdentity, error)
+	GetStatement(ctx context.Context, arg GetStatementParams) (Statement, error)
 	GetTransfer(ctx context.Context, id int64) (Transfer, error)
 	GetUser(ctx context.Context, username string) (User, error)
 	GetUserByEmail(ctx context.Context, email string) (User, error)
+	ListAPIKeys(ctx context.Context, username string) ([]ApiKey, error)
+	ListAccountBalanceMismatches(ctx context.Context) ([]ListAccountBalanceMismatchesRow, error)
 	ListAccounts(ctx context.Context, arg ListAccountsParams) ([]Account, error)
 	ListAccountsForUser(ctx context.Context, arg ListAccountsForUserParams) ([]Account, error)
+	ListCurrencyTotals(ctx context.Context) ([]ListCurrencyTotalsRow, error)
 	ListEntries(ctx context.Context, arg ListEntriesParams) ([]Entry, error)
 	ListEntriesForAccount(ctx context.Context, arg ListEntriesForAccountParams) ([]Entry, error)
+	ListEntriesForAccountBetween(ctx context.Context, arg ListEntriesForAccountBetweenParams) ([]Entry, error)
+	ListEntriesForJournal(ctx context.Context, journalID pgtype.Int8) ([]Entry, error)
+	ListOAuthConsents(ctx context.Context, username string) ([]ListOAuthConsentsRow, error)
 	ListTransfers(ctx context.Context, arg ListTransfersParams) ([]Transfer, error)
 	ListTransfersForAccount(ctx context.Context, arg ListTransfersForAccountParams) ([]Transfer, error)
+	ListUnbalancedJournals(ctx context.Context) ([]ListUnbalancedJournalsRow, error)
+	ListUnbalancedTransfers(ctx context.Context) ([]ListUnbalancedTransfersRow, error)
 	ListUsers(ctx context.Context, arg ListUsersParams) ([]User, error)
+	LockLogin(ctx context.Context, arg LockLoginParams) (LoginThrottle, error)
+	// Only succeeds while the user still has the given email address.
+	MarkUserEmailVerified(ctx context.Context, arg MarkUserEmailVerifiedParams) (User, error)
+	// Counting starts again when the previous failure is older than reset_before.
+	RecordLoginFailure(ctx context.Context, arg RecordLoginFailureParams) (LoginThrottle, error)
+	RevokeAPIKey(ctx context.Context, arg RevokeAPIKeyParams) (ApiKey, error)
+	SetUserRole(ctx context.Context, arg SetUserRoleParams) (User, error)
+	// Stores a pending secret. It cannot replace the secret of an enabled authenticator.
+	SetUserTOTPSecret(ctx context.Context, arg SetUserTOTPSecretParams) (User, error)
+	// Refills the bucket for the time since it was last updated and takes a token
+	// from it. Returns no row, and leaves the bucket alone, when it has no token.
+	TakeRateLimitToken(ctx context.Context, arg TakeRateLimitTokenParams) (RateLimitBucket, error)
+	TouchAPIKey(ctx context.Context, id uuid.UUID) error
+	TouchSSOIdentity(ctx context.Context, arg TouchSSOIdentityParams) (SsoIdentity, error)
+	UnfreezeAccount(ctx context.Context, id int64) (Account, error)
 	UpdateAccount(ctx context.Context, arg UpdateAccountParams) (Account, error)
 	UpdateUser(ctx context.Context, arg UpdateUserParams) (User, error)
+	// A new consent adds its scopes to those of an earlier one and keeps its grant
+	// time, so approving a narrower request does not revoke tokens issued before.
+	UpsertOAuthConsent(ctx context.Context, arg UpsertOAuthConsentParams) (OauthConsent, error)
+	UseOAuthAuthorizationCode(ctx context.Context, hashedCode string) (OauthAuthorizationCode, error)
+	// Resets created before the password last changed are no longer valid.
+	UsePasswordReset(ctx context.Context, arg UsePasswordResetParams) (PasswordReset, error)
+	UseRecoveryCode(ctx context.Context, arg UseRecoveryCodeParams) (RecoveryCode, error)
+	// Each time step can be used once, so an intercepted code cannot be replayed.
+	UseTOTPStep(ctx context.Context, arg UseTOTPStepParams) (User, error)
+	UseVerifyEmail(ctx context.Context, arg UseVerifyEmailParams) (VerifyEmail, error)
 }
 
 var _ Querier = (*Queries)(nil)
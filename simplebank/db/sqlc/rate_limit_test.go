@@ -0,0 +1,56 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/util"
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTakeRateLimitToken(t *testing.T) {
+	arg := TakeRateLimitTokenParams{
+		Key:   "test:user:" + util.RandomOwner(),
+		Burst: 3,
+		// slow enough not to refill a token during the test
+		Rate: 0.001,
+	}
+
+	for i := 1; i <= 3; i++ {
+		bucket, err := testQueries.TakeRateLimitToken(context.Background(), arg)
+		require.NoError(t, err)
+		require.Equal(t, arg.Key, bucket.Key)
+		require.InDelta(t, float64(3-i), bucket.Tokens, 0.01)
+		require.WithinDuration(t, time.Now(), bucket.UpdatedAt, time.Second)
+	}
+
+	// an empty bucket is left alone
+	_, err := testQueries.TakeRateLimitToken(context.Background(), arg)
+	require.ErrorIs(t, err, pgx.ErrNoRows)
+
+	bucket, err := testQueries.GetRateLimitBucket(context.Background(), arg.Key)
+	require.NoError(t, err)
+	require.Less(t, bucket.Tokens, 1.0)
+
+	// a fast rate refills the bucket for the time since it was updated
+	arg.Rate = 1000
+	time.Sleep(10 * time.Millisecond)
+	bucket, err = testQueries.TakeRateLimitToken(context.Background(), arg)
+	require.NoError(t, err)
+	require.InDelta(t, 2, bucket.Tokens, 0.01)
+}
+
+func TestDeleteRateLimitBuckets(t *testing.T) {
+	arg := TakeRateLimitTokenParams{Key: "test:ip:" + util.RandomOwner(), Burst: 1, Rate: 1}
+	_, err := testQueries.TakeRateLimitToken(context.Background(), arg)
+	require.NoError(t, err)
+
+	n, err := testQueries.DeleteRateLimitBuckets(context.Background(), time.Now().Add(time.Second))
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, n, int64(1))
+
+	_, err = testQueries.GetRateLimitBucket(context.Background(), arg.Key)
+	require.ErrorIs(t, err, pgx.ErrNoRows)
+}
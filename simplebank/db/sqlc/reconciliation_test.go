@@ -0,0 +1,56 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReconciliationTxBalanceMismatch(t *testing.T) {
+	store := NewStore(testDB)
+
+	user := createRandomUser(t)
+	account := createRandomAccount(t, user)
+
+	// bypass the ledger, like a manual balance fix would
+	_, err := testQueries.UpdateAccount(context.Background(), UpdateAccountParams{
+		ID:      account.ID,
+		Balance: account.Balance + 10,
+	})
+	require.NoError(t, err)
+
+	result, err := store.ReconciliationTx(context.Background())
+	require.NoError(t, err)
+
+	var mismatch *ListAccountBalanceMismatchesRow
+	for i := range result.BalanceMismatches {
+		if result.BalanceMismatches[i].AccountID == account.ID {
+			mismatch = &result.BalanceMismatches[i]
+		}
+	}
+	require.NotNil(t, mismatch)
+	require.Equal(t, account.Balance+10, mismatch.Balance)
+	require.NotEmpty(t, result.CurrencyTotals)
+}
+
+func TestReconciliationTxTransfers(t *testing.T) {
+	store := NewStore(testDB)
+
+	account1 := createRandomAccount(t, createRandomUser(t))
+	account2 := createRandomAccount(t, createRandomUser(t))
+
+	transferResult, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        10,
+	})
+	require.NoError(t, err)
+
+	result, err := store.ReconciliationTx(context.Background())
+	require.NoError(t, err)
+
+	for _, row := range result.UnbalancedTransfers {
+		require.NotEqual(t, transferResult.Transfer.ID, row.TransferID)
+	}
+}
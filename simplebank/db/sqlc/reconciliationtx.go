@@ -0,0 +1,49 @@
+package db
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type ReconciliationTxResult struct {
+	BalanceMismatches   []ListAccountBalanceMismatchesRow `json:"balance_mismatches"`
+	UnbalancedTransfers []ListUnbalancedTransfersRow      `json:"unbalanced_transfers"`
+	UnbalancedJournals  []ListUnbalancedJournalsRow       `json:"unbalanced_journals"`
+	CurrencyTotals      []ListCurrencyTotalsRow           `json:"currency_totals"`
+}
+
+// ReconciliationTx runs all ledger checks against a single snapshot, so
+// transfers committed while it runs cannot show up as false discrepancies.
+func (store *SQLStore) ReconciliationTx(ctx context.Context) (ReconciliationTxResult, error) {
+	var result ReconciliationTxResult
+
+	txOptions := pgx.TxOptions{
+		IsoLevel:   pgx.RepeatableRead,
+		AccessMode: pgx.ReadOnly,
+	}
+
+	err := store.execTxWithOptions(ctx, "ReconciliationTx", txOptions, func(ctx context.Context, q *Queries) error {
+		var err error
+
+		result.BalanceMismatches, err = q.ListAccountBalanceMismatches(ctx)
+		if err != nil {
+			return err
+		}
+
+		result.UnbalancedTransfers, err = q.ListUnbalancedTransfers(ctx)
+		if err != nil {
+			return err
+		}
+
+		result.UnbalancedJournals, err = q.ListUnbalancedJournals(ctx)
+		if err != nil {
+			return err
+		}
+
+		result.CurrencyTotals, err = q.ListCurrencyTotals(ctx)
+		return err
+	})
+
+	return result, err
+}
@@ -0,0 +1,41 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+type ResetPasswordTxParams struct {
+	ResetID        uuid.UUID `json:"reset_id"`
+	Username       string    `json:"username"`
+	HashedPassword string    `json:"hashed_password"`
+}
+
+// ResetPasswordTx uses up a password reset and sets the new password. Bumping
+// password_changed_at revokes every token and reset issued before it. It
+// returns pgx.ErrNoRows when the reset is unknown, used, expired or superseded.
+func (store *SQLStore) ResetPasswordTx(ctx context.Context, arg ResetPasswordTxParams) (User, error) {
+	var user User
+
+	err := store.execTx(ctx, "ResetPasswordTx", func(ctx context.Context, q *Queries) error {
+		_, err := q.UsePasswordReset(ctx, UsePasswordResetParams{
+			ID:       arg.ResetID,
+			Username: arg.Username,
+		})
+		if err != nil {
+			return err
+		}
+
+		user, err = q.UpdateUser(ctx, UpdateUserParams{
+			Username:          arg.Username,
+			HashedPassword:    pgtype.Text{String: arg.HashedPassword, Valid: true},
+			PasswordChangedAt: pgtype.Timestamptz{Time: time.Now(), Valid: true},
+		})
+		return err
+	})
+
+	return user, err
+}
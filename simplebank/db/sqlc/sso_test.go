@@ -0,0 +1,181 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/util"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/require"
+)
+
+const testSSOIssuer = "https://idp.example.com"
+
+func randomSSOLoginTxParams() SSOLoginTxParams {
+	return SSOLoginTxParams{
+		Issuer:   testSSOIssuer,
+		Subject:  util.RandomString(16),
+		Username: util.RandomOwner(),
+		FullName: util.RandomName(),
+		Email:    util.RandomEmail(),
+		Role:     util.DepositorRole,
+	}
+}
+
+func TestSSOLoginTxCreatesUser(t *testing.T) {
+	store := NewStore(testDB)
+	arg := randomSSOLoginTxParams()
+
+	result, err := store.SSOLoginTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.True(t, result.Created)
+
+	user := result.User
+	require.Equal(t, arg.Username, user.Username)
+	require.Equal(t, arg.FullName, user.FullName)
+	require.Equal(t, arg.Email, user.Email)
+	require.Empty(t, user.HashedPassword)
+	require.True(t, user.IsEmailVerified)
+	require.Equal(t, util.DepositorRole, user.Role)
+
+	identity, err := testQueries.GetSSOIdentity(context.Background(), GetSSOIdentityParams{
+		Issuer:  arg.Issuer,
+		Subject: arg.Subject,
+	})
+	require.NoError(t, err)
+	require.Equal(t, user.Username, identity.Username)
+	require.Equal(t, result.Identity.CreatedAt, identity.CreatedAt)
+	require.True(t, identity.ManagesRole)
+}
+
+func TestSSOLoginTxUsernameTaken(t *testing.T) {
+	store := NewStore(testDB)
+	other := createRandomUser(t)
+
+	arg := randomSSOLoginTxParams()
+	arg.Username = other.Username
+
+	result, err := store.SSOLoginTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.True(t, result.Created)
+	require.NotEqual(t, other.Username, result.User.Username)
+	require.Len(t, result.User.Username, len(other.Username)+4)
+	require.Equal(t, arg.Email, result.User.Email)
+}
+
+// createPasswordlessUser creates a user with a verified email who cannot
+// log in with a password
+func createPasswordlessUser(t *testing.T) User {
+	user, err := testQueries.CreateUser(context.Background(), CreateUserParams{
+		Username: util.RandomOwner(),
+		FullName: util.RandomName(),
+		Email:    util.RandomEmail(),
+	})
+	require.NoError(t, err)
+
+	user, err = testQueries.UpdateUser(context.Background(), UpdateUserParams{
+		Username:        user.Username,
+		IsEmailVerified: pgtype.Bool{Bool: true, Valid: true},
+	})
+	require.NoError(t, err)
+	return user
+}
+
+func TestSSOLoginTxLinksUserByEmail(t *testing.T) {
+	store := NewStore(testDB)
+	user := createPasswordlessUser(t)
+
+	arg := randomSSOLoginTxParams()
+	arg.Email = user.Email
+	arg.Role = util.AdminRole
+
+	result, err := store.SSOLoginTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.False(t, result.Created)
+	require.Equal(t, user.Username, result.User.Username)
+	require.Equal(t, user.Username, result.Identity.Username)
+
+	// the role of a linked user is left to admins
+	require.False(t, result.Identity.ManagesRole)
+	require.Equal(t, util.DepositorRole, result.User.Role)
+}
+
+func TestSSOLoginTxUserNotLinkable(t *testing.T) {
+	store := NewStore(testDB)
+
+	// anyone can sign up with the email of a staff member and a password
+	passwordUser := createRandomUser(t)
+	unverifiedUser, err := testQueries.CreateUser(context.Background(), CreateUserParams{
+		Username: util.RandomOwner(),
+		FullName: util.RandomName(),
+		Email:    util.RandomEmail(),
+	})
+	require.NoError(t, err)
+
+	for _, user := range []User{passwordUser, unverifiedUser} {
+		arg := randomSSOLoginTxParams()
+		arg.Email = user.Email
+		arg.Role = util.AdminRole
+
+		_, err := store.SSOLoginTx(context.Background(), arg)
+		require.ErrorIs(t, err, ErrSSOUserNotLinkable)
+
+		_, err = testQueries.GetSSOIdentity(context.Background(), GetSSOIdentityParams{
+			Issuer:  arg.Issuer,
+			Subject: arg.Subject,
+		})
+		require.ErrorIs(t, err, pgx.ErrNoRows)
+
+		user, err = testQueries.GetUser(context.Background(), user.Username)
+		require.NoError(t, err)
+		require.Equal(t, util.DepositorRole, user.Role)
+	}
+}
+
+func TestSSOLoginTxExistingIdentity(t *testing.T) {
+	store := NewStore(testDB)
+	arg := randomSSOLoginTxParams()
+
+	result1, err := store.SSOLoginTx(context.Background(), arg)
+	require.NoError(t, err)
+
+	// the identity is found by issuer and subject, whatever else changed
+	arg.Username = util.RandomOwner()
+	arg.Email = util.RandomEmail()
+	arg.Role = util.AdminRole
+
+	result2, err := store.SSOLoginTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.False(t, result2.Created)
+	require.Equal(t, result1.User.Username, result2.User.Username)
+	require.Equal(t, result1.User.Email, result2.User.Email)
+	require.Equal(t, util.AdminRole, result2.User.Role)
+	require.True(t, result2.Identity.LastLoginAt.After(result1.Identity.LastLoginAt))
+
+	// losing the admin group takes the role away again
+	arg.Role = util.DepositorRole
+	result3, err := store.SSOLoginTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, util.DepositorRole, result3.User.Role)
+
+	// once the user can log in with a password, the provider cannot make it an admin
+	_, err = testQueries.UpdateUser(context.Background(), UpdateUserParams{
+		Username:       result1.User.Username,
+		HashedPassword: pgtype.Text{String: "hashed", Valid: true},
+	})
+	require.NoError(t, err)
+
+	arg.Role = util.AdminRole
+	result4, err := store.SSOLoginTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, util.DepositorRole, result4.User.Role)
+}
+
+func TestGetSSOIdentityNotFound(t *testing.T) {
+	_, err := testQueries.GetSSOIdentity(context.Background(), GetSSOIdentityParams{
+		Issuer:  testSSOIssuer,
+		Subject: util.RandomString(16),
+	})
+	require.ErrorIs(t, err, pgx.ErrNoRows)
+}
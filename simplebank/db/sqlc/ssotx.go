@@ -0,0 +1,148 @@
+package db
+
+import (
+	"context"
+	"errors"
+
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/util"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// ssoUsernameAttempts bounds the random suffixes tried when the username
+// wanted for a new user is taken
+const ssoUsernameAttempts = 5
+
+var (
+	ErrSSOUsernameTaken = errors.New("no free username for the new user")
+	// ErrSSOUserNotLinkable is returned on the first login of an identity
+	// whose email belongs to a user that must not be linked to it, as the
+	// user can log in with a password or has not verified the email
+	ErrSSOUserNotLinkable = errors.New("the user with the email cannot be linked to the identity")
+)
+
+type SSOLoginTxParams struct {
+	Issuer  string `json:"issuer"`
+	Subject string `json:"subject"`
+	// Username is used for a new user, with a random suffix if it is taken
+	Username string `json:"username"`
+	FullName string `json:"full_name"`
+	// Email links the identity to an existing user on first login; the
+	// provider must have verified it
+	Email string `json:"email"`
+	// Role is the role the provider groups imply. It is only given to users
+	// the provider created.
+	Role string `json:"role"`
+}
+
+type SSOLoginTxResult struct {
+	User     User        `json:"user"`
+	Identity SsoIdentity `json:"identity"`
+	// Created is true when the login created the user
+	Created bool `json:"created"`
+}
+
+// SSOLoginTx finds the user an identity at an OpenID Connect provider
+// belongs to, linking it to the user with the same email or a new user on
+// its first login. Users the provider created get the role it implies;
+// the roles of linked users are left to admins.
+func (store *SQLStore) SSOLoginTx(ctx context.Context, arg SSOLoginTxParams) (SSOLoginTxResult, error) {
+	var result SSOLoginTxResult
+
+	err := store.execTx(ctx, "SSOLoginTx", func(ctx context.Context, q *Queries) error {
+		var err error
+
+		result.Identity, err = q.TouchSSOIdentity(ctx, TouchSSOIdentityParams{
+			Issuer:  arg.Issuer,
+			Subject: arg.Subject,
+		})
+		switch {
+		case err == nil:
+			result.User, err = q.GetUser(ctx, result.Identity.Username)
+			if err != nil {
+				return err
+			}
+		case err == pgx.ErrNoRows:
+			result.User, result.Created, err = ssoUser(ctx, q, arg)
+			if err != nil {
+				return err
+			}
+
+			result.Identity, err = q.CreateSSOIdentity(ctx, CreateSSOIdentityParams{
+				Issuer:      arg.Issuer,
+				Subject:     arg.Subject,
+				Username:    result.User.Username,
+				ManagesRole: result.Created,
+			})
+			if err != nil {
+				return err
+			}
+		default:
+			return err
+		}
+
+		if result.Identity.ManagesRole && result.User.Role != arg.Role && !raisesPasswordUser(result.User, arg.Role) {
+			result.User, err = q.SetUserRole(ctx, SetUserRoleParams{
+				Username: result.User.Username,
+				Role:     arg.Role,
+			})
+		}
+		return err
+	})
+
+	return result, err
+}
+
+// raisesPasswordUser reports whether giving role to user makes an admin of
+// a user who can log in with a password, e.g. one that was created through
+// the provider and then reset its password
+func raisesPasswordUser(user User, role string) bool {
+	return role == util.AdminRole && user.HashedPassword != ""
+}
+
+// ssoUser returns the user with the email of a new identity, or creates one.
+// Only users without a password who verified the email are linked, as anyone
+// can sign up with the email of a staff member. Created users have no
+// password, so they can only log in through the provider.
+func ssoUser(ctx context.Context, q *Queries, arg SSOLoginTxParams) (User, bool, error) {
+	user, err := q.GetUserByEmail(ctx, arg.Email)
+	switch {
+	case err == nil:
+		if user.HashedPassword != "" || !user.IsEmailVerified {
+			return user, false, ErrSSOUserNotLinkable
+		}
+		return user, false, nil
+	case err != pgx.ErrNoRows:
+		return user, false, err
+	}
+
+	username := arg.Username
+	for attempt := 0; ; attempt++ {
+		_, err := q.GetUser(ctx, username)
+		if err == pgx.ErrNoRows {
+			break
+		}
+		if err != nil {
+			return user, false, err
+		}
+		if attempt == ssoUsernameAttempts {
+			return user, false, ErrSSOUsernameTaken
+		}
+		username = arg.Username + util.RandomString(4)
+	}
+
+	user, err = q.CreateUser(ctx, CreateUserParams{
+		Username: username,
+		FullName: arg.FullName,
+		Email:    arg.Email,
+	})
+	if err != nil {
+		return user, false, err
+	}
+
+	user, err = q.UpdateUser(ctx, UpdateUserParams{
+		Username:        user.Username,
+		IsEmailVerified: pgtype.Bool{Bool: true, Valid: true},
+	})
+	return user, true, err
+}
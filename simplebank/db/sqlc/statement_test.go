@@ -0,0 +1,65 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateStatement(t *testing.T) {
+	user := createRandomUser(t)
+	account := createRandomAccount(t, user)
+
+	from := time.Date(2026, time.February, 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 1, 0)
+
+	arg := CreateStatementParams{
+		AccountID:   account.ID,
+		PeriodStart: from,
+		PeriodEnd:   to,
+		Content:     []byte(`{"closing_balance":100}`),
+	}
+
+	n, err := testQueries.CreateStatement(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), n)
+
+	// a stored statement is kept as it is
+	n, err = testQueries.CreateStatement(context.Background(), CreateStatementParams{
+		AccountID:   account.ID,
+		PeriodStart: from,
+		PeriodEnd:   to,
+		Content:     []byte(`{"closing_balance":200}`),
+	})
+	require.NoError(t, err)
+	require.Zero(t, n)
+
+	statement, err := testQueries.GetStatement(context.Background(), GetStatementParams{
+		AccountID:   account.ID,
+		PeriodStart: from,
+		PeriodEnd:   to,
+	})
+	require.NoError(t, err)
+	require.NotZero(t, statement.ID)
+	require.Equal(t, account.ID, statement.AccountID)
+	require.WithinDuration(t, from, statement.PeriodStart, time.Second)
+	require.WithinDuration(t, to, statement.PeriodEnd, time.Second)
+	require.JSONEq(t, string(arg.Content), string(statement.Content))
+	require.NotZero(t, statement.CreatedAt)
+}
+
+func TestGetStatementNotFound(t *testing.T) {
+	user := createRandomUser(t)
+	account := createRandomAccount(t, user)
+
+	from := time.Date(2026, time.February, 1, 0, 0, 0, 0, time.UTC)
+	_, err := testQueries.GetStatement(context.Background(), GetStatementParams{
+		AccountID:   account.ID,
+		PeriodStart: from,
+		PeriodEnd:   from.AddDate(0, 1, 0),
+	})
+	require.ErrorIs(t, err, pgx.ErrNoRows)
+}
@@ -0,0 +1,57 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type StatementTxParams struct {
+	AccountID int64     `json:"account_id"`
+	From      time.Time `json:"from"`
+	To        time.Time `json:"to"`
+}
+
+type StatementTxResult struct {
+	Account        Account `json:"account"`
+	OpeningBalance int64   `json:"opening_balance"`
+	Entries        []Entry `json:"entries"`
+}
+
+// StatementTx reads the balance at the start of the period and the entries
+// posted during it from a single snapshot, so the two always agree.
+func (store *SQLStore) StatementTx(ctx context.Context, arg StatementTxParams) (StatementTxResult, error) {
+	var result StatementTxResult
+
+	txOptions := pgx.TxOptions{
+		IsoLevel:   pgx.RepeatableRead,
+		AccessMode: pgx.ReadOnly,
+	}
+
+	err := store.execTxWithOptions(ctx, "StatementTx", txOptions, func(ctx context.Context, q *Queries) error {
+		var err error
+
+		result.Account, err = q.GetAccount(ctx, arg.AccountID)
+		if err != nil {
+			return err
+		}
+
+		result.OpeningBalance, err = q.GetAccountBalanceAt(ctx, GetAccountBalanceAtParams{
+			AccountID: arg.AccountID,
+			At:        arg.From,
+		})
+		if err != nil {
+			return err
+		}
+
+		result.Entries, err = q.ListEntriesForAccountBetween(ctx, ListEntriesForAccountBetweenParams{
+			AccountID: arg.AccountID,
+			FromTime:  arg.From,
+			ToTime:    arg.To,
+		})
+		return err
+	})
+
+	return result, err
+}
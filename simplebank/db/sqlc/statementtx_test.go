@@ -0,0 +1,40 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatementTx(t *testing.T) {
+	store := NewStore(testDB)
+
+	user := createRandomUser(t)
+	account := createRandomAccount(t, user)
+
+	before := createRandomEntry(t, account)
+	from := time.Now()
+
+	var entries []Entry
+	for range 3 {
+		entries = append(entries, createRandomEntry(t, account))
+	}
+	to := time.Now()
+
+	result, err := store.StatementTx(context.Background(), StatementTxParams{
+		AccountID: account.ID,
+		From:      from,
+		To:        to,
+	})
+	require.NoError(t, err)
+
+	require.Equal(t, account.ID, result.Account.ID)
+	require.Equal(t, before.Amount, result.OpeningBalance)
+	require.Len(t, result.Entries, len(entries))
+
+	for i, entry := range result.Entries {
+		require.Equal(t, entries[i].ID, entry.ID)
+	}
+}
@@ -3,14 +3,32 @@ package db
 import (
 	"context"
 	"fmt"
+	"strings"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.40.0"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type Store interface {
 	Querier
 	TransferTx(ctx context.Context, arg TransferTxParams) (TransferTxResult, error)
+	StatementTx(ctx context.Context, arg StatementTxParams) (StatementTxResult, error)
+	ReconciliationTx(ctx context.Context) (ReconciliationTxResult, error)
+	PostJournal(ctx context.Context, arg PostJournalParams) (PostJournalResult, error)
+	ReverseJournal(ctx context.Context, arg ReverseJournalParams) (PostJournalResult, error)
+	VerifyEmailTx(ctx context.Context, arg VerifyEmailTxParams) (VerifyEmailTxResult, error)
+	ResetPasswordTx(ctx context.Context, arg ResetPasswordTxParams) (User, error)
+	EnableTOTPTx(ctx context.Context, arg EnableTOTPTxParams) (User, error)
+	DisableTOTPTx(ctx context.Context, username string) (User, error)
+	AuthorizeOAuthTx(ctx context.Context, arg AuthorizeOAuthTxParams) (AuthorizeOAuthTxResult, error)
+	SSOLoginTx(ctx context.Context, arg SSOLoginTxParams) (SSOLoginTxResult, error)
+	CreateUserTx(ctx context.Context, arg CreateUserTxParams) (User, error)
+	Ping(ctx context.Context) error
+	MigrationVersion(ctx context.Context) (version int64, dirty bool, err error)
 }
 
 type SQLStore struct {
@@ -25,14 +43,36 @@ func NewStore(db *pgxpool.Pool) Store {
 	}
 }
 
-func (store *SQLStore) execTx(ctx context.Context, fn func(*Queries) error) error {
-	tx, err := store.db.BeginTx(ctx, pgx.TxOptions{})
+// tracer traces the transactions of the store. Their queries are traced by
+// the pgx tracer of the pool, as children of the transaction.
+var tracer = otel.Tracer("github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/db/sqlc")
+
+// execTx runs fn in a transaction traced as name. fn must use the context it
+// is given, so its queries are traced within the transaction.
+func (store *SQLStore) execTx(ctx context.Context, name string, fn func(context.Context, *Queries) error) error {
+	return store.execTxWithOptions(ctx, name, pgx.TxOptions{}, fn)
+}
+
+func (store *SQLStore) execTxWithOptions(ctx context.Context, name string, txOptions pgx.TxOptions, fn func(context.Context, *Queries) error) (err error) {
+	ctx, span := tracer.Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(semconv.DBSystemNamePostgreSQL),
+	)
+	defer func() {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}()
+
+	tx, err := store.db.BeginTx(ctx, txOptions)
 	if err != nil {
 		return err
 	}
 
 	q := store.Queries.WithTx(tx)
-	err = fn(q)
+	err = fn(ctx, q)
 	if err != nil {
 		if rbErr := tx.Rollback(ctx); rbErr != nil {
 			return fmt.Errorf("tx err: %v, rb err: %v", err, rbErr)
@@ -42,3 +82,13 @@ func (store *SQLStore) execTx(ctx context.Context, fn func(*Queries) error) erro
 
 	return tx.Commit(ctx)
 }
+
+// QueryName returns the name sqlc gives a query in its leading comment, e.g.
+// GetAccount, or else the statement on a single line
+func QueryName(sql string) string {
+	if rest, ok := strings.CutPrefix(sql, "-- name: "); ok {
+		name, _, _ := strings.Cut(rest, " ")
+		return name
+	}
+	return strings.Join(strings.Fields(sql), " ")
+}
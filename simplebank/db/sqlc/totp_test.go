@@ -0,0 +1,144 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/util"
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/require"
+)
+
+func enableRandomTOTP(t *testing.T, store Store, user User) (User, []string) {
+	secret := util.RandomString(32)
+
+	pending, err := testQueries.SetUserTOTPSecret(context.Background(), SetUserTOTPSecretParams{
+		Username:   user.Username,
+		TotpSecret: secret,
+	})
+	require.NoError(t, err)
+	require.Equal(t, secret, pending.TotpSecret)
+	require.False(t, pending.IsTotpEnabled)
+
+	hashedCodes := []string{util.RandomString(64), util.RandomString(64)}
+
+	enabled, err := store.EnableTOTPTx(context.Background(), EnableTOTPTxParams{
+		Username:            user.Username,
+		Secret:              secret,
+		Step:                100,
+		HashedRecoveryCodes: hashedCodes,
+	})
+	require.NoError(t, err)
+	require.True(t, enabled.IsTotpEnabled)
+	require.Equal(t, secret, enabled.TotpSecret)
+	require.Equal(t, int64(100), enabled.TotpLastStep)
+
+	return enabled, hashedCodes
+}
+
+func TestEnableTOTPTx(t *testing.T) {
+	store := NewStore(testDB)
+	user, _ := enableRandomTOTP(t, store, createRandomUser(t))
+
+	// an enabled secret can't be replaced by a new enrollment
+	_, err := testQueries.SetUserTOTPSecret(context.Background(), SetUserTOTPSecretParams{
+		Username:   user.Username,
+		TotpSecret: util.RandomString(32),
+	})
+	require.ErrorIs(t, err, pgx.ErrNoRows)
+
+	_, err = store.EnableTOTPTx(context.Background(), EnableTOTPTxParams{
+		Username: user.Username,
+		Secret:   user.TotpSecret,
+		Step:     200,
+	})
+	require.ErrorIs(t, err, pgx.ErrNoRows)
+}
+
+func TestEnableTOTPTxSecretChanged(t *testing.T) {
+	store := NewStore(testDB)
+	user := createRandomUser(t)
+
+	_, err := testQueries.SetUserTOTPSecret(context.Background(), SetUserTOTPSecretParams{
+		Username:   user.Username,
+		TotpSecret: util.RandomString(32),
+	})
+	require.NoError(t, err)
+
+	_, err = store.EnableTOTPTx(context.Background(), EnableTOTPTxParams{
+		Username: user.Username,
+		Secret:   util.RandomString(32),
+		Step:     100,
+	})
+	require.ErrorIs(t, err, pgx.ErrNoRows)
+}
+
+func TestUseTOTPStep(t *testing.T) {
+	store := NewStore(testDB)
+	user, _ := enableRandomTOTP(t, store, createRandomUser(t))
+
+	updated, err := testQueries.UseTOTPStep(context.Background(), UseTOTPStepParams{
+		Username:     user.Username,
+		TotpLastStep: user.TotpLastStep + 1,
+	})
+	require.NoError(t, err)
+	require.Equal(t, user.TotpLastStep+1, updated.TotpLastStep)
+
+	// the same or an earlier step is a replay
+	for _, step := range []int64{updated.TotpLastStep, updated.TotpLastStep - 1} {
+		_, err = testQueries.UseTOTPStep(context.Background(), UseTOTPStepParams{
+			Username:     user.Username,
+			TotpLastStep: step,
+		})
+		require.ErrorIs(t, err, pgx.ErrNoRows)
+	}
+}
+
+func TestUseRecoveryCode(t *testing.T) {
+	store := NewStore(testDB)
+	user, hashedCodes := enableRandomTOTP(t, store, createRandomUser(t))
+
+	arg := UseRecoveryCodeParams{
+		Username:   user.Username,
+		HashedCode: hashedCodes[0],
+	}
+
+	code, err := testQueries.UseRecoveryCode(context.Background(), arg)
+	require.NoError(t, err)
+	require.True(t, code.UsedAt.Valid)
+
+	// recovery codes are single-use
+	_, err = testQueries.UseRecoveryCode(context.Background(), arg)
+	require.ErrorIs(t, err, pgx.ErrNoRows)
+
+	// and only work for their own user
+	other := createRandomUser(t)
+	_, err = testQueries.UseRecoveryCode(context.Background(), UseRecoveryCodeParams{
+		Username:   other.Username,
+		HashedCode: hashedCodes[1],
+	})
+	require.ErrorIs(t, err, pgx.ErrNoRows)
+}
+
+func TestDisableTOTPTx(t *testing.T) {
+	store := NewStore(testDB)
+	user, hashedCodes := enableRandomTOTP(t, store, createRandomUser(t))
+
+	disabled, err := store.DisableTOTPTx(context.Background(), user.Username)
+	require.NoError(t, err)
+	require.False(t, disabled.IsTotpEnabled)
+	require.Empty(t, disabled.TotpSecret)
+
+	// recovery codes go with the authenticator
+	_, err = testQueries.UseRecoveryCode(context.Background(), UseRecoveryCodeParams{
+		Username:   user.Username,
+		HashedCode: hashedCodes[1],
+	})
+	require.ErrorIs(t, err, pgx.ErrNoRows)
+
+	_, err = testQueries.UseTOTPStep(context.Background(), UseTOTPStepParams{
+		Username:     user.Username,
+		TotpLastStep: user.TotpLastStep + 1,
+	})
+	require.ErrorIs(t, err, pgx.ErrNoRows)
+}
@@ -0,0 +1,75 @@
+package db
+
+import (
+	"context"
+)
+
+type EnableTOTPTxParams struct {
+	Username string `json:"username"`
+	// Secret is the pending secret the confirmation code was checked against
+	Secret string `json:"secret"`
+	// Step is the time step of the confirmation code, which can't be reused
+	Step                int64    `json:"step"`
+	HashedRecoveryCodes []string `json:"hashed_recovery_codes"`
+}
+
+// EnableTOTPTx turns on two-factor authentication with a confirmed secret and
+// replaces any previous recovery codes. It returns pgx.ErrNoRows when the
+// pending secret has changed or the authenticator is already enabled.
+func (store *SQLStore) EnableTOTPTx(ctx context.Context, arg EnableTOTPTxParams) (User, error) {
+	var user User
+
+	err := store.execTx(ctx, "EnableTOTPTx", func(ctx context.Context, q *Queries) error {
+		var err error
+
+		user, err = q.EnableUserTOTP(ctx, EnableUserTOTPParams{
+			Username:     arg.Username,
+			TotpSecret:   arg.Secret,
+			TotpLastStep: arg.Step,
+		})
+		if err != nil {
+			return err
+		}
+
+		return replaceRecoveryCodes(ctx, q, arg.Username, arg.HashedRecoveryCodes)
+	})
+
+	return user, err
+}
+
+// DisableTOTPTx turns off two-factor authentication, forgetting the secret
+// and every recovery code.
+func (store *SQLStore) DisableTOTPTx(ctx context.Context, username string) (User, error) {
+	var user User
+
+	err := store.execTx(ctx, "DisableTOTPTx", func(ctx context.Context, q *Queries) error {
+		var err error
+
+		user, err = q.DisableUserTOTP(ctx, username)
+		if err != nil {
+			return err
+		}
+
+		return q.DeleteRecoveryCodes(ctx, username)
+	})
+
+	return user, err
+}
+
+func replaceRecoveryCodes(ctx context.Context, q *Queries, username string, hashedCodes []string) error {
+	if err := q.DeleteRecoveryCodes(ctx, username); err != nil {
+		return err
+	}
+
+	for _, hashedCode := range hashedCodes {
+		_, err := q.CreateRecoveryCode(ctx, CreateRecoveryCodeParams{
+			Username:   username,
+			HashedCode: hashedCode,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
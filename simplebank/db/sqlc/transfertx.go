@@ -2,22 +2,35 @@ package db
 
 import (
 	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
 type TransferTxParams struct {
 	FromAccountID int64 `json:"from_account_id"`
 	ToAccountID   int64 `json:"to_account_id"`
 	Amount        int64 `json:"amount"`
+	// Fee, when positive, is charged to the sender and credited to
+	// FeeAccountID as part of the same journal as the transfer.
+	Fee          int64 `json:"fee"`
+	FeeAccountID int64 `json:"fee_account_id"`
 }
 
 type TransferTxResult struct {
+	Journal     Journal  `json:"journal"`
 	Transfer    Transfer `json:"transfer"`
 	FromAccount Account  `json:"from_account"`
 	ToAccount   Account  `json:"to_account"`
 	FromEntry   Entry    `json:"from_entry"`
 	ToEntry     Entry    `json:"to_entry"`
+	FeeEntry    *Entry   `json:"fee_entry,omitempty"`
 }
 
+// ErrAccountFrozen is returned for transfers from or to a frozen account
+var ErrAccountFrozen = errors.New("account is frozen")
+
 type ContextKey struct{}
 
 var txKey ContextKey
@@ -26,53 +39,56 @@ var txKey ContextKey
 func (store *SQLStore) TransferTx(ctx context.Context, arg TransferTxParams) (TransferTxResult, error) {
 	var result TransferTxResult
 
-	err := store.execTx(ctx, func(q *Queries) error {
+	if arg.Fee < 0 {
+		return result, fmt.Errorf("%w: fee must not be negative", ErrInvalidJournal)
+	}
+	if err := ValidateJournalLegs(transferLegs(arg, pgtype.Int8{})); err != nil {
+		return result, err
+	}
+
+	err := store.execTx(ctx, "TransferTx", func(ctx context.Context, q *Queries) error {
 		var err error
 
 		// txName := ctx.Value(txKey)
 
-		result.Transfer, err = q.CreateTransfer(ctx, CreateTransferParams(arg))
+		result.Journal, err = q.CreateJournal(ctx, CreateJournalParams{
+			Kind:        JournalKindTransfer,
+			Description: fmt.Sprintf("transfer from account %d to account %d", arg.FromAccountID, arg.ToAccountID),
+		})
 		if err != nil {
 			return err
 		}
 
-		result.FromEntry, err = q.CreateEntry(ctx, CreateEntryParams{
-			AccountID: arg.FromAccountID,
-			Amount:    -arg.Amount,
+		result.Transfer, err = q.CreateTransfer(ctx, CreateTransferParams{
+			FromAccountID: arg.FromAccountID,
+			ToAccountID:   arg.ToAccountID,
+			Amount:        arg.Amount,
+			JournalID:     pgtype.Int8{Int64: result.Journal.ID, Valid: true},
 		})
 		if err != nil {
 			return err
 		}
 
-		result.ToEntry, err = q.CreateEntry(ctx, CreateEntryParams{
-			AccountID: arg.ToAccountID,
-			Amount:    arg.Amount,
-		})
+		transferID := pgtype.Int8{Int64: result.Transfer.ID, Valid: true}
+		entries, accounts, err := postJournalLegs(ctx, q, result.Journal.ID, transferLegs(arg, transferID))
 		if err != nil {
 			return err
 		}
 
-		if arg.FromAccountID < arg.ToAccountID {
-			result.FromAccount, result.ToAccount, err = addAccountBalancePair(
-				ctx, q,
-				arg.FromAccountID,
-				arg.ToAccountID,
-				-arg.Amount,
-				arg.Amount,
-			)
-			if err != nil {
-				return err
-			}
-		} else {
-			result.ToAccount, result.FromAccount, err = addAccountBalancePair(
-				ctx, q,
-				arg.ToAccountID,
-				arg.FromAccountID,
-				arg.Amount,
-				-arg.Amount,
-			)
-			if err != nil {
-				return err
+		result.FromEntry, result.ToEntry = entries[0], entries[1]
+		if len(entries) > 2 {
+			result.FeeEntry = &entries[2]
+		}
+
+		posted := PostJournalResult{Accounts: accounts}
+		result.FromAccount = posted.Account(arg.FromAccountID)
+		result.ToAccount = posted.Account(arg.ToAccountID)
+
+		// the balance updates lock both accounts, so neither can be frozen
+		// after this check and before the transfer commits
+		for _, account := range []Account{result.FromAccount, result.ToAccount} {
+			if account.FrozenAt.Valid {
+				return fmt.Errorf("%w: account %d", ErrAccountFrozen, account.ID)
 			}
 		}
 
@@ -82,22 +98,18 @@ func (store *SQLStore) TransferTx(ctx context.Context, arg TransferTxParams) (Tr
 	return result, err
 }
 
-func addAccountBalancePair(ctx context.Context, q *Queries, accointID1, accountID2, amount1, amount2 int64) (account1, account2 Account, err error) {
-	account1, err = q.AddAccountBalance(ctx, AddAccountBalanceParams{
-		ID:     accointID1,
-		Amount: amount1,
-	})
-	if err != nil {
-		return
+// transferLegs returns the legs of a transfer journal: the transfer itself,
+// followed by the fee when there is one.
+func transferLegs(arg TransferTxParams, transferID pgtype.Int8) []JournalLeg {
+	legs := []JournalLeg{
+		{AccountID: arg.FromAccountID, Type: LegTypeDebit, Amount: arg.Amount, TransferID: transferID},
+		{AccountID: arg.ToAccountID, Type: LegTypeCredit, Amount: arg.Amount, TransferID: transferID},
 	}
 
-	account2, err = q.AddAccountBalance(ctx, AddAccountBalanceParams{
-		ID:     accountID2,
-		Amount: amount2,
-	})
-	if err != nil {
-		return
+	if arg.Fee > 0 {
+		fee := FeeJournal(arg.FromAccountID, arg.FeeAccountID, arg.Fee, "")
+		legs = append(legs, fee.Legs...)
 	}
 
-	return
+	return legs
 }
@@ -30,6 +30,9 @@ func createRandomUser(t *testing.T) User {
 	require.Equal(t, arg.Email, user.Email)
 
 	require.True(t, user.PasswordChangedAt.IsZero())
+	require.False(t, user.IsEmailVerified)
+	require.False(t, user.IsTotpEnabled)
+	require.Equal(t, util.DepositorRole, user.Role)
 	require.NotZero(t, user.CreatedAt)
 
 	return user
@@ -0,0 +1,34 @@
+package db
+
+import "context"
+
+type CreateUserTxParams struct {
+	CreateUserParams
+	// Role is the default role of new users when empty
+	Role string `json:"role"`
+}
+
+// CreateUserTx creates a user and sets its role in one transaction, so a user
+// never exists with a role other than the one asked for.
+func (store *SQLStore) CreateUserTx(ctx context.Context, arg CreateUserTxParams) (User, error) {
+	var user User
+
+	err := store.execTx(ctx, "CreateUserTx", func(ctx context.Context, q *Queries) error {
+		var err error
+
+		user, err = q.CreateUser(ctx, arg.CreateUserParams)
+		if err != nil {
+			return err
+		}
+
+		if arg.Role != "" && arg.Role != user.Role {
+			user, err = q.SetUserRole(ctx, SetUserRoleParams{
+				Username: user.Username,
+				Role:     arg.Role,
+			})
+		}
+		return err
+	})
+
+	return user, err
+}
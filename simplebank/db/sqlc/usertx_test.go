@@ -0,0 +1,57 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateUserTx(t *testing.T) {
+	store := NewStore(testDB)
+
+	for _, role := range []string{"", util.DepositorRole, util.AdminRole} {
+		arg := CreateUserTxParams{
+			CreateUserParams: CreateUserParams{
+				Username:       util.RandomOwner(),
+				HashedPassword: util.RandomString(32),
+				FullName:       util.RandomName(),
+				Email:          util.RandomEmail(),
+			},
+			Role: role,
+		}
+
+		user, err := store.CreateUserTx(context.Background(), arg)
+		require.NoError(t, err)
+		require.Equal(t, arg.Username, user.Username)
+
+		want := role
+		if want == "" {
+			want = util.DepositorRole
+		}
+		require.Equal(t, want, user.Role)
+	}
+}
+
+func TestCreateUserTxRollsBack(t *testing.T) {
+	store := NewStore(testDB)
+	existing := createRandomUser(t)
+
+	// the role is set in the same transaction, so a failed insert leaves no user
+	arg := CreateUserTxParams{
+		CreateUserParams: CreateUserParams{
+			Username:       util.RandomOwner(),
+			HashedPassword: util.RandomString(32),
+			FullName:       util.RandomName(),
+			Email:          existing.Email,
+		},
+		Role: util.AdminRole,
+	}
+
+	_, err := store.CreateUserTx(context.Background(), arg)
+	require.Error(t, err)
+
+	_, err = testQueries.GetUser(context.Background(), arg.Username)
+	require.Error(t, err)
+}
@@ -0,0 +1,44 @@
+package gapi
+
+import (
+	"context"
+
+	db "github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/db/sqlc"
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/token"
+)
+
+type userContextKey struct{}
+
+// authorizationUser returns the user checkTokenUser loaded
+func authorizationUser(ctx context.Context) db.User {
+	return ctx.Value(userContextKey{}).(db.User)
+}
+
+// checkTokenUser loads the user a token was issued to for the handlers. It
+// rejects tokens of deleted users and tokens issued before the user's last
+// password change.
+func (server *Server) checkTokenUser(ctx context.Context, payload *token.Payload) (context.Context, error) {
+	user, err := server.authenticator.TokenUser(ctx, payload)
+	if err != nil {
+		return ctx, authError(ctx, err)
+	}
+	return context.WithValue(ctx, userContextKey{}, user), nil
+}
+
+// checkTokenClient rejects tokens of oauth clients once the user has revoked
+// their consent, or when it does not cover the scopes of the token
+func (server *Server) checkTokenClient(ctx context.Context, payload *token.Payload) (context.Context, error) {
+	if err := server.authenticator.CheckTokenClient(ctx, payload); err != nil {
+		return ctx, authError(ctx, err)
+	}
+	return ctx, nil
+}
+
+// verifyAPIKey is the middleware.GRPCAPIKeyVerifier of the server
+func (server *Server) verifyAPIKey(ctx context.Context, key string) (*token.Payload, error) {
+	payload, err := server.authenticator.VerifyAPIKey(ctx, key)
+	if err != nil {
+		return nil, authError(ctx, err)
+	}
+	return payload, nil
+}
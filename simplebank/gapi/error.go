@@ -0,0 +1,91 @@
+package gapi
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/auth"
+	db "github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/db/sqlc"
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/util"
+	"github.com/jackc/pgx/v5/pgconn"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	pgUniqueViolation     = "23505"
+	pgForeignKeyViolation = "23503"
+)
+
+// uniqueViolations tell clients what already exists without the database
+// error, which names tables and values
+var uniqueViolations = map[string]string{
+	"users_pkey":                  "username already exists",
+	"users_email_key":             "email already exists",
+	"accounts_owner_currency_key": "an account in this currency already exists",
+}
+
+// authCodes are the status codes of the errors of the authenticator
+var authCodes = []struct {
+	err  error
+	code codes.Code
+}{
+	{auth.ErrInvalidCredentials, codes.Unauthenticated},
+	{auth.ErrUserNotFound, codes.Unauthenticated},
+	{auth.ErrTokenRevoked, codes.Unauthenticated},
+	{auth.ErrOAuthConsentRevoked, codes.Unauthenticated},
+	{util.ErrInvalidAPIKey, codes.Unauthenticated},
+	{auth.ErrAPIKeyRevoked, codes.Unauthenticated},
+	{auth.ErrAPIKeyExpired, codes.Unauthenticated},
+	{auth.ErrInvalidTOTPCode, codes.Unauthenticated},
+	{auth.ErrTOTPRequired, codes.Unauthenticated},
+	{auth.ErrStepUpNotEnabled, codes.PermissionDenied},
+	{auth.ErrAccountNotFound, codes.NotFound},
+	{auth.ErrAccountNotOwned, codes.PermissionDenied},
+	{auth.ErrCurrencyMismatch, codes.InvalidArgument},
+	{db.ErrAccountFrozen, codes.FailedPrecondition},
+}
+
+func invalidArgumentError(field string, err error) error {
+	return status.Errorf(codes.InvalidArgument, "invalid %s: %s", field, err)
+}
+
+// internalError logs err and hides it from the client behind a generic
+// message, as the HTTP API does
+func internalError(ctx context.Context, err error) error {
+	method, _ := grpc.Method(ctx)
+	slog.ErrorContext(ctx, "call failed", "method", method, "error", err)
+	return status.Error(codes.Internal, "internal server error")
+}
+
+// storeError returns the status of a failed write: violations of unique and
+// foreign keys are the client's to fix, anything else is an internal error
+func storeError(ctx context.Context, err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch pgErr.Code {
+		case pgUniqueViolation:
+			message, ok := uniqueViolations[pgErr.ConstraintName]
+			if !ok {
+				message = "resource already exists"
+			}
+			return status.Error(codes.AlreadyExists, message)
+		case pgForeignKeyViolation:
+			return status.Error(codes.FailedPrecondition, "request conflicts with existing data")
+		}
+	}
+	return internalError(ctx, err)
+}
+
+// authError returns the status of an error of the authenticator. Errors of
+// the store are internal errors.
+func authError(ctx context.Context, err error) error {
+	for _, c := range authCodes {
+		if errors.Is(err, c.err) {
+			return status.Error(c.code, err.Error())
+		}
+	}
+	return internalError(ctx, err)
+}
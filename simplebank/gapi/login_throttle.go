@@ -0,0 +1,82 @@
+package gapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"net"
+	"time"
+
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/auth"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	errLoginLocked = errors.New("too many failed login attempts, try again later")
+	errRateLimited = errors.New("too many requests, try again later")
+)
+
+// clientIP returns the address of the peer. Logins share their throttles
+// with the HTTP API, so failures over either count towards a lock of both.
+func clientIP(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+	return host
+}
+
+// checkLoginLock returns a ResourceExhausted error, with a retry-after
+// header, while either the username or the client ip is locked out
+func (server *Server) checkLoginLock(ctx context.Context, username string) error {
+	err := server.authenticator.CheckLoginLock(ctx, username, clientIP(ctx))
+
+	var locked *auth.LoginLockedError
+	if errors.As(err, &locked) {
+		setRetryAfter(ctx, locked.RetryAfter)
+		return status.Error(codes.ResourceExhausted, errLoginLocked.Error())
+	}
+	if err != nil {
+		return internalError(ctx, err)
+	}
+
+	return nil
+}
+
+// checkLoginRateLimit returns a ResourceExhausted error, with a retry-after
+// header, once the client ip has used up the login rate limit. Its bucket is
+// the one of logins over the HTTP API, so with the postgres backend both
+// count towards the same limit. Logins are let through when the store fails.
+func (server *Server) checkLoginRateLimit(ctx context.Context) error {
+	if server.rateLimits == nil || server.loginRateLimit.IsZero() {
+		return nil
+	}
+
+	result, err := server.rateLimits.Take(ctx, "login:ip:"+clientIP(ctx), server.loginRateLimit)
+	if err != nil {
+		slog.WarnContext(ctx, "cannot check rate limit", "group", "login", "error", err)
+		return nil
+	}
+
+	if !result.Allowed {
+		setRetryAfter(ctx, result.RetryAfter)
+		return status.Error(codes.ResourceExhausted, errRateLimited.Error())
+	}
+	return nil
+}
+
+// setRetryAfter tells the client how long to wait, in whole seconds rounded
+// up so clients waiting that long are not turned away
+func setRetryAfter(ctx context.Context, d time.Duration) {
+	_ = grpc.SetHeader(ctx, metadata.Pairs("retry-after", fmt.Sprint(int(math.Ceil(d.Seconds())))))
+}
@@ -0,0 +1,134 @@
+package gapi
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	mockdb "github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/db/mock"
+	db "github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/db/sqlc"
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/middleware"
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/util"
+	"github.com/golang/mock/gomock"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func newTestConfig() util.Config {
+	return util.Config{
+		TokenSymmetricKey:     util.RandomString(32),
+		AccessTokenDuration:   time.Minute,
+		AccountNumberCountry:  util.DefaultAccountNumberCountry,
+		TOTPChallengeDuration: time.Minute,
+	}
+}
+
+func newTestServer(t *testing.T, store db.Store) *Server {
+	return newTestServerWithConfig(t, store, newTestConfig())
+}
+
+func newTestServerWithConfig(t *testing.T, store db.Store, config util.Config) *Server {
+	server, err := NewServer(config, store)
+	require.NoError(t, err)
+
+	return server
+}
+
+// newTestClient serves the server over an in-memory listener, so calls go
+// through the same interceptors as in production
+func newTestClient(t *testing.T, server *Server) *grpc.ClientConn {
+	listener := bufconn.Listen(1024 * 1024)
+	grpcServer := server.NewGRPCServer()
+	go grpcServer.Serve(listener)
+	t.Cleanup(grpcServer.Stop)
+
+	conn, err := grpc.NewClient(
+		"passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		// an unexpected store call stops the handler without a response
+		grpc.WithUnaryInterceptor(func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+			ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+			defer cancel()
+			return invoker(ctx, method, req, reply, cc, opts...)
+		}),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	return conn
+}
+
+// withAccessToken returns a context that authenticates calls with a fresh
+// access token of the user
+func withAccessToken(t *testing.T, server *Server, username string, scopes ...string) context.Context {
+	accessToken, _, err := server.tokenMaker.CreateScopedToken(username, time.Minute, scopes...)
+	require.NoError(t, err)
+
+	return metadata.AppendToOutgoingContext(
+		context.Background(),
+		middleware.AuthorizationHeaderKey,
+		fmt.Sprintf("%s %s", middleware.AuthorizationTypeBearer, accessToken),
+	)
+}
+
+// stubAuthUser lets the auth interceptor load whichever user a test
+// authenticates as, with a verified email. Stubs set up before it take precedence.
+func stubAuthUser(store *mockdb.MockStore) {
+	store.EXPECT().
+		GetUser(gomock.Any(), gomock.Any()).
+		AnyTimes().
+		DoAndReturn(func(_ context.Context, username string) (db.User, error) {
+			return db.User{Username: username, IsEmailVerified: true}, nil
+		})
+}
+
+// stubLoginThrottle lets logins through and accepts any failed attempts
+// without locking. Stubs set up before it take precedence.
+func stubLoginThrottle(store *mockdb.MockStore) {
+	store.EXPECT().
+		GetLoginLockedUntil(gomock.Any(), gomock.Any()).
+		AnyTimes().
+		Return(pgtype.Timestamptz{}, nil)
+	store.EXPECT().
+		RecordLoginFailure(gomock.Any(), gomock.Any()).
+		AnyTimes().
+		Return(db.LoginThrottle{FailedAttempts: 1}, nil)
+	store.EXPECT().
+		DeleteLoginThrottle(gomock.Any(), gomock.Any()).
+		AnyTimes().
+		Return(int64(1), nil)
+}
+
+func randomUser(t *testing.T) (user db.User, password string) {
+	password = util.RandomString(12)
+	hashedPassword, err := util.HashPassword(password)
+	require.NoError(t, err)
+
+	user = db.User{
+		Email:          util.RandomEmail(),
+		Username:       util.RandomOwner(),
+		FullName:       util.RandomName(),
+		HashedPassword: hashedPassword,
+	}
+
+	return
+}
+
+func randomAccount(username string) db.Account {
+	return db.Account{
+		ID:            util.RandomInt(1, 1000),
+		Owner:         username,
+		Balance:       util.RandomAmount(),
+		Currency:      util.RandomCurrency(),
+		AccountNumber: util.RandomAccountNumber(),
+	}
+}
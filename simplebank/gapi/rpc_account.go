@@ -0,0 +1,128 @@
+package gapi
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/auth"
+	db "github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/db/sqlc"
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/middleware"
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/pb"
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/util"
+	"github.com/jackc/pgx/v5"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func (server *Server) CreateAccount(ctx context.Context, req *pb.CreateAccountRequest) (*pb.Account, error) {
+	if err := validateCurrency(req.GetCurrency()); err != nil {
+		return nil, invalidArgumentError("currency", err)
+	}
+
+	authPayload := middleware.AuthorizationPayload(ctx)
+
+	account, err := db.CreateAccountWithNumber(ctx, server.store, server.config.AccountNumberCountry, db.CreateAccountParams{
+		Owner:    authPayload.Username,
+		Currency: req.GetCurrency(),
+		Balance:  0,
+	})
+	if err != nil {
+		return nil, storeError(ctx, err)
+	}
+	return convertAccount(account), nil
+}
+
+func (server *Server) GetAccount(ctx context.Context, req *pb.GetAccountRequest) (*pb.Account, error) {
+	account, err := server.ownedAccount(ctx, req.GetRef())
+	if err != nil {
+		return nil, err
+	}
+	return convertAccount(account), nil
+}
+
+func (server *Server) ListAccounts(ctx context.Context, req *pb.ListAccountsRequest) (*pb.ListAccountsResponse, error) {
+	if req.GetPageId() < 1 {
+		return nil, status.Error(codes.InvalidArgument, "invalid page_id: must be at least 1")
+	}
+	if req.GetPageSize() < 5 || req.GetPageSize() > 10 {
+		return nil, status.Error(codes.InvalidArgument, "invalid page_size: must be from 5 to 10")
+	}
+
+	authPayload := middleware.AuthorizationPayload(ctx)
+
+	accounts, err := server.store.ListAccountsForUser(ctx, db.ListAccountsForUserParams{
+		Limit:    req.GetPageSize(),
+		Offset:   (req.GetPageId() - 1) * req.GetPageSize(),
+		Username: authPayload.Username,
+	})
+	if err != nil {
+		return nil, internalError(ctx, err)
+	}
+
+	rsp := &pb.ListAccountsResponse{Accounts: make([]*pb.Account, len(accounts))}
+	for i, account := range accounts {
+		rsp.Accounts[i] = convertAccount(account)
+	}
+	return rsp, nil
+}
+
+func (server *Server) GetAccountBalance(ctx context.Context, req *pb.GetAccountBalanceRequest) (*pb.AccountBalance, error) {
+	at := time.Now()
+	if req.At != nil {
+		if err := req.GetAt().CheckValid(); err != nil {
+			return nil, invalidArgumentError("at", err)
+		}
+		at = req.GetAt().AsTime()
+	}
+
+	account, err := server.ownedAccount(ctx, req.GetRef())
+	if err != nil {
+		return nil, err
+	}
+
+	balance, err := server.store.GetAccountBalanceAt(ctx, db.GetAccountBalanceAtParams{
+		AccountID: account.ID,
+		At:        at,
+	})
+	if err != nil {
+		return nil, internalError(ctx, err)
+	}
+
+	return &pb.AccountBalance{
+		AccountId:     account.ID,
+		AccountNumber: account.AccountNumber,
+		Currency:      account.Currency,
+		At:            timestamppb.New(at),
+		Balance:       balance,
+	}, nil
+}
+
+// ownedAccount looks up an account by its id or account number and checks
+// that it belongs to the authenticated user
+func (server *Server) ownedAccount(ctx context.Context, ref string) (db.Account, error) {
+	if err := validateAccountRef(ref); err != nil {
+		return db.Account{}, invalidArgumentError("ref", err)
+	}
+
+	var account db.Account
+	var err error
+	if util.IsValidAccountNumber(ref) {
+		account, err = server.store.GetAccountByNumber(ctx, ref)
+	} else {
+		id, _ := strconv.ParseInt(ref, 10, 64)
+		account, err = server.store.GetAccount(ctx, id)
+	}
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return account, status.Error(codes.NotFound, auth.ErrAccountNotFound.Error())
+		}
+		return account, internalError(ctx, err)
+	}
+
+	if account.Owner != middleware.AuthorizationPayload(ctx).Username {
+		return account, status.Error(codes.PermissionDenied, auth.ErrAccountNotOwned.Error())
+	}
+	return account, nil
+}
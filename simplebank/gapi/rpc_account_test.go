@@ -0,0 +1,241 @@
+package gapi
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	mockdb "github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/db/mock"
+	db "github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/db/sqlc"
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/pb"
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/token"
+	"github.com/golang/mock/gomock"
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestGetAccountRPC(t *testing.T) {
+	user, _ := randomUser(t)
+	account := randomAccount(user.Username)
+
+	testCases := []struct {
+		name          string
+		ref           string
+		setupAuth     func(t *testing.T, server *Server) context.Context
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, rsp *pb.Account, err error)
+	}{
+		{
+			name: "OK",
+			ref:  fmt.Sprint(account.ID),
+			setupAuth: func(t *testing.T, server *Server) context.Context {
+				return withAccessToken(t, server, user.Username)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+					Times(1).
+					Return(account, nil)
+				stubAuthUser(store)
+			},
+			checkResponse: func(t *testing.T, rsp *pb.Account, err error) {
+				require.NoError(t, err)
+				require.Equal(t, account.ID, rsp.GetId())
+				require.Equal(t, account.Balance, rsp.GetBalance())
+			},
+		},
+		{
+			name: "OKByAccountNumber",
+			ref:  account.AccountNumber,
+			setupAuth: func(t *testing.T, server *Server) context.Context {
+				return withAccessToken(t, server, user.Username, token.ScopeAccountsRead)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAccountByNumber(gomock.Any(), gomock.Eq(account.AccountNumber)).
+					Times(1).
+					Return(account, nil)
+				stubAuthUser(store)
+			},
+			checkResponse: func(t *testing.T, rsp *pb.Account, err error) {
+				require.NoError(t, err)
+				require.Equal(t, account.AccountNumber, rsp.GetAccountNumber())
+			},
+		},
+		{
+			name: "NotOwned",
+			ref:  fmt.Sprint(account.ID),
+			setupAuth: func(t *testing.T, server *Server) context.Context {
+				return withAccessToken(t, server, "unauthorized_user")
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+					Times(1).
+					Return(account, nil)
+				stubAuthUser(store)
+			},
+			checkResponse: func(t *testing.T, rsp *pb.Account, err error) {
+				require.Equal(t, codes.PermissionDenied, status.Code(err))
+			},
+		},
+		{
+			name: "NotFound",
+			ref:  fmt.Sprint(account.ID),
+			setupAuth: func(t *testing.T, server *Server) context.Context {
+				return withAccessToken(t, server, user.Username)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+					Times(1).
+					Return(db.Account{}, pgx.ErrNoRows)
+				stubAuthUser(store)
+			},
+			checkResponse: func(t *testing.T, rsp *pb.Account, err error) {
+				require.Equal(t, codes.NotFound, status.Code(err))
+			},
+		},
+		{
+			name: "InvalidRef",
+			ref:  "0",
+			setupAuth: func(t *testing.T, server *Server) context.Context {
+				return withAccessToken(t, server, user.Username)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Any()).
+					Times(0)
+				stubAuthUser(store)
+			},
+			checkResponse: func(t *testing.T, rsp *pb.Account, err error) {
+				require.Equal(t, codes.InvalidArgument, status.Code(err))
+			},
+		},
+		{
+			name: "MissingScope",
+			ref:  fmt.Sprint(account.ID),
+			setupAuth: func(t *testing.T, server *Server) context.Context {
+				return withAccessToken(t, server, user.Username, token.ScopeTransfersWrite)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Any()).
+					Times(0)
+				stubAuthUser(store)
+			},
+			checkResponse: func(t *testing.T, rsp *pb.Account, err error) {
+				require.Equal(t, codes.PermissionDenied, status.Code(err))
+			},
+		},
+		{
+			name: "NoAuthorization",
+			ref:  fmt.Sprint(account.ID),
+			setupAuth: func(t *testing.T, server *Server) context.Context {
+				return context.Background()
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, rsp *pb.Account, err error) {
+				require.Equal(t, codes.Unauthenticated, status.Code(err))
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			client := pb.NewAccountServiceClient(newTestClient(t, server))
+
+			rsp, err := client.GetAccount(tc.setupAuth(t, server), &pb.GetAccountRequest{Ref: tc.ref})
+			tc.checkResponse(t, rsp, err)
+		})
+	}
+}
+
+func TestListAccountsRPC(t *testing.T) {
+	user, _ := randomUser(t)
+
+	n := 5
+	accounts := make([]db.Account, n)
+	for i := 0; i < n; i++ {
+		accounts[i] = randomAccount(user.Username)
+	}
+
+	testCases := []struct {
+		name          string
+		req           *pb.ListAccountsRequest
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, rsp *pb.ListAccountsResponse, err error)
+	}{
+		{
+			name: "OK",
+			req:  &pb.ListAccountsRequest{PageId: 1, PageSize: int32(n)},
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.ListAccountsForUserParams{
+					Username: user.Username,
+					Limit:    int32(n),
+					Offset:   0,
+				}
+
+				store.EXPECT().
+					ListAccountsForUser(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(accounts, nil)
+				stubAuthUser(store)
+			},
+			checkResponse: func(t *testing.T, rsp *pb.ListAccountsResponse, err error) {
+				require.NoError(t, err)
+				require.Len(t, rsp.GetAccounts(), n)
+				for i, account := range rsp.GetAccounts() {
+					require.Equal(t, accounts[i].ID, account.GetId())
+				}
+			},
+		},
+		{
+			name: "InvalidPageSize",
+			req:  &pb.ListAccountsRequest{PageId: 1, PageSize: 100000},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ListAccountsForUser(gomock.Any(), gomock.Any()).
+					Times(0)
+				stubAuthUser(store)
+			},
+			checkResponse: func(t *testing.T, rsp *pb.ListAccountsResponse, err error) {
+				require.Equal(t, codes.InvalidArgument, status.Code(err))
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			client := pb.NewAccountServiceClient(newTestClient(t, server))
+
+			ctx := withAccessToken(t, server, user.Username)
+			rsp, err := client.ListAccounts(ctx, tc.req)
+			tc.checkResponse(t, rsp, err)
+		})
+	}
+}
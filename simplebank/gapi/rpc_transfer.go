@@ -0,0 +1,117 @@
+package gapi
+
+import (
+	"context"
+	"errors"
+
+	db "github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/db/sqlc"
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/pb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var errEmailNotVerified = errors.New("email address has not been verified")
+
+func (server *Server) CreateTransfer(ctx context.Context, req *pb.CreateTransferRequest) (*pb.CreateTransferResponse, error) {
+	if err := validateCreateTransferRequest(req); err != nil {
+		return nil, err
+	}
+
+	user := authorizationUser(ctx)
+	if !user.IsEmailVerified {
+		return nil, status.Error(codes.PermissionDenied, errEmailNotVerified.Error())
+	}
+
+	fromAccount, err := server.validAccount(ctx, user.Username, req.GetFromAccountId(), req.GetFromAccountNumber(), req.GetCurrency())
+	if err != nil {
+		return nil, err
+	}
+
+	toAccount, err := server.validAccount(ctx, "", req.GetToAccountId(), req.GetToAccountNumber(), req.GetCurrency())
+	if err != nil {
+		return nil, err
+	}
+
+	if err := server.checkStepUp(ctx, user, req.GetAmount(), req.GetTotpCode()); err != nil {
+		return nil, err
+	}
+
+	result, err := server.store.TransferTx(ctx, db.TransferTxParams{
+		FromAccountID: fromAccount.ID,
+		ToAccountID:   toAccount.ID,
+		Amount:        req.GetAmount(),
+	})
+	if err != nil {
+		if errors.Is(err, db.ErrAccountFrozen) {
+			return nil, status.Error(codes.FailedPrecondition, err.Error())
+		}
+		return nil, internalError(ctx, err)
+	}
+
+	rsp := &pb.CreateTransferResponse{
+		Transfer:    convertTransfer(result.Transfer),
+		FromAccount: convertAccount(result.FromAccount),
+		ToAccount:   convertAccount(result.ToAccount),
+		FromEntry:   convertEntry(result.FromEntry),
+		ToEntry:     convertEntry(result.ToEntry),
+	}
+	if result.FeeEntry != nil {
+		rsp.FeeEntry = convertEntry(*result.FeeEntry)
+	}
+	return rsp, nil
+}
+
+func validateCreateTransferRequest(req *pb.CreateTransferRequest) error {
+	switch from := req.GetFrom().(type) {
+	case *pb.CreateTransferRequest_FromAccountId:
+		if from.FromAccountId <= 0 {
+			return invalidArgumentError("from_account_id", errors.New("must be positive"))
+		}
+	case *pb.CreateTransferRequest_FromAccountNumber:
+		if err := validateAccountNumber(from.FromAccountNumber); err != nil {
+			return invalidArgumentError("from_account_number", err)
+		}
+	default:
+		return status.Error(codes.InvalidArgument, "from_account_id or from_account_number is required")
+	}
+
+	switch to := req.GetTo().(type) {
+	case *pb.CreateTransferRequest_ToAccountId:
+		if to.ToAccountId <= 0 {
+			return invalidArgumentError("to_account_id", errors.New("must be positive"))
+		}
+	case *pb.CreateTransferRequest_ToAccountNumber:
+		if err := validateAccountNumber(to.ToAccountNumber); err != nil {
+			return invalidArgumentError("to_account_number", err)
+		}
+	default:
+		return status.Error(codes.InvalidArgument, "to_account_id or to_account_number is required")
+	}
+
+	if req.GetAmount() < 1 {
+		return invalidArgumentError("amount", errors.New("must be positive"))
+	}
+	if err := validateCurrency(req.GetCurrency()); err != nil {
+		return invalidArgumentError("currency", err)
+	}
+	return nil
+}
+
+// validAccount looks up the account by number when one is given and by id
+// otherwise, and checks that it belongs to owner when one is given
+func (server *Server) validAccount(ctx context.Context, owner string, accountID int64, accountNumber string, currency string) (db.Account, error) {
+	account, err := server.authenticator.TransferAccount(ctx, owner, accountID, accountNumber, currency)
+	if err != nil {
+		return account, authError(ctx, err)
+	}
+	return account, nil
+}
+
+// checkStepUp asks for a TOTP code on transfers of at least the configured
+// step-up amount
+func (server *Server) checkStepUp(ctx context.Context, user db.User, amount int64, code string) error {
+	if err := server.authenticator.CheckStepUp(ctx, user, amount, code); err != nil {
+		return authError(ctx, err)
+	}
+	return nil
+}
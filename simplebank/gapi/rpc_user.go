@@ -0,0 +1,203 @@
+package gapi
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	db "github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/db/sqlc"
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/middleware"
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/pb"
+	"github.com/jackc/pgx/v5/pgtype"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+var errIncorrectPassword = errors.New("password is incorrect")
+
+func (server *Server) CreateUser(ctx context.Context, req *pb.CreateUserRequest) (*pb.User, error) {
+	if err := validateCreateUserRequest(req); err != nil {
+		return nil, err
+	}
+	if err := server.passwordPolicy.Check(req.GetPassword()); err != nil {
+		return nil, invalidArgumentError("password", err)
+	}
+
+	hashedPassword, err := server.passwordHasher.Hash(req.GetPassword())
+	if err != nil {
+		return nil, internalError(ctx, err)
+	}
+
+	user, err := server.store.CreateUser(ctx, db.CreateUserParams{
+		Username:       req.GetUsername(),
+		HashedPassword: hashedPassword,
+		FullName:       req.GetFullName(),
+		Email:          req.GetEmail(),
+	})
+	if err != nil {
+		return nil, storeError(ctx, err)
+	}
+
+	// the user can ask for a new link, so a mail failure doesn't fail the signup
+	if err := server.sendVerifyEmail(ctx, user); err != nil {
+		slog.ErrorContext(ctx, "cannot send verification email", "username", user.Username, "error", err)
+	}
+
+	return convertUser(user), nil
+}
+
+func validateCreateUserRequest(req *pb.CreateUserRequest) error {
+	if err := validateUsername(req.GetUsername()); err != nil {
+		return invalidArgumentError("username", err)
+	}
+	if err := validateFullName(req.GetFullName()); err != nil {
+		return invalidArgumentError("full_name", err)
+	}
+	if err := validateEmail(req.GetEmail()); err != nil {
+		return invalidArgumentError("email", err)
+	}
+	return nil
+}
+
+func (server *Server) LoginUser(ctx context.Context, req *pb.LoginUserRequest) (*pb.LoginUserResponse, error) {
+	if err := server.checkLoginRateLimit(ctx); err != nil {
+		return nil, err
+	}
+
+	if err := validateUsername(req.GetUsername()); err != nil {
+		return nil, invalidArgumentError("username", err)
+	}
+	if err := validateScopes(req.GetScopes()); err != nil {
+		return nil, invalidArgumentError("scopes", err)
+	}
+
+	if err := server.checkLoginLock(ctx, req.GetUsername()); err != nil {
+		return nil, err
+	}
+
+	user, err := server.authenticator.CheckPassword(ctx, req.GetUsername(), req.GetPassword(), clientIP(ctx))
+	if err != nil {
+		return nil, authError(ctx, err)
+	}
+
+	// users with two-factor authentication complete the login over HTTP
+	if user.IsTotpEnabled {
+		challengeToken, payload, err := server.challengeTokenMaker.CreateScopedToken(
+			user.Username,
+			server.config.TOTPChallengeDuration,
+			req.GetScopes()...,
+		)
+		if err != nil {
+			return nil, internalError(ctx, err)
+		}
+
+		return &pb.LoginUserResponse{
+			TotpRequired:       true,
+			ChallengeToken:     challengeToken,
+			ChallengeExpiresAt: timestamppb.New(payload.ExpiredAt),
+		}, nil
+	}
+
+	server.authenticator.ResetLoginFailures(ctx, user.Username)
+	return server.issueAccessToken(ctx, user, req.GetScopes()...)
+}
+
+func (server *Server) issueAccessToken(ctx context.Context, user db.User, scopes ...string) (*pb.LoginUserResponse, error) {
+	accessToken, payload, err := server.tokenMaker.CreateScopedToken(
+		user.Username,
+		server.config.AccessTokenDuration,
+		scopes...,
+	)
+	if err != nil {
+		return nil, internalError(ctx, err)
+	}
+
+	return &pb.LoginUserResponse{
+		AccessToken: accessToken,
+		Scopes:      payload.Scopes,
+		User:        convertUser(user),
+	}, nil
+}
+
+func (server *Server) GetCurrentUser(ctx context.Context, req *pb.GetCurrentUserRequest) (*pb.User, error) {
+	return convertUser(authorizationUser(ctx)), nil
+}
+
+func (server *Server) UpdateCurrentUser(ctx context.Context, req *pb.UpdateCurrentUserRequest) (*pb.User, error) {
+	if req.FullName == nil && req.Email == nil {
+		return nil, status.Error(codes.InvalidArgument, "full_name or email is required")
+	}
+	if req.FullName != nil {
+		if err := validateFullName(req.GetFullName()); err != nil {
+			return nil, invalidArgumentError("full_name", err)
+		}
+	}
+	if req.Email != nil {
+		if err := validateEmail(req.GetEmail()); err != nil {
+			return nil, invalidArgumentError("email", err)
+		}
+	}
+
+	user := authorizationUser(ctx)
+
+	arg := db.UpdateUserParams{
+		Username: user.Username,
+		FullName: pgtype.Text{String: req.GetFullName(), Valid: req.FullName != nil},
+	}
+
+	// a new email address has to be verified again
+	emailChanged := req.Email != nil && req.GetEmail() != user.Email
+	if emailChanged {
+		arg.Email = pgtype.Text{String: req.GetEmail(), Valid: true}
+		arg.IsEmailVerified = pgtype.Bool{Bool: false, Valid: true}
+	}
+
+	user, err := server.store.UpdateUser(ctx, arg)
+	if err != nil {
+		return nil, storeError(ctx, err)
+	}
+
+	if emailChanged {
+		if err := server.sendVerifyEmail(ctx, user); err != nil {
+			slog.ErrorContext(ctx, "cannot send verification email", "username", user.Username, "error", err)
+		}
+	}
+
+	return convertUser(user), nil
+}
+
+// UpdatePassword changes the password of the authenticated user. Tokens
+// issued before the change stop working, so a fresh access token is returned.
+func (server *Server) UpdatePassword(ctx context.Context, req *pb.UpdatePasswordRequest) (*pb.LoginUserResponse, error) {
+	if req.GetNewPassword() == req.GetCurrentPassword() {
+		return nil, status.Error(codes.InvalidArgument, "invalid new_password: must differ from current_password")
+	}
+	if err := server.passwordPolicy.Check(req.GetNewPassword()); err != nil {
+		return nil, invalidArgumentError("new_password", err)
+	}
+
+	user := authorizationUser(ctx)
+
+	if err := server.passwordHasher.Check(req.GetCurrentPassword(), user.HashedPassword); err != nil {
+		return nil, status.Error(codes.Unauthenticated, errIncorrectPassword.Error())
+	}
+
+	hashedPassword, err := server.passwordHasher.Hash(req.GetNewPassword())
+	if err != nil {
+		return nil, internalError(ctx, err)
+	}
+
+	user, err = server.store.UpdateUser(ctx, db.UpdateUserParams{
+		Username:          user.Username,
+		HashedPassword:    pgtype.Text{String: hashedPassword, Valid: true},
+		PasswordChangedAt: pgtype.Timestamptz{Time: time.Now(), Valid: true},
+	})
+	if err != nil {
+		return nil, internalError(ctx, err)
+	}
+
+	// the new token replaces the revoked one, with the same scopes
+	return server.issueAccessToken(ctx, user, middleware.AuthorizationPayload(ctx).Scopes...)
+}
@@ -0,0 +1,445 @@
+package gapi
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/auth"
+	mockdb "github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/db/mock"
+	db "github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/db/sqlc"
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/pb"
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/ratelimit"
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/token"
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/util"
+	"github.com/golang/mock/gomock"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestCreateUserRPC(t *testing.T) {
+	user, password := randomUser(t)
+
+	testCases := []struct {
+		name          string
+		req           *pb.CreateUserRequest
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, rsp *pb.User, err error)
+	}{
+		{
+			name: "OK",
+			req: &pb.CreateUserRequest{
+				Username: user.Username,
+				Password: password,
+				FullName: user.FullName,
+				Email:    user.Email,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateUser(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg db.CreateUserParams) (db.User, error) {
+						require.Equal(t, user.Username, arg.Username)
+						require.NoError(t, util.CheckPassword(password, arg.HashedPassword))
+						return user, nil
+					})
+				store.EXPECT().
+					CreateVerifyEmail(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.VerifyEmail{Username: user.Username, Email: user.Email}, nil)
+			},
+			checkResponse: func(t *testing.T, rsp *pb.User, err error) {
+				require.NoError(t, err)
+				require.Equal(t, user.Username, rsp.GetUsername())
+				require.Equal(t, user.Email, rsp.GetEmail())
+				require.False(t, rsp.GetIsEmailVerified())
+			},
+		},
+		{
+			name: "InvalidEmail",
+			req: &pb.CreateUserRequest{
+				Username: user.Username,
+				Password: password,
+				FullName: user.FullName,
+				Email:    "invalid-email",
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateUser(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, rsp *pb.User, err error) {
+				require.Equal(t, codes.InvalidArgument, status.Code(err))
+			},
+		},
+		{
+			name: "DuplicateUsername",
+			req: &pb.CreateUserRequest{
+				Username: user.Username,
+				Password: password,
+				FullName: user.FullName,
+				Email:    user.Email,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateUser(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.User{}, &pgconn.PgError{Code: "23505", ConstraintName: "users_pkey", Detail: "Key (username)=(" + user.Username + ") already exists."})
+			},
+			checkResponse: func(t *testing.T, rsp *pb.User, err error) {
+				require.Equal(t, codes.AlreadyExists, status.Code(err))
+				require.Equal(t, "username already exists", status.Convert(err).Message())
+			},
+		},
+		{
+			name: "InternalError",
+			req: &pb.CreateUserRequest{
+				Username: user.Username,
+				Password: password,
+				FullName: user.FullName,
+				Email:    user.Email,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateUser(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.User{}, errors.New("dial tcp 10.0.0.5:5432: connection refused"))
+			},
+			checkResponse: func(t *testing.T, rsp *pb.User, err error) {
+				require.Equal(t, codes.Internal, status.Code(err))
+				require.Equal(t, "internal server error", status.Convert(err).Message())
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			client := pb.NewUserServiceClient(newTestClient(t, server))
+
+			rsp, err := client.CreateUser(context.Background(), tc.req)
+			tc.checkResponse(t, rsp, err)
+		})
+	}
+}
+
+func TestLoginUserRPC(t *testing.T) {
+	user, password := randomUser(t)
+
+	totpUser, totpPassword := randomUser(t)
+	totpUser.IsTotpEnabled = true
+
+	testCases := []struct {
+		name          string
+		req           *pb.LoginUserRequest
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, server *Server, rsp *pb.LoginUserResponse, err error)
+	}{
+		{
+			name: "OK",
+			req: &pb.LoginUserRequest{
+				Username: user.Username,
+				Password: password,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					RecordLoginFailure(gomock.Any(), gomock.Any()).
+					Times(0)
+				stubLoginThrottle(store)
+			},
+			checkResponse: func(t *testing.T, server *Server, rsp *pb.LoginUserResponse, err error) {
+				require.NoError(t, err)
+				require.Equal(t, user.Username, rsp.GetUser().GetUsername())
+
+				payload, err := server.tokenMaker.VerifyToken(rsp.GetAccessToken())
+				require.NoError(t, err)
+				require.Equal(t, user.Username, payload.Username)
+			},
+		},
+		{
+			name: "ScopedToken",
+			req: &pb.LoginUserRequest{
+				Username: user.Username,
+				Password: password,
+				Scopes:   []string{token.ScopeAccountsRead},
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				stubLoginThrottle(store)
+			},
+			checkResponse: func(t *testing.T, server *Server, rsp *pb.LoginUserResponse, err error) {
+				require.NoError(t, err)
+				require.Equal(t, []string{token.ScopeAccountsRead}, rsp.GetScopes())
+			},
+		},
+		{
+			name: "IncorrectPassword",
+			req: &pb.LoginUserRequest{
+				Username: user.Username,
+				Password: "incorrect",
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					RecordLoginFailure(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.LoginThrottle{FailedAttempts: 1}, nil)
+				stubLoginThrottle(store)
+			},
+			checkResponse: func(t *testing.T, server *Server, rsp *pb.LoginUserResponse, err error) {
+				require.Equal(t, codes.Unauthenticated, status.Code(err))
+			},
+		},
+		{
+			name: "UserNotFound",
+			req: &pb.LoginUserRequest{
+				Username: user.Username,
+				Password: password,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.User{}, pgx.ErrNoRows)
+				stubLoginThrottle(store)
+			},
+			checkResponse: func(t *testing.T, server *Server, rsp *pb.LoginUserResponse, err error) {
+				require.Equal(t, codes.Unauthenticated, status.Code(err))
+				require.Equal(t, auth.ErrInvalidCredentials.Error(), status.Convert(err).Message())
+			},
+		},
+		{
+			name: "TOTPChallenge",
+			req: &pb.LoginUserRequest{
+				Username: totpUser.Username,
+				Password: totpPassword,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(totpUser.Username)).
+					Times(1).
+					Return(totpUser, nil)
+				store.EXPECT().
+					DeleteLoginThrottle(gomock.Any(), gomock.Any()).
+					Times(0)
+				stubLoginThrottle(store)
+			},
+			checkResponse: func(t *testing.T, server *Server, rsp *pb.LoginUserResponse, err error) {
+				require.NoError(t, err)
+				require.True(t, rsp.GetTotpRequired())
+				require.Empty(t, rsp.GetAccessToken())
+
+				// the challenge token is no access token
+				_, err = server.tokenMaker.VerifyToken(rsp.GetChallengeToken())
+				require.Error(t, err)
+				_, err = server.challengeTokenMaker.VerifyToken(rsp.GetChallengeToken())
+				require.NoError(t, err)
+			},
+		},
+		{
+			name: "InvalidScope",
+			req: &pb.LoginUserRequest{
+				Username: user.Username,
+				Password: password,
+				Scopes:   []string{"accounts:delete"},
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, server *Server, rsp *pb.LoginUserResponse, err error) {
+				require.Equal(t, codes.InvalidArgument, status.Code(err))
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			client := pb.NewUserServiceClient(newTestClient(t, server))
+
+			rsp, err := client.LoginUser(context.Background(), tc.req)
+			tc.checkResponse(t, server, rsp, err)
+		})
+	}
+}
+
+func TestLoginUserRPCRateLimit(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// an invalid username is rejected before the store is used, but still counts
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(0)
+
+	config := newTestConfig()
+	config.RateLimitBackend = ratelimit.BackendMemory
+	config.RateLimitLogin = "2/m"
+	server := newTestServerWithConfig(t, store, config)
+	client := pb.NewUserServiceClient(newTestClient(t, server))
+
+	req := &pb.LoginUserRequest{Username: "not-alphanum", Password: "secret"}
+	for range 2 {
+		_, err := client.LoginUser(context.Background(), req)
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+	}
+
+	var header metadata.MD
+	_, err := client.LoginUser(context.Background(), req, grpc.Header(&header))
+	require.Equal(t, codes.ResourceExhausted, status.Code(err))
+	require.Equal(t, []string{"30"}, header.Get("retry-after"))
+}
+
+func TestNewServerRateLimitConfig(t *testing.T) {
+	testCases := []struct {
+		name   string
+		modify func(config *util.Config)
+	}{
+		{
+			name: "UnknownBackend",
+			modify: func(config *util.Config) {
+				config.RateLimitBackend = "redis"
+			},
+		},
+		{
+			name: "InvalidLimit",
+			modify: func(config *util.Config) {
+				config.RateLimitBackend = ratelimit.BackendMemory
+				config.RateLimitLogin = "10 per minute"
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			config := newTestConfig()
+			tc.modify(&config)
+
+			_, err := NewServer(config, nil)
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestGetCurrentUserRPC(t *testing.T) {
+	user, _ := randomUser(t)
+
+	testCases := []struct {
+		name          string
+		setupAuth     func(t *testing.T, server *Server) context.Context
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, rsp *pb.User, err error)
+	}{
+		{
+			name: "OK",
+			setupAuth: func(t *testing.T, server *Server) context.Context {
+				return withAccessToken(t, server, user.Username)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+			},
+			checkResponse: func(t *testing.T, rsp *pb.User, err error) {
+				require.NoError(t, err)
+				require.Equal(t, user.Username, rsp.GetUsername())
+				require.Equal(t, user.FullName, rsp.GetFullName())
+			},
+		},
+		{
+			name: "NoAuthorization",
+			setupAuth: func(t *testing.T, server *Server) context.Context {
+				return context.Background()
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, rsp *pb.User, err error) {
+				require.Equal(t, codes.Unauthenticated, status.Code(err))
+			},
+		},
+		{
+			name: "MissingUserScope",
+			setupAuth: func(t *testing.T, server *Server) context.Context {
+				return withAccessToken(t, server, user.Username, token.ScopeAccountsRead)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+			},
+			checkResponse: func(t *testing.T, rsp *pb.User, err error) {
+				require.Equal(t, codes.PermissionDenied, status.Code(err))
+			},
+		},
+		{
+			name: "UserDeleted",
+			setupAuth: func(t *testing.T, server *Server) context.Context {
+				return withAccessToken(t, server, user.Username)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(db.User{}, pgx.ErrNoRows)
+			},
+			checkResponse: func(t *testing.T, rsp *pb.User, err error) {
+				require.Equal(t, codes.Unauthenticated, status.Code(err))
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			client := pb.NewUserServiceClient(newTestClient(t, server))
+
+			rsp, err := client.GetCurrentUser(tc.setupAuth(t, server), &pb.GetCurrentUserRequest{})
+			tc.checkResponse(t, rsp, err)
+		})
+	}
+}
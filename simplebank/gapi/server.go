@@ -0,0 +1,103 @@
+// Package gapi serves the users, accounts and transfers of the HTTP API over
+// gRPC for internal services.
+package gapi
+
+import (
+	"fmt"
+
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/auth"
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/core"
+	db "github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/db/sqlc"
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/mail"
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/middleware"
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/pb"
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/ratelimit"
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/token"
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/util"
+	"google.golang.org/grpc"
+)
+
+// Server implements the gRPC services. Tokens, api keys and challenge tokens
+// are interchangeable with those of the HTTP API.
+type Server struct {
+	pb.UnimplementedUserServiceServer
+	pb.UnimplementedAccountServiceServer
+	pb.UnimplementedTransferServiceServer
+
+	config              util.Config
+	store               db.Store
+	tokenMaker          token.Maker
+	challengeTokenMaker token.Maker
+	mailer              mail.Mailer
+	passwordHasher      util.PasswordHasher
+	passwordPolicy      *util.PasswordPolicy
+	authenticator       *auth.Authenticator
+	rateLimits          ratelimit.Store
+	loginRateLimit      ratelimit.Limit
+}
+
+func NewServer(config util.Config, store db.Store) (*Server, error) {
+	shared, err := core.New(config, store)
+	if err != nil {
+		return nil, err
+	}
+
+	rateLimits, err := ratelimit.NewStore(config.RateLimitBackend, store)
+	if err != nil {
+		return nil, err
+	}
+
+	loginRateLimit, err := ratelimit.ParseLimit(config.RateLimitLogin)
+	if err != nil {
+		return nil, fmt.Errorf("login rate limit: %w", err)
+	}
+
+	server := &Server{
+		config:              config,
+		store:               store,
+		tokenMaker:          shared.TokenMaker,
+		challengeTokenMaker: shared.ChallengeTokenMaker,
+		mailer:              shared.Mailer,
+		passwordHasher:      shared.PasswordHasher,
+		passwordPolicy:      shared.PasswordPolicy,
+		authenticator:       shared.Authenticator,
+		rateLimits:          rateLimits,
+		loginRateLimit:      loginRateLimit,
+	}
+	return server, nil
+}
+
+// methodAuth lists how each method is authorized, as the route groups of
+// the HTTP API do
+var methodAuth = map[string]middleware.MethodAuth{
+	pb.UserService_CreateUser_FullMethodName:        {Public: true},
+	pb.UserService_LoginUser_FullMethodName:         {Public: true},
+	pb.UserService_GetCurrentUser_FullMethodName:    {NoAPIKeys: true, Scopes: []string{token.ScopeUser}},
+	pb.UserService_UpdateCurrentUser_FullMethodName: {NoAPIKeys: true, Scopes: []string{token.ScopeUser}},
+	pb.UserService_UpdatePassword_FullMethodName:    {NoAPIKeys: true, Scopes: []string{token.ScopeUser}},
+
+	pb.AccountService_CreateAccount_FullMethodName:     {Scopes: []string{token.ScopeAccountsWrite}},
+	pb.AccountService_GetAccount_FullMethodName:        {Scopes: []string{token.ScopeAccountsRead}},
+	pb.AccountService_ListAccounts_FullMethodName:      {Scopes: []string{token.ScopeAccountsRead}},
+	pb.AccountService_GetAccountBalance_FullMethodName: {Scopes: []string{token.ScopeAccountsRead}},
+
+	pb.TransferService_CreateTransfer_FullMethodName: {Scopes: []string{token.ScopeTransfersWrite}},
+}
+
+// NewGRPCServer returns a gRPC server with the services registered behind
+// the auth interceptor
+func (server *Server) NewGRPCServer(opts ...grpc.ServerOption) *grpc.Server {
+	interceptor := middleware.AuthInterceptor(
+		server.tokenMaker,
+		server.verifyAPIKey,
+		methodAuth,
+		server.checkTokenUser,
+		server.checkTokenClient,
+	)
+
+	grpcServer := grpc.NewServer(append(opts, grpc.ChainUnaryInterceptor(interceptor))...)
+	pb.RegisterUserServiceServer(grpcServer, server)
+	pb.RegisterAccountServiceServer(grpcServer, server)
+	pb.RegisterTransferServiceServer(grpcServer, server)
+	return grpcServer
+}
@@ -0,0 +1,79 @@
+package gapi
+
+import (
+	"errors"
+	"fmt"
+	"net/mail"
+	"strconv"
+
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/token"
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/util"
+)
+
+// the validators apply the binding rules of the matching HTTP requests
+
+func validateUsername(username string) error {
+	if username == "" {
+		return errors.New("must not be empty")
+	}
+	for _, r := range username {
+		if !('a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' || '0' <= r && r <= '9') {
+			return errors.New("must contain only letters and digits")
+		}
+	}
+	return nil
+}
+
+func validateEmail(email string) error {
+	address, err := mail.ParseAddress(email)
+	if err != nil || address.Address != email {
+		return errors.New("is not a valid email address")
+	}
+	return nil
+}
+
+func validateFullName(fullName string) error {
+	if fullName == "" {
+		return errors.New("must not be empty")
+	}
+	return nil
+}
+
+func validateScopes(scopes []string) error {
+	seen := make(map[string]bool, len(scopes))
+	for _, scope := range scopes {
+		if !token.IsSupportedScope(scope) {
+			return fmt.Errorf("%q is not a supported scope", scope)
+		}
+		if seen[scope] {
+			return fmt.Errorf("%q is given twice", scope)
+		}
+		seen[scope] = true
+	}
+	return nil
+}
+
+func validateCurrency(currency string) error {
+	if !util.IsSupportedCurrency(currency) {
+		return errors.New("is not a supported currency")
+	}
+	return nil
+}
+
+func validateAccountNumber(accountNumber string) error {
+	if !util.IsValidAccountNumber(accountNumber) {
+		return errors.New("is not a valid account number")
+	}
+	return nil
+}
+
+// validateAccountRef accepts either a positive numeric account id or an account number
+func validateAccountRef(ref string) error {
+	if util.IsValidAccountNumber(ref) {
+		return nil
+	}
+	if id, err := strconv.ParseInt(ref, 10, 64); err != nil || id <= 0 {
+		return errors.New("must be an account id or account number")
+	}
+	return nil
+}
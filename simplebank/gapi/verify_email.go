@@ -0,0 +1,45 @@
+package gapi
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"time"
+
+	db "github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/db/sqlc"
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/mail"
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/util"
+)
+
+const verifyEmailSecretBytes = 32
+
+// sendVerifyEmail mails the same verification link as the HTTP API, which
+// verifies the address when opened
+func (server *Server) sendVerifyEmail(ctx context.Context, user db.User) error {
+	secretCode, err := util.GenerateSecret(verifyEmailSecretBytes)
+	if err != nil {
+		return err
+	}
+
+	verifyEmail, err := server.store.CreateVerifyEmail(ctx, db.CreateVerifyEmailParams{
+		Username:   user.Username,
+		Email:      user.Email,
+		SecretCode: secretCode,
+		ExpiredAt:  time.Now().Add(server.config.VerifyEmailDuration),
+	})
+	if err != nil {
+		return fmt.Errorf("cannot create verification code: %w", err)
+	}
+
+	link := fmt.Sprintf("%s?%s", server.config.VerifyEmailURL, url.Values{
+		"email_id":    {fmt.Sprint(verifyEmail.ID)},
+		"secret_code": {verifyEmail.SecretCode},
+	}.Encode())
+
+	return server.mailer.SendEmail(ctx, mail.Email{
+		To:      []string{user.Email},
+		Subject: "Verify your Simple Bank email address",
+		Body: fmt.Sprintf("Hello %s,\n\nPlease verify your email address by opening the link below:\n\n%s\n\nThe link expires at %s.\n",
+			user.FullName, link, verifyEmail.ExpiredAt.UTC().Format(time.RFC1123)),
+	})
+}
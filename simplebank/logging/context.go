@@ -0,0 +1,37 @@
+package logging
+
+import (
+	"context"
+	"net/url"
+)
+
+type requestIDContextKey struct{}
+
+// WithRequestID returns a context carrying the id of the request it serves.
+// Records logged with the context include the id.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDContextKey{}, id)
+}
+
+// RequestID returns the id of the request the context serves, if any
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDContextKey{}).(string)
+	return id
+}
+
+// RedactQuery returns the encoded query with the values of sensitive
+// parameters, such as codes and tokens in links, redacted
+func RedactQuery(query url.Values) string {
+	redacted := make(url.Values, len(query))
+	for key, values := range query {
+		if !Sensitive(key) {
+			redacted[key] = values
+			continue
+		}
+		redacted[key] = make([]string, len(values))
+		for i := range values {
+			redacted[key][i] = Redacted
+		}
+	}
+	return redacted.Encode()
+}
@@ -0,0 +1,96 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestNew(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := New(&buf, FormatJSON, "warn")
+	require.NoError(t, err)
+
+	ctx := WithRequestID(context.Background(), "req-1")
+	logger.InfoContext(ctx, "dropped")
+	require.Empty(t, buf.String())
+
+	logger.WarnContext(ctx, "login failed",
+		"username", "alice",
+		"password", "secret123",
+		slog.Group("request", "authorization", "Bearer abc", "path", "/users/login"),
+	)
+
+	var record map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &record))
+	require.Equal(t, "login failed", record["msg"])
+	require.Equal(t, "req-1", record["request_id"])
+	require.Equal(t, "alice", record["username"])
+	require.Equal(t, Redacted, record["password"])
+	require.Equal(t, map[string]any{"authorization": Redacted, "path": "/users/login"}, record["request"])
+}
+
+func TestNewTrace(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := New(&buf, FormatJSON, "info")
+	require.NoError(t, err)
+
+	traceID, err := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	require.NoError(t, err)
+	spanID, err := trace.SpanIDFromHex("00f067aa0ba902b7")
+	require.NoError(t, err)
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: traceID,
+		SpanID:  spanID,
+	}))
+
+	logger.InfoContext(ctx, "query")
+
+	var record map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &record))
+	require.Equal(t, traceID.String(), record["trace_id"])
+	require.Equal(t, spanID.String(), record["span_id"])
+}
+
+func TestNewText(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := New(&buf, FormatText, "debug")
+	require.NoError(t, err)
+
+	logger.With("component", "test").Debug("hello", "refresh_token", "abc")
+	require.Contains(t, buf.String(), "level=DEBUG msg=hello component=test refresh_token=[REDACTED]")
+	require.NotContains(t, buf.String(), "request_id")
+}
+
+func TestNewInvalid(t *testing.T) {
+	_, err := New(&bytes.Buffer{}, "xml", "info")
+	require.ErrorContains(t, err, `invalid log format "xml"`)
+
+	_, err = New(&bytes.Buffer{}, FormatJSON, "verbose")
+	require.ErrorContains(t, err, `invalid log level "verbose"`)
+}
+
+func TestSensitive(t *testing.T) {
+	for _, key := range []string{"password", "new_password", "client_secret", "secret_code", "refresh_token", "Authorization", "code", "api_key"} {
+		require.True(t, Sensitive(key), key)
+	}
+	for _, key := range []string{"username", "path", "status", "api_key_id", "request_id"} {
+		require.False(t, Sensitive(key), key)
+	}
+}
+
+func TestRedactQuery(t *testing.T) {
+	query := url.Values{
+		"email_id":    {"42"},
+		"secret_code": {"abc"},
+		"code":        {"1", "2"},
+	}
+	require.Equal(t, "code=%5BREDACTED%5D&code=%5BREDACTED%5D&email_id=42&secret_code=%5BREDACTED%5D", RedactQuery(query))
+	require.Equal(t, []string{"abc"}, query["secret_code"], "the query must not change")
+}
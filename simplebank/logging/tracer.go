@@ -0,0 +1,60 @@
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	db "github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/db/sqlc"
+	"github.com/jackc/pgx/v5"
+)
+
+type queryStartContextKey struct{}
+
+type queryStart struct {
+	query string
+	time  time.Time
+}
+
+// QueryTracer logs the database queries of a pgx connection at debug level,
+// and failed ones at warn level, with the request id of their context. Query
+// arguments are never logged, as they hold passwords and secrets.
+type QueryTracer struct {
+	logger *slog.Logger
+}
+
+func NewQueryTracer(logger *slog.Logger) *QueryTracer {
+	return &QueryTracer{logger: logger}
+}
+
+// TraceQueryStart implements pgx.QueryTracer
+func (tracer *QueryTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	if !tracer.logger.Enabled(ctx, slog.LevelWarn) {
+		return ctx
+	}
+	return context.WithValue(ctx, queryStartContextKey{}, queryStart{
+		query: db.QueryName(data.SQL),
+		time:  time.Now(),
+	})
+}
+
+// TraceQueryEnd implements pgx.QueryTracer
+func (tracer *QueryTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	start, ok := ctx.Value(queryStartContextKey{}).(queryStart)
+	if !ok {
+		return
+	}
+
+	attrs := []slog.Attr{
+		slog.String("query", start.query),
+		slog.Duration("duration", time.Since(start.time)),
+	}
+	if data.Err != nil {
+		attrs = append(attrs, slog.Any("error", data.Err))
+		tracer.logger.LogAttrs(ctx, slog.LevelWarn, "query failed", attrs...)
+		return
+	}
+
+	attrs = append(attrs, slog.Int64("rows", data.CommandTag.RowsAffected()))
+	tracer.logger.LogAttrs(ctx, slog.LevelDebug, "query", attrs...)
+}
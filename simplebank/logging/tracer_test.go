@@ -0,0 +1,57 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueryTracer(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := New(&buf, FormatJSON, "debug")
+	require.NoError(t, err)
+	tracer := NewQueryTracer(logger)
+
+	ctx := WithRequestID(context.Background(), "req-1")
+	queryCtx := tracer.TraceQueryStart(ctx, nil, pgx.TraceQueryStartData{
+		SQL:  "-- name: UpdateUser :one\nUPDATE users SET hashed_password = $1",
+		Args: []any{"$argon2id$hash"},
+	})
+	tracer.TraceQueryEnd(queryCtx, nil, pgx.TraceQueryEndData{CommandTag: pgconn.NewCommandTag("UPDATE 1")})
+
+	var record map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &record))
+	require.Equal(t, "DEBUG", record["level"])
+	require.Equal(t, "UpdateUser", record["query"])
+	require.Equal(t, "req-1", record["request_id"])
+	require.EqualValues(t, 1, record["rows"])
+	require.NotContains(t, buf.String(), "argon2id")
+
+	buf.Reset()
+	queryCtx = tracer.TraceQueryStart(ctx, nil, pgx.TraceQueryStartData{SQL: "SELECT\n  1"})
+	tracer.TraceQueryEnd(queryCtx, nil, pgx.TraceQueryEndData{Err: errors.New("connection reset")})
+
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &record))
+	require.Equal(t, "WARN", record["level"])
+	require.Equal(t, "SELECT 1", record["query"])
+	require.Equal(t, "connection reset", record["error"])
+}
+
+func TestQueryTracerDisabled(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := New(&buf, FormatJSON, "error")
+	require.NoError(t, err)
+	tracer := NewQueryTracer(logger)
+
+	ctx := context.Background()
+	queryCtx := tracer.TraceQueryStart(ctx, nil, pgx.TraceQueryStartData{SQL: "SELECT 1"})
+	require.Equal(t, ctx, queryCtx)
+	tracer.TraceQueryEnd(queryCtx, nil, pgx.TraceQueryEndData{Err: errors.New("connection reset")})
+	require.Empty(t, buf.String())
+}
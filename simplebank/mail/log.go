@@ -0,0 +1,47 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// LogMailer does not deliver emails. It writes each one to a file in dir, or
+// to the log when dir is empty, which is handy for development and tests.
+type LogMailer struct {
+	dir    string
+	sender mail.Address
+}
+
+func NewLogMailer(dir string, sender mail.Address) Mailer {
+	return &LogMailer{
+		dir:    dir,
+		sender: sender,
+	}
+}
+
+// SendEmail implements Mailer
+func (mailer *LogMailer) SendEmail(ctx context.Context, email Email) error {
+	msg, err := buildMessage(mailer.sender, email)
+	if err != nil {
+		return err
+	}
+
+	if mailer.dir == "" {
+		slog.InfoContext(ctx, "email", "to", email.To, "message", string(msg))
+		return nil
+	}
+
+	name := fmt.Sprintf("%s-%s.eml", time.Now().UTC().Format("20060102T150405"), uuid.NewString())
+	if err := os.WriteFile(filepath.Join(mailer.dir, name), msg, 0o600); err != nil {
+		return fmt.Errorf("failed to write email: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,51 @@
+package mail
+
+import (
+	"context"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogMailerWritesFiles(t *testing.T) {
+	dir := t.TempDir()
+	mailer := NewLogMailer(dir, mail.Address{Name: "Simple Bank", Address: "no-reply@simplebank.local"})
+
+	err := mailer.SendEmail(context.Background(), Email{
+		To:      []string{"alice@example.com"},
+		Subject: "Verify your email",
+		Body:    "Hello",
+	})
+	require.NoError(t, err)
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.eml"))
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+
+	data, err := os.ReadFile(files[0])
+	require.NoError(t, err)
+	require.Contains(t, string(data), "To: alice@example.com")
+	require.Contains(t, string(data), "Subject: Verify your email")
+}
+
+func TestNewMailer(t *testing.T) {
+	mailer, err := NewMailer(util.Config{Mailer: MailerLog})
+	require.NoError(t, err)
+	require.IsType(t, &LogMailer{}, mailer)
+
+	mailer, err = NewMailer(util.Config{
+		Mailer:             MailerSMTP,
+		SMTPHost:           "localhost",
+		SMTPPort:           25,
+		EmailSenderAddress: "no-reply@simplebank.local",
+	})
+	require.NoError(t, err)
+	require.IsType(t, &SMTPMailer{}, mailer)
+
+	_, err = NewMailer(util.Config{Mailer: "pigeon"})
+	require.Error(t, err)
+}
@@ -0,0 +1,71 @@
+package mail
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"mime"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/util"
+)
+
+// Supported mailers
+const (
+	MailerSMTP = "smtp"
+	MailerLog  = "log"
+)
+
+// Email is a plain text message to one or more recipients
+type Email struct {
+	To      []string
+	Subject string
+	Body    string
+}
+
+// Mailer is an interface for sending emails
+type Mailer interface {
+	// SendEmail delivers the email to all of its recipients
+	SendEmail(ctx context.Context, email Email) error
+}
+
+// NewMailer creates the mailer selected by the MAILER config
+func NewMailer(config util.Config) (Mailer, error) {
+	sender := mail.Address{Name: config.EmailSenderName, Address: config.EmailSenderAddress}
+
+	switch config.Mailer {
+	case MailerSMTP:
+		return NewSMTPMailer(config.SMTPHost, config.SMTPPort, config.SMTPUsername, config.SMTPPassword, sender)
+	case MailerLog, "":
+		return NewLogMailer(config.MailLogDir, sender), nil
+	default:
+		return nil, fmt.Errorf("unsupported mailer: %q", config.Mailer)
+	}
+}
+
+// buildMessage renders the email as an RFC 5322 message
+func buildMessage(from mail.Address, email Email) ([]byte, error) {
+	if len(email.To) == 0 {
+		return nil, fmt.Errorf("email has no recipients")
+	}
+
+	for _, to := range email.To {
+		if _, err := mail.ParseAddress(to); err != nil {
+			return nil, fmt.Errorf("invalid recipient %q: %w", to, err)
+		}
+	}
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", from.String())
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(email.To, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", email.Subject))
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=\"utf-8\"\r\n")
+	msg.WriteString("\r\n")
+	msg.WriteString(strings.ReplaceAll(email.Body, "\n", "\r\n"))
+
+	return msg.Bytes(), nil
+}
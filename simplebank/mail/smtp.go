@@ -0,0 +1,53 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+)
+
+// SMTPMailer sends emails through an SMTP server
+type SMTPMailer struct {
+	addr   string
+	auth   smtp.Auth
+	sender mail.Address
+}
+
+// NewSMTPMailer creates a mailer for the SMTP server at host:port. Servers
+// that need no authentication, like a local sink, take an empty username.
+func NewSMTPMailer(host string, port int, username, password string, sender mail.Address) (Mailer, error) {
+	if host == "" {
+		return nil, fmt.Errorf("smtp host is not configured")
+	}
+	if sender.Address == "" {
+		return nil, fmt.Errorf("email sender address is not configured")
+	}
+
+	mailer := &SMTPMailer{
+		addr:   net.JoinHostPort(host, strconv.Itoa(port)),
+		sender: sender,
+	}
+	if username != "" {
+		mailer.auth = smtp.PlainAuth("", username, password, host)
+	}
+
+	return mailer, nil
+}
+
+// SendEmail implements Mailer. The context is not used by net/smtp.
+func (mailer *SMTPMailer) SendEmail(ctx context.Context, email Email) error {
+	msg, err := buildMessage(mailer.sender, email)
+	if err != nil {
+		return err
+	}
+
+	err = smtp.SendMail(mailer.addr, mailer.auth, mailer.sender.Address, email.To, msg)
+	if err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,116 @@
+package mail
+
+import (
+	"context"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// smtpSink is a minimal SMTP server that accepts a single message
+type smtpSink struct {
+	listener net.Listener
+	from     string
+	to       []string
+	data     string
+	done     chan struct{}
+}
+
+func newSMTPSink(t *testing.T) *smtpSink {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+
+	sink := &smtpSink{listener: listener, done: make(chan struct{})}
+	go sink.serve()
+	return sink
+}
+
+func (sink *smtpSink) serve() {
+	defer close(sink.done)
+
+	conn, err := sink.listener.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	text := textproto.NewConn(conn)
+	text.PrintfLine("220 localhost ESMTP sink")
+
+	for {
+		line, err := text.ReadLine()
+		if err != nil {
+			return
+		}
+
+		command := strings.ToUpper(line)
+		switch {
+		case strings.HasPrefix(command, "EHLO"), strings.HasPrefix(command, "HELO"):
+			text.PrintfLine("250 localhost")
+		case strings.HasPrefix(command, "MAIL FROM:"):
+			sink.from = strings.Trim(line[len("MAIL FROM:"):], "<> ")
+			text.PrintfLine("250 OK")
+		case strings.HasPrefix(command, "RCPT TO:"):
+			sink.to = append(sink.to, strings.Trim(line[len("RCPT TO:"):], "<> "))
+			text.PrintfLine("250 OK")
+		case command == "DATA":
+			text.PrintfLine("354 end data with <CR><LF>.<CR><LF>")
+			data, err := text.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			sink.data = string(data)
+			text.PrintfLine("250 OK")
+		case command == "QUIT":
+			text.PrintfLine("221 bye")
+			return
+		default:
+			text.PrintfLine("502 command not implemented")
+		}
+	}
+}
+
+func TestSMTPMailer(t *testing.T) {
+	sink := newSMTPSink(t)
+	addr := sink.listener.Addr().(*net.TCPAddr)
+
+	sender := mail.Address{Name: "Simple Bank", Address: "no-reply@simplebank.local"}
+	mailer, err := NewSMTPMailer(addr.IP.String(), addr.Port, "", "", sender)
+	require.NoError(t, err)
+
+	err = mailer.SendEmail(context.Background(), Email{
+		To:      []string{"alice@example.com", "bob@example.com"},
+		Subject: "Verify your email",
+		Body:    "Hello\nWorld",
+	})
+	require.NoError(t, err)
+	<-sink.done
+
+	require.Equal(t, sender.Address, sink.from)
+	require.Equal(t, []string{"alice@example.com", "bob@example.com"}, sink.to)
+	require.Contains(t, sink.data, "Subject: Verify your email")
+	require.Contains(t, sink.data, "To: alice@example.com, bob@example.com")
+	require.Contains(t, sink.data, "Hello\nWorld")
+}
+
+func TestSMTPMailerInvalidRecipient(t *testing.T) {
+	sender := mail.Address{Address: "no-reply@simplebank.local"}
+	mailer, err := NewSMTPMailer("localhost", 25, "", "", sender)
+	require.NoError(t, err)
+
+	err = mailer.SendEmail(context.Background(), Email{
+		To:      []string{"not-an-email"},
+		Subject: "Verify your email",
+	})
+	require.Error(t, err)
+}
+
+func TestNewSMTPMailerMissingHost(t *testing.T) {
+	_, err := NewSMTPMailer("", 25, "", "", mail.Address{Address: "no-reply@simplebank.local"})
+	require.Error(t, err)
+}
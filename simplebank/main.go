@@ -2,37 +2,221 @@ package main
 
 import (
 	"context"
-	"log"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/api"
 	db "github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/db/sqlc"
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/gapi"
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/logging"
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/metrics"
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/tracing"
 	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/util"
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/worker"
+	"github.com/jackc/pgx/v5/multitracer"
 	"github.com/jackc/pgx/v5/pgxpool"
 	_ "github.com/lib/pq"
+	"golang.org/x/sync/errgroup"
 )
 
 func main() {
 	config, err := util.LoadConfig(".")
 	if err != nil {
-		log.Fatal("cannot load config:", err)
+		fatal("cannot load config", "error", err)
 	}
 
+	logger, err := logging.New(os.Stderr, config.LogFormat, config.LogLevel)
+	if err != nil {
+		fatal("cannot create logger", "error", err)
+	}
+	slog.SetDefault(logger)
+
+	tracerProvider, err := tracing.Setup(context.Background(), config, os.Stdout)
+	if err != nil {
+		fatal("cannot set up tracing", "error", err)
+	}
+	defer tracerProvider.Shutdown(context.Background())
+
 	dbSource := config.DBSource
-	serverAddress := config.ServerAddress
 
-	conn, err := pgxpool.New(context.Background(), dbSource)
+	poolConfig, err := pgxpool.ParseConfig(dbSource)
+	if err != nil {
+		fatal("cannot parse db source", "error", err)
+	}
+	// the span of a query is started first, so its log record carries it
+	poolConfig.ConnConfig.Tracer = multitracer.New(tracing.NewQueryTracer(), logging.NewQueryTracer(logger))
+
+	conn, err := pgxpool.NewWithConfig(context.Background(), poolConfig)
+	if err != nil {
+		fatal("cannot connect to db", "error", err)
+	}
+
+	appMetrics := metrics.New()
+	if err := appMetrics.RegisterPool(conn); err != nil {
+		fatal("cannot register db pool metrics", "error", err)
+	}
+
+	store := appMetrics.InstrumentStore(db.NewStore(conn))
+
+	if len(os.Args) > 1 {
+		runCommand(config, conn, store, os.Args[1], os.Args[2:])
+		return
+	}
+
+	if config.AutoMigrate {
+		autoMigrate(conn)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	err = serve(ctx, config, store, appMetrics)
+	conn.Close()
 	if err != nil {
-		log.Fatal("cannot connect to db:", err)
+		tracerProvider.Shutdown(context.Background())
+		fatal("server failed", "error", err)
 	}
+	slog.Info("shut down")
+}
 
-	store := db.NewStore(conn)
-	server, err := api.NewServer(config, store)
+// serve runs the servers and the workers until ctx is done. When one of the
+// servers fails, the others are shut down as on a signal before its error
+// is returned.
+func serve(ctx context.Context, config util.Config, store db.Store, appMetrics *metrics.Metrics) error {
+	server, err := api.NewServer(config, store, appMetrics)
 	if err != nil {
-		log.Fatal("cannot create server:", err)
+		return fmt.Errorf("cannot create server: %w", err)
 	}
 
-	err = server.Start(serverAddress)
+	grpcServer, err := gapi.NewServer(config, store)
 	if err != nil {
-		log.Fatal("cannot start server:", err)
+		return fmt.Errorf("cannot create gRPC server: %w", err)
 	}
+
+	group, ctx := errgroup.WithContext(ctx)
+
+	balanceSnapshotter := worker.NewBalanceSnapshotter(store, config.BalanceSnapshotJobInterval, config.ClosedPeriodLag)
+	group.Go(func() error {
+		balanceSnapshotter.Start(ctx)
+		return nil
+	})
+
+	statementGenerator := worker.NewStatementGenerator(store, config.StatementJobInterval, config.ClosedPeriodLag)
+	group.Go(func() error {
+		statementGenerator.Start(ctx)
+		return nil
+	})
+
+	reconciler := worker.NewReconciler(store, config.ReconciliationJobInterval)
+	group.Go(func() error {
+		reconciler.Start(ctx)
+		return nil
+	})
+
+	group.Go(func() error { return runGRPCServer(ctx, config, grpcServer) })
+	if config.MetricsServerAddress != "" {
+		group.Go(func() error { return runMetricsServer(ctx, config, appMetrics) })
+	}
+
+	group.Go(func() error {
+		if err := server.Start(ctx, config.ServerAddress); err != nil {
+			return fmt.Errorf("cannot serve HTTP: %w", err)
+		}
+		return nil
+	})
+
+	return group.Wait()
+}
+
+// runGRPCServer serves the gRPC API for internal services next to the HTTP
+// API until ctx is done. It then waits for the calls in flight until the
+// shutdown timeout.
+func runGRPCServer(ctx context.Context, config util.Config, server *gapi.Server) error {
+	listener, err := net.Listen("tcp", config.GRPCServerAddress)
+	if err != nil {
+		return fmt.Errorf("cannot listen for gRPC: %w", err)
+	}
+
+	grpcServer := server.NewGRPCServer()
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- grpcServer.Serve(listener)
+	}()
+	slog.Info("serving gRPC", "address", listener.Addr().String())
+
+	select {
+	case err := <-serveErr:
+		return fmt.Errorf("cannot serve gRPC: %w", err)
+	case <-ctx.Done():
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		grpcServer.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(config.ShutdownTimeout):
+		grpcServer.Stop()
+	}
+	slog.Info("gRPC server stopped")
+	return nil
+}
+
+// runMetricsServer serves the metrics on their own address, which is not
+// exposed to clients like the API, until ctx is done
+func runMetricsServer(ctx context.Context, config util.Config, appMetrics *metrics.Metrics) error {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", appMetrics.Handler())
+	httpServer := &http.Server{
+		Addr:              config.MetricsServerAddress,
+		Handler:           mux,
+		ReadHeaderTimeout: config.HTTPReadTimeout,
+	}
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- httpServer.ListenAndServe()
+	}()
+	slog.Info("serving metrics", "address", config.MetricsServerAddress)
+
+	select {
+	case err := <-serveErr:
+		return fmt.Errorf("cannot serve metrics: %w", err)
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), config.ShutdownTimeout)
+	defer cancel()
+	if err := httpServer.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("cannot shut down metrics server: %w", err)
+	}
+	return nil
+}
+
+// runCommand runs a one-off administrative subcommand instead of the server
+func runCommand(config util.Config, conn *pgxpool.Pool, store db.Store, name string, args []string) {
+	switch name {
+	case "migrate":
+		runMigrate(conn, args)
+	case "admin":
+		runAdmin(config, store, args)
+	case "reconcile":
+		runReconcile(store, args)
+	default:
+		fatal("unknown command", "command", name)
+	}
+}
+
+// fatal logs why the program cannot go on and exits with status 1
+func fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
 }
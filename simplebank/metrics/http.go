@@ -0,0 +1,29 @@
+package metrics
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// unmatchedRoute labels requests no route matched, so scanners probing random
+// paths don't create a series for each of them
+const unmatchedRoute = "unmatched"
+
+// HTTPMiddleware counts and times requests by their route template, e.g.
+// /accounts/:id, rather than by path
+func (m *Metrics) HTTPMiddleware() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		start := time.Now()
+		ctx.Next()
+
+		route := ctx.FullPath()
+		if route == "" {
+			route = unmatchedRoute
+		}
+		method := ctx.Request.Method
+		m.httpRequests.WithLabelValues(method, route, strconv.Itoa(ctx.Writer.Status())).Inc()
+		m.httpRequestDuration.WithLabelValues(method, route).Observe(time.Since(start).Seconds())
+	}
+}
@@ -0,0 +1,81 @@
+package metrics
+
+import (
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// poolCollector reads the statistics of a database pool on every scrape
+type poolCollector struct {
+	pool *pgxpool.Pool
+
+	acquiredConns           *prometheus.Desc
+	idleConns               *prometheus.Desc
+	constructingConns       *prometheus.Desc
+	totalConns              *prometheus.Desc
+	maxConns                *prometheus.Desc
+	acquires                *prometheus.Desc
+	acquireDuration         *prometheus.Desc
+	canceledAcquires        *prometheus.Desc
+	emptyAcquires           *prometheus.Desc
+	newConns                *prometheus.Desc
+	maxLifetimeDestroyConns *prometheus.Desc
+	maxIdleDestroyConns     *prometheus.Desc
+}
+
+// RegisterPool exposes the statistics of the database pool
+func (m *Metrics) RegisterPool(pool *pgxpool.Pool) error {
+	return m.registry.Register(newPoolCollector(pool))
+}
+
+func newPoolCollector(pool *pgxpool.Pool) *poolCollector {
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(namespace, "db_pool", name), help, nil, nil)
+	}
+
+	return &poolCollector{
+		pool:                    pool,
+		acquiredConns:           desc("acquired_conns", "Connections currently acquired from the pool."),
+		idleConns:               desc("idle_conns", "Idle connections in the pool."),
+		constructingConns:       desc("constructing_conns", "Connections being opened."),
+		totalConns:              desc("total_conns", "Connections in the pool, acquired, idle or being opened."),
+		maxConns:                desc("max_conns", "Largest number of connections the pool opens."),
+		acquires:                desc("acquires_total", "Connections acquired from the pool."),
+		acquireDuration:         desc("acquire_duration_seconds_total", "Time spent acquiring connections from the pool."),
+		canceledAcquires:        desc("canceled_acquires_total", "Acquires canceled by their context."),
+		emptyAcquires:           desc("empty_acquires_total", "Acquires that waited for a connection as none was idle."),
+		newConns:                desc("new_conns_total", "Connections opened."),
+		maxLifetimeDestroyConns: desc("max_lifetime_destroyed_conns_total", "Connections closed for exceeding their maximum lifetime."),
+		maxIdleDestroyConns:     desc("max_idle_destroyed_conns_total", "Connections closed for exceeding their maximum idle time."),
+	}
+}
+
+// Describe implements prometheus.Collector
+func (c *poolCollector) Describe(ch chan<- *prometheus.Desc) {
+	prometheus.DescribeByCollect(c, ch)
+}
+
+// Collect implements prometheus.Collector
+func (c *poolCollector) Collect(ch chan<- prometheus.Metric) {
+	stat := c.pool.Stat()
+
+	gauge := func(desc *prometheus.Desc, value float64) {
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, value)
+	}
+	counter := func(desc *prometheus.Desc, value float64) {
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.CounterValue, value)
+	}
+
+	gauge(c.acquiredConns, float64(stat.AcquiredConns()))
+	gauge(c.idleConns, float64(stat.IdleConns()))
+	gauge(c.constructingConns, float64(stat.ConstructingConns()))
+	gauge(c.totalConns, float64(stat.TotalConns()))
+	gauge(c.maxConns, float64(stat.MaxConns()))
+	counter(c.acquires, float64(stat.AcquireCount()))
+	counter(c.acquireDuration, stat.AcquireDuration().Seconds())
+	counter(c.canceledAcquires, float64(stat.CanceledAcquireCount()))
+	counter(c.emptyAcquires, float64(stat.EmptyAcquireCount()))
+	counter(c.newConns, float64(stat.NewConnsCount()))
+	counter(c.maxLifetimeDestroyConns, float64(stat.MaxLifetimeDestroyCount()))
+	counter(c.maxIdleDestroyConns, float64(stat.MaxIdleDestroyCount()))
+}
@@ -0,0 +1,63 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/apperr"
+	db "github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/db/sqlc"
+)
+
+// Reasons of failed transfer transactions besides the codes of apperr.From,
+// such as conflict for serialization failures
+const (
+	reasonInvalid  = "invalid"
+	reasonFrozen   = "frozen"
+	reasonCanceled = "canceled"
+)
+
+type instrumentedStore struct {
+	db.Store
+	metrics *Metrics
+}
+
+// InstrumentStore returns a store recording the duration and failures of
+// transfer transactions, and the transfers and amounts they move
+func (m *Metrics) InstrumentStore(store db.Store) db.Store {
+	return &instrumentedStore{
+		Store:   store,
+		metrics: m,
+	}
+}
+
+// TransferTx implements db.Store
+func (store *instrumentedStore) TransferTx(ctx context.Context, arg db.TransferTxParams) (db.TransferTxResult, error) {
+	start := time.Now()
+	result, err := store.Store.TransferTx(ctx, arg)
+	duration := time.Since(start).Seconds()
+
+	if err != nil {
+		store.metrics.transferTxDuration.WithLabelValues("failure").Observe(duration)
+		store.metrics.transferTxFailures.WithLabelValues(transferFailureReason(err)).Inc()
+		return result, err
+	}
+
+	store.metrics.transferTxDuration.WithLabelValues("success").Observe(duration)
+	currency := result.FromAccount.Currency
+	store.metrics.transfers.WithLabelValues(currency).Inc()
+	store.metrics.transferredAmount.WithLabelValues(currency).Add(float64(result.Transfer.Amount))
+	return result, err
+}
+
+func transferFailureReason(err error) string {
+	switch {
+	case errors.Is(err, db.ErrInvalidJournal), errors.Is(err, db.ErrJournalUnbalanced):
+		return reasonInvalid
+	case errors.Is(err, db.ErrAccountFrozen):
+		return reasonFrozen
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return reasonCanceled
+	}
+	return apperr.From(err).Code
+}
@@ -8,6 +8,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/apperr"
 	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/token"
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/require"
@@ -17,6 +18,7 @@ const (
 	AuthorizationPayloadKey = "authorization_payload"
 	AuthorizationHeaderKey  = "authorization"
 	AuthorizationTypeBearer = "bearer"
+	AuthorizationTypeAPIKey = "apikey"
 )
 
 func AddTestAuthorization(
@@ -34,41 +36,93 @@ func AddTestAuthorization(
 	request.Header.Set(AuthorizationHeaderKey, authorization)
 }
 
-func AuthMiddleware(tokenMaker token.Maker) gin.HandlerFunc {
+// PayloadCheck runs after a token has been verified and rejects the request
+// when it returns an error, e.g. because the token was issued before the
+// user's password was changed. Errors other than *apperr.Error reject the
+// request as unauthenticated.
+type PayloadCheck func(ctx *gin.Context, payload *token.Payload) error
+
+// APIKeyVerifier verifies an api key and returns a payload for its owner, so
+// handlers need not care how a request was authorized. Its errors are
+// treated like those of a PayloadCheck.
+type APIKeyVerifier func(ctx *gin.Context, key string) (*token.Payload, error)
+
+var (
+	errAuthorizationNotProvided = apperr.Unauthenticated("authorization header is not provided")
+	errAuthorizationFormat      = apperr.Unauthenticated("invalid authorization header format")
+	errAuthorizationType        = apperr.Unauthenticated("unsupported authorization type")
+	errAuthorizationRejected    = apperr.Unauthenticated("authorization was rejected")
+	errScopeRequired            = apperr.New(http.StatusForbidden, "scope_required", "authorization is missing a required scope")
+)
+
+// AuthMiddleware accepts bearer tokens made by tokenMaker and, unless apiKeys
+// is nil, api keys checked by apiKeys.
+func AuthMiddleware(tokenMaker token.Maker, apiKeys APIKeyVerifier, checks ...PayloadCheck) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		authorizationHeader := ctx.GetHeader(AuthorizationHeaderKey)
 		if len(authorizationHeader) == 0 {
-			err := errors.New("authorization header is not provided")
-			ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorsResponse(err))
+			AbortWithError(ctx, errAuthorizationNotProvided)
 			return
 		}
 
 		fields := strings.Fields(authorizationHeader)
 		if len(fields) < 2 {
-			err := errors.New("invalid authorization header format")
-			ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorsResponse(err))
+			AbortWithError(ctx, errAuthorizationFormat)
 			return
 		}
 
-		authorizationType := strings.ToLower(fields[0])
-		if authorizationType != AuthorizationTypeBearer {
-			err := errors.New("unsupported authorization type")
-			ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorsResponse(err))
-			return
+		var payload *token.Payload
+		var err error
+		switch authorizationType := strings.ToLower(fields[0]); {
+		case authorizationType == AuthorizationTypeBearer:
+			payload, err = tokenMaker.VerifyToken(fields[1])
+			if err != nil {
+				// the token errors are meant for clients
+				err = apperr.Unauthenticated(err.Error()).WithCause(err)
+			}
+		case authorizationType == AuthorizationTypeAPIKey && apiKeys != nil:
+			payload, err = apiKeys(ctx, fields[1])
+		default:
+			err = errAuthorizationType
 		}
-
-		accessToken := fields[1]
-		payload, err := tokenMaker.VerifyToken(accessToken)
 		if err != nil {
-			ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorsResponse(err))
+			AbortWithError(ctx, authorizationError(err))
 			return
 		}
 
+		for _, check := range checks {
+			if err := check(ctx, payload); err != nil {
+				AbortWithError(ctx, authorizationError(err))
+				return
+			}
+		}
+
 		ctx.Set(AuthorizationPayloadKey, payload)
 		ctx.Next()
 	}
 }
 
-func errorsResponse(err error) gin.H {
-	return gin.H{"error": err.Error()}
+// authorizationError passes on an *apperr.Error and hides other errors
+// behind errAuthorizationRejected
+func authorizationError(err error) error {
+	var appErr *apperr.Error
+	if errors.As(err, &appErr) {
+		return err
+	}
+	return errAuthorizationRejected.WithCause(err)
+}
+
+// RequireScopes only lets through requests whose payload, set by
+// AuthMiddleware, has all the given scopes.
+func RequireScopes(scopes ...string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		payload := ctx.MustGet(AuthorizationPayloadKey).(*token.Payload)
+		for _, scope := range scopes {
+			if !payload.HasScope(scope) {
+				AbortWithError(ctx, errScopeRequired)
+				return
+			}
+		}
+		ctx.Next()
+	}
 }
@@ -1,11 +1,13 @@
 package middleware
 
 import (
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 	"time"
 
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/apperr"
 	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/token"
 	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/util"
 	"github.com/gin-gonic/gin"
@@ -16,6 +18,8 @@ func TestAuthMiddleware(t *testing.T) {
 	testCases := []struct {
 		name          string
 		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		apiKeys       APIKeyVerifier
+		checks        []PayloadCheck
 		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
 	}{
 		{
@@ -27,6 +31,50 @@ func TestAuthMiddleware(t *testing.T) {
 				require.Equal(t, http.StatusOK, recorder.Code)
 			},
 		},
+		{
+			name: "OKWithChecks",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				AddTestAuthorization(t, request, tokenMaker, AuthorizationTypeBearer, "user", time.Minute)
+			},
+			checks: []PayloadCheck{
+				func(ctx *gin.Context, payload *token.Payload) error {
+					require.Equal(t, "user", payload.Username)
+					return nil
+				},
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "CheckFailed",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				AddTestAuthorization(t, request, tokenMaker, AuthorizationTypeBearer, "user", time.Minute)
+			},
+			checks: []PayloadCheck{
+				func(ctx *gin.Context, payload *token.Payload) error {
+					return errors.New("token has been revoked")
+				},
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				problem := requireProblem(t, recorder, http.StatusUnauthorized)
+				require.Equal(t, errAuthorizationRejected.Message, problem.Detail)
+			},
+		},
+		{
+			name: "CheckError",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				AddTestAuthorization(t, request, tokenMaker, AuthorizationTypeBearer, "user", time.Minute)
+			},
+			checks: []PayloadCheck{
+				func(ctx *gin.Context, payload *token.Payload) error {
+					return apperr.Internal(errors.New("connection refused"))
+				},
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireProblem(t, recorder, http.StatusInternalServerError)
+			},
+		},
 		{
 			name: "NoAuthorization",
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
@@ -53,6 +101,58 @@ func TestAuthMiddleware(t *testing.T) {
 				require.Equal(t, http.StatusUnauthorized, recorder.Code)
 			},
 		},
+		{
+			name: "APIKey",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				request.Header.Set(AuthorizationHeaderKey, "ApiKey sb_key")
+			},
+			apiKeys: func(ctx *gin.Context, key string) (*token.Payload, error) {
+				require.Equal(t, "sb_key", key)
+				return token.NewPayload("user", time.Minute)
+			},
+			checks: []PayloadCheck{
+				func(ctx *gin.Context, payload *token.Payload) error {
+					require.Equal(t, "user", payload.Username)
+					return nil
+				},
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "InvalidAPIKey",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				request.Header.Set(AuthorizationHeaderKey, "ApiKey sb_key")
+			},
+			apiKeys: func(ctx *gin.Context, key string) (*token.Payload, error) {
+				return nil, errors.New("api key is invalid")
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "APIKeyNotAccepted",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				request.Header.Set(AuthorizationHeaderKey, "ApiKey sb_key")
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "TokenAsAPIKey",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				AddTestAuthorization(t, request, tokenMaker, AuthorizationTypeAPIKey, "user", time.Minute)
+			},
+			apiKeys: func(ctx *gin.Context, key string) (*token.Payload, error) {
+				return nil, errors.New("api key is invalid")
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
 		{
 			name: "ExpiredToken",
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
@@ -75,7 +175,7 @@ func TestAuthMiddleware(t *testing.T) {
 			authPath := "/auth"
 			router.GET(
 				authPath,
-				AuthMiddleware(tokenMaker),
+				AuthMiddleware(tokenMaker, tc.apiKeys, tc.checks...),
 				func(ctx *gin.Context) {
 					ctx.JSON(http.StatusOK, gin.H{})
 				},
@@ -92,3 +192,65 @@ func TestAuthMiddleware(t *testing.T) {
 		})
 	}
 }
+
+func TestRequireScopes(t *testing.T) {
+	testCases := []struct {
+		name          string
+		tokenScopes   []string
+		requireScopes []string
+		status        int
+	}{
+		{
+			name:          "FullAccess",
+			requireScopes: []string{token.ScopeAccountsRead, token.ScopeTransfersWrite},
+			status:        http.StatusOK,
+		},
+		{
+			name:          "HasScopes",
+			tokenScopes:   []string{token.ScopeAccountsRead, token.ScopeTransfersWrite},
+			requireScopes: []string{token.ScopeAccountsRead, token.ScopeTransfersWrite},
+			status:        http.StatusOK,
+		},
+		{
+			name:          "MissingScope",
+			tokenScopes:   []string{token.ScopeAccountsRead},
+			requireScopes: []string{token.ScopeAccountsRead, token.ScopeTransfersWrite},
+			status:        http.StatusForbidden,
+		},
+		{
+			name:          "NothingRequired",
+			tokenScopes:   []string{token.ScopeAccountsRead},
+			requireScopes: nil,
+			status:        http.StatusOK,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			router := gin.New()
+			tokenMaker, err := token.NewPasetoMaker(util.RandomString(32))
+			require.NoError(t, err)
+
+			authPath := "/auth"
+			router.GET(
+				authPath,
+				AuthMiddleware(tokenMaker, nil),
+				RequireScopes(tc.requireScopes...),
+				func(ctx *gin.Context) {
+					ctx.JSON(http.StatusOK, gin.H{})
+				},
+			)
+
+			recorder := httptest.NewRecorder()
+			request, err := http.NewRequest(http.MethodGet, authPath, nil)
+			require.NoError(t, err)
+
+			accessToken, _, err := tokenMaker.CreateScopedToken("user", time.Minute, tc.tokenScopes...)
+			require.NoError(t, err)
+			request.Header.Set(AuthorizationHeaderKey, AuthorizationTypeBearer+" "+accessToken)
+
+			router.ServeHTTP(recorder, request)
+			require.Equal(t, tc.status, recorder.Code)
+		})
+	}
+}
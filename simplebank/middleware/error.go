@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"log/slog"
+
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/apperr"
+	"github.com/gin-gonic/gin"
+)
+
+// ErrorHandler responds with the problem details of the last error a
+// handler added with ctx.Error, unless the handler already responded.
+// Handlers only need to pick the error; its status and body follow from it.
+func ErrorHandler() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		ctx.Next()
+
+		if len(ctx.Errors) == 0 || ctx.Writer.Written() {
+			return
+		}
+		writeProblem(ctx, ctx.Errors.Last().Err)
+	}
+}
+
+// AbortWithError stops the request and responds with the problem details
+// of err, for middleware that must work without ErrorHandler.
+func AbortWithError(ctx *gin.Context, err error) {
+	ctx.Error(err)
+	ctx.Abort()
+	writeProblem(ctx, err)
+}
+
+func writeProblem(ctx *gin.Context, err error) {
+	appErr := apperr.From(err)
+	if appErr.Status >= 500 {
+		slog.ErrorContext(ctx.Request.Context(), "request failed",
+			"method", ctx.Request.Method,
+			"path", ctx.Request.URL.Path,
+			"error", err,
+		)
+	}
+
+	problem := appErr.Problem(ctx.Request.URL.Path)
+	problem.TraceID = traceID(ctx)
+
+	ctx.Header("Content-Type", apperr.ProblemContentType)
+	ctx.JSON(appErr.Status, problem)
+}
@@ -0,0 +1,133 @@
+package middleware
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/apperr"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/require"
+)
+
+func TestErrorHandler(t *testing.T) {
+	testCases := []struct {
+		name          string
+		handler       gin.HandlerFunc
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "Error",
+			handler: func(ctx *gin.Context) {
+				ctx.Error(apperr.PermissionDenied("not yours"))
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				problem := requireProblem(t, recorder, http.StatusForbidden)
+				require.Equal(t, apperr.CodePermissionDenied, problem.Code)
+				require.Equal(t, "not yours", problem.Detail)
+				require.Equal(t, "/test", problem.Instance)
+			},
+		},
+		{
+			name: "NoRows",
+			handler: func(ctx *gin.Context) {
+				ctx.Error(pgx.ErrNoRows)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				problem := requireProblem(t, recorder, http.StatusNotFound)
+				require.Equal(t, apperr.CodeNotFound, problem.Code)
+			},
+		},
+		{
+			name: "InternalError",
+			handler: func(ctx *gin.Context) {
+				ctx.Error(errors.New("dial tcp 10.0.0.1:5432: connection refused"))
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				problem := requireProblem(t, recorder, http.StatusInternalServerError)
+				require.Equal(t, apperr.CodeInternal, problem.Code)
+				require.NotContains(t, recorder.Body.String(), "10.0.0.1")
+			},
+		},
+		{
+			name: "LastError",
+			handler: func(ctx *gin.Context) {
+				ctx.Error(apperr.PermissionDenied("not yours"))
+				ctx.Error(apperr.NotFound("not found"))
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireProblem(t, recorder, http.StatusNotFound)
+			},
+		},
+		{
+			name: "AlreadyResponded",
+			handler: func(ctx *gin.Context) {
+				ctx.Error(errors.New("cannot record use"))
+				ctx.JSON(http.StatusOK, gin.H{})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.JSONEq(t, `{}`, recorder.Body.String())
+			},
+		},
+		{
+			name: "NoError",
+			handler: func(ctx *gin.Context) {
+				ctx.Status(http.StatusNoContent)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNoContent, recorder.Code)
+				require.Empty(t, recorder.Body.String())
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			router := gin.New()
+			router.Use(ErrorHandler())
+			router.GET("/test", tc.handler)
+
+			recorder := httptest.NewRecorder()
+			request, err := http.NewRequest(http.MethodGet, "/test", nil)
+			require.NoError(t, err)
+
+			router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestAbortWithError(t *testing.T) {
+	router := gin.New()
+	router.GET(
+		"/test",
+		func(ctx *gin.Context) {
+			AbortWithError(ctx, apperr.Unauthenticated("token is invalid"))
+		},
+		func(ctx *gin.Context) {
+			require.Fail(t, "the request was not aborted")
+		},
+	)
+
+	recorder := httptest.NewRecorder()
+	request, err := http.NewRequest(http.MethodGet, "/test", nil)
+	require.NoError(t, err)
+
+	router.ServeHTTP(recorder, request)
+	problem := requireProblem(t, recorder, http.StatusUnauthorized)
+	require.Equal(t, "token is invalid", problem.Detail)
+}
+
+func requireProblem(t *testing.T, recorder *httptest.ResponseRecorder, status int) apperr.Problem {
+	require.Equal(t, status, recorder.Code)
+	require.Equal(t, apperr.ProblemContentType, recorder.Header().Get("Content-Type"))
+
+	var problem apperr.Problem
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &problem))
+	require.Equal(t, status, problem.Status)
+	return problem
+}
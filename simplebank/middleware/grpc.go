@@ -0,0 +1,105 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/token"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+var errNoMethodAuth = errors.New("method has no authorization rule")
+
+type payloadContextKey struct{}
+
+// GRPCPayloadCheck is the PayloadCheck of AuthInterceptor. It returns the
+// context to handle the call with, so it can pass on what it loaded.
+type GRPCPayloadCheck func(ctx context.Context, payload *token.Payload) (context.Context, error)
+
+// GRPCAPIKeyVerifier is the APIKeyVerifier of AuthInterceptor
+type GRPCAPIKeyVerifier func(ctx context.Context, key string) (*token.Payload, error)
+
+// MethodAuth says how AuthInterceptor authorizes calls of a method
+type MethodAuth struct {
+	// Public methods are called without any authorization
+	Public bool
+	// NoAPIKeys turns away api keys, as the routes managing the user do
+	NoAPIKeys bool
+	// Scopes are required of the token or api key, as by RequireScopes
+	Scopes []string
+}
+
+// AuthInterceptor is AuthMiddleware for gRPC. It reads the authorization
+// metadata and authorizes each call by the rule of its full method name;
+// methods without a rule are refused. Handlers get the payload from
+// AuthorizationPayload.
+func AuthInterceptor(tokenMaker token.Maker, apiKeys GRPCAPIKeyVerifier, methods map[string]MethodAuth, checks ...GRPCPayloadCheck) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		auth, ok := methods[info.FullMethod]
+		if !ok {
+			return nil, status.Error(codes.PermissionDenied, errNoMethodAuth.Error())
+		}
+		if auth.Public {
+			return handler(ctx, req)
+		}
+
+		values := metadata.ValueFromIncomingContext(ctx, AuthorizationHeaderKey)
+		if len(values) == 0 {
+			return nil, status.Error(codes.Unauthenticated, "authorization metadata is not provided")
+		}
+
+		fields := strings.Fields(values[0])
+		if len(fields) < 2 {
+			return nil, status.Error(codes.Unauthenticated, "invalid authorization metadata format")
+		}
+
+		var payload *token.Payload
+		var err error
+		switch authorizationType := strings.ToLower(fields[0]); {
+		case authorizationType == AuthorizationTypeBearer:
+			payload, err = tokenMaker.VerifyToken(fields[1])
+		case authorizationType == AuthorizationTypeAPIKey && apiKeys != nil && !auth.NoAPIKeys:
+			payload, err = apiKeys(ctx, fields[1])
+		default:
+			err = errors.New("unsupported authorization type")
+		}
+		if err != nil {
+			return nil, unauthenticatedError(err)
+		}
+
+		for _, check := range checks {
+			ctx, err = check(ctx, payload)
+			if err != nil {
+				return nil, unauthenticatedError(err)
+			}
+		}
+
+		for _, scope := range auth.Scopes {
+			if !payload.HasScope(scope) {
+				return nil, status.Error(codes.PermissionDenied, errScopeRequired.Error())
+			}
+		}
+
+		return handler(context.WithValue(ctx, payloadContextKey{}, payload), req)
+	}
+}
+
+// unauthenticatedError rejects a call with err, unless err already has a
+// status, e.g. when the credentials could not be checked
+func unauthenticatedError(err error) error {
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+	return status.Error(codes.Unauthenticated, err.Error())
+}
+
+// AuthorizationPayload returns the payload AuthInterceptor authorized the
+// call with, or nil for public methods
+func AuthorizationPayload(ctx context.Context) *token.Payload {
+	payload, _ := ctx.Value(payloadContextKey{}).(*token.Payload)
+	return payload
+}
@@ -0,0 +1,225 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/token"
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/util"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	testPublicMethod  = "/pb.TestService/Public"
+	testPrivateMethod = "/pb.TestService/Private"
+	testUserMethod    = "/pb.TestService/User"
+)
+
+var testMethods = map[string]MethodAuth{
+	testPublicMethod:  {Public: true},
+	testPrivateMethod: {Scopes: []string{token.ScopeAccountsRead}},
+	testUserMethod:    {NoAPIKeys: true},
+}
+
+type testContextKey struct{}
+
+func bearerContext(t *testing.T, tokenMaker token.Maker, scopes ...string) context.Context {
+	accessToken, _, err := tokenMaker.CreateScopedToken("user", time.Minute, scopes...)
+	require.NoError(t, err)
+
+	md := metadata.Pairs(AuthorizationHeaderKey, fmt.Sprintf("%s %s", AuthorizationTypeBearer, accessToken))
+	return metadata.NewIncomingContext(context.Background(), md)
+}
+
+func TestAuthInterceptor(t *testing.T) {
+	apiKeys := func(ctx context.Context, key string) (*token.Payload, error) {
+		if key != "valid" {
+			return nil, errors.New("invalid api key")
+		}
+		return &token.Payload{Username: "user", Scopes: []string{token.ScopeAccountsRead}}, nil
+	}
+	apiKeyContext := func(key string) context.Context {
+		md := metadata.Pairs(AuthorizationHeaderKey, AuthorizationTypeAPIKey+" "+key)
+		return metadata.NewIncomingContext(context.Background(), md)
+	}
+
+	testCases := []struct {
+		name       string
+		method     string
+		setupAuth  func(t *testing.T, tokenMaker token.Maker) context.Context
+		checks     []GRPCPayloadCheck
+		expectCode codes.Code
+	}{
+		{
+			name:   "OK",
+			method: testPrivateMethod,
+			setupAuth: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				return bearerContext(t, tokenMaker)
+			},
+			expectCode: codes.OK,
+		},
+		{
+			name:   "OKWithChecks",
+			method: testPrivateMethod,
+			setupAuth: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				return bearerContext(t, tokenMaker)
+			},
+			checks: []GRPCPayloadCheck{
+				func(ctx context.Context, payload *token.Payload) (context.Context, error) {
+					require.Equal(t, "user", payload.Username)
+					return context.WithValue(ctx, testContextKey{}, payload.Username), nil
+				},
+			},
+			expectCode: codes.OK,
+		},
+		{
+			name:   "CheckFailed",
+			method: testPrivateMethod,
+			setupAuth: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				return bearerContext(t, tokenMaker)
+			},
+			checks: []GRPCPayloadCheck{
+				func(ctx context.Context, payload *token.Payload) (context.Context, error) {
+					return ctx, errors.New("token has been revoked")
+				},
+			},
+			expectCode: codes.Unauthenticated,
+		},
+		{
+			name:   "CheckFailedWithStatus",
+			method: testPrivateMethod,
+			setupAuth: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				return bearerContext(t, tokenMaker)
+			},
+			checks: []GRPCPayloadCheck{
+				func(ctx context.Context, payload *token.Payload) (context.Context, error) {
+					return ctx, status.Error(codes.Internal, "internal server error")
+				},
+			},
+			expectCode: codes.Internal,
+		},
+		{
+			name:   "Public",
+			method: testPublicMethod,
+			setupAuth: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				return context.Background()
+			},
+			expectCode: codes.OK,
+		},
+		{
+			name:   "UnknownMethod",
+			method: "/pb.TestService/Unknown",
+			setupAuth: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				return bearerContext(t, tokenMaker)
+			},
+			expectCode: codes.PermissionDenied,
+		},
+		{
+			name:   "NoAuthorization",
+			method: testPrivateMethod,
+			setupAuth: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				return context.Background()
+			},
+			expectCode: codes.Unauthenticated,
+		},
+		{
+			name:   "InvalidAuthorizationFormat",
+			method: testPrivateMethod,
+			setupAuth: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				md := metadata.Pairs(AuthorizationHeaderKey, AuthorizationTypeBearer)
+				return metadata.NewIncomingContext(context.Background(), md)
+			},
+			expectCode: codes.Unauthenticated,
+		},
+		{
+			name:   "UnsupportedAuthorization",
+			method: testPrivateMethod,
+			setupAuth: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				md := metadata.Pairs(AuthorizationHeaderKey, "basic dXNlcjpwYXNz")
+				return metadata.NewIncomingContext(context.Background(), md)
+			},
+			expectCode: codes.Unauthenticated,
+		},
+		{
+			name:   "ExpiredToken",
+			method: testPrivateMethod,
+			setupAuth: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				accessToken, err := tokenMaker.CreateToken("user", -time.Minute)
+				require.NoError(t, err)
+				md := metadata.Pairs(AuthorizationHeaderKey, AuthorizationTypeBearer+" "+accessToken)
+				return metadata.NewIncomingContext(context.Background(), md)
+			},
+			expectCode: codes.Unauthenticated,
+		},
+		{
+			name:   "MissingScope",
+			method: testPrivateMethod,
+			setupAuth: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				return bearerContext(t, tokenMaker, token.ScopeTransfersWrite)
+			},
+			expectCode: codes.PermissionDenied,
+		},
+		{
+			name:   "APIKey",
+			method: testPrivateMethod,
+			setupAuth: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				return apiKeyContext("valid")
+			},
+			expectCode: codes.OK,
+		},
+		{
+			name:   "InvalidAPIKey",
+			method: testPrivateMethod,
+			setupAuth: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				return apiKeyContext("invalid")
+			},
+			expectCode: codes.Unauthenticated,
+		},
+		{
+			name:   "APIKeyNotAllowed",
+			method: testUserMethod,
+			setupAuth: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				return apiKeyContext("valid")
+			},
+			expectCode: codes.Unauthenticated,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tokenMaker, err := token.NewPasetoMaker(util.RandomString(32))
+			require.NoError(t, err)
+
+			interceptor := AuthInterceptor(tokenMaker, apiKeys, testMethods, tc.checks...)
+
+			handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+				payload := AuthorizationPayload(ctx)
+				if tc.method == testPublicMethod {
+					require.Nil(t, payload)
+				} else {
+					require.Equal(t, "user", payload.Username)
+				}
+				if len(tc.checks) > 0 {
+					require.Equal(t, "user", ctx.Value(testContextKey{}))
+				}
+				return "response", nil
+			}
+
+			ctx := tc.setupAuth(t, tokenMaker)
+			rsp, err := interceptor(ctx, "request", &grpc.UnaryServerInfo{FullMethod: tc.method}, handler)
+			require.Equal(t, tc.expectCode, status.Code(err))
+			if tc.expectCode == codes.OK {
+				require.Equal(t, "response", rsp)
+			} else {
+				require.Nil(t, rsp)
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package middleware
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"runtime/debug"
+	"time"
+
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/logging"
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/token"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// RequestIDHeader carries the id of a request, from the client or a proxy in
+// front of the server, and back in the response
+const RequestIDHeader = "X-Request-ID"
+
+const maxRequestIDLength = 128
+
+// RequestID keeps the request id the client sent, or assigns a new one, and
+// echoes it in the response. Records logged with the context of the request,
+// including its database queries, carry the id.
+func RequestID() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		id := ctx.GetHeader(RequestIDHeader)
+		if !validRequestID(id) {
+			id = uuid.NewString()
+		}
+
+		ctx.Header(RequestIDHeader, id)
+		ctx.Request = ctx.Request.WithContext(logging.WithRequestID(ctx.Request.Context(), id))
+		ctx.Next()
+	}
+}
+
+// validRequestID accepts ids of printable ascii, so clients can't forge log
+// lines with them
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// Logger logs every request once it is handled, at error level for server
+// errors and warn level for client errors. Sensitive query parameters are
+// redacted.
+func Logger() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		start := time.Now()
+		ctx.Next()
+
+		path := ctx.Request.URL.Path
+		if query := ctx.Request.URL.Query(); len(query) > 0 {
+			path += "?" + logging.RedactQuery(query)
+		}
+
+		status := ctx.Writer.Status()
+		attrs := []slog.Attr{
+			slog.String("method", ctx.Request.Method),
+			slog.String("path", path),
+			slog.Int("status", status),
+			slog.Duration("latency", time.Since(start)),
+			slog.String("client_ip", ctx.ClientIP()),
+		}
+		if value, ok := ctx.Get(AuthorizationPayloadKey); ok {
+			attrs = append(attrs, slog.String("username", value.(*token.Payload).Username))
+		}
+
+		level := slog.LevelInfo
+		switch {
+		case status >= 500:
+			level = slog.LevelError
+		case status >= 400:
+			level = slog.LevelWarn
+		}
+		slog.Default().LogAttrs(ctx.Request.Context(), level, "request", attrs...)
+	}
+}
+
+// Recovery turns a panic in a handler into an internal server error,
+// logging the panic with its stack
+func Recovery() gin.HandlerFunc {
+	return gin.CustomRecoveryWithWriter(io.Discard, func(ctx *gin.Context, recovered any) {
+		AbortWithError(ctx, fmt.Errorf("panic: %v\n%s", recovered, debug.Stack()))
+	})
+}
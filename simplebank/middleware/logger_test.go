@@ -0,0 +1,151 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/apperr"
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/logging"
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/token"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+// captureLogs makes the default logger write json records to the returned
+// buffer for the rest of the test
+func captureLogs(t *testing.T) *bytes.Buffer {
+	var buf bytes.Buffer
+	logger, err := logging.New(&buf, logging.FormatJSON, "debug")
+	require.NoError(t, err)
+
+	previous := slog.Default()
+	slog.SetDefault(logger)
+	t.Cleanup(func() { slog.SetDefault(previous) })
+	return &buf
+}
+
+func decodeLogs(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	var records []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var record map[string]any
+		require.NoError(t, json.Unmarshal([]byte(line), &record))
+		records = append(records, record)
+	}
+	return records
+}
+
+func TestRequestID(t *testing.T) {
+	testCases := []struct {
+		name      string
+		requestID string
+		keep      bool
+	}{
+		{
+			name:      "Propagated",
+			requestID: "f5b1c3e2-trace",
+			keep:      true,
+		},
+		{
+			name: "Missing",
+		},
+		{
+			name:      "TooLong",
+			requestID: strings.Repeat("a", maxRequestIDLength+1),
+		},
+		{
+			name:      "Unprintable",
+			requestID: "abc\x1b[31m",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var contextID string
+			router := gin.New()
+			router.Use(RequestID())
+			router.GET("/test", func(ctx *gin.Context) {
+				contextID = logging.RequestID(ctx.Request.Context())
+			})
+
+			recorder := httptest.NewRecorder()
+			request, err := http.NewRequest(http.MethodGet, "/test", nil)
+			require.NoError(t, err)
+			if tc.requestID != "" {
+				request.Header.Set(RequestIDHeader, tc.requestID)
+			}
+
+			router.ServeHTTP(recorder, request)
+			responseID := recorder.Header().Get(RequestIDHeader)
+			require.NotEmpty(t, responseID)
+			require.Equal(t, responseID, contextID)
+			if tc.keep {
+				require.Equal(t, tc.requestID, responseID)
+			} else {
+				require.NotEqual(t, tc.requestID, responseID)
+			}
+		})
+	}
+}
+
+func TestLogger(t *testing.T) {
+	buf := captureLogs(t)
+
+	router := gin.New()
+	router.Use(RequestID(), Logger(), ErrorHandler())
+	router.GET("/test", func(ctx *gin.Context) {
+		ctx.Set(AuthorizationPayloadKey, &token.Payload{Username: "alice"})
+		ctx.Error(apperr.PermissionDenied("not yours"))
+	})
+
+	recorder := httptest.NewRecorder()
+	request, err := http.NewRequest(http.MethodGet, "/test?secret_code=abc&page=2", nil)
+	require.NoError(t, err)
+	request.Header.Set(RequestIDHeader, "req-1")
+
+	router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusForbidden, recorder.Code)
+
+	records := decodeLogs(t, buf)
+	require.Len(t, records, 1)
+	record := records[0]
+	require.Equal(t, "WARN", record["level"])
+	require.Equal(t, "request", record["msg"])
+	require.Equal(t, "req-1", record["request_id"])
+	require.Equal(t, http.MethodGet, record["method"])
+	require.Equal(t, "/test?page=2&secret_code=%5BREDACTED%5D", record["path"])
+	require.EqualValues(t, http.StatusForbidden, record["status"])
+	require.Equal(t, "alice", record["username"])
+	require.Contains(t, record, "latency")
+	require.NotContains(t, buf.String(), "abc")
+}
+
+func TestRecovery(t *testing.T) {
+	buf := captureLogs(t)
+
+	router := gin.New()
+	router.Use(RequestID(), Logger(), Recovery(), ErrorHandler())
+	router.GET("/test", func(ctx *gin.Context) {
+		panic("nil map")
+	})
+
+	recorder := httptest.NewRecorder()
+	request, err := http.NewRequest(http.MethodGet, "/test", nil)
+	require.NoError(t, err)
+
+	router.ServeHTTP(recorder, request)
+	problem := requireProblem(t, recorder, http.StatusInternalServerError)
+	require.Equal(t, apperr.CodeInternal, problem.Code)
+	require.NotContains(t, recorder.Body.String(), "nil map")
+
+	records := decodeLogs(t, buf)
+	require.Len(t, records, 2)
+	require.Equal(t, "request failed", records[0]["msg"])
+	require.Contains(t, records[0]["error"], "panic: nil map")
+	require.Equal(t, records[0]["request_id"], records[1]["request_id"])
+	require.EqualValues(t, http.StatusInternalServerError, records[1]["status"])
+}
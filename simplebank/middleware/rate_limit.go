@@ -0,0 +1,74 @@
+package middleware
+
+import (
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/apperr"
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/ratelimit"
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/token"
+	"github.com/gin-gonic/gin"
+)
+
+// Headers of rate limited routes, following the IETF RateLimit header
+// fields draft. Times are in whole seconds.
+const (
+	RateLimitLimitHeader     = "RateLimit-Limit"
+	RateLimitRemainingHeader = "RateLimit-Remaining"
+	RateLimitResetHeader     = "RateLimit-Reset"
+	RetryAfterHeader         = "Retry-After"
+)
+
+var errRateLimited = apperr.New(http.StatusTooManyRequests, "rate_limited", "too many requests, try again later")
+
+// RateLimitKey picks who a request is counted against
+type RateLimitKey func(ctx *gin.Context) string
+
+// RateLimitByIP counts requests against the client ip
+func RateLimitByIP(ctx *gin.Context) string {
+	return "ip:" + ctx.ClientIP()
+}
+
+// RateLimitByUser counts requests against the user of the payload set by
+// AuthMiddleware, whichever token or api key they used
+func RateLimitByUser(ctx *gin.Context) string {
+	payload := ctx.MustGet(AuthorizationPayloadKey).(*token.Payload)
+	return "user:" + payload.Username
+}
+
+// RateLimit takes a token for every request from the bucket of its key in
+// the given group, and rejects the request with 429 Too Many Requests when
+// there is none left. Groups with the same key have separate buckets, so
+// a route can be limited by several groups at once. Requests are let
+// through when the store fails, as an outage of the limiter should not be
+// one of the api.
+func RateLimit(store ratelimit.Store, group string, limit ratelimit.Limit, key RateLimitKey) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		result, err := store.Take(ctx, group+":"+key(ctx), limit)
+		if err != nil {
+			slog.WarnContext(ctx.Request.Context(), "cannot check rate limit", "group", group, "error", err)
+			ctx.Next()
+			return
+		}
+
+		header := ctx.Writer.Header()
+		header.Set(RateLimitLimitHeader, strconv.Itoa(limit.Burst))
+		header.Set(RateLimitRemainingHeader, strconv.Itoa(result.Remaining))
+		header.Set(RateLimitResetHeader, wholeSeconds(result.Reset))
+
+		if !result.Allowed {
+			header.Set(RetryAfterHeader, wholeSeconds(result.RetryAfter))
+			AbortWithError(ctx, errRateLimited)
+			return
+		}
+		ctx.Next()
+	}
+}
+
+// wholeSeconds rounds d up, so clients waiting that long are not turned away
+func wholeSeconds(d time.Duration) string {
+	return strconv.FormatInt(int64(math.Ceil(d.Seconds())), 10)
+}
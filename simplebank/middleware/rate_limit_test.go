@@ -0,0 +1,116 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/ratelimit"
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/token"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+// failingRateLimitStore stands in for a rate limit store that is down
+type failingRateLimitStore struct{}
+
+func (failingRateLimitStore) Take(context.Context, string, ratelimit.Limit) (ratelimit.Result, error) {
+	return ratelimit.Result{}, errors.New("connection refused")
+}
+
+func TestRateLimit(t *testing.T) {
+	limit := ratelimit.Limit{Rate: 0.5, Burst: 2}
+
+	router := gin.New()
+	router.Use(ErrorHandler())
+	router.GET("/test", RateLimit(ratelimit.NewMemoryStore(), "test", limit, RateLimitByIP), func(ctx *gin.Context) {
+		ctx.Status(http.StatusNoContent)
+	})
+
+	request := func(remoteAddr string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/test", nil)
+		req.RemoteAddr = remoteAddr
+		router.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	for _, remaining := range []string{"1", "0"} {
+		recorder := request("192.0.2.1:1234")
+		require.Equal(t, http.StatusNoContent, recorder.Code)
+		require.Equal(t, "2", recorder.Header().Get(RateLimitLimitHeader))
+		require.Equal(t, remaining, recorder.Header().Get(RateLimitRemainingHeader))
+		require.NotEmpty(t, recorder.Header().Get(RateLimitResetHeader))
+		require.Empty(t, recorder.Header().Get(RetryAfterHeader))
+	}
+
+	recorder := request("192.0.2.1:5678")
+	problem := requireProblem(t, recorder, http.StatusTooManyRequests)
+	require.Equal(t, "rate_limited", problem.Code)
+	require.Equal(t, "0", recorder.Header().Get(RateLimitRemainingHeader))
+	require.Equal(t, "4", recorder.Header().Get(RateLimitResetHeader))
+	require.Equal(t, "2", recorder.Header().Get(RetryAfterHeader))
+
+	// other clients have their own bucket
+	recorder = request("192.0.2.2:1234")
+	require.Equal(t, http.StatusNoContent, recorder.Code)
+}
+
+func TestRateLimitByUser(t *testing.T) {
+	limit := ratelimit.Limit{Rate: 1, Burst: 1}
+	store := ratelimit.NewMemoryStore()
+
+	router := gin.New()
+	router.Use(ErrorHandler())
+	router.GET("/test/:username",
+		func(ctx *gin.Context) {
+			ctx.Set(AuthorizationPayloadKey, &token.Payload{Username: ctx.Param("username")})
+		},
+		RateLimit(store, "test", limit, RateLimitByUser),
+		func(ctx *gin.Context) {
+			ctx.Status(http.StatusNoContent)
+		},
+	)
+
+	testCases := []struct {
+		username string
+		status   int
+	}{
+		{username: "alice", status: http.StatusNoContent},
+		{username: "alice", status: http.StatusTooManyRequests},
+		{username: "bob", status: http.StatusNoContent},
+	}
+
+	for _, tc := range testCases {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/test/"+tc.username, nil))
+		require.Equal(t, tc.status, recorder.Code, tc.username)
+	}
+}
+
+func TestRateLimitStoreFailure(t *testing.T) {
+	limit := ratelimit.Limit{Rate: 1, Burst: 1}
+
+	router := gin.New()
+	router.Use(ErrorHandler())
+	router.GET("/test", RateLimit(failingRateLimitStore{}, "test", limit, RateLimitByIP), func(ctx *gin.Context) {
+		ctx.Status(http.StatusNoContent)
+	})
+
+	for range 3 {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/test", nil))
+		require.Equal(t, http.StatusNoContent, recorder.Code)
+		require.Empty(t, recorder.Header().Get(RateLimitLimitHeader))
+	}
+}
+
+func TestWholeSeconds(t *testing.T) {
+	require.Equal(t, "0", wholeSeconds(0))
+	require.Equal(t, "1", wholeSeconds(time.Millisecond))
+	require.Equal(t, "1", wholeSeconds(time.Second))
+	require.Equal(t, "2", wholeSeconds(1500*time.Millisecond))
+}
@@ -0,0 +1,61 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.40.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Tracing traces every request with a span named after its route template,
+// continuing the trace of its W3C traceparent header if it has one. Spans
+// of server errors record the error of the handler.
+func Tracing() gin.HandlerFunc {
+	tracer := otel.Tracer("github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/middleware")
+
+	return func(ctx *gin.Context) {
+		parent := otel.GetTextMapPropagator().Extract(ctx.Request.Context(), propagation.HeaderCarrier(ctx.Request.Header))
+
+		name := ctx.Request.Method
+		route := ctx.FullPath()
+		if route != "" {
+			name += " " + route
+		}
+
+		spanCtx, span := tracer.Start(parent, name,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPRequestMethodKey.String(ctx.Request.Method),
+				semconv.HTTPRoute(route),
+				semconv.URLPath(ctx.Request.URL.Path),
+				semconv.ClientAddress(ctx.ClientIP()),
+			),
+		)
+		defer span.End()
+
+		ctx.Request = ctx.Request.WithContext(spanCtx)
+		ctx.Next()
+
+		status := ctx.Writer.Status()
+		span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+		if status >= http.StatusInternalServerError {
+			if len(ctx.Errors) > 0 {
+				span.RecordError(ctx.Errors.Last().Err)
+			}
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+	}
+}
+
+// traceID returns the id of the trace of the request, if it is traced
+func traceID(ctx *gin.Context) string {
+	span := trace.SpanContextFromContext(ctx.Request.Context())
+	if !span.IsValid() {
+		return ""
+	}
+	return span.TraceID().String()
+}
@@ -0,0 +1,85 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.40.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	testTraceID     = "4bf92f3577b34da6a3ce929d0e0e4736"
+	testTraceparent = "00-" + testTraceID + "-00f067aa0ba902b7-01"
+)
+
+func setupTestTracing(t *testing.T) *tracetest.SpanRecorder {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	return recorder
+}
+
+func TestTracing(t *testing.T) {
+	spans := setupTestTracing(t)
+
+	var handlerSpan trace.SpanContext
+	router := gin.New()
+	router.Use(Tracing(), ErrorHandler())
+	router.GET("/accounts/:id", func(ctx *gin.Context) {
+		handlerSpan = trace.SpanContextFromContext(ctx.Request.Context())
+		ctx.Error(errors.New("connection refused"))
+	})
+
+	recorder := httptest.NewRecorder()
+	request, err := http.NewRequest(http.MethodGet, "/accounts/1", nil)
+	require.NoError(t, err)
+	request.Header.Set("traceparent", testTraceparent)
+
+	router.ServeHTTP(recorder, request)
+	problem := requireProblem(t, recorder, http.StatusInternalServerError)
+	require.Equal(t, testTraceID, problem.TraceID)
+
+	ended := spans.Ended()
+	require.Len(t, ended, 1)
+	span := ended[0]
+	require.Equal(t, "GET /accounts/:id", span.Name())
+	require.Equal(t, trace.SpanKindServer, span.SpanKind())
+	require.Equal(t, testTraceID, span.SpanContext().TraceID().String())
+	require.Equal(t, "00f067aa0ba902b7", span.Parent().SpanID().String())
+	require.True(t, span.Parent().IsRemote())
+	require.Equal(t, span.SpanContext().SpanID(), handlerSpan.SpanID())
+	require.Contains(t, span.Attributes(), semconv.HTTPRoute("/accounts/:id"))
+	require.Contains(t, span.Attributes(), semconv.HTTPResponseStatusCode(http.StatusInternalServerError))
+	require.Equal(t, codes.Error, span.Status().Code)
+	require.Len(t, span.Events(), 1, "the error is recorded")
+}
+
+func TestTracingNewTrace(t *testing.T) {
+	spans := setupTestTracing(t)
+
+	router := gin.New()
+	router.Use(Tracing(), ErrorHandler())
+
+	recorder := httptest.NewRecorder()
+	request, err := http.NewRequest(http.MethodGet, "/unknown", nil)
+	require.NoError(t, err)
+
+	router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusNotFound, recorder.Code)
+
+	ended := spans.Ended()
+	require.Len(t, ended, 1)
+	require.Equal(t, http.MethodGet, ended[0].Name())
+	require.False(t, ended[0].Parent().IsValid())
+	require.Equal(t, codes.Unset, ended[0].Status().Code)
+}
@@ -0,0 +1,96 @@
+// Package oauth holds the protocol details of the OAuth 2.0 authorization
+// code flow (RFC 6749) with PKCE (RFC 7636) that are independent of storage
+// and http handling.
+package oauth
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net"
+	"net/url"
+	"strings"
+
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/util"
+)
+
+// Error codes of RFC 6749
+const (
+	ErrorInvalidRequest          = "invalid_request"
+	ErrorInvalidClient           = "invalid_client"
+	ErrorInvalidGrant            = "invalid_grant"
+	ErrorInvalidScope            = "invalid_scope"
+	ErrorAccessDenied            = "access_denied"
+	ErrorUnsupportedGrantType    = "unsupported_grant_type"
+	ErrorUnsupportedResponseType = "unsupported_response_type"
+)
+
+const (
+	ResponseTypeCode           = "code"
+	GrantTypeAuthorizationCode = "authorization_code"
+	TokenTypeBearer            = "Bearer"
+)
+
+// ParseScope splits a space-delimited scope parameter
+func ParseScope(scope string) []string {
+	return strings.Fields(scope)
+}
+
+// FormatScope joins scopes into a space-delimited scope parameter
+func FormatScope(scopes []string) string {
+	return strings.Join(scopes, " ")
+}
+
+// IsValidRedirectURI accepts absolute https uris without a fragment, and http
+// ones on the loopback interface for native apps and local testing.
+func IsValidRedirectURI(uri string) bool {
+	u, err := url.Parse(uri)
+	if err != nil || !u.IsAbs() || u.Host == "" || u.Fragment != "" {
+		return false
+	}
+
+	switch u.Scheme {
+	case "https":
+		return true
+	case "http":
+		if u.Hostname() == "localhost" {
+			return true
+		}
+		ip := net.ParseIP(u.Hostname())
+		return ip != nil && ip.IsLoopback()
+	}
+	return false
+}
+
+// RedirectURL adds the parameters to the query of a registered redirect uri
+func RedirectURL(redirectURI string, params url.Values) string {
+	u, err := url.Parse(redirectURI)
+	if err != nil {
+		return redirectURI
+	}
+
+	query := u.Query()
+	for key, values := range params {
+		for _, value := range values {
+			query.Add(key, value)
+		}
+	}
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+// GenerateSecret returns a random secret, used for authorization codes and
+// client secrets, together with the hash it is stored as.
+func GenerateSecret() (secret, hashedSecret string, err error) {
+	secret, err = util.GenerateSecret(32)
+	if err != nil {
+		return "", "", err
+	}
+	return secret, HashSecret(secret), nil
+}
+
+// HashSecret returns the hash a secret is stored and looked up by. Secrets
+// carry 256 bits of randomness, so a fast hash is enough, unlike passwords.
+func HashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
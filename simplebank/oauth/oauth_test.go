@@ -0,0 +1,66 @@
+package oauth
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestScope(t *testing.T) {
+	scopes := ParseScope(" accounts:read  transfers:write ")
+	require.Equal(t, []string{"accounts:read", "transfers:write"}, scopes)
+	require.Equal(t, "accounts:read transfers:write", FormatScope(scopes))
+	require.Empty(t, ParseScope(""))
+}
+
+func TestIsValidRedirectURI(t *testing.T) {
+	valid := []string{
+		"https://partner.example.com/callback",
+		"https://partner.example.com/callback?tenant=1",
+		"http://localhost:8080/callback",
+		"http://127.0.0.1/callback",
+		"http://[::1]:9000/callback",
+	}
+	for _, uri := range valid {
+		require.True(t, IsValidRedirectURI(uri), uri)
+	}
+
+	invalid := []string{
+		"",
+		"/callback",
+		"http://partner.example.com/callback",
+		"https://partner.example.com/callback#fragment",
+		"javascript:alert(1)",
+		"ftp://partner.example.com/callback",
+	}
+	for _, uri := range invalid {
+		require.False(t, IsValidRedirectURI(uri), uri)
+	}
+}
+
+func TestRedirectURL(t *testing.T) {
+	redirect := RedirectURL("https://partner.example.com/callback?tenant=1", url.Values{
+		"code":  {"abc"},
+		"state": {"a b"},
+	})
+
+	u, err := url.Parse(redirect)
+	require.NoError(t, err)
+	require.Equal(t, "partner.example.com", u.Host)
+	require.Equal(t, "/callback", u.Path)
+	require.Equal(t, "1", u.Query().Get("tenant"))
+	require.Equal(t, "abc", u.Query().Get("code"))
+	require.Equal(t, "a b", u.Query().Get("state"))
+}
+
+func TestGenerateSecret(t *testing.T) {
+	secret1, hashed1, err := GenerateSecret()
+	require.NoError(t, err)
+	require.Equal(t, HashSecret(secret1), hashed1)
+	require.NotEqual(t, secret1, hashed1)
+
+	secret2, _, err := GenerateSecret()
+	require.NoError(t, err)
+	require.NotEqual(t, secret1, secret2)
+}
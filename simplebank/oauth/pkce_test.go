@@ -0,0 +1,37 @@
+package oauth
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCodeChallenge(t *testing.T) {
+	// RFC 7636 appendix B
+	verifier := "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+	challenge := "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"
+
+	require.Equal(t, challenge, CodeChallenge(verifier))
+	require.True(t, IsValidCodeChallenge(challenge))
+	require.True(t, VerifyCodeChallenge(verifier, challenge))
+
+	require.False(t, VerifyCodeChallenge(verifier+"x", challenge))
+	require.False(t, VerifyCodeChallenge(verifier, CodeChallenge(verifier+"x")))
+}
+
+func TestVerifyCodeChallengeInvalidVerifier(t *testing.T) {
+	for _, verifier := range []string{
+		"",
+		"too-short",
+		strings.Repeat("a", 129),
+		strings.Repeat("a", 42) + "!",
+	} {
+		require.False(t, VerifyCodeChallenge(verifier, CodeChallenge(verifier)), verifier)
+	}
+
+	// the plain method is not supported
+	verifier := strings.Repeat("a", 43)
+	require.False(t, IsValidCodeChallenge(verifier+"a"))
+	require.False(t, VerifyCodeChallenge(verifier, verifier))
+}
@@ -0,0 +1,290 @@
+// Package oidc is an OpenID Connect relying party for the authorization
+// code flow: it discovers the provider, builds authorization requests,
+// exchanges codes and verifies ID tokens against the provider's keys.
+package oidc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/oauth"
+	"github.com/golang-jwt/jwt"
+)
+
+// clockSkew is the leeway given to the provider's clock when checking times
+const clockSkew = time.Minute
+
+// maxResponseSize bounds the documents read from the provider
+const maxResponseSize = 1 << 20
+
+var (
+	ErrInvalidIDToken = errors.New("id token is invalid")
+	ErrExpiredIDToken = errors.New("id token has expired")
+)
+
+// DefaultScopes are requested when Config.Scopes is empty
+var DefaultScopes = []string{"openid", "email", "profile"}
+
+// Config is the registration of the bank with the provider
+type Config struct {
+	IssuerURL    string
+	ClientID     string
+	ClientSecret string
+	// RedirectURL is where the provider sends the user back with the code
+	RedirectURL string
+	Scopes      []string
+}
+
+// Provider holds the endpoints of the discovery document
+type Provider struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// IDToken holds the verified claims of an ID token
+type IDToken struct {
+	Issuer            string    `json:"iss"`
+	Subject           string    `json:"sub"`
+	Audience          []string  `json:"-"`
+	Nonce             string    `json:"nonce"`
+	Email             string    `json:"email"`
+	EmailVerified     bool      `json:"email_verified"`
+	Name              string    `json:"name"`
+	PreferredUsername string    `json:"preferred_username"`
+	ExpiresAt         time.Time `json:"-"`
+	IssuedAt          time.Time `json:"-"`
+
+	claims jwt.MapClaims
+}
+
+// Strings returns a claim that is a string or a list of strings, such as
+// the groups of the user
+func (t *IDToken) Strings(claim string) []string {
+	switch value := t.claims[claim].(type) {
+	case string:
+		return []string{value}
+	case []interface{}:
+		values := make([]string, 0, len(value))
+		for _, v := range value {
+			if s, ok := v.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+	return nil
+}
+
+// Client is the relying party. The provider is discovered on first use, so
+// the server can start while the provider is unreachable.
+type Client struct {
+	config     Config
+	httpClient *http.Client
+
+	mu       sync.Mutex
+	provider *Provider
+	keys     *keySet
+}
+
+func NewClient(config Config, httpClient *http.Client) *Client {
+	if len(config.Scopes) == 0 {
+		config.Scopes = DefaultScopes
+	}
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &Client{
+		config:     config,
+		httpClient: httpClient,
+	}
+}
+
+// Issuer returns the issuer ID tokens must come from
+func (c *Client) Issuer() string {
+	return strings.TrimSuffix(c.config.IssuerURL, "/")
+}
+
+// Provider returns the discovered provider, fetching the discovery document
+// until it has been fetched successfully once.
+func (c *Client) Provider(ctx context.Context) (*Provider, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.provider != nil {
+		return c.provider, nil
+	}
+
+	var provider Provider
+	if err := c.getJSON(ctx, c.Issuer()+"/.well-known/openid-configuration", &provider); err != nil {
+		return nil, fmt.Errorf("cannot discover provider: %w", err)
+	}
+	if provider.Issuer != c.Issuer() {
+		return nil, fmt.Errorf("provider claims to be issuer %q, not %q", provider.Issuer, c.Issuer())
+	}
+	if provider.AuthorizationEndpoint == "" || provider.TokenEndpoint == "" || provider.JWKSURI == "" {
+		return nil, errors.New("discovery document is missing endpoints")
+	}
+
+	c.provider = &provider
+	c.keys = newKeySet(provider.JWKSURI, c.getJSON)
+	return c.provider, nil
+}
+
+// AuthCodeURL returns where to send the user to log in. The nonce comes back
+// in the ID token and the S256 challenge binds the code to its verifier.
+func (c *Client) AuthCodeURL(ctx context.Context, state, nonce, codeChallenge string) (string, error) {
+	provider, err := c.Provider(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	params := url.Values{
+		"response_type":         {oauth.ResponseTypeCode},
+		"client_id":             {c.config.ClientID},
+		"redirect_uri":          {c.config.RedirectURL},
+		"scope":                 {oauth.FormatScope(c.config.Scopes)},
+		"state":                 {state},
+		"nonce":                 {nonce},
+		"code_challenge":        {codeChallenge},
+		"code_challenge_method": {oauth.CodeChallengeMethodS256},
+	}
+	return oauth.RedirectURL(provider.AuthorizationEndpoint, params), nil
+}
+
+type tokenResponse struct {
+	IDToken          string `json:"id_token"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// Exchange redeems an authorization code at the token endpoint and returns
+// the verified ID token. The caller must still check its nonce.
+func (c *Client) Exchange(ctx context.Context, code, codeVerifier string) (*IDToken, error) {
+	provider, err := c.Provider(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{
+		"grant_type":    {oauth.GrantTypeAuthorizationCode},
+		"code":          {code},
+		"redirect_uri":  {c.config.RedirectURL},
+		"code_verifier": {codeVerifier},
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, provider.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Accept", "application/json")
+	request.SetBasicAuth(url.QueryEscape(c.config.ClientID), url.QueryEscape(c.config.ClientSecret))
+
+	response, err := c.httpClient.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	var rsp tokenResponse
+	if err := json.NewDecoder(io.LimitReader(response.Body, maxResponseSize)).Decode(&rsp); err != nil {
+		return nil, fmt.Errorf("cannot read token response: %w", err)
+	}
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("provider rejected the code: %s %s", rsp.Error, rsp.ErrorDescription)
+	}
+	if rsp.IDToken == "" {
+		return nil, errors.New("provider returned no id token")
+	}
+
+	return c.Verify(ctx, rsp.IDToken)
+}
+
+// Verify checks the signature of an ID token against the provider's keys,
+// and that the token was issued by the provider to this client and is current.
+func (c *Client) Verify(ctx context.Context, rawIDToken string) (*IDToken, error) {
+	if _, err := c.Provider(ctx); err != nil {
+		return nil, err
+	}
+
+	parser := jwt.Parser{
+		ValidMethods:         []string{"RS256", "RS384", "RS512", "ES256", "ES384", "ES512"},
+		SkipClaimsValidation: true,
+	}
+	claims := jwt.MapClaims{}
+	_, err := parser.ParseWithClaims(rawIDToken, claims, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		return c.keys.get(ctx, kid)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidIDToken, err)
+	}
+
+	data, err := json.Marshal(claims)
+	if err != nil {
+		return nil, err
+	}
+	idToken := &IDToken{claims: claims}
+	if err := json.Unmarshal(data, idToken); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidIDToken, err)
+	}
+	idToken.Audience = idToken.Strings("aud")
+	idToken.ExpiresAt = numericDate(claims["exp"])
+	idToken.IssuedAt = numericDate(claims["iat"])
+
+	now := time.Now()
+	switch {
+	case idToken.Issuer != c.Issuer():
+		return nil, fmt.Errorf("%w: issued by %q", ErrInvalidIDToken, idToken.Issuer)
+	case !claims.VerifyAudience(c.config.ClientID, true):
+		return nil, fmt.Errorf("%w: issued to %q", ErrInvalidIDToken, idToken.Audience)
+	case len(idToken.Audience) > 1 && claims["azp"] != c.config.ClientID:
+		return nil, fmt.Errorf("%w: authorized party is not the client", ErrInvalidIDToken)
+	case idToken.Subject == "":
+		return nil, fmt.Errorf("%w: no subject", ErrInvalidIDToken)
+	case idToken.ExpiresAt.IsZero() || idToken.IssuedAt.IsZero():
+		return nil, fmt.Errorf("%w: no expiry or issue time", ErrInvalidIDToken)
+	case idToken.IssuedAt.After(now.Add(clockSkew)):
+		return nil, fmt.Errorf("%w: issued in the future", ErrInvalidIDToken)
+	case now.After(idToken.ExpiresAt.Add(clockSkew)):
+		return nil, ErrExpiredIDToken
+	}
+
+	return idToken, nil
+}
+
+func numericDate(value interface{}) time.Time {
+	seconds, ok := value.(float64)
+	if !ok {
+		return time.Time{}
+	}
+	return time.Unix(int64(seconds), 0)
+}
+
+func (c *Client) getJSON(ctx context.Context, url string, v interface{}) error {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Accept", "application/json")
+
+	response, err := c.httpClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: %s", url, response.Status)
+	}
+	return json.NewDecoder(io.LimitReader(response.Body, maxResponseSize)).Decode(v)
+}
@@ -0,0 +1,267 @@
+package oidc
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/oauth"
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/oidc/oidctest"
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/util"
+	"github.com/golang-jwt/jwt"
+	"github.com/stretchr/testify/require"
+)
+
+const testRedirectURL = "http://localhost:3000/sso/callback"
+
+var testUser = oidctest.User{
+	Subject:       "00u1staff",
+	Email:         "jane@bank.example.com",
+	EmailVerified: true,
+	Name:          "Jane Staff",
+	Groups:        []string{"staff", "bank-admins"},
+}
+
+func newTestProvider(t *testing.T) (*oidctest.Provider, *Client) {
+	server, provider, err := oidctest.NewServer("simplebank", "secret", testRedirectURL)
+	require.NoError(t, err)
+	t.Cleanup(server.Close)
+	provider.SetUser(testUser)
+
+	client := NewClient(Config{
+		IssuerURL:    server.URL,
+		ClientID:     "simplebank",
+		ClientSecret: "secret",
+		RedirectURL:  testRedirectURL,
+	}, server.Client())
+	return provider, client
+}
+
+// login follows the authorization url and returns the code the provider
+// sends the user back with
+func login(t *testing.T, authURL string) url.Values {
+	httpClient := &http.Client{
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	response, err := httpClient.Get(authURL)
+	require.NoError(t, err)
+	defer response.Body.Close()
+	require.Equal(t, http.StatusFound, response.StatusCode)
+
+	location, err := url.Parse(response.Header.Get("Location"))
+	require.NoError(t, err)
+	return location.Query()
+}
+
+func TestClientFlow(t *testing.T) {
+	_, client := newTestProvider(t)
+	ctx := context.Background()
+
+	verifier := util.RandomString(43)
+	authURL, err := client.AuthCodeURL(ctx, "state123", "nonce123", oauth.CodeChallenge(verifier))
+	require.NoError(t, err)
+
+	query, err := url.Parse(authURL)
+	require.NoError(t, err)
+	require.Equal(t, "openid email profile", query.Query().Get("scope"))
+	require.Equal(t, testRedirectURL, query.Query().Get("redirect_uri"))
+
+	callback := login(t, authURL)
+	require.Equal(t, "state123", callback.Get("state"))
+	require.NotEmpty(t, callback.Get("code"))
+
+	idToken, err := client.Exchange(ctx, callback.Get("code"), verifier)
+	require.NoError(t, err)
+	require.Equal(t, client.Issuer(), idToken.Issuer)
+	require.Equal(t, testUser.Subject, idToken.Subject)
+	require.Equal(t, []string{"simplebank"}, idToken.Audience)
+	require.Equal(t, "nonce123", idToken.Nonce)
+	require.Equal(t, testUser.Email, idToken.Email)
+	require.True(t, idToken.EmailVerified)
+	require.Equal(t, testUser.Name, idToken.Name)
+	require.Equal(t, testUser.Groups, idToken.Strings("groups"))
+	require.WithinDuration(t, time.Now(), idToken.IssuedAt, time.Minute)
+
+	// codes work once
+	_, err = client.Exchange(ctx, callback.Get("code"), verifier)
+	require.Error(t, err)
+}
+
+func TestExchangeWrongVerifier(t *testing.T) {
+	_, client := newTestProvider(t)
+	ctx := context.Background()
+
+	authURL, err := client.AuthCodeURL(ctx, "state", "nonce", oauth.CodeChallenge(util.RandomString(43)))
+	require.NoError(t, err)
+
+	callback := login(t, authURL)
+	_, err = client.Exchange(ctx, callback.Get("code"), util.RandomString(43))
+	require.Error(t, err)
+}
+
+func TestDiscoveryIssuerMismatch(t *testing.T) {
+	provider, err := oidctest.NewProvider("https://idp.example.com", "simplebank", "secret", testRedirectURL)
+	require.NoError(t, err)
+	server := httptest.NewServer(provider)
+	defer server.Close()
+
+	client := NewClient(Config{IssuerURL: server.URL, ClientID: "simplebank"}, server.Client())
+	_, err = client.Provider(context.Background())
+	require.Error(t, err)
+}
+
+func TestVerify(t *testing.T) {
+	provider, client := newTestProvider(t)
+	other, _ := newTestProvider(t)
+
+	testCases := []struct {
+		name   string
+		sign   func() (string, error)
+		expect error
+	}{
+		{
+			name: "OK",
+			sign: func() (string, error) {
+				return provider.SignIDToken(provider.Claims(testUser, ""))
+			},
+		},
+		{
+			name: "OtherAudiences",
+			sign: func() (string, error) {
+				claims := provider.Claims(testUser, "")
+				claims["aud"] = []string{"simplebank", "reporting"}
+				claims["azp"] = "simplebank"
+				return provider.SignIDToken(claims)
+			},
+		},
+		{
+			name: "OtherAuthorizedParty",
+			sign: func() (string, error) {
+				claims := provider.Claims(testUser, "")
+				claims["aud"] = []string{"simplebank", "reporting"}
+				claims["azp"] = "reporting"
+				return provider.SignIDToken(claims)
+			},
+			expect: ErrInvalidIDToken,
+		},
+		{
+			name: "WrongAudience",
+			sign: func() (string, error) {
+				claims := provider.Claims(testUser, "")
+				claims["aud"] = "reporting"
+				return provider.SignIDToken(claims)
+			},
+			expect: ErrInvalidIDToken,
+		},
+		{
+			name: "WrongIssuer",
+			sign: func() (string, error) {
+				claims := provider.Claims(testUser, "")
+				claims["iss"] = "https://idp.example.com"
+				return provider.SignIDToken(claims)
+			},
+			expect: ErrInvalidIDToken,
+		},
+		{
+			name: "NoSubject",
+			sign: func() (string, error) {
+				return provider.SignIDToken(provider.Claims(oidctest.User{Email: testUser.Email}, ""))
+			},
+			expect: ErrInvalidIDToken,
+		},
+		{
+			name: "Expired",
+			sign: func() (string, error) {
+				claims := provider.Claims(testUser, "")
+				claims["iat"] = time.Now().Add(-time.Hour).Unix()
+				claims["exp"] = time.Now().Add(-2 * clockSkew).Unix()
+				return provider.SignIDToken(claims)
+			},
+			expect: ErrExpiredIDToken,
+		},
+		{
+			name: "ExpiredWithinClockSkew",
+			sign: func() (string, error) {
+				claims := provider.Claims(testUser, "")
+				claims["exp"] = time.Now().Add(-clockSkew / 2).Unix()
+				return provider.SignIDToken(claims)
+			},
+		},
+		{
+			name: "IssuedInTheFuture",
+			sign: func() (string, error) {
+				claims := provider.Claims(testUser, "")
+				claims["iat"] = time.Now().Add(2 * clockSkew).Unix()
+				return provider.SignIDToken(claims)
+			},
+			expect: ErrInvalidIDToken,
+		},
+		{
+			name: "NoExpiry",
+			sign: func() (string, error) {
+				claims := provider.Claims(testUser, "")
+				delete(claims, "exp")
+				return provider.SignIDToken(claims)
+			},
+			expect: ErrInvalidIDToken,
+		},
+		{
+			name: "SignedByOtherProvider",
+			sign: func() (string, error) {
+				return other.SignIDToken(provider.Claims(testUser, ""))
+			},
+			expect: ErrInvalidIDToken,
+		},
+		{
+			// a shared secret would let anyone who knows it forge tokens
+			name: "SymmetricSignature",
+			sign: func() (string, error) {
+				return jwt.NewWithClaims(jwt.SigningMethodHS256, provider.Claims(testUser, "")).SignedString([]byte("secret"))
+			},
+			expect: ErrInvalidIDToken,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rawIDToken, err := tc.sign()
+			require.NoError(t, err)
+
+			idToken, err := client.Verify(context.Background(), rawIDToken)
+			if tc.expect != nil {
+				require.ErrorIs(t, err, tc.expect)
+				require.Nil(t, idToken)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, testUser.Subject, idToken.Subject)
+		})
+	}
+}
+
+func TestVerifyAfterKeyRotation(t *testing.T) {
+	provider, client := newTestProvider(t)
+	ctx := context.Background()
+
+	rawIDToken, err := provider.SignIDToken(provider.Claims(testUser, ""))
+	require.NoError(t, err)
+	_, err = client.Verify(ctx, rawIDToken)
+	require.NoError(t, err)
+
+	require.NoError(t, provider.RotateKey())
+	rawIDToken, err = provider.SignIDToken(provider.Claims(testUser, ""))
+	require.NoError(t, err)
+
+	// the keys were just fetched, so they are not fetched again yet
+	_, err = client.Verify(ctx, rawIDToken)
+	require.ErrorIs(t, err, ErrInvalidIDToken)
+
+	client.keys.refreshInterval = 0
+	_, err = client.Verify(ctx, rawIDToken)
+	require.NoError(t, err)
+}
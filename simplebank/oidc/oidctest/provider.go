@@ -0,0 +1,278 @@
+// Package oidctest is a mock OpenID Connect provider for tests and local
+// development. It logs in a configurable user without asking for
+// credentials and signs its ID tokens with a generated RSA key.
+package oidctest
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/oauth"
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/util"
+	"github.com/golang-jwt/jwt"
+)
+
+// User is who the provider logs in at its authorization endpoint
+type User struct {
+	Subject           string
+	Email             string
+	EmailVerified     bool
+	Name              string
+	PreferredUsername string
+	Groups            []string
+}
+
+type authorization struct {
+	user          User
+	redirectURI   string
+	nonce         string
+	codeChallenge string
+}
+
+// Provider is the mock provider. It is an http.Handler, so it can be served
+// by httptest or, for local development, by http.ListenAndServe.
+type Provider struct {
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	// IDTokenDuration defaults to five minutes
+	IDTokenDuration time.Duration
+
+	mu    sync.Mutex
+	user  User
+	key   *rsa.PrivateKey
+	kid   string
+	codes map[string]authorization
+	mux   *http.ServeMux
+}
+
+// NewProvider returns a provider at the issuer url with a single client
+func NewProvider(issuer, clientID, clientSecret, redirectURL string) (*Provider, error) {
+	provider := &Provider{
+		Issuer:          issuer,
+		ClientID:        clientID,
+		ClientSecret:    clientSecret,
+		RedirectURL:     redirectURL,
+		IDTokenDuration: 5 * time.Minute,
+		codes:           map[string]authorization{},
+		mux:             http.NewServeMux(),
+	}
+	if err := provider.RotateKey(); err != nil {
+		return nil, err
+	}
+
+	provider.mux.HandleFunc("GET /.well-known/openid-configuration", provider.discovery)
+	provider.mux.HandleFunc("GET /authorize", provider.authorize)
+	provider.mux.HandleFunc("POST /token", provider.token)
+	provider.mux.HandleFunc("GET /jwks", provider.jwks)
+	return provider, nil
+}
+
+// NewServer starts a provider on a local port. The caller must close it.
+func NewServer(clientID, clientSecret, redirectURL string) (*httptest.Server, *Provider, error) {
+	server := httptest.NewUnstartedServer(nil)
+	issuer := "http://" + server.Listener.Addr().String()
+
+	provider, err := NewProvider(issuer, clientID, clientSecret, redirectURL)
+	if err != nil {
+		return nil, nil, err
+	}
+	server.Config.Handler = provider
+	server.Start()
+	return server, provider, nil
+}
+
+func (p *Provider) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	p.mux.ServeHTTP(w, r)
+}
+
+// SetUser sets who logs in from now on
+func (p *Provider) SetUser(user User) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.user = user
+}
+
+// RotateKey replaces the signing key, as providers do from time to time
+func (p *Provider) RotateKey() error {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return err
+	}
+	kid, err := util.GenerateSecret(8)
+	if err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.key, p.kid = key, kid
+	return nil
+}
+
+// SignIDToken signs arbitrary claims with the current key, to test how
+// relying parties deal with tokens the provider would not issue
+func (p *Provider) SignIDToken(claims jwt.MapClaims) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	idToken := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	idToken.Header["kid"] = p.kid
+	return idToken.SignedString(p.key)
+}
+
+// Claims returns the claims of an ID token for the user
+func (p *Provider) Claims(user User, nonce string) jwt.MapClaims {
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss":            p.Issuer,
+		"sub":            user.Subject,
+		"aud":            p.ClientID,
+		"iat":            now.Unix(),
+		"exp":            now.Add(p.IDTokenDuration).Unix(),
+		"email":          user.Email,
+		"email_verified": user.EmailVerified,
+		"name":           user.Name,
+		"groups":         user.Groups,
+	}
+	if nonce != "" {
+		claims["nonce"] = nonce
+	}
+	if user.PreferredUsername != "" {
+		claims["preferred_username"] = user.PreferredUsername
+	}
+	return claims
+}
+
+func (p *Provider) discovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"issuer":                                p.Issuer,
+		"authorization_endpoint":                p.Issuer + "/authorize",
+		"token_endpoint":                        p.Issuer + "/token",
+		"jwks_uri":                              p.Issuer + "/jwks",
+		"response_types_supported":              []string{oauth.ResponseTypeCode},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"code_challenge_methods_supported":      []string{oauth.CodeChallengeMethodS256},
+	})
+}
+
+// authorize logs the user in straight away and redirects back with a code
+func (p *Provider) authorize(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if query.Get("client_id") != p.ClientID || query.Get("redirect_uri") != p.RedirectURL {
+		http.Error(w, "unknown client or redirect_uri", http.StatusBadRequest)
+		return
+	}
+
+	params := url.Values{"state": {query.Get("state")}}
+	switch {
+	case query.Get("response_type") != oauth.ResponseTypeCode:
+		params.Set("error", oauth.ErrorUnsupportedResponseType)
+	case query.Get("code_challenge_method") != oauth.CodeChallengeMethodS256 || !oauth.IsValidCodeChallenge(query.Get("code_challenge")):
+		params.Set("error", oauth.ErrorInvalidRequest)
+	default:
+		code, err := util.GenerateSecret(16)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		p.mu.Lock()
+		p.codes[code] = authorization{
+			user:          p.user,
+			redirectURI:   query.Get("redirect_uri"),
+			nonce:         query.Get("nonce"),
+			codeChallenge: query.Get("code_challenge"),
+		}
+		p.mu.Unlock()
+		params.Set("code", code)
+	}
+
+	http.Redirect(w, r, oauth.RedirectURL(p.RedirectURL, params), http.StatusFound)
+}
+
+func (p *Provider) token(w http.ResponseWriter, r *http.Request) {
+	clientID, clientSecret, ok := r.BasicAuth()
+	if ok {
+		clientID, _ = url.QueryUnescape(clientID)
+		clientSecret, _ = url.QueryUnescape(clientSecret)
+	} else {
+		clientID, clientSecret = r.PostFormValue("client_id"), r.PostFormValue("client_secret")
+	}
+	if clientID != p.ClientID || clientSecret != p.ClientSecret {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": oauth.ErrorInvalidClient})
+		return
+	}
+
+	if r.PostFormValue("grant_type") != oauth.GrantTypeAuthorizationCode {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": oauth.ErrorUnsupportedGrantType})
+		return
+	}
+
+	p.mu.Lock()
+	code := r.PostFormValue("code")
+	auth, ok := p.codes[code]
+	delete(p.codes, code)
+	p.mu.Unlock()
+
+	if !ok || auth.redirectURI != r.PostFormValue("redirect_uri") ||
+		!oauth.VerifyCodeChallenge(r.PostFormValue("code_verifier"), auth.codeChallenge) {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": oauth.ErrorInvalidGrant})
+		return
+	}
+
+	idToken, err := p.SignIDToken(p.Claims(auth.user, auth.nonce))
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+
+	accessToken, err := util.GenerateSecret(16)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token": accessToken,
+		"token_type":   oauth.TokenTypeBearer,
+		"expires_in":   int64(p.IDTokenDuration.Seconds()),
+		"id_token":     idToken,
+	})
+}
+
+func (p *Provider) jwks(w http.ResponseWriter, r *http.Request) {
+	p.mu.Lock()
+	key, kid := p.key.PublicKey, p.kid
+	p.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"keys": []map[string]string{
+			{
+				"kid": kid,
+				"kty": "RSA",
+				"use": "sig",
+				"alg": "RS256",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			},
+		},
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,358 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        v5.29.3
+// source: service_transfer.proto
+
+package pb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// CreateTransferRequest identifies each account either by id or by account number
+type CreateTransferRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Types that are valid to be assigned to From:
+	//
+	//	*CreateTransferRequest_FromAccountId
+	//	*CreateTransferRequest_FromAccountNumber
+	From isCreateTransferRequest_From `protobuf_oneof:"from"`
+	// Types that are valid to be assigned to To:
+	//
+	//	*CreateTransferRequest_ToAccountId
+	//	*CreateTransferRequest_ToAccountNumber
+	To       isCreateTransferRequest_To `protobuf_oneof:"to"`
+	Amount   int64                      `protobuf:"varint,5,opt,name=amount,proto3" json:"amount,omitempty"`
+	Currency string                     `protobuf:"bytes,6,opt,name=currency,proto3" json:"currency,omitempty"`
+	// totp_code is required for transfers of at least the step-up amount
+	TotpCode      string `protobuf:"bytes,7,opt,name=totp_code,json=totpCode,proto3" json:"totp_code,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreateTransferRequest) Reset() {
+	*x = CreateTransferRequest{}
+	mi := &file_service_transfer_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateTransferRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateTransferRequest) ProtoMessage() {}
+
+func (x *CreateTransferRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_service_transfer_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateTransferRequest.ProtoReflect.Descriptor instead.
+func (*CreateTransferRequest) Descriptor() ([]byte, []int) {
+	return file_service_transfer_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *CreateTransferRequest) GetFrom() isCreateTransferRequest_From {
+	if x != nil {
+		return x.From
+	}
+	return nil
+}
+
+func (x *CreateTransferRequest) GetFromAccountId() int64 {
+	if x != nil {
+		if x, ok := x.From.(*CreateTransferRequest_FromAccountId); ok {
+			return x.FromAccountId
+		}
+	}
+	return 0
+}
+
+func (x *CreateTransferRequest) GetFromAccountNumber() string {
+	if x != nil {
+		if x, ok := x.From.(*CreateTransferRequest_FromAccountNumber); ok {
+			return x.FromAccountNumber
+		}
+	}
+	return ""
+}
+
+func (x *CreateTransferRequest) GetTo() isCreateTransferRequest_To {
+	if x != nil {
+		return x.To
+	}
+	return nil
+}
+
+func (x *CreateTransferRequest) GetToAccountId() int64 {
+	if x != nil {
+		if x, ok := x.To.(*CreateTransferRequest_ToAccountId); ok {
+			return x.ToAccountId
+		}
+	}
+	return 0
+}
+
+func (x *CreateTransferRequest) GetToAccountNumber() string {
+	if x != nil {
+		if x, ok := x.To.(*CreateTransferRequest_ToAccountNumber); ok {
+			return x.ToAccountNumber
+		}
+	}
+	return ""
+}
+
+func (x *CreateTransferRequest) GetAmount() int64 {
+	if x != nil {
+		return x.Amount
+	}
+	return 0
+}
+
+func (x *CreateTransferRequest) GetCurrency() string {
+	if x != nil {
+		return x.Currency
+	}
+	return ""
+}
+
+func (x *CreateTransferRequest) GetTotpCode() string {
+	if x != nil {
+		return x.TotpCode
+	}
+	return ""
+}
+
+type isCreateTransferRequest_From interface {
+	isCreateTransferRequest_From()
+}
+
+type CreateTransferRequest_FromAccountId struct {
+	FromAccountId int64 `protobuf:"varint,1,opt,name=from_account_id,json=fromAccountId,proto3,oneof"`
+}
+
+type CreateTransferRequest_FromAccountNumber struct {
+	FromAccountNumber string `protobuf:"bytes,2,opt,name=from_account_number,json=fromAccountNumber,proto3,oneof"`
+}
+
+func (*CreateTransferRequest_FromAccountId) isCreateTransferRequest_From() {}
+
+func (*CreateTransferRequest_FromAccountNumber) isCreateTransferRequest_From() {}
+
+type isCreateTransferRequest_To interface {
+	isCreateTransferRequest_To()
+}
+
+type CreateTransferRequest_ToAccountId struct {
+	ToAccountId int64 `protobuf:"varint,3,opt,name=to_account_id,json=toAccountId,proto3,oneof"`
+}
+
+type CreateTransferRequest_ToAccountNumber struct {
+	ToAccountNumber string `protobuf:"bytes,4,opt,name=to_account_number,json=toAccountNumber,proto3,oneof"`
+}
+
+func (*CreateTransferRequest_ToAccountId) isCreateTransferRequest_To() {}
+
+func (*CreateTransferRequest_ToAccountNumber) isCreateTransferRequest_To() {}
+
+type CreateTransferResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Transfer      *Transfer              `protobuf:"bytes,1,opt,name=transfer,proto3" json:"transfer,omitempty"`
+	FromAccount   *Account               `protobuf:"bytes,2,opt,name=from_account,json=fromAccount,proto3" json:"from_account,omitempty"`
+	ToAccount     *Account               `protobuf:"bytes,3,opt,name=to_account,json=toAccount,proto3" json:"to_account,omitempty"`
+	FromEntry     *Entry                 `protobuf:"bytes,4,opt,name=from_entry,json=fromEntry,proto3" json:"from_entry,omitempty"`
+	ToEntry       *Entry                 `protobuf:"bytes,5,opt,name=to_entry,json=toEntry,proto3" json:"to_entry,omitempty"`
+	FeeEntry      *Entry                 `protobuf:"bytes,6,opt,name=fee_entry,json=feeEntry,proto3,oneof" json:"fee_entry,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreateTransferResponse) Reset() {
+	*x = CreateTransferResponse{}
+	mi := &file_service_transfer_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateTransferResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateTransferResponse) ProtoMessage() {}
+
+func (x *CreateTransferResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_service_transfer_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateTransferResponse.ProtoReflect.Descriptor instead.
+func (*CreateTransferResponse) Descriptor() ([]byte, []int) {
+	return file_service_transfer_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *CreateTransferResponse) GetTransfer() *Transfer {
+	if x != nil {
+		return x.Transfer
+	}
+	return nil
+}
+
+func (x *CreateTransferResponse) GetFromAccount() *Account {
+	if x != nil {
+		return x.FromAccount
+	}
+	return nil
+}
+
+func (x *CreateTransferResponse) GetToAccount() *Account {
+	if x != nil {
+		return x.ToAccount
+	}
+	return nil
+}
+
+func (x *CreateTransferResponse) GetFromEntry() *Entry {
+	if x != nil {
+		return x.FromEntry
+	}
+	return nil
+}
+
+func (x *CreateTransferResponse) GetToEntry() *Entry {
+	if x != nil {
+		return x.ToEntry
+	}
+	return nil
+}
+
+func (x *CreateTransferResponse) GetFeeEntry() *Entry {
+	if x != nil {
+		return x.FeeEntry
+	}
+	return nil
+}
+
+var File_service_transfer_proto protoreflect.FileDescriptor
+
+const file_service_transfer_proto_rawDesc = "" +
+	"\n" +
+	"\x16service_transfer.proto\x12\x02pb\x1a\raccount.proto\x1a\x0etransfer.proto\"\xa6\x02\n" +
+	"\x15CreateTransferRequest\x12(\n" +
+	"\x0ffrom_account_id\x18\x01 \x01(\x03H\x00R\rfromAccountId\x120\n" +
+	"\x13from_account_number\x18\x02 \x01(\tH\x00R\x11fromAccountNumber\x12$\n" +
+	"\rto_account_id\x18\x03 \x01(\x03H\x01R\vtoAccountId\x12,\n" +
+	"\x11to_account_number\x18\x04 \x01(\tH\x01R\x0ftoAccountNumber\x12\x16\n" +
+	"\x06amount\x18\x05 \x01(\x03R\x06amount\x12\x1a\n" +
+	"\bcurrency\x18\x06 \x01(\tR\bcurrency\x12\x1b\n" +
+	"\ttotp_code\x18\a \x01(\tR\btotpCodeB\x06\n" +
+	"\x04fromB\x04\n" +
+	"\x02to\"\xa9\x02\n" +
+	"\x16CreateTransferResponse\x12(\n" +
+	"\btransfer\x18\x01 \x01(\v2\f.pb.TransferR\btransfer\x12.\n" +
+	"\ffrom_account\x18\x02 \x01(\v2\v.pb.AccountR\vfromAccount\x12*\n" +
+	"\n" +
+	"to_account\x18\x03 \x01(\v2\v.pb.AccountR\ttoAccount\x12(\n" +
+	"\n" +
+	"from_entry\x18\x04 \x01(\v2\t.pb.EntryR\tfromEntry\x12$\n" +
+	"\bto_entry\x18\x05 \x01(\v2\t.pb.EntryR\atoEntry\x12+\n" +
+	"\tfee_entry\x18\x06 \x01(\v2\t.pb.EntryH\x00R\bfeeEntry\x88\x01\x01B\f\n" +
+	"\n" +
+	"_fee_entry2Z\n" +
+	"\x0fTransferService\x12G\n" +
+	"\x0eCreateTransfer\x12\x19.pb.CreateTransferRequest\x1a\x1a.pb.CreateTransferResponseB>Z<github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/pbb\x06proto3"
+
+var (
+	file_service_transfer_proto_rawDescOnce sync.Once
+	file_service_transfer_proto_rawDescData []byte
+)
+
+func file_service_transfer_proto_rawDescGZIP() []byte {
+	file_service_transfer_proto_rawDescOnce.Do(func() {
+		file_service_transfer_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_service_transfer_proto_rawDesc), len(file_service_transfer_proto_rawDesc)))
+	})
+	return file_service_transfer_proto_rawDescData
+}
+
+var file_service_transfer_proto_msgTypes = make([]protoimpl.MessageInfo, 2)
+var file_service_transfer_proto_goTypes = []any{
+	(*CreateTransferRequest)(nil),  // 0: pb.CreateTransferRequest
+	(*CreateTransferResponse)(nil), // 1: pb.CreateTransferResponse
+	(*Transfer)(nil),               // 2: pb.Transfer
+	(*Account)(nil),                // 3: pb.Account
+	(*Entry)(nil),                  // 4: pb.Entry
+}
+var file_service_transfer_proto_depIdxs = []int32{
+	2, // 0: pb.CreateTransferResponse.transfer:type_name -> pb.Transfer
+	3, // 1: pb.CreateTransferResponse.from_account:type_name -> pb.Account
+	3, // 2: pb.CreateTransferResponse.to_account:type_name -> pb.Account
+	4, // 3: pb.CreateTransferResponse.from_entry:type_name -> pb.Entry
+	4, // 4: pb.CreateTransferResponse.to_entry:type_name -> pb.Entry
+	4, // 5: pb.CreateTransferResponse.fee_entry:type_name -> pb.Entry
+	0, // 6: pb.TransferService.CreateTransfer:input_type -> pb.CreateTransferRequest
+	1, // 7: pb.TransferService.CreateTransfer:output_type -> pb.CreateTransferResponse
+	7, // [7:8] is the sub-list for method output_type
+	6, // [6:7] is the sub-list for method input_type
+	6, // [6:6] is the sub-list for extension type_name
+	6, // [6:6] is the sub-list for extension extendee
+	0, // [0:6] is the sub-list for field type_name
+}
+
+func init() { file_service_transfer_proto_init() }
+func file_service_transfer_proto_init() {
+	if File_service_transfer_proto != nil {
+		return
+	}
+	file_account_proto_init()
+	file_transfer_proto_init()
+	file_service_transfer_proto_msgTypes[0].OneofWrappers = []any{
+		(*CreateTransferRequest_FromAccountId)(nil),
+		(*CreateTransferRequest_FromAccountNumber)(nil),
+		(*CreateTransferRequest_ToAccountId)(nil),
+		(*CreateTransferRequest_ToAccountNumber)(nil),
+	}
+	file_service_transfer_proto_msgTypes[1].OneofWrappers = []any{}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_service_transfer_proto_rawDesc), len(file_service_transfer_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   2,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_service_transfer_proto_goTypes,
+		DependencyIndexes: file_service_transfer_proto_depIdxs,
+		MessageInfos:      file_service_transfer_proto_msgTypes,
+	}.Build()
+	File_service_transfer_proto = out.File
+	file_service_transfer_proto_goTypes = nil
+	file_service_transfer_proto_depIdxs = nil
+}
@@ -0,0 +1,69 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// pruneInterval is how often full buckets are dropped, as they are no
+// different from buckets that were never used
+const pruneInterval = time.Minute
+
+type bucket struct {
+	limit     Limit
+	tokens    float64
+	updatedAt time.Time
+}
+
+// MemoryStore keeps the buckets of a single instance. Clients spreading
+// their requests over several instances get the limit of each.
+type MemoryStore struct {
+	now func() time.Time
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	nextPrune time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		now:     time.Now,
+		buckets: make(map[string]*bucket),
+	}
+}
+
+func (store *MemoryStore) Take(_ context.Context, key string, limit Limit) (Result, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	now := store.now()
+	if now.After(store.nextPrune) {
+		store.prune(now)
+		store.nextPrune = now.Add(pruneInterval)
+	}
+
+	b, ok := store.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(limit.Burst)}
+		store.buckets[key] = b
+	} else {
+		b.tokens = limit.refill(b.tokens, now.Sub(b.updatedAt))
+	}
+	b.limit = limit
+	b.updatedAt = now
+
+	if b.tokens < 1 {
+		return newResult(limit, false, b.tokens), nil
+	}
+	b.tokens--
+	return newResult(limit, true, b.tokens), nil
+}
+
+func (store *MemoryStore) prune(now time.Time) {
+	for key, b := range store.buckets {
+		if b.limit.refill(b.tokens, now.Sub(b.updatedAt)) >= float64(b.limit.Burst) {
+			delete(store.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	db "github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/db/sqlc"
+	"github.com/jackc/pgx/v5"
+)
+
+// PostgresStore keeps the buckets in the database, so all instances share
+// them. Every request costs a query.
+type PostgresStore struct {
+	store db.Store
+	now   func() time.Time
+
+	mu sync.Mutex
+	// fillTime is the longest fill time of the limits taken from, as
+	// buckets idle for longer are full and can be dropped
+	fillTime  time.Duration
+	nextPrune time.Time
+}
+
+func NewPostgresStore(store db.Store) *PostgresStore {
+	// wait a while before the first prune, to learn the limits in use
+	return &PostgresStore{
+		store:     store,
+		now:       time.Now,
+		nextPrune: time.Now().Add(pruneInterval),
+	}
+}
+
+func (store *PostgresStore) Take(ctx context.Context, key string, limit Limit) (Result, error) {
+	store.prune(ctx, limit)
+
+	bucket, err := store.store.TakeRateLimitToken(ctx, db.TakeRateLimitTokenParams{
+		Key:   key,
+		Burst: float64(limit.Burst),
+		Rate:  limit.Rate,
+	})
+	if err == nil {
+		return newResult(limit, true, bucket.Tokens), nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return Result{}, err
+	}
+
+	// the bucket had no token, so it was left as it was
+	bucket, err = store.store.GetRateLimitBucket(ctx, key)
+	if err != nil {
+		return Result{}, err
+	}
+	tokens := limit.refill(bucket.Tokens, store.now().Sub(bucket.UpdatedAt))
+	return newResult(limit, false, tokens), nil
+}
+
+// prune drops the buckets that are full every pruneInterval. One instance
+// doing so is enough, but each does as it cannot know about the others.
+func (store *PostgresStore) prune(ctx context.Context, limit Limit) {
+	store.mu.Lock()
+	store.fillTime = max(store.fillTime, limit.fillTime())
+	now := store.now()
+	if now.Before(store.nextPrune) {
+		store.mu.Unlock()
+		return
+	}
+	store.nextPrune = now.Add(pruneInterval)
+	fillTime := store.fillTime
+	store.mu.Unlock()
+
+	if _, err := store.store.DeleteRateLimitBuckets(ctx, now.Add(-fillTime)); err != nil {
+		slog.WarnContext(ctx, "cannot prune rate limit buckets", "error", err)
+	}
+}
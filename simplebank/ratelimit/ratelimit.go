@@ -0,0 +1,131 @@
+// Package ratelimit limits how often a client may do something with token
+// buckets: a bucket holds up to Burst tokens, refills at Rate tokens per
+// second and every request takes one token. Buckets are kept in memory, for
+// a single instance, or in Postgres, shared by all instances.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	db "github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/db/sqlc"
+)
+
+// Backends keeping the token buckets
+const (
+	BackendMemory   = "memory"
+	BackendPostgres = "postgres"
+	BackendNone     = "none"
+)
+
+// Limit allows Burst requests at once and Rate requests per second after
+// that. The zero Limit allows everything.
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// ParseLimit parses a limit written as <count>/<period>, e.g. 10/m for ten
+// requests a minute. The period is s, m, h or a duration like 30s, and the
+// whole count may be used at once. An empty string is the zero Limit.
+func ParseLimit(s string) (Limit, error) {
+	if s == "" {
+		return Limit{}, nil
+	}
+
+	countText, periodText, ok := strings.Cut(s, "/")
+	if !ok {
+		return Limit{}, fmt.Errorf("invalid rate limit %q: want <count>/<period>", s)
+	}
+
+	count, err := strconv.Atoi(countText)
+	if err != nil || count < 1 {
+		return Limit{}, fmt.Errorf("invalid rate limit %q: count must be a positive integer", s)
+	}
+
+	var period time.Duration
+	switch periodText {
+	case "s":
+		period = time.Second
+	case "m":
+		period = time.Minute
+	case "h":
+		period = time.Hour
+	default:
+		period, err = time.ParseDuration(periodText)
+		if err != nil || period <= 0 {
+			return Limit{}, fmt.Errorf("invalid rate limit %q: period must be s, m, h or a positive duration", s)
+		}
+	}
+
+	return Limit{Rate: float64(count) / period.Seconds(), Burst: count}, nil
+}
+
+func (limit Limit) IsZero() bool {
+	return limit == Limit{}
+}
+
+// fillTime is how long an empty bucket takes to fill up
+func (limit Limit) fillTime() time.Duration {
+	return seconds(float64(limit.Burst) / limit.Rate)
+}
+
+// refill returns the tokens of a bucket that had tokens elapsed ago
+func (limit Limit) refill(tokens float64, elapsed time.Duration) float64 {
+	return math.Min(float64(limit.Burst), tokens+limit.Rate*max(elapsed, 0).Seconds())
+}
+
+// Result tells whether a request may go ahead and how the bucket stands
+// after it
+type Result struct {
+	Allowed bool
+	Limit   Limit
+	// Remaining is the number of requests allowed right away
+	Remaining int
+	// RetryAfter is how long a denied request has to wait for a token
+	RetryAfter time.Duration
+	// Reset is how long the bucket takes to fill up again
+	Reset time.Duration
+}
+
+func newResult(limit Limit, allowed bool, tokens float64) Result {
+	result := Result{
+		Allowed:   allowed,
+		Limit:     limit,
+		Remaining: int(math.Floor(tokens)),
+		Reset:     seconds((float64(limit.Burst) - tokens) / limit.Rate),
+	}
+	if !allowed {
+		result.RetryAfter = seconds((1 - tokens) / limit.Rate)
+	}
+	return result
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(math.Ceil(max(s, 0) * float64(time.Second)))
+}
+
+// Store keeps the token buckets, one for each key
+type Store interface {
+	// Take takes a token from the bucket of key, unless it has none left
+	Take(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+// NewStore returns the store of the named backend. It returns nil for none,
+// when nothing is rate limited.
+func NewStore(backend string, store db.Store) (Store, error) {
+	switch backend {
+	case BackendMemory:
+		return NewMemoryStore(), nil
+	case BackendPostgres:
+		return NewPostgresStore(store), nil
+	case BackendNone, "":
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("unknown rate limit backend: %q", backend)
+	}
+}
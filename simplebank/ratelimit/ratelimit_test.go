@@ -0,0 +1,216 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	mockdb "github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/db/mock"
+	db "github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/db/sqlc"
+	"github.com/golang/mock/gomock"
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLimit(t *testing.T) {
+	testCases := []struct {
+		text  string
+		limit Limit
+		ok    bool
+	}{
+		{text: "", limit: Limit{}, ok: true},
+		{text: "10/s", limit: Limit{Rate: 10, Burst: 10}, ok: true},
+		{text: "60/m", limit: Limit{Rate: 1, Burst: 60}, ok: true},
+		{text: "36/h", limit: Limit{Rate: 0.01, Burst: 36}, ok: true},
+		{text: "5/500ms", limit: Limit{Rate: 10, Burst: 5}, ok: true},
+		{text: "10"},
+		{text: "0/m"},
+		{text: "-1/m"},
+		{text: "ten/m"},
+		{text: "10/week"},
+		{text: "10/-1s"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.text, func(t *testing.T) {
+			limit, err := ParseLimit(tc.text)
+			if !tc.ok {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.limit.Burst, limit.Burst)
+			require.InDelta(t, tc.limit.Rate, limit.Rate, 1e-9)
+		})
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	now := time.Now()
+	store := NewMemoryStore()
+	store.now = func() time.Time { return now }
+
+	limit := Limit{Rate: 1, Burst: 3}
+	ctx := context.Background()
+
+	for i := 2; i >= 0; i-- {
+		result, err := store.Take(ctx, "a", limit)
+		require.NoError(t, err)
+		require.True(t, result.Allowed)
+		require.Equal(t, i, result.Remaining)
+		require.Equal(t, time.Duration(3-i)*time.Second, result.Reset)
+	}
+
+	result, err := store.Take(ctx, "a", limit)
+	require.NoError(t, err)
+	require.False(t, result.Allowed)
+	require.Equal(t, 0, result.Remaining)
+	require.Equal(t, time.Second, result.RetryAfter)
+
+	// other keys have their own bucket
+	result, err = store.Take(ctx, "b", limit)
+	require.NoError(t, err)
+	require.True(t, result.Allowed)
+
+	// half a token is not enough
+	now = now.Add(500 * time.Millisecond)
+	result, err = store.Take(ctx, "a", limit)
+	require.NoError(t, err)
+	require.False(t, result.Allowed)
+	require.Equal(t, 500*time.Millisecond, result.RetryAfter)
+
+	now = now.Add(500 * time.Millisecond)
+	result, err = store.Take(ctx, "a", limit)
+	require.NoError(t, err)
+	require.True(t, result.Allowed)
+	require.Equal(t, 0, result.Remaining)
+}
+
+func TestMemoryStorePrune(t *testing.T) {
+	now := time.Now()
+	store := NewMemoryStore()
+	store.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	_, err := store.Take(ctx, "short", Limit{Rate: 1, Burst: 1})
+	require.NoError(t, err)
+	_, err = store.Take(ctx, "long", Limit{Rate: 1.0 / 3600, Burst: 1})
+	require.NoError(t, err)
+
+	now = now.Add(2 * pruneInterval)
+	_, err = store.Take(ctx, "other", Limit{Rate: 1, Burst: 1})
+	require.NoError(t, err)
+
+	require.NotContains(t, store.buckets, "short")
+	require.Contains(t, store.buckets, "long")
+	require.Contains(t, store.buckets, "other")
+}
+
+func TestPostgresStore(t *testing.T) {
+	limit := Limit{Rate: 2, Burst: 10}
+	key := "login:ip:192.0.2.1"
+	arg := db.TakeRateLimitTokenParams{Key: key, Burst: 10, Rate: 2}
+	now := time.Now()
+
+	testCases := []struct {
+		name       string
+		buildStubs func(store *mockdb.MockStore)
+		check      func(t *testing.T, result Result, err error)
+	}{
+		{
+			name: "Allowed",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					TakeRateLimitToken(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(db.RateLimitBucket{Key: key, Tokens: 4.5, UpdatedAt: now}, nil)
+				store.EXPECT().GetRateLimitBucket(gomock.Any(), gomock.Any()).Times(0)
+			},
+			check: func(t *testing.T, result Result, err error) {
+				require.NoError(t, err)
+				require.True(t, result.Allowed)
+				require.Equal(t, 4, result.Remaining)
+				require.Equal(t, 2750*time.Millisecond, result.Reset)
+				require.Zero(t, result.RetryAfter)
+			},
+		},
+		{
+			name: "Denied",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					TakeRateLimitToken(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(db.RateLimitBucket{}, pgx.ErrNoRows)
+				store.EXPECT().
+					GetRateLimitBucket(gomock.Any(), gomock.Eq(key)).
+					Times(1).
+					Return(db.RateLimitBucket{Key: key, Tokens: 0.2, UpdatedAt: now.Add(-100 * time.Millisecond)}, nil)
+			},
+			check: func(t *testing.T, result Result, err error) {
+				require.NoError(t, err)
+				require.False(t, result.Allowed)
+				require.Equal(t, 0, result.Remaining)
+				require.Equal(t, 300*time.Millisecond, result.RetryAfter)
+			},
+		},
+		{
+			name: "InternalError",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					TakeRateLimitToken(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.RateLimitBucket{}, errors.New("connection refused"))
+			},
+			check: func(t *testing.T, _ Result, err error) {
+				require.Error(t, err)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			rateLimits := NewPostgresStore(store)
+			rateLimits.now = func() time.Time { return now }
+
+			result, err := rateLimits.Take(context.Background(), key, limit)
+			tc.check(t, result, err)
+		})
+	}
+}
+
+func TestPostgresStorePrune(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	now := time.Now()
+	store := mockdb.NewMockStore(ctrl)
+	rateLimits := NewPostgresStore(store)
+	rateLimits.now = func() time.Time { return now }
+
+	store.EXPECT().TakeRateLimitToken(gomock.Any(), gomock.Any()).AnyTimes().Return(db.RateLimitBucket{Tokens: 1}, nil)
+
+	// nothing is pruned until the limits in use are known
+	store.EXPECT().DeleteRateLimitBuckets(gomock.Any(), gomock.Any()).Times(0)
+	_, err := rateLimits.Take(context.Background(), "a", Limit{Rate: 1, Burst: 60})
+	require.NoError(t, err)
+	_, err = rateLimits.Take(context.Background(), "b", Limit{Rate: 1, Burst: 10})
+	require.NoError(t, err)
+
+	// buckets are full once idle for the longest fill time
+	now = now.Add(2 * pruneInterval)
+	store.EXPECT().
+		DeleteRateLimitBuckets(gomock.Any(), gomock.Eq(now.Add(-time.Minute))).
+		Times(1).
+		Return(int64(2), nil)
+	for range 3 {
+		_, err = rateLimits.Take(context.Background(), "a", Limit{Rate: 1, Burst: 60})
+		require.NoError(t, err)
+	}
+}
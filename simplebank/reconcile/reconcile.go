@@ -0,0 +1,127 @@
+package reconcile
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	db "github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/db/sqlc"
+)
+
+// Kinds of discrepancies a reconciliation can report
+const (
+	KindBalanceMismatch      = "balance_mismatch"
+	KindUnbalancedTransfer   = "unbalanced_transfer"
+	KindUnbalancedJournal    = "unbalanced_journal"
+	KindCurrencyNotConserved = "currency_not_conserved"
+)
+
+// Discrepancy is a single broken ledger invariant. Expected and Actual hold
+// the amounts or entry counts that were compared.
+type Discrepancy struct {
+	Kind       string `json:"kind"`
+	AccountID  int64  `json:"account_id,omitempty"`
+	TransferID int64  `json:"transfer_id,omitempty"`
+	JournalID  int64  `json:"journal_id,omitempty"`
+	Currency   string `json:"currency,omitempty"`
+	Expected   int64  `json:"expected"`
+	Actual     int64  `json:"actual"`
+	Detail     string `json:"detail"`
+}
+
+// CurrencyTotal sums up the money held in one currency
+type CurrencyTotal struct {
+	Currency             string `json:"currency"`
+	BalanceTotal         int64  `json:"balance_total"`
+	EntriesTotal         int64  `json:"entries_total"`
+	TransferEntriesTotal int64  `json:"transfer_entries_total"`
+}
+
+// Report is the machine-readable outcome of a reconciliation
+type Report struct {
+	GeneratedAt   time.Time       `json:"generated_at"`
+	OK            bool            `json:"ok"`
+	Discrepancies []Discrepancy   `json:"discrepancies"`
+	Currencies    []CurrencyTotal `json:"currencies"`
+}
+
+// Run checks that every account balance equals the sum of its entries, that
+// every transfer has exactly one matching debit and credit entry, that every
+// journal balances, and that transfers neither create nor destroy money in
+// any currency.
+func Run(ctx context.Context, store db.Store) (*Report, error) {
+	result, err := store.ReconciliationTx(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("cannot reconcile ledger: %w", err)
+	}
+
+	return NewReport(result), nil
+}
+
+// NewReport turns the raw results of the reconciliation queries into a report
+func NewReport(result db.ReconciliationTxResult) *Report {
+	report := &Report{
+		GeneratedAt:   time.Now().UTC(),
+		Discrepancies: []Discrepancy{},
+		Currencies:    []CurrencyTotal{},
+	}
+
+	for _, row := range result.BalanceMismatches {
+		report.Discrepancies = append(report.Discrepancies, Discrepancy{
+			Kind:      KindBalanceMismatch,
+			AccountID: row.AccountID,
+			Currency:  row.Currency,
+			Expected:  row.EntriesTotal,
+			Actual:    row.Balance,
+			Detail:    fmt.Sprintf("account balance differs from the sum of its entries by %d", row.Balance-row.EntriesTotal),
+		})
+	}
+
+	for _, row := range result.UnbalancedTransfers {
+		report.Discrepancies = append(report.Discrepancies, Discrepancy{
+			Kind:       KindUnbalancedTransfer,
+			TransferID: row.TransferID,
+			Expected:   2,
+			Actual:     row.TotalEntries,
+			Detail: fmt.Sprintf("expected one debit of account %d and one credit of account %d for %d, found %d debit(s), %d credit(s) and %d entries in total",
+				row.FromAccountID, row.ToAccountID, row.Amount, row.DebitEntries, row.CreditEntries, row.TotalEntries),
+		})
+	}
+
+	for _, row := range result.UnbalancedJournals {
+		report.Discrepancies = append(report.Discrepancies, Discrepancy{
+			Kind:      KindUnbalancedJournal,
+			JournalID: row.JournalID,
+			Currency:  row.Currency,
+			Expected:  0,
+			Actual:    row.Total,
+			Detail:    fmt.Sprintf("journal legs in %s do not sum to zero", row.Currency),
+		})
+	}
+
+	for _, row := range result.CurrencyTotals {
+		report.Currencies = append(report.Currencies, CurrencyTotal(row))
+
+		if row.TransferEntriesTotal != 0 {
+			report.Discrepancies = append(report.Discrepancies, Discrepancy{
+				Kind:     KindCurrencyNotConserved,
+				Currency: row.Currency,
+				Expected: 0,
+				Actual:   row.TransferEntriesTotal,
+				Detail:   fmt.Sprintf("transfer entries in %s do not sum to zero", row.Currency),
+			})
+		}
+		if row.BalanceTotal != row.EntriesTotal {
+			report.Discrepancies = append(report.Discrepancies, Discrepancy{
+				Kind:     KindCurrencyNotConserved,
+				Currency: row.Currency,
+				Expected: row.EntriesTotal,
+				Actual:   row.BalanceTotal,
+				Detail:   fmt.Sprintf("total balance in %s differs from the sum of its entries", row.Currency),
+			})
+		}
+	}
+
+	report.OK = len(report.Discrepancies) == 0
+	return report
+}
@@ -0,0 +1,102 @@
+package reconcile
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	mockdb "github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/db/mock"
+	db "github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/db/sqlc"
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/util"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunOK(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		ReconciliationTx(gomock.Any()).
+		Times(1).
+		Return(db.ReconciliationTxResult{
+			CurrencyTotals: []db.ListCurrencyTotalsRow{
+				{Currency: util.USD, BalanceTotal: 300, EntriesTotal: 300},
+			},
+		}, nil)
+
+	report, err := Run(context.Background(), store)
+	require.NoError(t, err)
+	require.True(t, report.OK)
+	require.Empty(t, report.Discrepancies)
+	require.Len(t, report.Currencies, 1)
+	require.Equal(t, int64(300), report.Currencies[0].BalanceTotal)
+}
+
+func TestRunDiscrepancies(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		ReconciliationTx(gomock.Any()).
+		Times(1).
+		Return(db.ReconciliationTxResult{
+			BalanceMismatches: []db.ListAccountBalanceMismatchesRow{
+				{AccountID: 1, Currency: util.USD, Balance: 150, EntriesTotal: 100},
+			},
+			UnbalancedTransfers: []db.ListUnbalancedTransfersRow{
+				{TransferID: 7, FromAccountID: 1, ToAccountID: 2, Amount: 10, DebitEntries: 1, TotalEntries: 1},
+			},
+			UnbalancedJournals: []db.ListUnbalancedJournalsRow{
+				{JournalID: 9, Currency: util.EUR, Total: 5},
+			},
+			CurrencyTotals: []db.ListCurrencyTotalsRow{
+				{Currency: util.USD, BalanceTotal: 150, EntriesTotal: 90, TransferEntriesTotal: -10},
+				{Currency: util.EUR, BalanceTotal: 50, EntriesTotal: 50},
+			},
+		}, nil)
+
+	report, err := Run(context.Background(), store)
+	require.NoError(t, err)
+	require.False(t, report.OK)
+	require.Len(t, report.Discrepancies, 5)
+	require.Len(t, report.Currencies, 2)
+
+	require.Equal(t, KindBalanceMismatch, report.Discrepancies[0].Kind)
+	require.Equal(t, int64(1), report.Discrepancies[0].AccountID)
+	require.Equal(t, int64(100), report.Discrepancies[0].Expected)
+	require.Equal(t, int64(150), report.Discrepancies[0].Actual)
+
+	require.Equal(t, KindUnbalancedTransfer, report.Discrepancies[1].Kind)
+	require.Equal(t, int64(7), report.Discrepancies[1].TransferID)
+	require.Equal(t, int64(1), report.Discrepancies[1].Actual)
+
+	require.Equal(t, KindUnbalancedJournal, report.Discrepancies[2].Kind)
+	require.Equal(t, int64(9), report.Discrepancies[2].JournalID)
+	require.Equal(t, int64(5), report.Discrepancies[2].Actual)
+
+	require.Equal(t, KindCurrencyNotConserved, report.Discrepancies[3].Kind)
+	require.Equal(t, util.USD, report.Discrepancies[3].Currency)
+	require.Equal(t, int64(-10), report.Discrepancies[3].Actual)
+
+	require.Equal(t, KindCurrencyNotConserved, report.Discrepancies[4].Kind)
+	require.Equal(t, int64(90), report.Discrepancies[4].Expected)
+	require.Equal(t, int64(150), report.Discrepancies[4].Actual)
+}
+
+func TestRunError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		ReconciliationTx(gomock.Any()).
+		Times(1).
+		Return(db.ReconciliationTxResult{}, errors.New("connection refused"))
+
+	report, err := Run(context.Background(), store)
+	require.Error(t, err)
+	require.Nil(t, report)
+}
@@ -0,0 +1,37 @@
+package statement
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/util"
+)
+
+const dateLayout = "2006-01-02"
+
+// WriteCSV renders the statement as CSV with an opening and a closing balance row
+func (s *Statement) WriteCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+
+	records := [][]string{
+		{"date", "entry_id", "description", "amount", "balance"},
+		{s.From.Format(dateLayout), "", "Opening balance", "", util.FormatAmount(s.OpeningBalance)},
+	}
+
+	for _, line := range s.Lines {
+		records = append(records, []string{
+			line.Date.Format(dateLayout),
+			strconv.FormatInt(line.EntryID, 10),
+			line.Description,
+			util.FormatAmount(line.Amount),
+			util.FormatAmount(line.Balance),
+		})
+	}
+
+	records = append(records, []string{
+		s.To.AddDate(0, 0, -1).Format(dateLayout), "", "Closing balance", "", util.FormatAmount(s.ClosingBalance),
+	})
+
+	return writer.WriteAll(records)
+}
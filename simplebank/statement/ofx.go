@@ -0,0 +1,127 @@
+package statement
+
+import (
+	"encoding/xml"
+	"io"
+	"strconv"
+
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/util"
+)
+
+const (
+	ofxHeader     = `<?xml version="1.0" encoding="UTF-8" standalone="no"?>` + "\n" + `<?OFX OFXHEADER="200" VERSION="220" SECURITY="NONE" OLDFILEUID="NONE" NEWFILEUID="NONE"?>` + "\n"
+	ofxDateLayout = "20060102150405"
+	ofxBankID     = "SIMPLEBANK"
+)
+
+type ofxDocument struct {
+	XMLName xml.Name             `xml:"OFX"`
+	SignOn  ofxSignOn            `xml:"SIGNONMSGSRSV1>SONRS"`
+	Bank    ofxStatementResponse `xml:"BANKMSGSRSV1>STMTTRNRS"`
+}
+
+type ofxStatus struct {
+	Code     int    `xml:"CODE"`
+	Severity string `xml:"SEVERITY"`
+}
+
+type ofxSignOn struct {
+	Status   ofxStatus `xml:"STATUS"`
+	DTServer string    `xml:"DTSERVER"`
+	Language string    `xml:"LANGUAGE"`
+}
+
+type ofxStatementResponse struct {
+	TrnUID    string       `xml:"TRNUID"`
+	Status    ofxStatus    `xml:"STATUS"`
+	Statement ofxStatement `xml:"STMTRS"`
+}
+
+type ofxStatement struct {
+	Currency    string         `xml:"CURDEF"`
+	Account     ofxBankAccount `xml:"BANKACCTFROM"`
+	Transaction ofxTranList    `xml:"BANKTRANLIST"`
+	LedgerBal   ofxBalance     `xml:"LEDGERBAL"`
+}
+
+type ofxBankAccount struct {
+	BankID   string `xml:"BANKID"`
+	AcctID   string `xml:"ACCTID"`
+	AcctType string `xml:"ACCTTYPE"`
+}
+
+type ofxTranList struct {
+	DTStart      string           `xml:"DTSTART"`
+	DTEnd        string           `xml:"DTEND"`
+	Transactions []ofxTransaction `xml:"STMTTRN"`
+}
+
+type ofxTransaction struct {
+	TrnType  string `xml:"TRNTYPE"`
+	DTPosted string `xml:"DTPOSTED"`
+	TrnAmt   string `xml:"TRNAMT"`
+	FitID    string `xml:"FITID"`
+	Name     string `xml:"NAME"`
+}
+
+type ofxBalance struct {
+	BalAmt string `xml:"BALAMT"`
+	DTAsOf string `xml:"DTASOF"`
+}
+
+// WriteOFX renders the statement as an OFX 2.2 bank statement response
+func (s *Statement) WriteOFX(w io.Writer) error {
+	transactions := make([]ofxTransaction, 0, len(s.Lines))
+	for _, line := range s.Lines {
+		trnType := "CREDIT"
+		if line.Amount < 0 {
+			trnType = "DEBIT"
+		}
+
+		transactions = append(transactions, ofxTransaction{
+			TrnType:  trnType,
+			DTPosted: line.Date.UTC().Format(ofxDateLayout),
+			TrnAmt:   util.FormatAmount(line.Amount),
+			FitID:    strconv.FormatInt(line.EntryID, 10),
+			Name:     line.Description,
+		})
+	}
+
+	ok := ofxStatus{Code: 0, Severity: "INFO"}
+	document := ofxDocument{
+		SignOn: ofxSignOn{
+			Status:   ok,
+			DTServer: s.GeneratedAt.UTC().Format(ofxDateLayout),
+			Language: "ENG",
+		},
+		Bank: ofxStatementResponse{
+			TrnUID: "0",
+			Status: ok,
+			Statement: ofxStatement{
+				Currency: s.Account.Currency,
+				Account: ofxBankAccount{
+					BankID:   ofxBankID,
+					AcctID:   s.Account.AccountNumber,
+					AcctType: "CHECKING",
+				},
+				Transaction: ofxTranList{
+					DTStart:      s.From.UTC().Format(ofxDateLayout),
+					DTEnd:        s.To.UTC().Format(ofxDateLayout),
+					Transactions: transactions,
+				},
+				LedgerBal: ofxBalance{
+					BalAmt: util.FormatAmount(s.ClosingBalance),
+					DTAsOf: s.To.UTC().Format(ofxDateLayout),
+				},
+			},
+		},
+	}
+
+	if _, err := io.WriteString(w, ofxHeader); err != nil {
+		return err
+	}
+
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	return encoder.Encode(document)
+}
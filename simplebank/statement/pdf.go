@@ -0,0 +1,70 @@
+package statement
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/util"
+	"github.com/go-pdf/fpdf"
+)
+
+var pdfColumns = []struct {
+	title string
+	width float64
+	align string
+}{
+	{"Date", 35, "L"},
+	{"Entry", 25, "L"},
+	{"Description", 60, "L"},
+	{"Amount", 35, "R"},
+	{"Balance", 35, "R"},
+}
+
+// WritePDF renders the statement as an A4 PDF document
+func (s *Statement) WritePDF(w io.Writer) error {
+	pdf := fpdf.New("P", "mm", "A4", "")
+	pdf.SetTitle(fmt.Sprintf("Statement %s", s.Account.AccountNumber), false)
+	pdf.SetCreationDate(s.GeneratedAt)
+	pdf.AddPage()
+
+	pdf.SetFont("Helvetica", "B", 16)
+	pdf.CellFormat(0, 10, "Simple Bank account statement", "", 1, "L", false, 0, "")
+
+	pdf.SetFont("Helvetica", "", 10)
+	details := []string{
+		fmt.Sprintf("Account: %s (%s)", s.Account.AccountNumber, s.Account.Currency),
+		fmt.Sprintf("Owner: %s", s.Account.Owner),
+		fmt.Sprintf("Period: %s to %s", s.From.Format(dateLayout), s.To.AddDate(0, 0, -1).Format(dateLayout)),
+		fmt.Sprintf("Opening balance: %s", util.FormatAmount(s.OpeningBalance)),
+		fmt.Sprintf("Closing balance: %s", util.FormatAmount(s.ClosingBalance)),
+		fmt.Sprintf("Total credits: %s  Total debits: %s", util.FormatAmount(s.TotalCredits), util.FormatAmount(s.TotalDebits)),
+	}
+	for _, detail := range details {
+		pdf.CellFormat(0, 6, detail, "", 1, "L", false, 0, "")
+	}
+	pdf.Ln(4)
+
+	pdf.SetFont("Helvetica", "B", 10)
+	for _, column := range pdfColumns {
+		pdf.CellFormat(column.width, 7, column.title, "B", 0, column.align, false, 0, "")
+	}
+	pdf.Ln(-1)
+
+	pdf.SetFont("Helvetica", "", 10)
+	for _, line := range s.Lines {
+		values := []string{
+			line.Date.Format(dateLayout),
+			strconv.FormatInt(line.EntryID, 10),
+			line.Description,
+			util.FormatAmount(line.Amount),
+			util.FormatAmount(line.Balance),
+		}
+		for i, column := range pdfColumns {
+			pdf.CellFormat(column.width, 6, values[i], "", 0, column.align, false, 0, "")
+		}
+		pdf.Ln(-1)
+	}
+
+	return pdf.Output(w)
+}
@@ -0,0 +1,85 @@
+package statement
+
+import (
+	"time"
+
+	db "github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/db/sqlc"
+)
+
+// Supported statement formats
+const (
+	FormatCSV = "csv"
+	FormatOFX = "ofx"
+	FormatPDF = "pdf"
+)
+
+// Month returns the calendar month (UTC) containing t as the period [from, to)
+func Month(t time.Time) (from, to time.Time) {
+	t = t.UTC()
+	from = time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	return from, from.AddDate(0, 1, 0)
+}
+
+// Line is a single entry of a statement together with the balance after it
+type Line struct {
+	EntryID     int64     `json:"entry_id"`
+	Date        time.Time `json:"date"`
+	Description string    `json:"description"`
+	Amount      int64     `json:"amount"`
+	Balance     int64     `json:"balance"`
+}
+
+// Statement lists the entries of an account over the period [From, To)
+type Statement struct {
+	Account        db.Account `json:"account"`
+	From           time.Time  `json:"from"`
+	To             time.Time  `json:"to"`
+	OpeningBalance int64      `json:"opening_balance"`
+	ClosingBalance int64      `json:"closing_balance"`
+	TotalCredits   int64      `json:"total_credits"`
+	TotalDebits    int64      `json:"total_debits"`
+	Lines          []Line     `json:"lines"`
+	GeneratedAt    time.Time  `json:"generated_at"`
+}
+
+// New builds a statement from the balance at the start of the period and the
+// entries posted during it, which must be ordered by creation time.
+func New(account db.Account, from, to time.Time, openingBalance int64, entries []db.Entry) *Statement {
+	statement := &Statement{
+		Account:        account,
+		From:           from,
+		To:             to,
+		OpeningBalance: openingBalance,
+		Lines:          make([]Line, 0, len(entries)),
+		GeneratedAt:    time.Now(),
+	}
+
+	balance := openingBalance
+	for _, entry := range entries {
+		balance += entry.Amount
+
+		if entry.Amount < 0 {
+			statement.TotalDebits -= entry.Amount
+		} else {
+			statement.TotalCredits += entry.Amount
+		}
+
+		statement.Lines = append(statement.Lines, Line{
+			EntryID:     entry.ID,
+			Date:        entry.CreatedAt,
+			Description: describe(entry),
+			Amount:      entry.Amount,
+			Balance:     balance,
+		})
+	}
+	statement.ClosingBalance = balance
+
+	return statement
+}
+
+func describe(entry db.Entry) string {
+	if entry.Amount < 0 {
+		return "Debit"
+	}
+	return "Credit"
+}
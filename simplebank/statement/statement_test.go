@@ -0,0 +1,124 @@
+package statement
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"testing"
+	"time"
+
+	db "github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/db/sqlc"
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func randomStatement(t *testing.T, n int) *Statement {
+	account := db.Account{
+		ID:            util.RandomInt(1, 1000),
+		Owner:         util.RandomOwner(),
+		Currency:      util.RandomCurrency(),
+		AccountNumber: util.RandomAccountNumber(),
+	}
+
+	from := time.Date(2026, time.March, 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 1, 0)
+
+	entries := make([]db.Entry, n)
+	for i := range entries {
+		entries[i] = db.Entry{
+			ID:        int64(i + 1),
+			AccountID: account.ID,
+			Amount:    util.RandomAmount(),
+			CreatedAt: from.Add(time.Duration(i) * time.Hour),
+		}
+	}
+
+	return New(account, from, to, util.RandomMoney(), entries)
+}
+
+func TestNew(t *testing.T) {
+	stmt := randomStatement(t, 10)
+	require.Len(t, stmt.Lines, 10)
+
+	balance := stmt.OpeningBalance
+	var credits, debits int64
+	for _, line := range stmt.Lines {
+		balance += line.Amount
+		require.Equal(t, balance, line.Balance)
+
+		if line.Amount < 0 {
+			debits -= line.Amount
+			require.Equal(t, "Debit", line.Description)
+		} else {
+			credits += line.Amount
+			require.Equal(t, "Credit", line.Description)
+		}
+	}
+
+	require.Equal(t, balance, stmt.ClosingBalance)
+	require.Equal(t, credits, stmt.TotalCredits)
+	require.Equal(t, debits, stmt.TotalDebits)
+	require.Equal(t, stmt.OpeningBalance+credits-debits, stmt.ClosingBalance)
+}
+
+func TestNewWithoutEntries(t *testing.T) {
+	stmt := randomStatement(t, 0)
+	require.Empty(t, stmt.Lines)
+	require.Equal(t, stmt.OpeningBalance, stmt.ClosingBalance)
+}
+
+func TestMonth(t *testing.T) {
+	from, to := Month(time.Date(2026, time.March, 31, 23, 30, 0, 0, time.FixedZone("WAT", 3600)))
+	require.Equal(t, time.Date(2026, time.March, 1, 0, 0, 0, 0, time.UTC), from)
+	require.Equal(t, time.Date(2026, time.April, 1, 0, 0, 0, 0, time.UTC), to)
+}
+
+func TestWriteCSV(t *testing.T) {
+	stmt := randomStatement(t, 5)
+
+	var buf bytes.Buffer
+	require.NoError(t, stmt.WriteCSV(&buf))
+
+	records, err := csv.NewReader(&buf).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, len(stmt.Lines)+3)
+
+	require.Equal(t, []string{"date", "entry_id", "description", "amount", "balance"}, records[0])
+	require.Equal(t, "Opening balance", records[1][2])
+	require.Equal(t, util.FormatAmount(stmt.OpeningBalance), records[1][4])
+
+	last := records[len(records)-1]
+	require.Equal(t, "2026-03-31", last[0])
+	require.Equal(t, "Closing balance", last[2])
+	require.Equal(t, util.FormatAmount(stmt.ClosingBalance), last[4])
+}
+
+func TestWriteOFX(t *testing.T) {
+	stmt := randomStatement(t, 5)
+
+	var buf bytes.Buffer
+	require.NoError(t, stmt.WriteOFX(&buf))
+	require.Contains(t, buf.String(), `OFXHEADER="200"`)
+
+	var document ofxDocument
+	require.NoError(t, xml.Unmarshal(buf.Bytes(), &document))
+
+	statementResponse := document.Bank.Statement
+	require.Equal(t, stmt.Account.Currency, statementResponse.Currency)
+	require.Equal(t, stmt.Account.AccountNumber, statementResponse.Account.AcctID)
+	require.Equal(t, util.FormatAmount(stmt.ClosingBalance), statementResponse.LedgerBal.BalAmt)
+	require.Equal(t, "20260301000000", statementResponse.Transaction.DTStart)
+	require.Len(t, statementResponse.Transaction.Transactions, len(stmt.Lines))
+
+	for i, transaction := range statementResponse.Transaction.Transactions {
+		require.Equal(t, util.FormatAmount(stmt.Lines[i].Amount), transaction.TrnAmt)
+	}
+}
+
+func TestWritePDF(t *testing.T) {
+	stmt := randomStatement(t, 5)
+
+	var buf bytes.Buffer
+	require.NoError(t, stmt.WritePDF(&buf))
+	require.True(t, bytes.HasPrefix(buf.Bytes(), []byte("%PDF-")))
+}
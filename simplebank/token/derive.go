@@ -0,0 +1,24 @@
+package token
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+)
+
+// Purposes of tokens signed with keys derived from the main symmetric key
+const (
+	PurposePasswordReset = "password_reset"
+	PurposeTOTPChallenge = "totp_challenge"
+	PurposeSSOState      = "sso_state"
+	PurposeSSONonce      = "sso_nonce"
+	PurposeSSOVerifier   = "sso_verifier"
+)
+
+// DeriveKey derives a 32 byte key for a single purpose from the main token
+// key. Makers built from different derived keys reject each other's tokens,
+// so e.g. a password reset token can never be used as an access token.
+func DeriveKey(symmetricKey, purpose string) string {
+	mac := hmac.New(sha256.New, []byte(symmetricKey))
+	mac.Write([]byte(purpose))
+	return string(mac.Sum(nil))
+}
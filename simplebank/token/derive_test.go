@@ -0,0 +1,33 @@
+package token
+
+import (
+	"testing"
+	"time"
+
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeriveKey(t *testing.T) {
+	symmetricKey := util.RandomString(32)
+
+	key := DeriveKey(symmetricKey, PurposePasswordReset)
+	require.Len(t, key, 32)
+	require.Equal(t, key, DeriveKey(symmetricKey, PurposePasswordReset))
+	require.NotEqual(t, key, DeriveKey(symmetricKey, "other"))
+
+	mainMaker, err := NewPasetoMaker(symmetricKey)
+	require.NoError(t, err)
+
+	derivedMaker, err := NewPasetoMaker(key)
+	require.NoError(t, err)
+
+	token, err := derivedMaker.CreateToken(util.RandomOwner(), time.Minute)
+	require.NoError(t, err)
+
+	_, err = derivedMaker.VerifyToken(token)
+	require.NoError(t, err)
+
+	_, err = mainMaker.VerifyToken(token)
+	require.ErrorIs(t, err, ErrInvalidToken)
+}
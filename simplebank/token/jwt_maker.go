@@ -23,16 +23,32 @@ func NewJWTMaker(secretKey string) (Maker, error) {
 
 // CreateToken creates a new token for a specific username and duration
 func (maker *JWTMaker) CreateToken(username string, duration time.Duration) (string, error) {
-	payload, err := NewPayload(username, duration)
+	token, _, err := maker.CreateScopedToken(username, duration)
+	return token, err
+}
+
+// CreateScopedToken creates a new token limited to the given scopes
+func (maker *JWTMaker) CreateScopedToken(username string, duration time.Duration, scopes ...string) (string, *Payload, error) {
+	return maker.CreateClientToken(username, "", duration, scopes...)
+}
+
+// CreateClientToken creates a new token issued to an oauth client
+func (maker *JWTMaker) CreateClientToken(username, clientID string, duration time.Duration, scopes ...string) (string, *Payload, error) {
+	payload, err := NewPayload(username, duration, scopes...)
 	if err != nil {
-		return "", err
+		return "", nil, err
 	}
+	payload.ClientID = clientID
 
 	jwtToken := jwt.NewWithClaims(jwt.SigningMethodHS256, payload)
 
 	// Sign and get the complete encoded token as a string using the secret
 	tokenString, err := jwtToken.SignedString([]byte(maker.secretKey))
-	return tokenString, nil
+	if err != nil {
+		return "", nil, err
+	}
+
+	return tokenString, payload, nil
 }
 
 // VerifyToken checks if the input token is valid or not
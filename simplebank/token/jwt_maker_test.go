@@ -31,6 +31,43 @@ func TestJWTMaker(t *testing.T) {
 	require.Equal(t, username, payload.Username)
 	require.WithinDuration(t, issuedAt, payload.IssuedAt, time.Second)
 	require.WithinDuration(t, expiredAt, payload.ExpiredAt, time.Second)
+	require.Empty(t, payload.Scopes)
+	require.True(t, payload.HasScope(ScopeTransfersWrite))
+	require.Empty(t, payload.ClientID)
+}
+
+func TestJWTMakerScopes(t *testing.T) {
+	maker, err := NewJWTMaker(util.RandomString(32))
+	require.NoError(t, err)
+
+	token, createdPayload, err := maker.CreateScopedToken(util.RandomOwner(), time.Minute, ScopeAccountsRead)
+	require.NoError(t, err)
+	require.Equal(t, []string{ScopeAccountsRead}, createdPayload.Scopes)
+
+	payload, err := maker.VerifyToken(token)
+	require.NoError(t, err)
+	require.Equal(t, createdPayload.ID, payload.ID)
+	require.Equal(t, []string{ScopeAccountsRead}, payload.Scopes)
+	require.True(t, payload.HasScope(ScopeAccountsRead))
+	require.False(t, payload.HasScope(ScopeTransfersWrite))
+}
+
+func TestJWTMakerClientToken(t *testing.T) {
+	maker, err := NewJWTMaker(util.RandomString(32))
+	require.NoError(t, err)
+
+	username := util.RandomOwner()
+	clientID := util.RandomString(16)
+
+	token, createdPayload, err := maker.CreateClientToken(username, clientID, time.Minute, ScopeAccountsRead)
+	require.NoError(t, err)
+	require.Equal(t, clientID, createdPayload.ClientID)
+
+	payload, err := maker.VerifyToken(token)
+	require.NoError(t, err)
+	require.Equal(t, username, payload.Username)
+	require.Equal(t, clientID, payload.ClientID)
+	require.Equal(t, []string{ScopeAccountsRead}, payload.Scopes)
 }
 
 func TestExpiredJWTToken(t *testing.T) {
@@ -7,6 +7,15 @@ type Maker interface {
 	// CreateToken creates a new token for a specific username and duration
 	CreateToken(username string, duration time.Duration) (string, error)
 
+	// CreateScopedToken creates a token like CreateToken and returns it
+	// together with its payload. The token is limited to the given scopes;
+	// without any it grants full access.
+	CreateScopedToken(username string, duration time.Duration, scopes ...string) (string, *Payload, error)
+
+	// CreateClientToken creates a token like CreateToken that is issued to
+	// a third-party oauth client acting on behalf of the user
+	CreateClientToken(username, clientID string, duration time.Duration, scopes ...string) (string, *Payload, error)
+
 	// VerifyToken checks if the input token is valid or not
 	VerifyToken(token string) (*Payload, error)
 }
@@ -15,12 +15,29 @@ type PasetoMaker struct {
 
 // CreateToken implements Maker.
 func (p *PasetoMaker) CreateToken(username string, duration time.Duration) (string, error) {
-	payload, err := NewPayload(username, duration)
+	token, _, err := p.CreateScopedToken(username, duration)
+	return token, err
+}
+
+// CreateScopedToken implements Maker.
+func (p *PasetoMaker) CreateScopedToken(username string, duration time.Duration, scopes ...string) (string, *Payload, error) {
+	return p.CreateClientToken(username, "", duration, scopes...)
+}
+
+// CreateClientToken implements Maker.
+func (p *PasetoMaker) CreateClientToken(username, clientID string, duration time.Duration, scopes ...string) (string, *Payload, error) {
+	payload, err := NewPayload(username, duration, scopes...)
+	if err != nil {
+		return "", nil, err
+	}
+	payload.ClientID = clientID
+
+	token, err := p.paseto.Encrypt(p.symmetricKey, payload, nil)
 	if err != nil {
-		return "", err
+		return "", nil, err
 	}
 
-	return p.paseto.Encrypt(p.symmetricKey, payload, nil)
+	return token, payload, nil
 }
 
 // VerifyToken implements Maker.
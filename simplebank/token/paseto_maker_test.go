@@ -30,6 +30,43 @@ func TestPasetoMaker(t *testing.T) {
 	require.Equal(t, username, payload.Username)
 	require.WithinDuration(t, issuedAt, payload.IssuedAt, time.Second)
 	require.WithinDuration(t, expiredAt, payload.ExpiredAt, time.Second)
+	require.Empty(t, payload.Scopes)
+	require.True(t, payload.HasScope(ScopeTransfersWrite))
+	require.Empty(t, payload.ClientID)
+}
+
+func TestPasetoMakerScopes(t *testing.T) {
+	maker, err := NewPasetoMaker(util.RandomString(32))
+	require.NoError(t, err)
+
+	token, createdPayload, err := maker.CreateScopedToken(util.RandomOwner(), time.Minute, ScopeAccountsRead)
+	require.NoError(t, err)
+	require.Equal(t, []string{ScopeAccountsRead}, createdPayload.Scopes)
+
+	payload, err := maker.VerifyToken(token)
+	require.NoError(t, err)
+	require.Equal(t, createdPayload.ID, payload.ID)
+	require.Equal(t, []string{ScopeAccountsRead}, payload.Scopes)
+	require.True(t, payload.HasScope(ScopeAccountsRead))
+	require.False(t, payload.HasScope(ScopeTransfersWrite))
+}
+
+func TestPasetoMakerClientToken(t *testing.T) {
+	maker, err := NewPasetoMaker(util.RandomString(32))
+	require.NoError(t, err)
+
+	username := util.RandomOwner()
+	clientID := util.RandomString(16)
+
+	token, createdPayload, err := maker.CreateClientToken(username, clientID, time.Minute, ScopeAccountsRead)
+	require.NoError(t, err)
+	require.Equal(t, clientID, createdPayload.ClientID)
+
+	payload, err := maker.VerifyToken(token)
+	require.NoError(t, err)
+	require.Equal(t, username, payload.Username)
+	require.Equal(t, clientID, payload.ClientID)
+	require.Equal(t, []string{ScopeAccountsRead}, payload.Scopes)
 }
 
 func TestExpiredPasetoToken(t *testing.T) {
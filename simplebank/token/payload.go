@@ -2,6 +2,7 @@ package token
 
 import (
 	"errors"
+	"slices"
 	"time"
 
 	"github.com/google/uuid"
@@ -17,6 +18,11 @@ type Payload struct {
 	Username  string    `json:"username"`
 	IssuedAt  time.Time `json:"issued_at"`
 	ExpiredAt time.Time `json:"expired_at"`
+	// Scopes limit what the token can be used for. Tokens without scopes,
+	// including those issued before scopes existed, grant full access.
+	Scopes []string `json:"scopes,omitempty"`
+	// ClientID is set on tokens issued to third-party oauth clients
+	ClientID string `json:"client_id,omitempty"`
 }
 
 // Valid implements jwt.Claims.
@@ -27,7 +33,12 @@ func (p *Payload) Valid() error {
 	return nil
 }
 
-func NewPayload(username string, duration time.Duration) (*Payload, error) {
+// HasScope reports whether the token can be used for the scope
+func (p *Payload) HasScope(scope string) bool {
+	return len(p.Scopes) == 0 || slices.Contains(p.Scopes, scope)
+}
+
+func NewPayload(username string, duration time.Duration, scopes ...string) (*Payload, error) {
 	tokenID, err := uuid.NewRandom()
 	if err != nil {
 		return nil, err
@@ -38,6 +49,7 @@ func NewPayload(username string, duration time.Duration) (*Payload, error) {
 		Username:  username,
 		IssuedAt:  time.Now(),
 		ExpiredAt: time.Now().Add(duration),
+		Scopes:    scopes,
 	}
 
 	return payload, nil
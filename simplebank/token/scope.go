@@ -0,0 +1,21 @@
+package token
+
+import "slices"
+
+// Scopes limit what a credential can be used for
+const (
+	ScopeAccountsRead   = "accounts:read"
+	ScopeAccountsWrite  = "accounts:write"
+	ScopeTransfersWrite = "transfers:write"
+	ScopeAdmin          = "admin"
+	// ScopeUser covers managing the user itself: profile, password,
+	// two-factor authentication and api keys
+	ScopeUser = "user"
+)
+
+// SupportedScopes lists the scopes a credential can be limited to
+var SupportedScopes = []string{ScopeAccountsRead, ScopeAccountsWrite, ScopeTransfersWrite, ScopeAdmin, ScopeUser}
+
+func IsSupportedScope(scope string) bool {
+	return slices.Contains(SupportedScopes, scope)
+}
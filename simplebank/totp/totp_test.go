@@ -0,0 +1,95 @@
+package totp
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// base32 of the RFC 6238 SHA1 test key "12345678901234567890"
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestCodeRFC6238(t *testing.T) {
+	// the RFC lists 8 digit codes; ours are their last 6 digits
+	testCases := []struct {
+		unix int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+
+	for _, tc := range testCases {
+		code, err := Code(rfcSecret, time.Unix(tc.unix, 0))
+		require.NoError(t, err)
+		require.Equal(t, tc.code, code)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	secret, err := GenerateSecret()
+	require.NoError(t, err)
+	require.Len(t, secret, 32)
+
+	now := time.Now()
+	code, err := Code(secret, now)
+	require.NoError(t, err)
+
+	step, err := Validate(secret, code, now)
+	require.NoError(t, err)
+	require.Equal(t, Step(now), step)
+
+	// allows for a period of clock drift either way
+	step, err = Validate(secret, code, now.Add(Period))
+	require.NoError(t, err)
+	require.Equal(t, Step(now), step)
+
+	_, err = Validate(secret, code, now.Add(3*Period))
+	require.ErrorIs(t, err, ErrInvalidCode)
+
+	_, err = Validate(secret, "12345", now)
+	require.ErrorIs(t, err, ErrInvalidCode)
+
+	_, err = Validate("not base32!", code, now)
+	require.ErrorIs(t, err, ErrInvalidSecret)
+}
+
+func TestURI(t *testing.T) {
+	uri := URI("Simple Bank", "alice", rfcSecret)
+
+	link, err := url.Parse(uri)
+	require.NoError(t, err)
+	require.Equal(t, "otpauth", link.Scheme)
+	require.Equal(t, "totp", link.Host)
+	require.Equal(t, "/Simple Bank:alice", link.Path)
+	require.Equal(t, rfcSecret, link.Query().Get("secret"))
+	require.Equal(t, "Simple Bank", link.Query().Get("issuer"))
+	require.Equal(t, "6", link.Query().Get("digits"))
+	require.Equal(t, "30", link.Query().Get("period"))
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	codes, err := GenerateRecoveryCodes(RecoveryCodeCount)
+	require.NoError(t, err)
+	require.Len(t, codes, RecoveryCodeCount)
+
+	seen := make(map[string]bool)
+	for _, code := range codes {
+		require.Regexp(t, `^[a-z2-9]{5}-[a-z2-9]{5}$`, code)
+		require.False(t, seen[code])
+		seen[code] = true
+	}
+
+	// hashing ignores case, spacing and the dash
+	hash := HashRecoveryCode(codes[0])
+	require.Len(t, hash, 64)
+	require.Equal(t, hash, HashRecoveryCode(" "+codes[0][:5]+codes[0][6:]+" "))
+	require.Equal(t, hash, HashRecoveryCode(strings.ToUpper(codes[0])))
+	require.NotEqual(t, hash, HashRecoveryCode(codes[1]))
+}
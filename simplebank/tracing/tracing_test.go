@@ -0,0 +1,81 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/util"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.40.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestSetupStdout(t *testing.T) {
+	var buf bytes.Buffer
+	provider, err := Setup(context.Background(), util.Config{TraceExporter: ExporterStdout}, &buf)
+	require.NoError(t, err)
+
+	_, span := otel.Tracer("test").Start(context.Background(), "GetAccount")
+	span.End()
+	require.NoError(t, provider.Shutdown(context.Background()))
+
+	require.Contains(t, buf.String(), `"Name": "GetAccount"`)
+	require.Contains(t, buf.String(), ServiceName)
+}
+
+func TestSetupNone(t *testing.T) {
+	provider, err := Setup(context.Background(), util.Config{TraceExporter: ExporterNone}, nil)
+	require.NoError(t, err)
+	defer provider.Shutdown(context.Background())
+
+	// spans are recorded, so logs and errors get their trace ids
+	_, span := otel.Tracer("test").Start(context.Background(), "GetAccount")
+	defer span.End()
+	require.True(t, span.SpanContext().IsValid())
+}
+
+func TestSetupInvalidExporter(t *testing.T) {
+	_, err := Setup(context.Background(), util.Config{TraceExporter: "jaeger"}, nil)
+	require.ErrorContains(t, err, `invalid trace exporter "jaeger"`)
+}
+
+func TestQueryTracer(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	tracer := NewQueryTracer()
+
+	ctx, parent := otel.Tracer("test").Start(context.Background(), "TransferTx")
+	queryCtx := tracer.TraceQueryStart(ctx, nil, pgx.TraceQueryStartData{
+		SQL:  "-- name: AddAccountBalance :one\nUPDATE accounts SET balance = balance + $1",
+		Args: []any{int64(10)},
+	})
+	require.NotEqual(t, parent.SpanContext().SpanID(), trace.SpanContextFromContext(queryCtx).SpanID())
+	tracer.TraceQueryEnd(queryCtx, nil, pgx.TraceQueryEndData{CommandTag: pgconn.NewCommandTag("UPDATE 1")})
+
+	queryCtx = tracer.TraceQueryStart(ctx, nil, pgx.TraceQueryStartData{SQL: "-- name: GetAccount :one\nSELECT 1"})
+	tracer.TraceQueryEnd(queryCtx, nil, pgx.TraceQueryEndData{Err: errors.New("connection reset")})
+	parent.End()
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 3)
+
+	update := spans[0]
+	require.Equal(t, "AddAccountBalance", update.Name())
+	require.Equal(t, trace.SpanKindClient, update.SpanKind())
+	require.Equal(t, parent.SpanContext().SpanID(), update.Parent().SpanID())
+	require.Contains(t, update.Attributes(), semconv.DBSystemNamePostgreSQL)
+	require.Contains(t, update.Attributes(), semconv.DBResponseReturnedRows(1))
+
+	get := spans[1]
+	require.Equal(t, "GetAccount", get.Name())
+	require.Equal(t, codes.Error, get.Status().Code)
+	require.Equal(t, "connection reset", get.Status().Description)
+}
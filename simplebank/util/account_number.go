@@ -0,0 +1,86 @@
+package util
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math/big"
+)
+
+const (
+	// DefaultAccountNumberCountry is the country prefix used when none is configured
+	DefaultAccountNumberCountry = "NG"
+
+	accountNumberBBANLength = 10
+	accountNumberLength     = 4 + accountNumberBBANLength
+)
+
+// GenerateAccountNumber returns a new IBAN-style account number made of the
+// country prefix, two mod-97 check digits and a random 10 digit basic account number.
+func GenerateAccountNumber(country string) (string, error) {
+	if !IsValidCountryCode(country) {
+		return "", fmt.Errorf("invalid account number country prefix: %q", country)
+	}
+
+	max := new(big.Int).Exp(big.NewInt(10), big.NewInt(accountNumberBBANLength), nil)
+	n, err := rand.Int(rand.Reader, max)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate account number: %w", err)
+	}
+
+	bban := fmt.Sprintf("%0*d", accountNumberBBANLength, n)
+	check := 98 - mod97(bban+country+"00")
+
+	return fmt.Sprintf("%s%02d%s", country, check, bban), nil
+}
+
+// IsValidAccountNumber checks the format and the mod-97 check digits of an account number
+func IsValidAccountNumber(accountNumber string) bool {
+	if len(accountNumber) != accountNumberLength {
+		return false
+	}
+
+	country, check, bban := accountNumber[:2], accountNumber[2:4], accountNumber[4:]
+	if !IsValidCountryCode(country) || !isDigits(check) || !isDigits(bban) {
+		return false
+	}
+
+	return mod97(bban+country+check) == 1
+}
+
+// IsValidCountryCode checks if the string is a two letter upper case country code
+func IsValidCountryCode(country string) bool {
+	return len(country) == 2 && isLetters(country)
+}
+
+// mod97 computes the ISO 7064 mod 97-10 remainder of a string of digits and
+// upper case letters, where letters count as two digits (A = 10 ... Z = 35).
+func mod97(s string) int {
+	remainder := 0
+	for _, c := range s {
+		switch {
+		case c >= '0' && c <= '9':
+			remainder = (remainder*10 + int(c-'0')) % 97
+		case c >= 'A' && c <= 'Z':
+			remainder = (remainder*100 + int(c-'A') + 10) % 97
+		}
+	}
+	return remainder
+}
+
+func isDigits(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func isLetters(s string) bool {
+	for _, c := range s {
+		if c < 'A' || c > 'Z' {
+			return false
+		}
+	}
+	return true
+}
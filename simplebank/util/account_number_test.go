@@ -0,0 +1,57 @@
+package util
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateAccountNumber(t *testing.T) {
+	accountNumber1, err := GenerateAccountNumber("NG")
+	require.NoError(t, err)
+	require.Len(t, accountNumber1, accountNumberLength)
+	require.Equal(t, "NG", accountNumber1[:2])
+	require.True(t, IsValidAccountNumber(accountNumber1))
+
+	accountNumber2, err := GenerateAccountNumber("NG")
+	require.NoError(t, err)
+	require.NotEqual(t, accountNumber1, accountNumber2)
+
+	_, err = GenerateAccountNumber("ng")
+	require.Error(t, err)
+
+	_, err = GenerateAccountNumber("NGA")
+	require.Error(t, err)
+}
+
+func TestIsValidAccountNumber(t *testing.T) {
+	accountNumber := RandomAccountNumber()
+	require.True(t, IsValidAccountNumber(accountNumber))
+
+	// The check digits of the IBAN test vector GB82 WEST 1234 5698 7654 32
+	// are computed the same way as ours.
+	require.Equal(t, 1, mod97("WEST12345698765432GB82"))
+
+	testCases := []string{
+		"",
+		"12345",
+		"ng" + accountNumber[2:],
+		accountNumber[:4] + "12345678X0",
+		accountNumber[:2] + "AB" + accountNumber[4:],
+		accountNumber + "0",
+	}
+
+	for _, tc := range testCases {
+		require.False(t, IsValidAccountNumber(tc), tc)
+	}
+
+	// Swapping two different adjacent digits must break the checksum
+	for i := 4; i < len(accountNumber)-1; i++ {
+		if accountNumber[i] == accountNumber[i+1] {
+			continue
+		}
+		swapped := []byte(accountNumber)
+		swapped[i], swapped[i+1] = swapped[i+1], swapped[i]
+		require.False(t, IsValidAccountNumber(string(swapped)), string(swapped))
+	}
+}
@@ -0,0 +1,64 @@
+package util
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"strings"
+)
+
+const (
+	apiKeyTag          = "sb"
+	apiKeyPrefixBytes  = 6
+	apiKeySecretBytes  = 32
+	apiKeyPrefixLength = 2 * apiKeyPrefixBytes
+	apiKeySecretLength = 2 * apiKeySecretBytes
+)
+
+var ErrInvalidAPIKey = errors.New("api key is invalid")
+
+// GenerateAPIKey returns a new api key of the form sb_<prefix>_<secret>
+// together with the prefix it is looked up by and the hash of its secret.
+// The key is only shown to the user once; store the prefix and hash.
+func GenerateAPIKey() (key, prefix, hashedSecret string, err error) {
+	prefix, err = GenerateSecret(apiKeyPrefixBytes)
+	if err != nil {
+		return
+	}
+
+	secret, err := GenerateSecret(apiKeySecretBytes)
+	if err != nil {
+		return
+	}
+
+	key = apiKeyTag + "_" + prefix + "_" + secret
+	hashedSecret = HashAPIKeySecret(secret)
+	return
+}
+
+// ParseAPIKey splits an api key into its prefix and the hash of its secret
+func ParseAPIKey(key string) (prefix, hashedSecret string, err error) {
+	parts := strings.Split(key, "_")
+	if len(parts) != 3 || parts[0] != apiKeyTag ||
+		len(parts[1]) != apiKeyPrefixLength || len(parts[2]) != apiKeySecretLength {
+		return "", "", ErrInvalidAPIKey
+	}
+	return parts[1], HashAPIKeySecret(parts[2]), nil
+}
+
+// HashAPIKeySecret returns the hash the secret part of an api key is stored as.
+// Secrets carry 256 bits of randomness, so a fast hash is enough, unlike passwords.
+func HashAPIKeySecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// CheckAPIKeySecret compares the hashed secret of a presented key with the
+// stored one in constant time
+func CheckAPIKeySecret(hashedSecret, storedHash string) error {
+	if subtle.ConstantTimeCompare([]byte(hashedSecret), []byte(storedHash)) != 1 {
+		return ErrInvalidAPIKey
+	}
+	return nil
+}
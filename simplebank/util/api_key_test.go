@@ -0,0 +1,44 @@
+package util
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateAPIKey(t *testing.T) {
+	key, prefix, hashedSecret, err := GenerateAPIKey()
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(key, "sb_"+prefix+"_"))
+	require.Len(t, prefix, 12)
+	require.NotContains(t, key, hashedSecret)
+
+	parsedPrefix, parsedHash, err := ParseAPIKey(key)
+	require.NoError(t, err)
+	require.Equal(t, prefix, parsedPrefix)
+	require.NoError(t, CheckAPIKeySecret(parsedHash, hashedSecret))
+
+	otherKey, otherPrefix, otherHash, err := GenerateAPIKey()
+	require.NoError(t, err)
+	require.NotEqual(t, key, otherKey)
+	require.NotEqual(t, prefix, otherPrefix)
+	require.ErrorIs(t, CheckAPIKeySecret(otherHash, hashedSecret), ErrInvalidAPIKey)
+}
+
+func TestParseAPIKeyInvalid(t *testing.T) {
+	key, _, _, err := GenerateAPIKey()
+	require.NoError(t, err)
+
+	for _, invalid := range []string{
+		"",
+		"sb_",
+		key[:len(key)-1],
+		key + "0",
+		"xx" + key[2:],
+		strings.Replace(key, "_", "-", 1),
+	} {
+		_, _, err := ParseAPIKey(invalid)
+		require.ErrorIs(t, err, ErrInvalidAPIKey, invalid)
+	}
+}
@@ -11,8 +11,129 @@ type Config struct {
 	DBDriver            string        `mapstructure:"DB_DRIVER"`
 	DBSource            string        `mapstructure:"DB_SOURCE"`
 	ServerAddress       string        `mapstructure:"SERVER_ADDRESS"`
+	GRPCServerAddress   string        `mapstructure:"GRPC_SERVER_ADDRESS"`
 	TokenSymmetricKey   string        `mapstructure:"TOKEN_SYMMETRIC_KEY"`
 	AccessTokenDuration time.Duration `mapstructure:"ACCESS_TOKEN_DURATION"`
+
+	// MetricsServerAddress serves the metrics to Prometheus, apart from the
+	// API as they are not meant for clients; empty turns it off
+	MetricsServerAddress string `mapstructure:"METRICS_SERVER_ADDRESS"`
+
+	// AutoMigrate applies the missing migrations when the server starts
+	AutoMigrate bool `mapstructure:"AUTO_MIGRATE"`
+
+	// Timeouts of the HTTP server; zero means none
+	HTTPReadTimeout  time.Duration `mapstructure:"HTTP_READ_TIMEOUT"`
+	HTTPWriteTimeout time.Duration `mapstructure:"HTTP_WRITE_TIMEOUT"`
+	HTTPIdleTimeout  time.Duration `mapstructure:"HTTP_IDLE_TIMEOUT"`
+	// On SIGINT or SIGTERM the server fails its readiness probe for
+	// ShutdownDrainPeriod, so load balancers stop sending it requests, and
+	// then waits up to ShutdownTimeout for the requests in flight.
+	ShutdownDrainPeriod time.Duration `mapstructure:"SHUTDOWN_DRAIN_PERIOD"`
+	ShutdownTimeout     time.Duration `mapstructure:"SHUTDOWN_TIMEOUT"`
+
+	// LogFormat is json or text. LogLevel is the lowest level logged: debug,
+	// which includes database queries, info, warn or error.
+	LogFormat string `mapstructure:"LOG_FORMAT"`
+	LogLevel  string `mapstructure:"LOG_LEVEL"`
+	// TraceExporter is none, otlp or stdout. The otlp exporter sends spans
+	// over HTTP to TraceOTLPEndpoint, e.g. http://localhost:4318, or to the
+	// endpoint of the standard OTEL_EXPORTER_OTLP variables when it is empty.
+	TraceExporter     string `mapstructure:"TRACE_EXPORTER"`
+	TraceOTLPEndpoint string `mapstructure:"TRACE_OTLP_ENDPOINT"`
+
+	AccountNumberCountry string `mapstructure:"ACCOUNT_NUMBER_COUNTRY"`
+
+	BalanceSnapshotJobInterval time.Duration `mapstructure:"BALANCE_SNAPSHOT_JOB_INTERVAL"`
+	StatementJobInterval       time.Duration `mapstructure:"STATEMENT_JOB_INTERVAL"`
+	ReconciliationJobInterval  time.Duration `mapstructure:"RECONCILIATION_JOB_INTERVAL"`
+	// ClosedPeriodLag is how long a day or month has to be over before its
+	// balances are snapshotted or its statements stored, so that transfers
+	// started before it ended have committed
+	ClosedPeriodLag time.Duration `mapstructure:"CLOSED_PERIOD_LAG"`
+
+	Mailer              string        `mapstructure:"MAILER"`
+	MailLogDir          string        `mapstructure:"MAIL_LOG_DIR"`
+	SMTPHost            string        `mapstructure:"SMTP_HOST"`
+	SMTPPort            int           `mapstructure:"SMTP_PORT"`
+	SMTPUsername        string        `mapstructure:"SMTP_USERNAME"`
+	SMTPPassword        string        `mapstructure:"SMTP_PASSWORD"`
+	EmailSenderName     string        `mapstructure:"EMAIL_SENDER_NAME"`
+	EmailSenderAddress  string        `mapstructure:"EMAIL_SENDER_ADDRESS"`
+	VerifyEmailURL      string        `mapstructure:"VERIFY_EMAIL_URL"`
+	VerifyEmailDuration time.Duration `mapstructure:"VERIFY_EMAIL_DURATION"`
+
+	PasswordResetURL      string        `mapstructure:"PASSWORD_RESET_URL"`
+	PasswordResetDuration time.Duration `mapstructure:"PASSWORD_RESET_DURATION"`
+
+	TOTPIssuer            string        `mapstructure:"TOTP_ISSUER"`
+	TOTPChallengeDuration time.Duration `mapstructure:"TOTP_CHALLENGE_DURATION"`
+	// TOTPStepUpAmount is the transfer amount from which a TOTP code is
+	// required as well as the access token. Zero turns step-up off.
+	TOTPStepUpAmount int64 `mapstructure:"TOTP_STEP_UP_AMOUNT"`
+
+	// Failed logins beyond the free attempts lock the username or client ip
+	// for LoginBackoffBase, doubling with each further failure up to
+	// LoginLockoutDuration. Counts start again after LoginFailureWindow
+	// without a failure.
+	LoginUserFreeAttempts int32         `mapstructure:"LOGIN_USER_FREE_ATTEMPTS"`
+	LoginIPFreeAttempts   int32         `mapstructure:"LOGIN_IP_FREE_ATTEMPTS"`
+	LoginBackoffBase      time.Duration `mapstructure:"LOGIN_BACKOFF_BASE"`
+	LoginLockoutDuration  time.Duration `mapstructure:"LOGIN_LOCKOUT_DURATION"`
+	LoginFailureWindow    time.Duration `mapstructure:"LOGIN_FAILURE_WINDOW"`
+	// RateLimitBackend keeps the rate limit buckets: memory, for a single
+	// instance, postgres, shared by all instances, or none. Limits are
+	// written as <count>/<period>, e.g. 10/m; an empty limit turns it off.
+	// Public routes are limited by client ip and the others by
+	// RateLimitIP before their credentials are checked and by user after,
+	// with RateLimitLogin on top for logins and password resets, over HTTP
+	// and gRPC, and RateLimitTransfer for transfers.
+	RateLimitBackend  string `mapstructure:"RATE_LIMIT_BACKEND"`
+	RateLimitPublic   string `mapstructure:"RATE_LIMIT_PUBLIC"`
+	RateLimitLogin    string `mapstructure:"RATE_LIMIT_LOGIN"`
+	RateLimitIP       string `mapstructure:"RATE_LIMIT_IP"`
+	RateLimitUser     string `mapstructure:"RATE_LIMIT_USER"`
+	RateLimitTransfer string `mapstructure:"RATE_LIMIT_TRANSFER"`
+	// TrustedProxies may set X-Forwarded-For; by default the client ip is the peer address
+	TrustedProxies []string `mapstructure:"TRUSTED_PROXIES"`
+
+	// New passwords are hashed with PasswordHashAlgorithm and older hashes are
+	// upgraded on login. Zero costs mean the library defaults.
+	PasswordHashAlgorithm string `mapstructure:"PASSWORD_HASH_ALGORITHM"`
+	Argon2Memory          uint32 `mapstructure:"ARGON2_MEMORY"`
+	Argon2Iterations      uint32 `mapstructure:"ARGON2_ITERATIONS"`
+	Argon2Parallelism     uint8  `mapstructure:"ARGON2_PARALLELISM"`
+	BcryptCost            int    `mapstructure:"BCRYPT_COST"`
+
+	PasswordMinLength     int    `mapstructure:"PASSWORD_MIN_LENGTH"`
+	PasswordMaxLength     int    `mapstructure:"PASSWORD_MAX_LENGTH"`
+	BreachedPasswordsFile string `mapstructure:"BREACHED_PASSWORDS_FILE"`
+
+	// APIKeyMaxDuration is the longest, and default, lifetime of an api key
+	APIKeyMaxDuration time.Duration `mapstructure:"API_KEY_MAX_DURATION"`
+
+	// OAuthCodeDuration is how long a third-party client has to exchange an
+	// authorization code. Its access tokens last AccessTokenDuration.
+	OAuthCodeDuration time.Duration `mapstructure:"OAUTH_CODE_DURATION"`
+
+	// Staff log in through the OpenID Connect provider at OIDCIssuerURL when
+	// it is set. OIDCRedirectURL is the page of the staff frontend that passes
+	// the code and state it gets back on to POST /sso/callback.
+	OIDCIssuerURL    string   `mapstructure:"OIDC_ISSUER_URL"`
+	OIDCClientID     string   `mapstructure:"OIDC_CLIENT_ID"`
+	OIDCClientSecret string   `mapstructure:"OIDC_CLIENT_SECRET"`
+	OIDCRedirectURL  string   `mapstructure:"OIDC_REDIRECT_URL"`
+	OIDCScopes       []string `mapstructure:"OIDC_SCOPES"`
+	// OIDCGroupsClaim holds the groups of the user. Users created by their
+	// first login through the provider get the admin role as members of
+	// OIDCAdminGroups and the depositor role otherwise; the roles of existing
+	// users it is linked to are left alone. When OIDCAllowedGroups is set,
+	// only its members can log in.
+	OIDCGroupsClaim   string   `mapstructure:"OIDC_GROUPS_CLAIM"`
+	OIDCAllowedGroups []string `mapstructure:"OIDC_ALLOWED_GROUPS"`
+	OIDCAdminGroups   []string `mapstructure:"OIDC_ADMIN_GROUPS"`
+	// OIDCLoginDuration is how long the user has to log in at the provider
+	OIDCLoginDuration time.Duration `mapstructure:"OIDC_LOGIN_DURATION"`
 }
 
 func LoadConfig(path string) (config Config, err error) {
@@ -25,9 +146,115 @@ func LoadConfig(path string) (config Config, err error) {
 
 	_ = viper.BindEnv("DB_DRIVER")
 	_ = viper.BindEnv("DB_SOURCE")
+	_ = viper.BindEnv("AUTO_MIGRATE")
 	_ = viper.BindEnv("SERVER_ADDRESS")
+	_ = viper.BindEnv("GRPC_SERVER_ADDRESS")
+	_ = viper.BindEnv("METRICS_SERVER_ADDRESS")
 	_ = viper.BindEnv("TOKEN_SYMMETRIC_KEY")
 	_ = viper.BindEnv("ACCESS_TOKEN_DURATION")
+	_ = viper.BindEnv("HTTP_READ_TIMEOUT")
+	_ = viper.BindEnv("HTTP_WRITE_TIMEOUT")
+	_ = viper.BindEnv("HTTP_IDLE_TIMEOUT")
+	_ = viper.BindEnv("SHUTDOWN_DRAIN_PERIOD")
+	_ = viper.BindEnv("SHUTDOWN_TIMEOUT")
+	_ = viper.BindEnv("LOG_FORMAT")
+	_ = viper.BindEnv("LOG_LEVEL")
+	_ = viper.BindEnv("TRACE_EXPORTER")
+	_ = viper.BindEnv("TRACE_OTLP_ENDPOINT")
+	_ = viper.BindEnv("ACCOUNT_NUMBER_COUNTRY")
+	_ = viper.BindEnv("BALANCE_SNAPSHOT_JOB_INTERVAL")
+	_ = viper.BindEnv("STATEMENT_JOB_INTERVAL")
+	_ = viper.BindEnv("CLOSED_PERIOD_LAG")
+	_ = viper.BindEnv("RECONCILIATION_JOB_INTERVAL")
+	_ = viper.BindEnv("MAILER")
+	_ = viper.BindEnv("MAIL_LOG_DIR")
+	_ = viper.BindEnv("SMTP_HOST")
+	_ = viper.BindEnv("SMTP_PORT")
+	_ = viper.BindEnv("SMTP_USERNAME")
+	_ = viper.BindEnv("SMTP_PASSWORD")
+	_ = viper.BindEnv("EMAIL_SENDER_NAME")
+	_ = viper.BindEnv("EMAIL_SENDER_ADDRESS")
+	_ = viper.BindEnv("VERIFY_EMAIL_URL")
+	_ = viper.BindEnv("VERIFY_EMAIL_DURATION")
+	_ = viper.BindEnv("PASSWORD_RESET_URL")
+	_ = viper.BindEnv("PASSWORD_RESET_DURATION")
+	_ = viper.BindEnv("TOTP_ISSUER")
+	_ = viper.BindEnv("TOTP_CHALLENGE_DURATION")
+	_ = viper.BindEnv("TOTP_STEP_UP_AMOUNT")
+	_ = viper.BindEnv("LOGIN_USER_FREE_ATTEMPTS")
+	_ = viper.BindEnv("LOGIN_IP_FREE_ATTEMPTS")
+	_ = viper.BindEnv("LOGIN_BACKOFF_BASE")
+	_ = viper.BindEnv("LOGIN_LOCKOUT_DURATION")
+	_ = viper.BindEnv("LOGIN_FAILURE_WINDOW")
+	_ = viper.BindEnv("RATE_LIMIT_BACKEND")
+	_ = viper.BindEnv("RATE_LIMIT_PUBLIC")
+	_ = viper.BindEnv("RATE_LIMIT_LOGIN")
+	_ = viper.BindEnv("RATE_LIMIT_IP")
+	_ = viper.BindEnv("RATE_LIMIT_USER")
+	_ = viper.BindEnv("RATE_LIMIT_TRANSFER")
+	_ = viper.BindEnv("TRUSTED_PROXIES")
+	_ = viper.BindEnv("PASSWORD_HASH_ALGORITHM")
+	_ = viper.BindEnv("ARGON2_MEMORY")
+	_ = viper.BindEnv("ARGON2_ITERATIONS")
+	_ = viper.BindEnv("ARGON2_PARALLELISM")
+	_ = viper.BindEnv("BCRYPT_COST")
+	_ = viper.BindEnv("PASSWORD_MIN_LENGTH")
+	_ = viper.BindEnv("PASSWORD_MAX_LENGTH")
+	_ = viper.BindEnv("BREACHED_PASSWORDS_FILE")
+	_ = viper.BindEnv("API_KEY_MAX_DURATION")
+	_ = viper.BindEnv("OAUTH_CODE_DURATION")
+	_ = viper.BindEnv("OIDC_ISSUER_URL")
+	_ = viper.BindEnv("OIDC_CLIENT_ID")
+	_ = viper.BindEnv("OIDC_CLIENT_SECRET")
+	_ = viper.BindEnv("OIDC_REDIRECT_URL")
+	_ = viper.BindEnv("OIDC_SCOPES")
+	_ = viper.BindEnv("OIDC_GROUPS_CLAIM")
+	_ = viper.BindEnv("OIDC_ALLOWED_GROUPS")
+	_ = viper.BindEnv("OIDC_ADMIN_GROUPS")
+	_ = viper.BindEnv("OIDC_LOGIN_DURATION")
+
+	viper.SetDefault("GRPC_SERVER_ADDRESS", "0.0.0.0:9090")
+	viper.SetDefault("METRICS_SERVER_ADDRESS", "0.0.0.0:9100")
+	viper.SetDefault("HTTP_READ_TIMEOUT", 15*time.Second)
+	viper.SetDefault("HTTP_WRITE_TIMEOUT", 30*time.Second)
+	viper.SetDefault("HTTP_IDLE_TIMEOUT", time.Minute)
+	viper.SetDefault("SHUTDOWN_DRAIN_PERIOD", 5*time.Second)
+	viper.SetDefault("SHUTDOWN_TIMEOUT", 30*time.Second)
+	viper.SetDefault("LOG_FORMAT", "text")
+	viper.SetDefault("LOG_LEVEL", "info")
+	viper.SetDefault("TRACE_EXPORTER", "none")
+	viper.SetDefault("ACCOUNT_NUMBER_COUNTRY", DefaultAccountNumberCountry)
+	viper.SetDefault("BALANCE_SNAPSHOT_JOB_INTERVAL", time.Hour)
+	viper.SetDefault("STATEMENT_JOB_INTERVAL", time.Hour)
+	viper.SetDefault("CLOSED_PERIOD_LAG", time.Hour)
+	viper.SetDefault("RECONCILIATION_JOB_INTERVAL", 6*time.Hour)
+	viper.SetDefault("MAILER", "log")
+	viper.SetDefault("SMTP_PORT", 587)
+	viper.SetDefault("EMAIL_SENDER_NAME", "Simple Bank")
+	viper.SetDefault("EMAIL_SENDER_ADDRESS", "no-reply@simplebank.local")
+	viper.SetDefault("VERIFY_EMAIL_URL", "http://localhost:8080/verify_email")
+	viper.SetDefault("VERIFY_EMAIL_DURATION", 24*time.Hour)
+	viper.SetDefault("PASSWORD_RESET_URL", "http://localhost:8080/reset_password")
+	viper.SetDefault("PASSWORD_RESET_DURATION", 15*time.Minute)
+	viper.SetDefault("TOTP_ISSUER", "Simple Bank")
+	viper.SetDefault("TOTP_CHALLENGE_DURATION", 5*time.Minute)
+	viper.SetDefault("LOGIN_USER_FREE_ATTEMPTS", 5)
+	viper.SetDefault("LOGIN_IP_FREE_ATTEMPTS", 20)
+	viper.SetDefault("LOGIN_BACKOFF_BASE", time.Second)
+	viper.SetDefault("LOGIN_LOCKOUT_DURATION", 15*time.Minute)
+	viper.SetDefault("LOGIN_FAILURE_WINDOW", 24*time.Hour)
+	viper.SetDefault("RATE_LIMIT_BACKEND", "memory")
+	viper.SetDefault("RATE_LIMIT_PUBLIC", "60/m")
+	viper.SetDefault("RATE_LIMIT_LOGIN", "10/m")
+	viper.SetDefault("RATE_LIMIT_IP", "600/m")
+	viper.SetDefault("RATE_LIMIT_USER", "300/m")
+	viper.SetDefault("RATE_LIMIT_TRANSFER", "30/m")
+	viper.SetDefault("PASSWORD_HASH_ALGORITHM", PasswordHashArgon2id)
+	viper.SetDefault("PASSWORD_MIN_LENGTH", 8)
+	viper.SetDefault("API_KEY_MAX_DURATION", 365*24*time.Hour)
+	viper.SetDefault("OAUTH_CODE_DURATION", 5*time.Minute)
+	viper.SetDefault("OIDC_GROUPS_CLAIM", "groups")
+	viper.SetDefault("OIDC_LOGIN_DURATION", 10*time.Minute)
 
 	err = viper.ReadInConfig()
 
@@ -1,5 +1,10 @@
 package util
 
+import (
+	"fmt"
+	"slices"
+)
+
 const (
 	USD = "USD"
 	EUR = "EUR"
@@ -7,10 +12,19 @@ const (
 	NGN = "NGN"
 )
 
+// SupportedCurrencies lists the currencies accounts can be opened in
+var SupportedCurrencies = []string{USD, EUR, CAD, NGN}
+
 func IsSupportedCurrency(currency string) bool {
-	switch currency {
-	case USD, EUR, CAD, NGN:
-		return true
+	return slices.Contains(SupportedCurrencies, currency)
+}
+
+// FormatAmount formats an amount in minor units (e.g. cents) as a decimal string
+func FormatAmount(amount int64) string {
+	sign := ""
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
 	}
-	return false
+	return fmt.Sprintf("%s%d.%02d", sign, amount/100, amount%100)
 }
@@ -1,25 +1,230 @@
 package util
 
 import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
 	"fmt"
+	"strings"
 
+	"golang.org/x/crypto/argon2"
 	"golang.org/x/crypto/bcrypt"
 )
 
-// HashPassword returns the bcrypt hash of the password string
+// Password hashing algorithms
+const (
+	PasswordHashArgon2id = "argon2id"
+	PasswordHashBcrypt   = "bcrypt"
+)
+
+// bcryptMaxPasswordBytes is the longest password bcrypt hashes
+const bcryptMaxPasswordBytes = 72
+
+var (
+	ErrPasswordMismatch        = errors.New("password does not match")
+	ErrUnsupportedPasswordHash = errors.New("unsupported password hash format")
+)
+
+// PasswordHasher hashes new passwords with one configured algorithm and
+// verifies hashes made with any supported algorithm, so the algorithm or its
+// parameters can change without locking out existing users.
+type PasswordHasher interface {
+	// Hash returns the encoded hash of the password, including the algorithm and its parameters
+	Hash(password string) (string, error)
+	// Check returns ErrPasswordMismatch when the password doesn't match the hash
+	Check(password, hashedPassword string) error
+	// NeedsRehash reports whether the hash was made with another algorithm or
+	// other parameters than Hash uses now
+	NeedsRehash(hashedPassword string) bool
+}
+
+// Argon2idParams are the argon2id cost parameters. Zero fields take the
+// defaults recommended by OWASP.
+type Argon2idParams struct {
+	// Memory in KiB
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+}
+
+const (
+	defaultArgon2idMemory      = 19 * 1024
+	defaultArgon2idIterations  = 2
+	defaultArgon2idParallelism = 1
+
+	argon2idSaltLength = 16
+	argon2idKeyLength  = 32
+)
+
+func (params Argon2idParams) withDefaults() Argon2idParams {
+	if params.Memory == 0 {
+		params.Memory = defaultArgon2idMemory
+	}
+	if params.Iterations == 0 {
+		params.Iterations = defaultArgon2idIterations
+	}
+	if params.Parallelism == 0 {
+		params.Parallelism = defaultArgon2idParallelism
+	}
+	return params
+}
+
+type passwordHasher struct {
+	algorithm  string
+	argon2id   Argon2idParams
+	bcryptCost int
+}
+
+// NewPasswordHasher returns a hasher that hashes with the given algorithm,
+// argon2id by default. A zero bcrypt cost means bcrypt.DefaultCost.
+func NewPasswordHasher(algorithm string, argon2id Argon2idParams, bcryptCost int) (PasswordHasher, error) {
+	if algorithm == "" {
+		algorithm = PasswordHashArgon2id
+	}
+	if algorithm != PasswordHashArgon2id && algorithm != PasswordHashBcrypt {
+		return nil, fmt.Errorf("unknown password hash algorithm %q", algorithm)
+	}
+
+	if bcryptCost == 0 {
+		bcryptCost = bcrypt.DefaultCost
+	}
+	if bcryptCost < bcrypt.MinCost || bcryptCost > bcrypt.MaxCost {
+		return nil, fmt.Errorf("invalid bcrypt cost %d", bcryptCost)
+	}
+
+	return &passwordHasher{
+		algorithm:  algorithm,
+		argon2id:   argon2id.withDefaults(),
+		bcryptCost: bcryptCost,
+	}, nil
+}
+
+// DefaultPasswordHasher hashes with argon2id using the default parameters
+var DefaultPasswordHasher PasswordHasher = &passwordHasher{
+	algorithm:  PasswordHashArgon2id,
+	argon2id:   Argon2idParams{}.withDefaults(),
+	bcryptCost: bcrypt.DefaultCost,
+}
+
+// HashPassword hashes the password with DefaultPasswordHasher
 func HashPassword(password string) (string, error) {
-	hashedPassword, err := bcrypt.GenerateFromPassword(
-		[]byte(password), bcrypt.DefaultCost,
+	return DefaultPasswordHasher.Hash(password)
+}
+
+// CheckPassword checks the password against a hash in any supported format
+func CheckPassword(password, hashedPassword string) error {
+	return DefaultPasswordHasher.Check(password, hashedPassword)
+}
+
+func (hasher *passwordHasher) Hash(password string) (string, error) {
+	if hasher.algorithm == PasswordHashBcrypt {
+		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), hasher.bcryptCost)
+		if err != nil {
+			return "", fmt.Errorf("failed to hash password: %w", err)
+		}
+		return string(hashedPassword), nil
+	}
+
+	salt := make([]byte, argon2idSaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	hash := hasher.argon2id
+	key := argon2.IDKey([]byte(password), salt, hash.Iterations, hash.Memory, hash.Parallelism, argon2idKeyLength)
+	return encodeArgon2id(hash, salt, key), nil
+}
+
+func (hasher *passwordHasher) Check(password, hashedPassword string) error {
+	if isBcryptHash(hashedPassword) {
+		err := bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return ErrPasswordMismatch
+		}
+		return err
+	}
+
+	params, salt, key, err := decodeArgon2id(hashedPassword)
+	if err != nil {
+		return err
+	}
+
+	otherKey := argon2.IDKey([]byte(password), salt, params.Iterations, params.Memory, params.Parallelism, uint32(len(key)))
+	if subtle.ConstantTimeCompare(key, otherKey) != 1 {
+		return ErrPasswordMismatch
+	}
+	return nil
+}
+
+func (hasher *passwordHasher) NeedsRehash(hashedPassword string) bool {
+	if hasher.algorithm == PasswordHashBcrypt {
+		cost, err := bcrypt.Cost([]byte(hashedPassword))
+		return err != nil || cost != hasher.bcryptCost
+	}
+
+	params, _, key, err := decodeArgon2id(hashedPassword)
+	return err != nil || params != hasher.argon2id || len(key) != argon2idKeyLength
+}
+
+func isBcryptHash(hashedPassword string) bool {
+	return strings.HasPrefix(hashedPassword, "$2a$") ||
+		strings.HasPrefix(hashedPassword, "$2b$") ||
+		strings.HasPrefix(hashedPassword, "$2y$")
+}
+
+// encodeArgon2id encodes a hash in the PHC string format also used by the
+// reference implementation: $argon2id$v=19$m=19456,t=2,p=1$<salt>$<key>
+func encodeArgon2id(params Argon2idParams, salt, key []byte) string {
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, params.Memory, params.Iterations, params.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
 	)
+}
+
+func decodeArgon2id(hashedPassword string) (params Argon2idParams, salt, key []byte, err error) {
+	parts := strings.Split(hashedPassword, "$")
+	if len(parts) != 6 || parts[0] != "" || parts[1] != PasswordHashArgon2id {
+		err = ErrUnsupportedPasswordHash
+		return
+	}
+
+	var version int
+	if _, err = fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		err = ErrUnsupportedPasswordHash
+		return
+	}
+
+	if _, err = fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Iterations, &params.Parallelism); err != nil {
+		err = ErrUnsupportedPasswordHash
+		return
+	}
+
+	salt, err = base64.RawStdEncoding.DecodeString(parts[4])
 	if err != nil {
-		return "", fmt.Errorf("failed to hash password: %w", err)
+		err = ErrUnsupportedPasswordHash
+		return
 	}
-	return string(hashedPassword), err
+
+	key, err = base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		err = ErrUnsupportedPasswordHash
+		return
+	}
+
+	return
 }
 
-// CheckPassword checks if the provided password is correct or not
-func CheckPassword(password, hashedPassword string) error {
-	return bcrypt.CompareHashAndPassword(
-		[]byte(hashedPassword), []byte(password),
+// NewConfiguredPasswordHasher returns the hasher configured by config
+func NewConfiguredPasswordHasher(config Config) (PasswordHasher, error) {
+	return NewPasswordHasher(
+		config.PasswordHashAlgorithm,
+		Argon2idParams{
+			Memory:      config.Argon2Memory,
+			Iterations:  config.Argon2Iterations,
+			Parallelism: config.Argon2Parallelism,
+		},
+		config.BcryptCost,
 	)
 }
@@ -0,0 +1,121 @@
+package util
+
+import (
+	"bufio"
+	_ "embed"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	defaultPasswordMinLength = 8
+	defaultPasswordMaxLength = 128
+)
+
+var ErrPasswordBreached = errors.New("password appears in a list of breached passwords")
+
+//go:embed breached_passwords.txt
+var breachedPasswords string
+
+// PasswordPolicy decides which new passwords are acceptable
+type PasswordPolicy struct {
+	minLength int
+	maxLength int
+	// maxBytes limits the encoded length when it is not zero
+	maxBytes int
+	breached map[string]struct{}
+}
+
+// NewPasswordPolicy returns a policy enforcing the length limits, where zero
+// means the default, and rejecting frequently breached passwords: a built-in
+// list plus, when breachedFile is set, the passwords in that file.
+func NewPasswordPolicy(minLength, maxLength int, breachedFile string) (*PasswordPolicy, error) {
+	if minLength == 0 {
+		minLength = defaultPasswordMinLength
+	}
+	if maxLength == 0 {
+		maxLength = defaultPasswordMaxLength
+	}
+	if minLength < 1 || maxLength < minLength {
+		return nil, fmt.Errorf("invalid password length limits %d-%d", minLength, maxLength)
+	}
+
+	policy := &PasswordPolicy{
+		minLength: minLength,
+		maxLength: maxLength,
+		breached:  make(map[string]struct{}),
+	}
+
+	if err := policy.addBreached(strings.NewReader(breachedPasswords)); err != nil {
+		return nil, err
+	}
+
+	if breachedFile != "" {
+		file, err := os.Open(breachedFile)
+		if err != nil {
+			return nil, fmt.Errorf("cannot open breached passwords: %w", err)
+		}
+		defer file.Close()
+
+		if err := policy.addBreached(file); err != nil {
+			return nil, fmt.Errorf("cannot read breached passwords: %w", err)
+		}
+	}
+
+	return policy, nil
+}
+
+func (policy *PasswordPolicy) addBreached(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		policy.breached[strings.ToLower(line)] = struct{}{}
+	}
+	return scanner.Err()
+}
+
+// Check returns an error describing why the password is not acceptable
+func (policy *PasswordPolicy) Check(password string) error {
+	length := utf8.RuneCountInString(password)
+	if length < policy.minLength {
+		return fmt.Errorf("password must be at least %d characters", policy.minLength)
+	}
+	if length > policy.maxLength {
+		return fmt.Errorf("password must be at most %d characters", policy.maxLength)
+	}
+	if policy.maxBytes > 0 && len(password) > policy.maxBytes {
+		return fmt.Errorf("password must be at most %d bytes", policy.maxBytes)
+	}
+
+	if _, ok := policy.breached[strings.ToLower(password)]; ok {
+		return ErrPasswordBreached
+	}
+
+	return nil
+}
+
+// NewConfiguredPasswordPolicy returns the policy configured by config. When
+// new passwords are hashed with bcrypt, which refuses passwords longer than
+// 72 bytes, the maximum length is capped to that.
+func NewConfiguredPasswordPolicy(config Config) (*PasswordPolicy, error) {
+	policy, err := NewPasswordPolicy(config.PasswordMinLength, config.PasswordMaxLength, config.BreachedPasswordsFile)
+	if err != nil {
+		return nil, err
+	}
+
+	if config.PasswordHashAlgorithm == PasswordHashBcrypt {
+		if policy.minLength > bcryptMaxPasswordBytes {
+			return nil, fmt.Errorf("invalid password min length %d: bcrypt hashes at most %d bytes", policy.minLength, bcryptMaxPasswordBytes)
+		}
+		policy.maxLength = min(policy.maxLength, bcryptMaxPasswordBytes)
+		policy.maxBytes = bcryptMaxPasswordBytes
+	}
+	return policy, nil
+}
@@ -1,6 +1,9 @@
 package util
 
 import (
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -13,16 +16,140 @@ func TestHashPassword(t *testing.T) {
 	hashedPassword1, err := HashPassword(password)
 	require.NoError(t, err)
 	require.NotEmpty(t, hashedPassword1)
+	require.True(t, strings.HasPrefix(hashedPassword1, "$argon2id$v=19$m=19456,t=2,p=1$"))
 
 	err = CheckPassword(password, hashedPassword1)
 	require.NoError(t, err)
 
 	wrongPassword := RandomString(10)
 	err = CheckPassword(wrongPassword, hashedPassword1)
-	require.EqualError(t, err, bcrypt.ErrMismatchedHashAndPassword.Error())
+	require.ErrorIs(t, err, ErrPasswordMismatch)
 
 	hashedPassword2, err := HashPassword(password)
 	require.NoError(t, err)
 	require.NotEmpty(t, hashedPassword1)
 	require.NotEqual(t, hashedPassword1, hashedPassword2)
 }
+
+func TestPasswordHasherBcrypt(t *testing.T) {
+	hasher, err := NewPasswordHasher(PasswordHashBcrypt, Argon2idParams{}, bcrypt.MinCost)
+	require.NoError(t, err)
+
+	password := RandomString(12)
+	hashedPassword, err := hasher.Hash(password)
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(hashedPassword, "$2a$"))
+
+	require.NoError(t, hasher.Check(password, hashedPassword))
+	require.ErrorIs(t, hasher.Check(RandomString(12), hashedPassword), ErrPasswordMismatch)
+	require.False(t, hasher.NeedsRehash(hashedPassword))
+
+	// bcrypt hashes stay valid after switching to argon2id, but get upgraded
+	require.NoError(t, CheckPassword(password, hashedPassword))
+	require.True(t, DefaultPasswordHasher.NeedsRehash(hashedPassword))
+
+	argon2idHash, err := HashPassword(password)
+	require.NoError(t, err)
+	require.NoError(t, hasher.Check(password, argon2idHash))
+	require.True(t, hasher.NeedsRehash(argon2idHash))
+
+	otherCost, err := NewPasswordHasher(PasswordHashBcrypt, Argon2idParams{}, bcrypt.MinCost+1)
+	require.NoError(t, err)
+	require.True(t, otherCost.NeedsRehash(hashedPassword))
+}
+
+func TestPasswordHasherArgon2idParams(t *testing.T) {
+	params := Argon2idParams{Memory: 8 * 1024, Iterations: 1, Parallelism: 2}
+	hasher, err := NewPasswordHasher(PasswordHashArgon2id, params, 0)
+	require.NoError(t, err)
+
+	password := RandomString(12)
+	hashedPassword, err := hasher.Hash(password)
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(hashedPassword, "$argon2id$v=19$m=8192,t=1,p=2$"))
+	require.False(t, hasher.NeedsRehash(hashedPassword))
+
+	// the parameters are read from the hash, so any hasher can check it
+	require.NoError(t, CheckPassword(password, hashedPassword))
+	require.True(t, DefaultPasswordHasher.NeedsRehash(hashedPassword))
+}
+
+func TestPasswordHasherUnsupported(t *testing.T) {
+	for _, hashedPassword := range []string{
+		"",
+		"plaintext",
+		"$argon2i$v=19$m=19456,t=2,p=1$c2FsdHNhbHQ$a2V5",
+		"$argon2id$v=16$m=19456,t=2,p=1$c2FsdHNhbHQ$a2V5",
+		"$argon2id$v=19$m=19456,t=2$c2FsdHNhbHQ$a2V5",
+		"$argon2id$v=19$m=19456,t=2,p=1$not base64!$a2V5",
+		"$argon2id$v=19$m=19456,t=2,p=1$c2FsdHNhbHQ$",
+	} {
+		require.ErrorIs(t, CheckPassword("password", hashedPassword), ErrUnsupportedPasswordHash, hashedPassword)
+		require.True(t, DefaultPasswordHasher.NeedsRehash(hashedPassword), hashedPassword)
+	}
+
+	_, err := NewPasswordHasher("md5", Argon2idParams{}, 0)
+	require.Error(t, err)
+
+	_, err = NewPasswordHasher(PasswordHashBcrypt, Argon2idParams{}, bcrypt.MaxCost+1)
+	require.Error(t, err)
+}
+
+func TestPasswordPolicy(t *testing.T) {
+	policy, err := NewPasswordPolicy(0, 0, "")
+	require.NoError(t, err)
+
+	require.NoError(t, policy.Check(RandomString(8)))
+	require.Error(t, policy.Check(RandomString(7)))
+	require.NoError(t, policy.Check(strings.Repeat("é", 128)))
+	require.Error(t, policy.Check(RandomString(129)))
+
+	require.ErrorIs(t, policy.Check("password"), ErrPasswordBreached)
+	require.ErrorIs(t, policy.Check("PassWord1"), ErrPasswordBreached)
+
+	_, err = NewPasswordPolicy(10, 5, "")
+	require.Error(t, err)
+}
+
+func TestConfiguredPasswordPolicyBcrypt(t *testing.T) {
+	policy, err := NewConfiguredPasswordPolicy(Config{PasswordHashAlgorithm: PasswordHashBcrypt})
+	require.NoError(t, err)
+
+	hasher, err := NewPasswordHasher(PasswordHashBcrypt, Argon2idParams{}, bcrypt.MinCost)
+	require.NoError(t, err)
+
+	// every password the policy accepts can be hashed
+	for _, password := range []string{RandomString(72), strings.Repeat("é", 36)} {
+		require.NoError(t, policy.Check(password))
+		_, err := hasher.Hash(password)
+		require.NoError(t, err)
+	}
+	require.Error(t, policy.Check(RandomString(73)))
+	require.Error(t, policy.Check(strings.Repeat("é", 37)))
+
+	_, err = NewConfiguredPasswordPolicy(Config{PasswordHashAlgorithm: PasswordHashBcrypt, PasswordMinLength: 80, PasswordMaxLength: 100})
+	require.Error(t, err)
+
+	// argon2id hashes passwords of any length
+	policy, err = NewConfiguredPasswordPolicy(Config{})
+	require.NoError(t, err)
+	require.NoError(t, policy.Check(strings.Repeat("é", 128)))
+}
+
+func TestPasswordPolicyBreachedFile(t *testing.T) {
+	breached := RandomString(12)
+	file := filepath.Join(t.TempDir(), "breached.txt")
+	err := os.WriteFile(file, []byte("# company passwords\n\n"+breached+"\n"), 0o600)
+	require.NoError(t, err)
+
+	policy, err := NewPasswordPolicy(12, 0, file)
+	require.NoError(t, err)
+
+	require.ErrorIs(t, policy.Check(strings.ToUpper(breached)), ErrPasswordBreached)
+	require.ErrorIs(t, policy.Check("password1234"), ErrPasswordBreached)
+	require.Error(t, policy.Check(RandomString(11)))
+	require.NoError(t, policy.Check(RandomString(12)))
+
+	_, err = NewPasswordPolicy(0, 0, filepath.Join(t.TempDir(), "missing.txt"))
+	require.Error(t, err)
+}
@@ -55,3 +55,9 @@ func RandomCurrency() string {
 	n := len(currencies)
 	return currencies[rand.Int63n(int64(n))]
 }
+
+// RandomAccountNumber generates a random account number with the default country prefix.
+func RandomAccountNumber() string {
+	accountNumber, _ := GenerateAccountNumber(DefaultAccountNumberCountry)
+	return accountNumber
+}
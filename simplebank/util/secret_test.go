@@ -0,0 +1,17 @@
+package util
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateSecret(t *testing.T) {
+	secret1, err := GenerateSecret(32)
+	require.NoError(t, err)
+	require.Len(t, secret1, 64)
+
+	secret2, err := GenerateSecret(32)
+	require.NoError(t, err)
+	require.NotEqual(t, secret1, secret2)
+}
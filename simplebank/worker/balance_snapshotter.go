@@ -0,0 +1,56 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	db "github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/db/sqlc"
+)
+
+// BalanceSnapshotter records the end of day balance of every account, which
+// keeps point-in-time balance queries fast for accounts with long histories.
+//
+// Entries are dated when their transaction starts, so an entry dated before
+// midnight may only become visible after it. A day is therefore snapshotted
+// once it has been over for lag, which must be longer than any transfer
+// transaction can take; a snapshot is never rebuilt.
+type BalanceSnapshotter struct {
+	store    db.Store
+	interval time.Duration
+	lag      time.Duration
+}
+
+func NewBalanceSnapshotter(store db.Store, interval, lag time.Duration) *BalanceSnapshotter {
+	return &BalanceSnapshotter{
+		store:    store,
+		interval: interval,
+		lag:      lag,
+	}
+}
+
+// Start snapshots the latest UTC day that has been over for the lag right
+// away and then again on every interval until the context is cancelled. Days
+// that are already snapshotted are left untouched.
+func (snapshotter *BalanceSnapshotter) Start(ctx context.Context) {
+	runEvery(ctx, snapshotter.interval, func(ctx context.Context) {
+		day := time.Now().UTC().Add(-snapshotter.lag).AddDate(0, 0, -1)
+		if _, err := snapshotter.SnapshotDay(ctx, day); err != nil && ctx.Err() == nil {
+			slog.ErrorContext(ctx, "cannot snapshot account balances", "error", err)
+		}
+	})
+}
+
+// SnapshotDay snapshots the balances at the end of the UTC day containing t,
+// which must have been over for the lag, and returns the number of snapshots
+// created.
+func (snapshotter *BalanceSnapshotter) SnapshotDay(ctx context.Context, t time.Time) (int64, error) {
+	t = t.UTC()
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	if !day.AddDate(0, 0, 1).Add(snapshotter.lag).Before(time.Now()) {
+		return 0, fmt.Errorf("cannot snapshot %s until it has been over for %s", day.Format("2006-01-02"), snapshotter.lag)
+	}
+
+	return snapshotter.store.CreateBalanceSnapshots(ctx, day)
+}
@@ -0,0 +1,58 @@
+package worker
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	mockdb "github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/db/mock"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSnapshotDay(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+
+	day := time.Date(2026, time.March, 31, 0, 0, 0, 0, time.UTC)
+	store.EXPECT().
+		CreateBalanceSnapshots(gomock.Any(), gomock.Eq(day)).
+		Times(1).
+		Return(int64(3), nil)
+
+	snapshotter := NewBalanceSnapshotter(store, time.Hour, time.Hour)
+	n, err := snapshotter.SnapshotDay(context.Background(), day.Add(15*time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, int64(3), n)
+}
+
+func TestSnapshotDayNotOver(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		CreateBalanceSnapshots(gomock.Any(), gomock.Any()).
+		Times(0)
+
+	snapshotter := NewBalanceSnapshotter(store, time.Hour, time.Hour)
+	_, err := snapshotter.SnapshotDay(context.Background(), time.Now())
+	require.Error(t, err)
+}
+
+func TestSnapshotDayWithinLag(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		CreateBalanceSnapshots(gomock.Any(), gomock.Any()).
+		Times(0)
+
+	// yesterday is over, but entries dated yesterday may still be committing
+	snapshotter := NewBalanceSnapshotter(store, time.Hour, 48*time.Hour)
+	_, err := snapshotter.SnapshotDay(context.Background(), time.Now().AddDate(0, 0, -1))
+	require.Error(t, err)
+}
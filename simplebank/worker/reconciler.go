@@ -0,0 +1,52 @@
+package worker
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	db "github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/db/sqlc"
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/reconcile"
+)
+
+// Reconciler periodically checks the ledger invariants and logs a report
+// whenever they are broken, e.g. by balance updates that bypass the entries.
+type Reconciler struct {
+	store    db.Store
+	interval time.Duration
+}
+
+func NewReconciler(store db.Store, interval time.Duration) *Reconciler {
+	return &Reconciler{
+		store:    store,
+		interval: interval,
+	}
+}
+
+// Start reconciles the ledger right away and then again on every interval
+// until the context is cancelled.
+func (reconciler *Reconciler) Start(ctx context.Context) {
+	runEvery(ctx, reconciler.interval, func(ctx context.Context) {
+		if _, err := reconciler.Reconcile(ctx); err != nil && ctx.Err() == nil {
+			slog.ErrorContext(ctx, "cannot reconcile ledger", "error", err)
+		}
+	})
+}
+
+// Reconcile runs a single reconciliation and logs its report when it finds
+// any discrepancies.
+func (reconciler *Reconciler) Reconcile(ctx context.Context) (*reconcile.Report, error) {
+	report, err := reconcile.Run(ctx, reconciler.store)
+	if err != nil {
+		return nil, err
+	}
+
+	if !report.OK {
+		slog.WarnContext(ctx, "ledger reconciliation found discrepancies",
+			"count", len(report.Discrepancies),
+			"report", report,
+		)
+	}
+
+	return report, nil
+}
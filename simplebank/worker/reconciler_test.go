@@ -0,0 +1,33 @@
+package worker
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	mockdb "github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/db/mock"
+	db "github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/db/sqlc"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReconcile(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		ReconciliationTx(gomock.Any()).
+		Times(1).
+		Return(db.ReconciliationTxResult{
+			BalanceMismatches: []db.ListAccountBalanceMismatchesRow{
+				{AccountID: 1, Currency: "USD", Balance: 20, EntriesTotal: 10},
+			},
+		}, nil)
+
+	reconciler := NewReconciler(store, time.Hour)
+	report, err := reconciler.Reconcile(context.Background())
+	require.NoError(t, err)
+	require.False(t, report.OK)
+	require.Len(t, report.Discrepancies, 1)
+}
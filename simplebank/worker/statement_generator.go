@@ -0,0 +1,120 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	db "github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/db/sqlc"
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/statement"
+	"github.com/jackc/pgx/v5"
+)
+
+const accountsPageSize = 100
+
+// StatementGenerator stores the monthly statement of every account, which is
+// served when a whole month is downloaded instead of being built again.
+//
+// Like balance snapshots, a month is only generated once it has been over
+// for lag, so every entry dated in it has committed; a stored statement is
+// never rebuilt.
+type StatementGenerator struct {
+	store    db.Store
+	interval time.Duration
+	lag      time.Duration
+}
+
+func NewStatementGenerator(store db.Store, interval, lag time.Duration) *StatementGenerator {
+	return &StatementGenerator{
+		store:    store,
+		interval: interval,
+		lag:      lag,
+	}
+}
+
+// Start generates the statements of the latest UTC month that has been over
+// for the lag right away and then again on every interval until the context
+// is cancelled. Accounts that already have the statement are skipped.
+func (generator *StatementGenerator) Start(ctx context.Context) {
+	runEvery(ctx, generator.interval, func(ctx context.Context) {
+		thisMonth, _ := statement.Month(time.Now().Add(-generator.lag))
+		if _, err := generator.GenerateMonth(ctx, thisMonth.AddDate(0, -1, 0)); err != nil && ctx.Err() == nil {
+			slog.ErrorContext(ctx, "cannot generate monthly statements", "error", err)
+		}
+	})
+}
+
+// GenerateMonth stores the statement of every account for the UTC month
+// containing t, which must have been over for the lag, and returns the
+// number of statements created.
+func (generator *StatementGenerator) GenerateMonth(ctx context.Context, t time.Time) (int64, error) {
+	from, to := statement.Month(t)
+	if !to.Add(generator.lag).Before(time.Now()) {
+		return 0, fmt.Errorf("cannot generate statements of %s until it has been over for %s", from.Format("2006-01"), generator.lag)
+	}
+
+	var created int64
+	for page := int32(0); ; page++ {
+		accounts, err := generator.store.ListAccounts(ctx, db.ListAccountsParams{
+			Limit:  accountsPageSize,
+			Offset: page * accountsPageSize,
+		})
+		if err != nil {
+			return created, fmt.Errorf("cannot list accounts: %w", err)
+		}
+
+		for _, account := range accounts {
+			// accounts opened after the month have nothing to state
+			if !account.CreatedAt.Before(to) {
+				continue
+			}
+
+			n, err := generator.generateStatement(ctx, account.ID, from, to)
+			if err != nil {
+				return created, fmt.Errorf("cannot generate statement for account %d: %w", account.ID, err)
+			}
+			created += n
+		}
+
+		if len(accounts) < accountsPageSize {
+			return created, nil
+		}
+	}
+}
+
+func (generator *StatementGenerator) generateStatement(ctx context.Context, accountID int64, from, to time.Time) (int64, error) {
+	_, err := generator.store.GetStatement(ctx, db.GetStatementParams{
+		AccountID:   accountID,
+		PeriodStart: from,
+		PeriodEnd:   to,
+	})
+	if err == nil {
+		return 0, nil
+	}
+	if err != pgx.ErrNoRows {
+		return 0, err
+	}
+
+	result, err := generator.store.StatementTx(ctx, db.StatementTxParams{
+		AccountID: accountID,
+		From:      from,
+		To:        to,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	content, err := json.Marshal(statement.New(result.Account, from, to, result.OpeningBalance, result.Entries))
+	if err != nil {
+		return 0, err
+	}
+
+	return generator.store.CreateStatement(ctx, db.CreateStatementParams{
+		AccountID:   accountID,
+		PeriodStart: from,
+		PeriodEnd:   to,
+		Content:     content,
+	})
+}
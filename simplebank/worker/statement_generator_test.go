@@ -0,0 +1,176 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	mockdb "github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/db/mock"
+	db "github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/db/sqlc"
+	"github.com/UcGeorge/Upskill/BackendMasterClass/simplebank/statement"
+	"github.com/golang/mock/gomock"
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/require"
+)
+
+type eqCreateStatementParamsMatcher struct {
+	arg            db.CreateStatementParams
+	closingBalance int64
+}
+
+func (expected eqCreateStatementParamsMatcher) Matches(x any) bool {
+	arg, ok := x.(db.CreateStatementParams)
+	if !ok {
+		return false
+	}
+
+	var stmt statement.Statement
+	if err := json.Unmarshal(arg.Content, &stmt); err != nil {
+		return false
+	}
+
+	return arg.AccountID == expected.arg.AccountID &&
+		arg.PeriodStart.Equal(expected.arg.PeriodStart) &&
+		arg.PeriodEnd.Equal(expected.arg.PeriodEnd) &&
+		stmt.ClosingBalance == expected.closingBalance
+}
+
+func (expected eqCreateStatementParamsMatcher) String() string {
+	return fmt.Sprintf("matches arg %v and closing balance %d", expected.arg, expected.closingBalance)
+}
+
+func EqCreateStatementParams(arg db.CreateStatementParams, closingBalance int64) gomock.Matcher {
+	return eqCreateStatementParamsMatcher{arg, closingBalance}
+}
+
+func TestGenerateMonth(t *testing.T) {
+	from := time.Date(2026, time.February, 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 1, 0)
+
+	account := db.Account{ID: 1, CreatedAt: from.AddDate(0, -1, 0)}
+	stored := db.Account{ID: 2, CreatedAt: from.AddDate(0, -1, 0)}
+	opened := db.Account{ID: 3, CreatedAt: to.Add(time.Minute)}
+
+	entries := []db.Entry{
+		{ID: 1, AccountID: account.ID, Amount: 50, CreatedAt: from.Add(time.Hour)},
+		{ID: 2, AccountID: account.ID, Amount: -20, CreatedAt: from.Add(2 * time.Hour)},
+	}
+
+	testCases := []struct {
+		name          string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, n int64, err error)
+	}{
+		{
+			name: "OK",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ListAccounts(gomock.Any(), gomock.Eq(db.ListAccountsParams{Limit: accountsPageSize, Offset: 0})).
+					Times(1).
+					Return([]db.Account{account, stored, opened}, nil)
+
+				store.EXPECT().
+					GetStatement(gomock.Any(), gomock.Eq(db.GetStatementParams{AccountID: account.ID, PeriodStart: from, PeriodEnd: to})).
+					Times(1).
+					Return(db.Statement{}, pgx.ErrNoRows)
+				store.EXPECT().
+					StatementTx(gomock.Any(), gomock.Eq(db.StatementTxParams{AccountID: account.ID, From: from, To: to})).
+					Times(1).
+					Return(db.StatementTxResult{Account: account, OpeningBalance: 100, Entries: entries}, nil)
+				store.EXPECT().
+					CreateStatement(gomock.Any(), EqCreateStatementParams(db.CreateStatementParams{AccountID: account.ID, PeriodStart: from, PeriodEnd: to}, 130)).
+					Times(1).
+					Return(int64(1), nil)
+
+				// the statement of this account is already stored
+				store.EXPECT().
+					GetStatement(gomock.Any(), gomock.Eq(db.GetStatementParams{AccountID: stored.ID, PeriodStart: from, PeriodEnd: to})).
+					Times(1).
+					Return(db.Statement{ID: 1, AccountID: stored.ID}, nil)
+				store.EXPECT().
+					StatementTx(gomock.Any(), gomock.Eq(db.StatementTxParams{AccountID: stored.ID, From: from, To: to})).
+					Times(0)
+
+				// this account was opened after the month
+				store.EXPECT().
+					GetStatement(gomock.Any(), gomock.Eq(db.GetStatementParams{AccountID: opened.ID, PeriodStart: from, PeriodEnd: to})).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, n int64, err error) {
+				require.NoError(t, err)
+				require.Equal(t, int64(1), n)
+			},
+		},
+		{
+			name: "ListAccountsError",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ListAccounts(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(nil, pgx.ErrTxClosed)
+				store.EXPECT().
+					CreateStatement(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, n int64, err error) {
+				require.ErrorIs(t, err, pgx.ErrTxClosed)
+				require.Zero(t, n)
+			},
+		},
+		{
+			name: "StatementTxError",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ListAccounts(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return([]db.Account{account}, nil)
+				store.EXPECT().
+					GetStatement(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.Statement{}, pgx.ErrNoRows)
+				store.EXPECT().
+					StatementTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.StatementTxResult{}, errors.New("statement failed"))
+				store.EXPECT().
+					CreateStatement(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, n int64, err error) {
+				require.Error(t, err)
+				require.Zero(t, n)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			generator := NewStatementGenerator(store, time.Hour, time.Hour)
+			n, err := generator.GenerateMonth(context.Background(), from.AddDate(0, 0, 14))
+			tc.checkResponse(t, n, err)
+		})
+	}
+}
+
+func TestGenerateMonthNotOver(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		ListAccounts(gomock.Any(), gomock.Any()).
+		Times(0)
+
+	generator := NewStatementGenerator(store, time.Hour, time.Hour)
+	_, err := generator.GenerateMonth(context.Background(), time.Now())
+	require.Error(t, err)
+}
@@ -0,0 +1,23 @@
+package worker
+
+import (
+	"context"
+	"time"
+)
+
+// runEvery calls fn right away and then once per interval until the context is cancelled.
+// A run cut short by the cancellation on shutdown is not logged as failed.
+func runEvery(ctx context.Context, interval time.Duration, fn func(ctx context.Context)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		fn(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}